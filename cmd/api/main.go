@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
 	"strings"
@@ -11,12 +12,17 @@ import (
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/config"
+	"github.com/Adhiana46/echo-boilerplate/database/seeds"
 	cachePkg "github.com/Adhiana46/echo-boilerplate/pkg/cache"
 	"github.com/Adhiana46/echo-boilerplate/pkg/logger"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
 	tokenmanager "github.com/Adhiana46/echo-boilerplate/pkg/token-manager"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/Adhiana46/echo-boilerplate/server"
+	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/stdlib"
 	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
 
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
@@ -24,7 +30,8 @@ import (
 
 var (
 	cfg          *config.Config
-	db           *sqlx.DB
+	db           *txmanager.DB
+	mem          *memdb.DB
 	cache        cachePkg.Cache
 	tokenManager *tokenmanager.TokenManager
 )
@@ -40,11 +47,19 @@ func main() {
 
 	logger.Println("[Boot]:", "Config loaded")
 
-	db, err = openDb(cfg.Pg)
-	if err != nil {
-		logger.Panic("[Error][DB]:", err)
+	switch cfg.Db.Driver {
+	case "memory":
+		// seeded once the server declared the permissions
+		mem = memdb.New()
+	case "postgres", "mysql", "sqlite":
+		db, err = openDb(cfg)
+		if err != nil {
+			logger.Panic("[Error][DB]:", err)
+		}
+		defer db.Close()
+	default:
+		logger.Panic("[Error][DB]:", fmt.Sprintf("unknown database driver %q", cfg.Db.Driver))
 	}
-	defer db.Close()
 
 	// cache
 	cache, err = openCache(cfg)
@@ -75,20 +90,35 @@ func run() {
 		cmd = args[0]
 	}
 
+	// the in-memory database is seeded on boot and has nothing to migrate
+	if mem != nil && strings.HasPrefix(cmd, "migrate") {
+		logger.Fatal("[Error][DB]:", fmt.Sprintf("%s needs a SQL driver", cmd))
+	}
+
+	// routes and policies declare their permissions when the server is built,
+	// the seeders create the declared ones
+	srv := server.NewServer(cfg, db, mem, cache, tokenManager)
+
+	if mem != nil {
+		if err := seedMemoryDb(mem); err != nil {
+			logger.Panic("[Error][DB]:", err)
+		}
+	}
+
 	switch cmd {
 	case "migrate":
-		if err := runMigration(db.DB); err != nil {
+		if err := runMigration(db); err != nil {
 			panic(err)
 		}
 	case "migrate:rollback":
-		if err := rollbackMigration(db.DB); err != nil {
+		if err := rollbackMigration(db); err != nil {
 			panic(err)
 		}
 	case "migrate:fresh":
-		if err := rollbackMigration(db.DB); err != nil {
+		if err := rollbackMigration(db); err != nil {
 			panic(err)
 		}
-		if err := runMigration(db.DB); err != nil {
+		if err := runMigration(db); err != nil {
 			panic(err)
 		}
 		if err := runSeeder(db.DB); err != nil {
@@ -102,9 +132,35 @@ func run() {
 		if err := rollbackSeeder(db.DB); err != nil {
 			panic(err)
 		}
+	case "permissions:sync":
+		res, err := srv.SyncPermissions(context.Background())
+		if err != nil {
+			panic(err)
+		}
+
+		logger.Println("[Permission]:", fmt.Sprintf("%d permissions created", len(res.Created)))
+		for _, name := range res.Created {
+			logger.Println("[Permission]:\t-", name)
+		}
+	case "grants:sweep":
+		swept, err := srv.SweepExpiredGrants(context.Background())
+		if err != nil {
+			panic(err)
+		}
+
+		logger.Println("[Grant]:", fmt.Sprintf("%d expired grants removed", swept))
+	case "trash:purge":
+		res, err := srv.PurgeTrash(context.Background())
+		if err != nil {
+			panic(err)
+		}
+
+		logger.Println("[Trash]:", fmt.Sprintf("%d users, %d roles and %d permissions purged", res.Users, res.Roles, res.Permissions))
 	default:
 		// run server
-		srv := server.NewServer(cfg, db, cache, tokenManager)
+		if err := srv.VerifyPermissions(context.Background()); err != nil {
+			logger.Fatal("[Error][Permission]:", err)
+		}
 
 		go func() {
 			if err := srv.Run(); err != nil {
@@ -112,9 +168,13 @@ func run() {
 			}
 		}()
 
+		sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+		go srv.RunGrantSweeper(sweeperCtx)
+
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
+		stopSweeper()
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := srv.Shutdown(ctx); err != nil {
@@ -141,7 +201,19 @@ func initLogger(cfg *config.LogConfig) {
 	logger.SetLogger(logDriver)
 }
 
-func openDb(cfg config.PgConfig) (*sqlx.DB, error) {
+// openDb connects to the database of the configured driver
+func openDb(cfg *config.Config) (*txmanager.DB, error) {
+	switch cfg.Db.Driver {
+	case "mysql":
+		return openMysqlDb(cfg.Mysql)
+	case "sqlite":
+		return openSqliteDb(cfg.Sqlite)
+	}
+
+	return openPgDb(cfg.Pg)
+}
+
+func openPgDb(cfg config.PgConfig) (*txmanager.DB, error) {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s",
 		cfg.Host,
 		cfg.Port,
@@ -150,22 +222,113 @@ func openDb(cfg config.PgConfig) (*sqlx.DB, error) {
 		cfg.Pass,
 	)
 
-	dbConn, err := sqlx.Connect("pgx", dsn)
+	primary, err := sqlx.Connect("pgx", dsn)
 	if err != nil {
 		return nil, err
 	}
+	setupPool(primary)
 
-	dbConn.SetMaxOpenConns(60)
-	dbConn.SetConnMaxLifetime(120 * time.Second)
-	dbConn.SetMaxIdleConns(30)
-	dbConn.SetConnMaxIdleTime(20 * time.Second)
-	if err = dbConn.Ping(); err != nil {
+	if err = primary.Ping(); err != nil {
 		return nil, err
 	} else {
 		logger.Println("[Boot]:", "Database connected successfully!")
 	}
 
-	return dbConn, nil
+	// replicas are checked in the background, one that is down at boot is
+	// used once it answers
+	replicas := []*sqlx.DB{}
+	for _, replicaDsn := range cfg.Replicas {
+		if strings.TrimSpace(replicaDsn) == "" {
+			continue
+		}
+
+		replica, err := sqlx.Open("pgx", replicaDsn)
+		if err != nil {
+			return nil, err
+		}
+		setupPool(replica)
+
+		replicas = append(replicas, replica)
+	}
+
+	if len(replicas) > 0 {
+		logger.Println("[Boot]:", fmt.Sprintf("%d database replicas configured", len(replicas)))
+	}
+
+	return txmanager.NewDB(primary, txmanager.Options{
+		Replicas:       replicas,
+		CheckInterval:  cfg.ReplicaCheckInterval,
+		ReadYourWrites: cfg.ReadYourWrites,
+	}), nil
+}
+
+// openMysqlDb connects to MySQL, ANSI_QUOTES lets the queries shared with the
+// other databases quote identifiers with double quotes and clientFoundRows
+// makes updates report the rows they matched like the other databases do
+func openMysqlDb(cfg config.MysqlConfig) (*txmanager.DB, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&multiStatements=true&clientFoundRows=true&sql_mode=%s",
+		cfg.User,
+		cfg.Pass,
+		cfg.Host,
+		cfg.Port,
+		cfg.DbName,
+		url.QueryEscape("'ANSI_QUOTES,STRICT_ALL_TABLES,ONLY_FULL_GROUP_BY'"),
+	)
+
+	primary, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	setupPool(primary)
+
+	logger.Println("[Boot]:", "Database connected successfully!")
+
+	return txmanager.NewDB(primary, txmanager.Options{
+		Dialect: txmanager.MySQL,
+	}), nil
+}
+
+// openSqliteDb opens the SQLite file, writers wait for each other instead of
+// failing right away and transactions take the write lock when they begin
+func openSqliteDb(cfg config.SqliteConfig) (*txmanager.DB, error) {
+	dsn := "file:" + cfg.Path + "?" + strings.Join([]string{
+		"_pragma=foreign_keys(1)",
+		"_pragma=busy_timeout(5000)",
+		"_pragma=journal_mode(WAL)",
+		"_time_format=sqlite",
+		"_txlock=immediate",
+	}, "&")
+
+	primary, err := sqlx.Connect("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	setupPool(primary)
+
+	logger.Println("[Boot]:", "Database connected successfully!")
+
+	return txmanager.NewDB(primary, txmanager.Options{
+		Dialect: txmanager.SQLite,
+	}), nil
+}
+
+// seedMemoryDb writes the declared permissions, the roles, the default tenant
+// and the users to the in-memory database
+func seedMemoryDb(mem *memdb.DB) error {
+	if err := seeds.SeedMemory(context.Background(), mem); err != nil {
+		return err
+	}
+
+	logger.Println("[Boot]:", "In-memory database seeded, data is lost on exit")
+
+	return nil
+}
+
+func setupPool(dbConn *sqlx.DB) {
+	dbConn.SetMaxOpenConns(60)
+	dbConn.SetConnMaxLifetime(120 * time.Second)
+	dbConn.SetMaxIdleConns(30)
+	dbConn.SetConnMaxIdleTime(20 * time.Second)
 }
 
 func openCache(cfg *config.Config) (cachePkg.Cache, error) {
@@ -2,26 +2,48 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"log"
 
 	"github.com/Adhiana46/echo-boilerplate/database/seeds"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/jmoiron/sqlx"
 )
 
-func runMigration(db *sql.DB) error {
-	log.Println("[Migration]:", "Running database migrations...")
-
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+// newMigrate returns the migrations of the dialect of db, Postgres ones are at
+// the root of database/migrations and the others in a folder named after
+// their dialect
+func newMigrate(db *txmanager.DB) (*migrate.Migrate, error) {
+	var driver database.Driver
+	var err error
+
+	dir := "file://./database/migrations"
+
+	switch db.Dialect() {
+	case txmanager.MySQL:
+		driver, err = mysql.WithInstance(db.DB.DB, &mysql.Config{})
+		dir += "/mysql"
+	case txmanager.SQLite:
+		driver, err = sqlite.WithInstance(db.DB.DB, &sqlite.Config{})
+		dir += "/sqlite"
+	default:
+		driver, err = postgres.WithInstance(db.DB.DB, &postgres.Config{})
+	}
 	if err != nil {
-		log.Println("[Migration]: ", err)
-		return err
+		return nil, err
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://./database/migrations",
-		"postgres", driver)
+	return migrate.NewWithDatabaseInstance(dir, string(db.Dialect()), driver)
+}
+
+func runMigration(db *txmanager.DB) error {
+	log.Println("[Migration]:", "Running database migrations...")
+
+	m, err := newMigrate(db)
 	if err != nil {
 		log.Println("[Migration]:", err)
 		return err
@@ -38,18 +60,10 @@ func runMigration(db *sql.DB) error {
 	return nil
 }
 
-func rollbackMigration(db *sql.DB) error {
+func rollbackMigration(db *txmanager.DB) error {
 	log.Println("[Migration]:", "Rollback database migrations...")
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		log.Println("[Migration]: ", err)
-		return err
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://./database/migrations",
-		"postgres", driver)
+	m, err := newMigrate(db)
 	if err != nil {
 		log.Println("[Migration]:", err)
 		return err
@@ -66,7 +80,7 @@ func rollbackMigration(db *sql.DB) error {
 	return nil
 }
 
-func runSeeder(db *sql.DB) error {
+func runSeeder(db *sqlx.DB) error {
 	log.Println("[Seeder]:", "Running database seeder")
 
 	seeder := seeds.NewSeeder(db)
@@ -80,7 +94,7 @@ func runSeeder(db *sql.DB) error {
 	return nil
 }
 
-func rollbackSeeder(db *sql.DB) error {
+func rollbackSeeder(db *sqlx.DB) error {
 	log.Println("[Seeder]:", "Rollback database seeder")
 
 	seeder := seeds.NewSeeder(db)
@@ -2,27 +2,38 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	App       AppConfig       `yaml:"app"`
-	Log       LogConfig       `yaml:"log"`
-	JWT       JWTConfig       `yaml:"jwt"`
-	Http      HttpConfig      `yaml:"http"`
-	Pg        PgConfig        `yaml:"postgres"`
-	Cache     CacheConfig     `yaml:"cache"`
-	Redis     RedisConfig     `yaml:"redis"`
-	Memcached MemcachedConfig `yaml:"memcached"`
-	Firebase  FirebaseConfig  `yaml:"firebase"`
+	App        AppConfig        `yaml:"app"`
+	Log        LogConfig        `yaml:"log"`
+	JWT        JWTConfig        `yaml:"jwt"`
+	Http       HttpConfig       `yaml:"http"`
+	Db         DbConfig         `yaml:"database"`
+	Pg         PgConfig         `yaml:"postgres"`
+	Mysql      MysqlConfig      `yaml:"mysql"`
+	Sqlite     SqliteConfig     `yaml:"sqlite"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Memcached  MemcachedConfig  `yaml:"memcached"`
+	Firebase   FirebaseConfig   `yaml:"firebase"`
+	Grant      GrantConfig      `yaml:"grant"`
+	Pagination PaginationConfig `yaml:"pagination"`
+	Trash      TrashConfig      `yaml:"trash"`
 }
 
 type AppConfig struct {
-	Name    string `env-required:"true" env:"APP_NAME" yaml:"name"`
-	Version string `env-required:"true" env:"APP_VERSION" yaml:"version"`
-	Debug   bool   `env:"APP_DEBUG" yaml:"debug" env-default:"false"`
+	Name            string `env-required:"true" env:"APP_NAME" yaml:"name"`
+	Version         string `env-required:"true" env:"APP_VERSION" yaml:"version"`
+	Debug           bool   `env:"APP_DEBUG" yaml:"debug" env-default:"false"`
+	PermissionCheck string `env:"APP_PERMISSION_CHECK" yaml:"permission_check" env-default:"warn"` // off|warn|fail
 }
 
 type LogConfig struct {
@@ -30,21 +41,83 @@ type LogConfig struct {
 	Level string `env-required:"true" env:"LOG_LEVEL" yaml:"level"`
 }
 
+// HttpConfig lists the proxies, by IP or CIDR, whose X-Forwarded-For header
+// gives the IP of the client. Without any the IP of the connection is used.
 type HttpConfig struct {
-	Host string `env-required:"true" env:"HTTP_HOST" yaml:"host"`
-	Port string `env-required:"true" env:"HTTP_PORT" yaml:"port"`
+	Host           string   `env-required:"true" env:"HTTP_HOST" yaml:"host"`
+	Port           string   `env-required:"true" env:"HTTP_PORT" yaml:"port"`
+	TrustedProxies []string `env:"HTTP_TRUSTED_PROXIES" yaml:"trusted_proxies"`
+}
+
+// TrustedNets parses the trusted proxies, an IP is a network of one address
+func (c HttpConfig) TrustedNets() ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, proxy := range c.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("http.trusted_proxies: %q is neither an IP nor a CIDR", proxy)
+			}
+
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("http.trusted_proxies: %q is neither an IP nor a CIDR", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+// DbConfig selects the persistence backend, memory keeps everything in the
+// process and is lost on exit. Only the settings of the selected driver are
+// read.
+type DbConfig struct {
+	Driver string `env:"DB_DRIVER" yaml:"driver" env-default:"postgres"` // postgres|mysql|sqlite|memory
 }
 
 type PgConfig struct {
-	Host   string `env-required:"true" env:"PG_HOST" yaml:"host"`
-	Port   string `env-required:"true" env:"PG_PORT" yaml:"port"`
-	User   string `env-required:"true" env:"PG_USER" yaml:"user"`
-	Pass   string `env-required:"true" env:"PG_PASS" yaml:"pass"`
-	DbName string `env-required:"true" env:"PG_DBNAME" yaml:"dbname"`
+	Host   string `env:"PG_HOST" yaml:"host"`
+	Port   string `env:"PG_PORT" yaml:"port"`
+	User   string `env:"PG_USER" yaml:"user"`
+	Pass   string `env:"PG_PASS" yaml:"pass"`
+	DbName string `env:"PG_DBNAME" yaml:"dbname"`
+	// Replicas are the DSNs of the read replicas, comma separated in the env
+	Replicas             []string      `env:"PG_REPLICAS" yaml:"replicas"`
+	ReplicaCheckInterval time.Duration `env:"PG_REPLICA_CHECK_INTERVAL" yaml:"replica_check_interval" env-default:"10s"`
+	// ReadYourWrites sends the reads of a request to the primary for this long
+	// after it wrote, 0 leaves them on the replicas
+	ReadYourWrites time.Duration `env:"PG_READ_YOUR_WRITES" yaml:"read_your_writes" env-default:"0s"`
+}
+
+type MysqlConfig struct {
+	Host   string `env:"MYSQL_HOST" yaml:"host"`
+	Port   string `env:"MYSQL_PORT" yaml:"port" env-default:"3306"`
+	User   string `env:"MYSQL_USER" yaml:"user"`
+	Pass   string `env:"MYSQL_PASS" yaml:"pass"`
+	DbName string `env:"MYSQL_DBNAME" yaml:"dbname"`
+}
+
+// SqliteConfig points to the database file, it is created when missing
+type SqliteConfig struct {
+	Path string `env:"SQLITE_PATH" yaml:"path" env-default:"./echo-boilerplate.db"`
 }
 
 type CacheConfig struct {
 	Driver string `env:"CACHE_DRIVER" yaml:"driver"`
+	// the TTLs of the entities the repositories read through the cache, under
+	// a second reads them from the database every time
+	UserTTL       time.Duration `env:"CACHE_USER_TTL" yaml:"user_ttl" env-default:"5m"`
+	RoleTTL       time.Duration `env:"CACHE_ROLE_TTL" yaml:"role_ttl" env-default:"10m"`
+	PermissionTTL time.Duration `env:"CACHE_PERMISSION_TTL" yaml:"permission_ttl" env-default:"1h"`
 }
 
 type RedisConfig struct {
@@ -70,6 +143,26 @@ type FirebaseConfig struct {
 	ClientX509CertUrl       string `env:"FIREBASE_CLIENT_X509_CERT_URL" yaml:"client_x509_cert_url" json:"client_x509_cert_url"`
 }
 
+// GrantConfig controls the sweeper of temporary role grants, expired grants
+// stay visible for Retention before being removed
+type GrantConfig struct {
+	SweepInterval time.Duration `env:"GRANT_SWEEP_INTERVAL" yaml:"sweep_interval" env-default:"1m"`
+	Retention     time.Duration `env:"GRANT_RETENTION" yaml:"retention" env-default:"24h"`
+}
+
+// PaginationConfig holds the key list cursors are signed with, the JWT secret
+// is used when empty, and the largest page a list request may ask for
+type PaginationConfig struct {
+	CursorSecret string `env:"PAGINATION_CURSOR_SECRET" yaml:"cursor_secret"`
+	MaxLimit     int    `env:"PAGINATION_MAX_LIMIT" yaml:"max_limit" env-default:"100"`
+}
+
+// TrashConfig sets how long deleted users, roles and permissions can be
+// restored before trash:purge removes them for good
+type TrashConfig struct {
+	Retention time.Duration `env:"TRASH_RETENTION" yaml:"retention" env-default:"720h"`
+}
+
 type JWTConfig struct {
 	SecretKey string `env:"JWT_SECRET" yaml:"secret"`
 	Issuer    string `env:"JWT_ISSUER" yaml:"issuer"`
@@ -100,5 +193,27 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	err = cfg.validate()
+	if err != nil {
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+
 	return cfg, nil
 }
+
+// validate rejects the settings the loader cannot check by itself
+func (c *Config) validate() error {
+	switch c.App.PermissionCheck {
+	case "off", "warn", "fail":
+	default:
+		return fmt.Errorf("app.permission_check: %q is none of off, warn and fail", c.App.PermissionCheck)
+	}
+
+	if c.Pagination.MaxLimit < constants.DEFAULT_PAGINATION_LIMIT {
+		return fmt.Errorf("pagination.max_limit: %d is below the default limit of %d", c.Pagination.MaxLimit, constants.DEFAULT_PAGINATION_LIMIT)
+	}
+
+	_, err := c.Http.TrustedNets()
+
+	return err
+}
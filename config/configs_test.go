@@ -0,0 +1,99 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name:   "permission check off",
+			modify: func(cfg *Config) { cfg.App.PermissionCheck = "off" },
+		},
+		{
+			name:   "permission check fail",
+			modify: func(cfg *Config) { cfg.App.PermissionCheck = "fail" },
+		},
+		{
+			name:    "unknown permission check",
+			modify:  func(cfg *Config) { cfg.App.PermissionCheck = "strict" },
+			wantErr: true,
+		},
+		{
+			name:    "empty permission check",
+			modify:  func(cfg *Config) { cfg.App.PermissionCheck = "" },
+			wantErr: true,
+		},
+		{
+			name:    "permission check in capitals",
+			modify:  func(cfg *Config) { cfg.App.PermissionCheck = "FAIL" },
+			wantErr: true,
+		},
+		{
+			name:   "trusted proxies",
+			modify: func(cfg *Config) { cfg.Http.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.10", "::1"} },
+		},
+		{
+			name:    "malformed trusted proxy",
+			modify:  func(cfg *Config) { cfg.Http.TrustedProxies = []string{"10.0.0.0/33"} },
+			wantErr: true,
+		},
+		{
+			name:   "max limit at the default limit",
+			modify: func(cfg *Config) { cfg.Pagination.MaxLimit = constants.DEFAULT_PAGINATION_LIMIT },
+		},
+		{
+			name:    "max limit below the default limit",
+			modify:  func(cfg *Config) { cfg.Pagination.MaxLimit = constants.DEFAULT_PAGINATION_LIMIT - 1 },
+			wantErr: true,
+		},
+		{
+			name:    "max limit unset",
+			modify:  func(cfg *Config) { cfg.Pagination.MaxLimit = 0 },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				App:        AppConfig{PermissionCheck: "warn"},
+				Pagination: PaginationConfig{MaxLimit: 100},
+			}
+			tt.modify(&cfg)
+
+			err := cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrustedNets(t *testing.T) {
+	nets, err := HttpConfig{TrustedProxies: []string{"192.168.1.10", "10.0.0.0/8", "::1"}}.TrustedNets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"192.168.1.10/32", "10.0.0.0/8", "::1/128"}
+	if len(nets) != len(want) {
+		t.Fatalf("got %d nets, want %d", len(nets), len(want))
+	}
+
+	for i, ipNet := range nets {
+		if ipNet.String() != want[i] {
+			t.Errorf("net %d: got %s, want %s", i, ipNet, want[i])
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package constants
+
+// actions of the audit log
+const (
+	AUDIT_ACTION_CREATE            = "create"
+	AUDIT_ACTION_UPDATE            = "update"
+	AUDIT_ACTION_DELETE            = "delete"
+	AUDIT_ACTION_RESTORE           = "restore"
+	AUDIT_ACTION_PURGE             = "purge"
+	AUDIT_ACTION_PERMISSIONS_GRANT = "permissions.grant"
+	AUDIT_ACTION_PERMISSION_REVOKE = "permission.revoke"
+	AUDIT_ACTION_USERS_ASSIGN      = "users.assign"
+	AUDIT_ACTION_USERS_UNASSIGN    = "users.unassign"
+	AUDIT_ACTION_ROLE_GRANT        = "role.grant"
+	AUDIT_ACTION_ROLE_REVOKE       = "role.revoke"
+)
+
+// resource types of the audit log
+const (
+	AUDIT_RESOURCE_USER       = "user"
+	AUDIT_RESOURCE_ROLE       = "role"
+	AUDIT_RESOURCE_PERMISSION = "permission"
+)
+
+// AUDIT_REDACTED replaces the values of secret fields in the audit log
+const AUDIT_REDACTED = "[REDACTED]"
@@ -6,3 +6,15 @@ const (
 	ACCESS_TOKEN_DURATION  time.Duration = time.Minute * time.Duration(15) // 15 minutes
 	REFRESH_TOKEN_DURATION time.Duration = time.Minute * time.Duration(30) // 30 minutes
 )
+
+// ROLE_MANAGEMENT_PERMISSION must stay granted to at least one user, removing
+// the last holder would lock everyone out of role management
+const ROLE_MANAGEMENT_PERMISSION = "roles.update"
+
+// PRIVILEGE_ESCALATION_PERMISSION lets its holder grant roles and permissions
+// they do not hold themselves, and change the credentials of users holding them
+const PRIVILEGE_ESCALATION_PERMISSION = "roles.escalate"
+
+// TENANT_HEADER selects the tenant (uuid or slug) when signing in, on
+// authenticated requests it must match the tenant of the token
+const TENANT_HEADER = "X-Tenant-ID"
@@ -0,0 +1,8 @@
+package constants
+
+// namespaces of the entities the repositories read through the cache
+const (
+	CACHE_NAMESPACE_USERS       = "users"
+	CACHE_NAMESPACE_ROLES       = "roles"
+	CACHE_NAMESPACE_PERMISSIONS = "permissions"
+)
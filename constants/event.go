@@ -0,0 +1,7 @@
+package constants
+
+const (
+	// EVENT_ROLE_GRANT_EXPIRED is published with the *entity.RoleGrant removed
+	// by the sweeper
+	EVENT_ROLE_GRANT_EXPIRED = "role_grant.expired"
+)
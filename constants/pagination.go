@@ -2,4 +2,5 @@ package constants
 
 const (
 	DEFAULT_PAGINATION_LIMIT = 10
+	MAX_PAGINATION_LIMIT     = 100
 )
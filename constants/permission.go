@@ -0,0 +1,20 @@
+package constants
+
+const (
+	PERMISSION_TYPE_MENU   = "menu"
+	PERMISSION_TYPE_ACTION = "action"
+)
+
+// sources of a permission grant
+const (
+	GRANT_SOURCE_ROLE      = "role"
+	GRANT_SOURCE_GROUP     = "group"
+	GRANT_SOURCE_TEMPORARY = "temporary"
+)
+
+// statuses of a temporary role grant
+const (
+	GRANT_STATUS_PENDING = "pending"
+	GRANT_STATUS_ACTIVE  = "active"
+	GRANT_STATUS_EXPIRED = "expired"
+)
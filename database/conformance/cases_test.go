@@ -0,0 +1,1058 @@
+package conformance
+
+import (
+	"context"
+	"database/sql"
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/google/uuid"
+)
+
+func permissionCase(ctx context.Context, b Backend, f *Fixture) error {
+	p, err := f.Permission(ctx, b, "reports.read")
+	if err != nil {
+		return err
+	}
+
+	numrows, err := b.Permissions.CountByName(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+	if err := expect("count by name", numrows, 1); err != nil {
+		return err
+	}
+
+	p.Type = "menu"
+	p.UpdatedAt = now()
+	updated, err := b.Permissions.Update(ctx, p)
+	if err != nil {
+		return err
+	}
+	if err := first(expect("type", updated.Type, "menu"), expect("version", updated.Version, p.Version+1)); err != nil {
+		return err
+	}
+
+	// p still holds the version it was read with
+	_, err = b.Permissions.Update(ctx, p)
+	if !stdErrors.Is(err, errors.ErrVersionConflict) {
+		return fmt.Errorf("stale update: got %v, want %v", err, errors.ErrVersionConflict)
+	}
+
+	updated.DeletedAt = now()
+	err = b.Permissions.Destroy(ctx, updated)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.Permissions.FindByName(ctx, p.Name)
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("find trashed by name: got %v, want %v", err, sql.ErrNoRows)
+	}
+
+	// a trashed permission does not hold on to its name
+	_, err = f.Permission(ctx, b, "reports.read")
+	if err != nil {
+		return fmt.Errorf("reuse of a trashed name: %w", err)
+	}
+
+	_, err = b.Permissions.FindTrashedByUuid(ctx, p.Uuid)
+	if err != nil {
+		return err
+	}
+
+	purged, err := b.Permissions.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	if err := expect("purged", len(purged) > 0 && purged[len(purged)-1].Uuid == p.Uuid, true); err != nil {
+		return err
+	}
+
+	_, err = b.Permissions.FindTrashedByUuid(ctx, p.Uuid)
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("find purged: got %v, want %v", err, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func roleCase(ctx context.Context, b Backend, f *Fixture) error {
+	read, err := f.Permission(ctx, b, "reports.read")
+	if err != nil {
+		return err
+	}
+	write, err := f.Permission(ctx, b, "reports.write")
+	if err != nil {
+		return err
+	}
+
+	viewer, err := f.Role(ctx, b, "viewer", []*entity.Permission{read}, nil)
+	if err != nil {
+		return err
+	}
+	editor, err := f.Role(ctx, b, "editor", []*entity.Permission{write}, []*entity.Role{viewer})
+	if err != nil {
+		return err
+	}
+
+	if err := first(
+		expect("tenant", editor.TenantId, f.Tenant.Id),
+		expect("permissions", len(editor.Permissions), 1),
+		expect("parents", len(editor.Parents), 1),
+		expect("inherited permissions", len(editor.InheritedPermissions), 1),
+		expect("inherits read", editor.HasPermission(read.Name), true),
+	); err != nil {
+		return err
+	}
+
+	ancestors, err := b.Roles.FindAncestorIds(ctx, editor.Id)
+	if err != nil {
+		return err
+	}
+	if err := expect("ancestors", fmt.Sprint(ancestors), fmt.Sprint([]int{viewer.Id})); err != nil {
+		return err
+	}
+
+	paths, err := b.Roles.FindPermissionPaths(ctx, editor.Id, read.Name)
+	if err != nil {
+		return err
+	}
+	if err := expect("paths", fmt.Sprint(paths), fmt.Sprint([][]string{{editor.Name, viewer.Name}})); err != nil {
+		return err
+	}
+
+	found, err := b.Roles.FindByName(ctx, editor.Name)
+	if err != nil {
+		return err
+	}
+	if err := expect("find by name", found.Id, editor.Id); err != nil {
+		return err
+	}
+
+	spec, err := queryspec.New(1, 10, "name.desc", f.Key)
+	if err != nil {
+		return err
+	}
+	roles, err := b.Roles.FindAll(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := expect("listed roles", len(roles), 2); err != nil {
+		return err
+	}
+	if err := expect("sorted by name", roles[0].Name, viewer.Name); err != nil {
+		return err
+	}
+
+	editor.Permissions = []*entity.Permission{read, write}
+	editor.UpdatedAt = now()
+	updated, err := b.Roles.Update(ctx, editor)
+	if err != nil {
+		return err
+	}
+	if err := expect("permissions after update", len(updated.Permissions), 2); err != nil {
+		return err
+	}
+
+	_, err = b.Roles.Update(ctx, editor)
+	if !stdErrors.Is(err, errors.ErrVersionConflict) {
+		return fmt.Errorf("stale update: got %v, want %v", err, errors.ErrVersionConflict)
+	}
+
+	viewer.DeletedAt = now()
+	err = b.Roles.Destroy(ctx, viewer)
+	if err != nil {
+		return err
+	}
+
+	// a trashed parent grants nothing
+	reloaded, err := b.Roles.FindById(ctx, editor.Id)
+	if err != nil {
+		return err
+	}
+	if err := expect("parents of a trashed parent", len(reloaded.Parents), 0); err != nil {
+		return err
+	}
+
+	// permission paths only go through the live roles of the tenant
+	paths, err = b.Roles.FindPermissionPaths(ctx, editor.Id, read.Name)
+	if err != nil {
+		return err
+	}
+	trashedPaths, err := b.Roles.FindPermissionPaths(ctx, viewer.Id, read.Name)
+	if err != nil {
+		return err
+	}
+	other, err := newFixture(ctx, b)
+	if err != nil {
+		return err
+	}
+	otherPaths, err := b.Roles.FindPermissionPaths(other.ctx, editor.Id, write.Name)
+	if err != nil {
+		return err
+	}
+	if err := first(
+		expect("paths through a trashed parent", fmt.Sprint(paths), fmt.Sprint([][]string{{editor.Name}})),
+		expect("paths of a trashed role", len(trashedPaths), 0),
+		expect("paths of another tenant", len(otherPaths), 0),
+	); err != nil {
+		return err
+	}
+
+	trashed, err := b.Roles.FindTrashedByUuid(ctx, viewer.Uuid)
+	if err != nil {
+		return err
+	}
+
+	trashed.UpdatedAt = now()
+	_, err = b.Roles.Restore(ctx, trashed)
+
+	return err
+}
+
+func userSearchCase(ctx context.Context, b Backend, f *Fixture) error {
+	alice, err := f.User(ctx, b, "alice", "Alice Walker", 0)
+	if err != nil {
+		return err
+	}
+	bob, err := f.User(ctx, b, "bob", "Bob Stone", 0)
+	if err != nil {
+		return err
+	}
+
+	// signing in is not an edit of the user
+	err = b.Users.SaveLastLogin(ctx, bob.Id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// the username and the email are unique among live accounts
+	numrows, err := b.Users.CountByUsername(ctx, alice.Username)
+	if err != nil {
+		return err
+	}
+	if err := expect("count by username", numrows, 1); err != nil {
+		return err
+	}
+
+	spec, err := queryspec.New(1, 10, "", f.Key)
+	if err != nil {
+		return err
+	}
+	results, err := b.Users.Search(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := expect("search by key", len(results), 2); err != nil {
+		return err
+	}
+
+	spec, err = queryspec.New(1, 10, "", "walk")
+	if err != nil {
+		return err
+	}
+	results, err = b.Users.Search(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := expect("search by prefix", len(results), 1); err != nil {
+		return err
+	}
+	if err := expect("found by prefix", results[0].Uuid, alice.Uuid); err != nil {
+		return err
+	}
+
+	spec, err = queryspec.New(1, 10, "username.desc", "")
+	if err != nil {
+		return err
+	}
+	spec.Filters = []queryspec.Filter{{Field: "name", Op: queryspec.OpLike, Value: "STONE"}}
+	users, err := b.Users.FindAll(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := expect("filtered users", len(users), 1); err != nil {
+		return err
+	}
+	if err := first(expect("listed version", users[0].Version, bob.Version), expect("listed last login", users[0].LastLoginAt.Valid, true)); err != nil {
+		return err
+	}
+
+	alice.DeletedAt = now()
+	err = b.Users.Destroy(ctx, alice)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.Users.FindByUuid(ctx, alice.Uuid)
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("find trashed: got %v, want %v", err, sql.ErrNoRows)
+	}
+
+	// the account was only a member of this tenant, it is trashed too
+	numrows, err = b.Users.CountByUsername(ctx, alice.Username)
+	if err != nil {
+		return err
+	}
+	if err := expect("count by username of a trashed account", numrows, 0); err != nil {
+		return err
+	}
+
+	trashed, err := b.Users.FindTrashedByUuid(ctx, alice.Uuid)
+	if err != nil {
+		return err
+	}
+
+	trashed.UpdatedAt = now()
+	restored, err := b.Users.Restore(ctx, trashed)
+	if err != nil {
+		return err
+	}
+	if err := expect("restored", restored.Uuid, alice.Uuid); err != nil {
+		return err
+	}
+
+	// a purged member comes back with the tenant it was purged from
+	restored.DeletedAt = now()
+	err = b.Users.Destroy(ctx, restored)
+	if err != nil {
+		return err
+	}
+
+	purged, err := b.Users.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	if err := expect("purged members", len(purged), 1); err != nil {
+		return err
+	}
+
+	return first(
+		expect("purged member", purged[0].Uuid, alice.Uuid),
+		expect("purged from", purged[0].TenantId, f.Tenant.Id),
+		expect("purged hash", purged[0].Password, ""),
+	)
+}
+
+func userCursorCase(ctx context.Context, b Backend, f *Fixture) error {
+	// the three Bobs share their name, the key orders them from page to page
+	want := []string{}
+	for _, name := range []string{"Bob", "Alice", "Bob", "Carol", "Bob"} {
+		u, err := f.User(ctx, b, uuid.NewString()[:8], name, 0)
+		if err != nil {
+			return err
+		}
+		want = append(want, u.Uuid)
+	}
+	want = []string{want[1], want[0], want[2], want[4], want[3]}
+
+	page := func(token string) ([]string, *queryspec.Spec, error) {
+		spec, err := queryspec.New(0, 2, "name.asc", "")
+		if err != nil {
+			return nil, nil, err
+		}
+		err = spec.UseCursor(token)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		users, err := b.Users.FindAll(ctx, spec)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		uuids := []string{}
+		for _, u := range users {
+			uuids = append(uuids, u.Uuid)
+		}
+
+		return uuids, spec, nil
+	}
+
+	// forward to the last page
+	got, token, last := []string{}, "", &queryspec.Spec{}
+	for i := 0; i < 3; i++ {
+		uuids, spec, err := page(token)
+		if err != nil {
+			return err
+		}
+		got, token, last = append(got, uuids...), spec.Next, spec
+	}
+	if err := first(
+		expect("pages forward", fmt.Sprint(got), fmt.Sprint(want)),
+		expect("next of the last page", token, ""),
+	); err != nil {
+		return err
+	}
+
+	// and back to the first one
+	got, token = []string{}, last.Prev
+	for i := 0; i < 2; i++ {
+		uuids, spec, err := page(token)
+		if err != nil {
+			return err
+		}
+		got, token = append(uuids, got...), spec.Prev
+	}
+	if err := first(
+		expect("pages backwards", fmt.Sprint(got), fmt.Sprint(want[:4])),
+		expect("previous of the first page", token, ""),
+	); err != nil {
+		return err
+	}
+
+	// a cursor only goes with the sort it was made for
+	spec, err := queryspec.New(0, 2, "name.desc", "")
+	if err != nil {
+		return err
+	}
+	err = spec.UseCursor(last.Prev)
+	if err != nil {
+		return err
+	}
+	_, err = b.Users.FindAll(ctx, spec)
+	if customErr, ok := err.(errors.CustomError); !ok || customErr.StatusCode() != http.StatusBadRequest {
+		return fmt.Errorf("cursor of another sort: got %v, want a bad request", err)
+	}
+
+	return nil
+}
+
+func userRoleCase(ctx context.Context, b Backend, f *Fixture) error {
+	read, err := f.Permission(ctx, b, "reports.read")
+	if err != nil {
+		return err
+	}
+	viewer, err := f.Role(ctx, b, "viewer", []*entity.Permission{read}, nil)
+	if err != nil {
+		return err
+	}
+	editor, err := f.Role(ctx, b, "editor", nil, []*entity.Role{viewer})
+	if err != nil {
+		return err
+	}
+
+	alice, err := f.User(ctx, b, "alice", "Alice", editor.Id)
+	if err != nil {
+		return err
+	}
+	bob, err := f.User(ctx, b, "bob", "Bob", 0)
+	if err != nil {
+		return err
+	}
+
+	// alice holds read through the parent of her role
+	holders, err := b.Users.CountByPermission(ctx, read.Name, user.HolderExclusions{})
+	if err != nil {
+		return err
+	}
+	if err := expect("holders", holders, 1); err != nil {
+		return err
+	}
+
+	assigned, err := b.Users.AssignRole(ctx, viewer.Id, []string{bob.Uuid}, sql.NullInt64{})
+	if err != nil {
+		return err
+	}
+	if err := expect("assigned", assigned, 1); err != nil {
+		return err
+	}
+
+	holders, err = b.Users.CountByPermission(ctx, read.Name, user.HolderExclusions{UserUuids: []string{alice.Uuid}})
+	if err != nil {
+		return err
+	}
+	if err := expect("holders without alice", holders, 1); err != nil {
+		return err
+	}
+
+	// only the members holding the role lose it
+	unassigned, err := b.Users.UnassignRole(ctx, viewer.Id, []string{alice.Uuid, bob.Uuid}, sql.NullInt64{})
+	if err != nil {
+		return err
+	}
+	if err := expect("unassigned", unassigned, 1); err != nil {
+		return err
+	}
+
+	reloaded, err := b.Users.FindById(ctx, alice.Id)
+	if err != nil {
+		return err
+	}
+	if err := expect("role kept", reloaded.RoleId, editor.Id); err != nil {
+		return err
+	}
+
+	holders, err = b.Users.CountByPermission(ctx, read.Name, user.HolderExclusions{RoleIds: []int{viewer.Id}})
+	if err != nil {
+		return err
+	}
+
+	return expect("holders without the granting role", holders, 0)
+}
+
+func userPasswordCase(ctx context.Context, b Backend, f *Fixture) error {
+	alice, err := f.User(ctx, b, "alice", "Alice", 0)
+	if err != nil {
+		return err
+	}
+	if err := expect("created member hash", alice.Password, ""); err != nil {
+		return err
+	}
+
+	// an update without a hash keeps the saved one
+	alice.Name = "Alice Walker"
+	alice.UpdatedAt = now()
+	alice, err = b.Users.Update(ctx, alice)
+	if err != nil {
+		return err
+	}
+
+	account, err := b.Users.FindByUsernameOrEmail(ctx, alice.Username)
+	if err != nil {
+		return err
+	}
+	if err := expect("account hash", account.Password, "secret"); err != nil {
+		return err
+	}
+
+	err = b.Users.SavePassword(ctx, alice.Id, "changed")
+	if err != nil {
+		return err
+	}
+
+	account, err = b.Users.FindByUsernameOrEmail(ctx, alice.Email)
+	if err != nil {
+		return err
+	}
+	member, err := b.Users.FindByUuid(ctx, alice.Uuid)
+	if err != nil {
+		return err
+	}
+
+	return first(
+		expect("saved hash", account.Password, "changed"),
+		expect("member hash", member.Password, ""),
+		expect("version after saving the hash", member.Version, alice.Version),
+	)
+}
+
+func tenantCase(ctx context.Context, b Backend, f *Fixture) error {
+	alice, err := f.User(ctx, b, "alice", "Alice", 0)
+	if err != nil {
+		return err
+	}
+
+	other, err := b.Tenants.Create(ctx, &entity.Tenant{
+		Uuid:      uuid.NewString(),
+		Slug:      f.Name("other"),
+		Name:      "Other",
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	numrows, err := b.Tenants.CountBySlug(ctx, other.Slug)
+	if err != nil {
+		return err
+	}
+	if err := expect("count by slug", numrows, 1); err != nil {
+		return err
+	}
+
+	// adding a member again updates the membership
+	for i := 0; i < 2; i++ {
+		added, err := b.Tenants.AddUsers(ctx, other.Id, 0, []string{alice.Uuid, uuid.NewString()})
+		if err != nil {
+			return err
+		}
+		if err := expect("added", added, 1); err != nil {
+			return err
+		}
+	}
+
+	tenants, err := b.Tenants.FindAllByUserId(ctx, alice.Id)
+	if err != nil {
+		return err
+	}
+	if err := expect("tenants", len(tenants), 2); err != nil {
+		return err
+	}
+
+	removed, err := b.Tenants.RemoveUsers(ctx, other.Id, []string{alice.Uuid})
+	if err != nil {
+		return err
+	}
+	if err := expect("removed", removed, 1); err != nil {
+		return err
+	}
+
+	tenants, err = b.Tenants.FindAllByUserId(ctx, alice.Id)
+	if err != nil {
+		return err
+	}
+
+	return expect("tenants after removal", len(tenants), 1)
+}
+
+func groupCase(ctx context.Context, b Backend, f *Fixture) error {
+	read, err := f.Permission(ctx, b, "reports.read")
+	if err != nil {
+		return err
+	}
+	viewer, err := f.Role(ctx, b, "viewer", []*entity.Permission{read}, nil)
+	if err != nil {
+		return err
+	}
+	alice, err := f.User(ctx, b, "alice", "Alice", 0)
+	if err != nil {
+		return err
+	}
+
+	g, err := b.Groups.Create(ctx, &entity.Group{
+		Uuid:      uuid.NewString(),
+		Name:      f.Name("readers"),
+		Roles:     []*entity.Role{viewer},
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := expect("roles", len(g.Roles), 1); err != nil {
+		return err
+	}
+
+	// granting a role the group has keeps it once
+	g, err = b.Groups.GrantRoles(ctx, g, []*entity.Role{viewer})
+	if err != nil {
+		return err
+	}
+	if err := expect("roles granted again", len(g.Roles), 1); err != nil {
+		return err
+	}
+
+	for i, want := range []int{1, 0} {
+		added, err := b.Groups.AddUsers(ctx, g.Id, []string{alice.Uuid})
+		if err != nil {
+			return err
+		}
+		if err := expect(fmt.Sprintf("added (%d)", i+1), added, want); err != nil {
+			return err
+		}
+	}
+
+	members, err := b.Groups.FindAllMembers(ctx, g.Id)
+	if err != nil {
+		return err
+	}
+	if err := expect("members", len(members), 1); err != nil {
+		return err
+	}
+
+	holders, err := b.Users.CountByPermission(ctx, read.Name, user.HolderExclusions{})
+	if err != nil {
+		return err
+	}
+	if err := expect("holders through the group", holders, 1); err != nil {
+		return err
+	}
+
+	holders, err = b.Users.CountByPermission(ctx, read.Name, user.HolderExclusions{GroupId: g.Id, GroupMemberUuids: []string{alice.Uuid}})
+	if err != nil {
+		return err
+	}
+	if err := expect("holders once alice leaves", holders, 0); err != nil {
+		return err
+	}
+
+	removed, err := b.Groups.RemoveUsers(ctx, g.Id, []string{alice.Uuid})
+	if err != nil {
+		return err
+	}
+	if err := expect("removed", removed, 1); err != nil {
+		return err
+	}
+
+	g, err = b.Groups.RevokeRole(ctx, g, viewer)
+	if err != nil {
+		return err
+	}
+
+	return expect("roles after revoke", len(g.Roles), 0)
+}
+
+func grantCase(ctx context.Context, b Backend, f *Fixture) error {
+	viewer, err := f.Role(ctx, b, "viewer", nil, nil)
+	if err != nil {
+		return err
+	}
+	alice, err := f.User(ctx, b, "alice", "Alice", 0)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now().Truncate(time.Second)
+	expired, err := b.Grants.Create(ctx, &entity.RoleGrant{
+		Uuid:       uuid.NewString(),
+		UserId:     alice.Id,
+		RoleId:     viewer.Id,
+		ValidFrom:  start.Add(-2 * time.Hour),
+		ValidUntil: start.Add(-time.Hour),
+		Reason:     "conformance",
+		CreatedAt:  now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = b.Grants.Create(ctx, &entity.RoleGrant{
+		Uuid:       uuid.NewString(),
+		UserId:     alice.Id,
+		RoleId:     viewer.Id,
+		ValidFrom:  start,
+		ValidUntil: start.Add(time.Hour),
+		CreatedAt:  now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	grants, err := b.Grants.FindAllByUserId(ctx, alice.Id)
+	if err != nil {
+		return err
+	}
+	if err := expect("grants", len(grants), 2); err != nil {
+		return err
+	}
+
+	swept, err := b.Grants.DestroyExpired(ctx, start)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, g := range swept {
+		found = found || g.Uuid == expired.Uuid
+	}
+	if err := expect("expired grant swept", found, true); err != nil {
+		return err
+	}
+
+	grants, err = b.Grants.FindAllByUserId(ctx, alice.Id)
+	if err != nil {
+		return err
+	}
+
+	return expect("grants after the sweep", len(grants), 1)
+}
+
+func deviceCase(ctx context.Context, b Backend, f *Fixture) error {
+	alice, err := f.User(ctx, b, "alice", "Alice", 0)
+	if err != nil {
+		return err
+	}
+
+	d, err := b.Devices.Create(ctx, &entity.UserDevice{
+		Uuid:      uuid.NewString(),
+		UserId:    alice.Id,
+		Token:     f.Name("token"),
+		Platform:  "android",
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	d.Platform = "ios"
+	d.UpdatedAt = now()
+	_, err = b.Devices.Update(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	found, err := b.Devices.FindByToken(ctx, alice.Id, d.Token)
+	if err != nil {
+		return err
+	}
+	if err := first(expect("device", found.Uuid, d.Uuid), expect("platform", found.Platform, "ios")); err != nil {
+		return err
+	}
+
+	err = b.Devices.Destroy(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.Devices.FindByUuid(ctx, d.Uuid)
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("find destroyed: got %v, want %v", err, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func auditLogCase(ctx context.Context, b Backend, f *Fixture) error {
+	alice, err := f.User(ctx, b, "alice", "Alice", 0)
+	if err != nil {
+		return err
+	}
+
+	before := *alice
+	alice.Name = "Alice Walker"
+	alice.Password = "changed"
+
+	actorCtx := context.WithValue(ctx, "user", &dto.UserResponseWithID{ID: alice.Id, Uuid: alice.Uuid})
+	err = audit.Record(actorCtx, b.AuditLogs, audit.Change{
+		Action:       constants.AUDIT_ACTION_UPDATE,
+		ResourceType: constants.AUDIT_RESOURCE_USER,
+		ResourceUuid: alice.Uuid,
+		Before:       &before,
+		After:        alice,
+	})
+	if err != nil {
+		return err
+	}
+
+	// an hour later, without an actor
+	later, err := b.AuditLogs.Create(ctx, &entity.AuditLog{
+		Uuid:         uuid.NewString(),
+		TenantId:     f.Tenant.Id,
+		Action:       constants.AUDIT_ACTION_DELETE,
+		ResourceType: constants.AUDIT_RESOURCE_ROLE,
+		ResourceUuid: uuid.NewString(),
+		OldValues:    sql.NullString{String: `{"name":"editor"}`, Valid: true},
+		CreatedAt:    sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	spec, err := queryspec.New(1, 10, "", "")
+	if err != nil {
+		return err
+	}
+	logs, err := b.AuditLogs.FindAll(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := expect("logs", len(logs), 2); err != nil {
+		return err
+	}
+	if err := expect("newest first", logs[0].Uuid, later.Uuid); err != nil {
+		return err
+	}
+
+	// only the changed fields are kept, the password hash is never serialized
+	updated := logs[1]
+	if err := first(
+		expect("old values", updated.OldValues.String, `{"name":"Alice"}`),
+		expect("new values", updated.NewValues.String, `{"name":"Alice Walker"}`),
+		expect("actor", updated.ActorId.Int64, int64(alice.Id)),
+	); err != nil {
+		return err
+	}
+
+	spec.Filters = []queryspec.Filter{
+		{Field: "resource_type", Op: queryspec.OpEq, Value: constants.AUDIT_RESOURCE_USER},
+		{Field: "actor", Op: queryspec.OpEq, Value: fmt.Sprint(alice.Id)},
+	}
+	numrows, err := b.AuditLogs.CountAll(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := expect("count by resource type and actor", numrows, 1); err != nil {
+		return err
+	}
+
+	spec.Filters = []queryspec.Filter{{Field: "actor", Op: queryspec.OpIsNull, Value: "true"}}
+	logs, err = b.AuditLogs.FindAll(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := expect("logs without an actor", len(logs), 1); err != nil {
+		return err
+	}
+
+	// the logs of a tenant are not seen from outside it
+	numrows, err = b.AuditLogs.CountAll(utils.WithTenant(ctx, &dto.TenantResponseWithID{}), spec)
+	if err != nil {
+		return err
+	}
+
+	return expect("count outside the tenant", numrows, 0)
+}
+
+func isolationCase(ctx context.Context, b Backend, f *Fixture) error {
+	viewer, err := f.Role(ctx, b, "viewer", nil, nil)
+	if err != nil {
+		return err
+	}
+	alice, err := f.User(ctx, b, "alice", "Alice", viewer.Id)
+	if err != nil {
+		return err
+	}
+	g, err := b.Grants.Create(ctx, &entity.RoleGrant{
+		Uuid:       uuid.NewString(),
+		UserId:     alice.Id,
+		RoleId:     viewer.Id,
+		ValidFrom:  time.Now().Truncate(time.Second),
+		ValidUntil: time.Now().Add(time.Hour).Truncate(time.Second),
+		CreatedAt:  now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	other, err := newFixture(ctx, b)
+	if err != nil {
+		return err
+	}
+	otherCtx := other.ctx
+
+	// reads from the other tenant
+	_, err = b.Users.FindByUuid(otherCtx, alice.Uuid)
+	if err := expectNoRows("find user", err); err != nil {
+		return err
+	}
+	_, err = b.Roles.FindByUuid(otherCtx, viewer.Uuid)
+	if err := expectNoRows("find role", err); err != nil {
+		return err
+	}
+	_, err = b.Roles.FindByName(otherCtx, viewer.Name)
+	if err := expectNoRows("find role by name", err); err != nil {
+		return err
+	}
+	_, err = b.Grants.FindByUuid(otherCtx, g.Uuid)
+	if err := expectNoRows("find grant", err); err != nil {
+		return err
+	}
+
+	spec, err := queryspec.New(1, 10, "", "")
+	if err != nil {
+		return err
+	}
+	spec.Filters = []queryspec.Filter{{Field: "username", Op: queryspec.OpLike, Value: f.Key}}
+	users, err := b.Users.FindAll(otherCtx, spec)
+	if err != nil {
+		return err
+	}
+	numrows, err := b.Users.CountAll(otherCtx, spec)
+	if err != nil {
+		return err
+	}
+	if err := first(expect("listed users", len(users), 0), expect("counted users", numrows, 0)); err != nil {
+		return err
+	}
+
+	spec, err = queryspec.New(1, 10, "", "")
+	if err != nil {
+		return err
+	}
+	spec.Filters = []queryspec.Filter{{Field: "name", Op: queryspec.OpLike, Value: f.Key}}
+	roles, err := b.Roles.FindAll(otherCtx, spec)
+	if err != nil {
+		return err
+	}
+	numrows, err = b.Roles.CountByName(otherCtx, viewer.Name)
+	if err != nil {
+		return err
+	}
+	if err := first(expect("listed roles", len(roles), 0), expect("counted roles", numrows, 0)); err != nil {
+		return err
+	}
+
+	actors, err := b.Users.FindActorsByIds(ctx, []int{alice.Id})
+	if err != nil {
+		return err
+	}
+	otherActors, err := b.Users.FindActorsByIds(otherCtx, []int{alice.Id})
+	if err != nil {
+		return err
+	}
+	if err := first(expect("actors", len(actors), 1), expect("actors of the other tenant", len(otherActors), 0)); err != nil {
+		return err
+	}
+
+	grants, err := b.Grants.FindAllByUserId(otherCtx, alice.Id)
+	if err != nil {
+		return err
+	}
+	if err := expect("listed grants", len(grants), 0); err != nil {
+		return err
+	}
+
+	// writes from the other tenant
+	renamed := *alice
+	renamed.Name = "Mallory"
+	_, err = b.Users.Update(otherCtx, &renamed)
+	if err := expectNoRows("update user", err); err != nil {
+		return err
+	}
+	err = b.Users.Destroy(otherCtx, &entity.User{Id: alice.Id, Uuid: alice.Uuid, DeletedAt: now()})
+	if err := expectNoRows("delete user", err); err != nil {
+		return err
+	}
+	err = b.Users.SavePassword(otherCtx, alice.Id, "taken over")
+	if err := expectNoRows("save password", err); err != nil {
+		return err
+	}
+
+	renamedRole := *viewer
+	renamedRole.Name = other.Name("viewer")
+	_, err = b.Roles.Update(otherCtx, &renamedRole)
+	if err := expectNoRows("update role", err); err != nil {
+		return err
+	}
+	err = b.Roles.Destroy(otherCtx, &entity.Role{Id: viewer.Id, Uuid: viewer.Uuid, DeletedAt: now()})
+	if err := expectNoRows("delete role", err); err != nil {
+		return err
+	}
+
+	err = b.Grants.Destroy(otherCtx, g)
+	if err := expectNoRows("delete grant", err); err != nil {
+		return err
+	}
+
+	affected, err := b.Users.AssignRole(otherCtx, viewer.Id, []string{alice.Uuid}, sql.NullInt64{})
+	if err != nil {
+		return err
+	}
+	if err := expect("assigned users", affected, 0); err != nil {
+		return err
+	}
+
+	// nothing changed in the tenant of the rows
+	foundUser, err := b.Users.FindByUuid(ctx, alice.Uuid)
+	if err != nil {
+		return err
+	}
+	foundRole, err := b.Roles.FindByUuid(ctx, viewer.Uuid)
+	if err != nil {
+		return err
+	}
+	_, err = b.Grants.FindByUuid(ctx, g.Uuid)
+	if err != nil {
+		return err
+	}
+
+	return first(
+		expect("user name", foundUser.Name, "Alice"),
+		expect("user version", foundUser.Version, alice.Version),
+		expect("role name", foundRole.Name, viewer.Name),
+		expect("role version", foundRole.Version, viewer.Version),
+	)
+}
@@ -0,0 +1,366 @@
+// Package conformance checks that the persistence backends behave alike.
+// Every case runs against the persistents of each backend inside a
+// transaction that is rolled back.
+//
+// The cases run against the in-memory backend and a SQLite database migrated
+// in a temporary folder. Setting TEST_DB_DRIVER (postgres or mysql) and
+// TEST_DB_DSN runs them against that database instead of SQLite, it is
+// migrated first and nothing the cases write is kept. A MySQL DSN needs the
+// options of the application: parseTime, clientFoundRows and ANSI_QUOTES in
+// its sql_mode. The persistents are singletons, a single SQL database is
+// checked per run.
+package conformance
+
+import (
+	"context"
+	"database/sql"
+	stdErrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	auditData "github.com/Adhiana46/echo-boilerplate/internal/audit/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	grantData "github.com/Adhiana46/echo-boilerplate/internal/grant/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/group"
+	groupData "github.com/Adhiana46/echo-boilerplate/internal/group/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	permissionData "github.com/Adhiana46/echo-boilerplate/internal/permission/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	roleData "github.com/Adhiana46/echo-boilerplate/internal/role/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
+	tenantData "github.com/Adhiana46/echo-boilerplate/internal/tenant/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	userData "github.com/Adhiana46/echo-boilerplate/internal/user/data"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/stdlib"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+// Backend holds the persistents of one backend
+type Backend struct {
+	TxManager   txmanager.TxManager
+	AuditLogs   audit.AuditLogPersistent
+	Grants      grant.GrantPersistent
+	Groups      group.GroupPersistent
+	Permissions permission.PermissionPersistent
+	Roles       role.RolePersistent
+	Tenants     tenant.TenantPersistent
+	Users       user.UserPersistent
+	Devices     user.UserDevicePersistent
+}
+
+// Case is one behaviour every backend must have, ctx carries a tenant created
+// for the case
+type Case struct {
+	Name string
+	Run  func(ctx context.Context, b Backend, f *Fixture) error
+}
+
+// Cases are run in order against every backend
+var Cases = []Case{
+	{"permissions: unique names, versions, trash and purge", permissionCase},
+	{"roles: inheritance, permission paths and versions", roleCase},
+	{"users: search, filters, trash and restore", userSearchCase},
+	{"users: cursor pages", userCursorCase},
+	{"users: role assignment and permission holders", userRoleCase},
+	{"users: password hash on the credential path only", userPasswordCase},
+	{"tenants: adding and removing members", tenantCase},
+	{"groups: members and roles", groupCase},
+	{"grants: expiry sweep", grantCase},
+	{"devices: tokens", deviceCase},
+	{"audit logs: diffs, filters and tenants", auditLogCase},
+	{"tenants: users, roles and grants stay in their tenant", isolationCase},
+}
+
+// errRollback ends the transaction of a passed case
+var errRollback = stdErrors.New("conformance: rollback")
+
+func TestMemory(t *testing.T) {
+	db := memdb.New()
+
+	run(t, Backend{
+		TxManager:   memdb.NewTxManager(db),
+		AuditLogs:   auditData.NewMemoryAuditLogPersistent(db),
+		Grants:      grantData.NewMemoryGrantPersistent(db),
+		Groups:      groupData.NewMemoryGroupPersistent(db),
+		Permissions: permissionData.NewMemoryPermissionPersistent(db),
+		Roles:       roleData.NewMemoryRolePersistent(db),
+		Tenants:     tenantData.NewMemoryTenantPersistent(db),
+		Users:       userData.NewMemoryUserPersistent(db),
+		Devices:     userData.NewMemoryUserDevicePersistent(db),
+	})
+}
+
+func TestSql(t *testing.T) {
+	db := openDb(t)
+
+	run(t, Backend{
+		TxManager:   txmanager.NewTxManager(db),
+		AuditLogs:   auditData.NewSqlAuditLogPersistent(db),
+		Grants:      grantData.NewSqlGrantPersistent(db),
+		Groups:      groupData.NewSqlGroupPersistent(db),
+		Permissions: permissionData.NewSqlPermissionPersistent(db),
+		Roles:       roleData.NewSqlRolePersistent(db),
+		Tenants:     tenantData.NewSqlTenantPersistent(db),
+		Users:       userData.NewSqlUserPersistent(db),
+		Devices:     userData.NewSqlUserDevicePersistent(db),
+	})
+
+	t.Run("audit logs: append-only", func(t *testing.T) {
+		for _, query := range []string{`UPDATE audit_logs SET action = 'forged'`, `DELETE FROM audit_logs`} {
+			err := appendOnly(db, query)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	})
+}
+
+// appendOnly checks the query fails on a logged change, the change is rolled
+// back either way
+func appendOnly(db *txmanager.DB, query string) error {
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, db.Rebind(`INSERT INTO audit_logs (uuid, action, resource_type, resource_uuid, user_agent) VALUES (?, ?, ?, ?, ?)`),
+		uuid.NewString(), constants.AUDIT_ACTION_CREATE, constants.AUDIT_RESOURCE_USER, uuid.NewString(), "")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query)
+	if err == nil {
+		return fmt.Errorf("%s: succeeded on an append-only log", query)
+	}
+
+	return nil
+}
+
+// openDb opens the database of TEST_DB_DRIVER and TEST_DB_DSN, or a SQLite
+// file removed with the test, and runs its migrations
+func openDb(t *testing.T) *txmanager.DB {
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DB_DSN")
+
+	var dialect txmanager.Dialect
+	switch {
+	case dsn == "":
+		driver, dialect = "sqlite", txmanager.SQLite
+		dsn = "file:" + filepath.Join(t.TempDir(), "conformance.db") + "?_pragma=foreign_keys(1)&_time_format=sqlite&_txlock=immediate"
+	case driver == "mysql":
+		dialect = txmanager.MySQL
+	case driver == "postgres":
+		driver, dialect = "pgx", txmanager.Postgres
+	default:
+		t.Fatalf("TEST_DB_DRIVER: %q is neither postgres nor mysql", driver)
+	}
+
+	conn, err := sqlx.Connect(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db := txmanager.NewDB(conn, txmanager.Options{Dialect: dialect})
+
+	err = migrateUp(db)
+	if err != nil {
+		t.Fatal("migrate: ", err)
+	}
+
+	return db
+}
+
+// migrateUp runs the migrations of the dialect of db, like the migrate command
+func migrateUp(db *txmanager.DB) error {
+	var driver database.Driver
+	var err error
+
+	dir := "file://../migrations"
+
+	switch db.Dialect() {
+	case txmanager.MySQL:
+		driver, err = mysql.WithInstance(db.DB.DB, &mysql.Config{})
+		dir += "/mysql"
+	case txmanager.SQLite:
+		driver, err = sqlite.WithInstance(db.DB.DB, &sqlite.Config{})
+		dir += "/sqlite"
+	default:
+		driver, err = postgres.WithInstance(db.DB.DB, &postgres.Config{})
+	}
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithDatabaseInstance(dir, string(db.Dialect()), driver)
+	if err != nil {
+		return err
+	}
+
+	err = m.Up()
+	if err == migrate.ErrNoChange {
+		return nil
+	}
+
+	return err
+}
+
+// run runs every case against the backend, nothing they write is kept
+func run(t *testing.T, b Backend) {
+	for _, c := range Cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			err := b.TxManager.WithinTx(context.Background(), func(ctx context.Context) error {
+				f, err := newFixture(ctx, b)
+				if err != nil {
+					return fmt.Errorf("fixture: %w", err)
+				}
+
+				err = c.Run(f.ctx, b, f)
+				if err != nil {
+					return err
+				}
+
+				return errRollback
+			})
+			if err != errRollback {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Fixture is the tenant a case runs in, names made from Key do not collide
+// with the data already in the database
+type Fixture struct {
+	Key    string
+	Tenant *entity.Tenant
+
+	ctx context.Context
+}
+
+func newFixture(ctx context.Context, b Backend) (*Fixture, error) {
+	key := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+
+	t, err := b.Tenants.Create(ctx, &entity.Tenant{
+		Uuid:      uuid.NewString(),
+		Slug:      "conformance-" + key,
+		Name:      "Conformance " + key,
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Fixture{
+		Key:    key,
+		Tenant: t,
+		ctx: utils.WithTenant(ctx, &dto.TenantResponseWithID{
+			ID:   t.Id,
+			Uuid: t.Uuid,
+			Slug: t.Slug,
+			Name: t.Name,
+		}),
+	}, nil
+}
+
+// Name returns a name unique to the fixture
+func (f *Fixture) Name(name string) string {
+	return f.Key + "-" + name
+}
+
+// Permission creates a permission of the catalog
+func (f *Fixture) Permission(ctx context.Context, b Backend, name string) (*entity.Permission, error) {
+	return b.Permissions.Create(ctx, &entity.Permission{
+		Uuid:      uuid.NewString(),
+		Name:      f.Name(name),
+		Type:      "action",
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	})
+}
+
+// Role creates a role of the tenant
+func (f *Fixture) Role(ctx context.Context, b Backend, name string, permissions []*entity.Permission, parents []*entity.Role) (*entity.Role, error) {
+	return b.Roles.Create(ctx, &entity.Role{
+		Uuid:        uuid.NewString(),
+		Name:        f.Name(name),
+		Permissions: permissions,
+		Parents:     parents,
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+	})
+}
+
+// User creates a member of the tenant
+func (f *Fixture) User(ctx context.Context, b Backend, username string, name string, roleId int) (*entity.User, error) {
+	return b.Users.Create(ctx, &entity.User{
+		Uuid:      uuid.NewString(),
+		Username:  f.Name(username),
+		Email:     f.Name(username) + "@example.com",
+		Password:  "secret",
+		Name:      name,
+		RoleId:    roleId,
+		Status:    1,
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	})
+}
+
+// now is truncated to the second, the precision of the columns
+func now() sql.NullTime {
+	return sql.NullTime{Time: time.Now().Truncate(time.Second), Valid: true}
+}
+
+// expect returns an error describing the mismatch when got is not want
+func expect[T comparable](what string, got T, want T) error {
+	if got != want {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+
+	return nil
+}
+
+// expectNoRows returns an error unless err is sql.ErrNoRows
+func expectNoRows(what string, err error) error {
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("%s: got %v, want %v", what, err, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// first returns the first error
+func first(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
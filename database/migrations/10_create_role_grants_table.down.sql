@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS role_grants;
+DROP SEQUENCE IF EXISTS role_grants_seq;
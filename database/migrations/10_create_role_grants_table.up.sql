@@ -0,0 +1,26 @@
+CREATE SEQUENCE role_grants_seq;
+
+-- temporary roles granted to a member of a tenant on top of their own role
+CREATE TABLE role_grants
+(
+	id INT NOT NULL DEFAULT NEXTVAL ('role_grants_seq'),
+	uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role_id INT NOT NULL,
+    valid_from TIMESTAMP(0) NOT NULL,
+    valid_until TIMESTAMP(0) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_role_grants_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_grants_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_grants_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT ck_role_grants_validity CHECK (valid_until > valid_from),
+
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_role_grants_tenant_id_user_id ON role_grants (tenant_id, user_id);
+CREATE INDEX idx_role_grants_valid_until ON role_grants (valid_until);
@@ -0,0 +1,16 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- full-text document of a user, username weighs the most. A stored generated
+-- column is computed for the existing rows when it is added
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', COALESCE(username, '')), 'A') ||
+    setweight(to_tsvector('simple', COALESCE(name, '')), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(email, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
+
+-- partial matches, also used by LOWER(column) LIKE '%term%' in user lists
+CREATE INDEX idx_users_username_trgm ON users USING GIN (LOWER(username) gin_trgm_ops);
+CREATE INDEX idx_users_email_trgm ON users USING GIN (LOWER(email) gin_trgm_ops);
+CREATE INDEX idx_users_name_trgm ON users USING GIN (LOWER(name) gin_trgm_ops);
@@ -0,0 +1,31 @@
+DROP INDEX IF EXISTS idx_permissions_deleted_at;
+DROP INDEX IF EXISTS idx_roles_deleted_at;
+DROP INDEX IF EXISTS idx_tenant_users_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+-- trashed rows would break the unique constraints, they are purged
+DELETE FROM tenant_users WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+UPDATE tenant_users SET role_id = NULL WHERE role_id IN (SELECT id FROM roles WHERE deleted_at IS NOT NULL);
+DELETE FROM roles WHERE deleted_at IS NOT NULL;
+DELETE FROM permissions WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS permissions_name_key;
+ALTER TABLE permissions ADD CONSTRAINT permissions_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS roles_tenant_id_name_key;
+CREATE UNIQUE INDEX roles_tenant_id_name_key ON roles ((COALESCE(tenant_id, 0)), name);
+
+DROP INDEX IF EXISTS users_email_key;
+DROP INDEX IF EXISTS users_username_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+
+ALTER TABLE permissions DROP COLUMN deleted_by;
+ALTER TABLE permissions DROP COLUMN deleted_at;
+ALTER TABLE roles DROP COLUMN deleted_by;
+ALTER TABLE roles DROP COLUMN deleted_at;
+ALTER TABLE tenant_users DROP COLUMN deleted_by;
+ALTER TABLE tenant_users DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN deleted_by;
+ALTER TABLE users DROP COLUMN deleted_at;
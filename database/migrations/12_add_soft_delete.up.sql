@@ -0,0 +1,28 @@
+-- a user is trashed from a tenant through their membership, the account
+-- itself is trashed once it is not a member of any tenant anymore
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP(0) DEFAULT NULL;
+ALTER TABLE users ADD COLUMN deleted_by INT DEFAULT NULL;
+ALTER TABLE tenant_users ADD COLUMN deleted_at TIMESTAMP(0) DEFAULT NULL;
+ALTER TABLE tenant_users ADD COLUMN deleted_by INT DEFAULT NULL;
+ALTER TABLE roles ADD COLUMN deleted_at TIMESTAMP(0) DEFAULT NULL;
+ALTER TABLE roles ADD COLUMN deleted_by INT DEFAULT NULL;
+ALTER TABLE permissions ADD COLUMN deleted_at TIMESTAMP(0) DEFAULT NULL;
+ALTER TABLE permissions ADD COLUMN deleted_by INT DEFAULT NULL;
+
+-- trashed rows do not hold on to their unique values
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX users_username_key ON users (username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS roles_tenant_id_name_key;
+CREATE UNIQUE INDEX roles_tenant_id_name_key ON roles ((COALESCE(tenant_id, 0)), name) WHERE deleted_at IS NULL;
+
+ALTER TABLE permissions DROP CONSTRAINT IF EXISTS permissions_name_key;
+CREATE UNIQUE INDEX permissions_name_key ON permissions (name) WHERE deleted_at IS NULL;
+
+-- purging looks trashed rows up by age
+CREATE INDEX idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_tenant_users_deleted_at ON tenant_users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_roles_deleted_at ON roles (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_permissions_deleted_at ON permissions (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE permissions DROP COLUMN IF EXISTS version;
+ALTER TABLE roles DROP COLUMN IF EXISTS version;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
@@ -0,0 +1,5 @@
+-- bumped by every update, updates are only applied to the version they were
+-- based on
+ALTER TABLE users ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE roles ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE permissions ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_logs;
+DROP FUNCTION IF EXISTS audit_logs_append_only();
+DROP SEQUENCE IF EXISTS audit_logs_seq;
@@ -0,0 +1,36 @@
+CREATE SEQUENCE audit_logs_seq;
+
+-- changes made to users, roles and permissions, rows outlive the tenant, the
+-- actor and the resource they refer to
+CREATE TABLE audit_logs
+(
+    id INT NOT NULL DEFAULT NEXTVAL ('audit_logs_seq'),
+    uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL DEFAULT 0,
+    actor_id INT DEFAULT NULL,
+    action VARCHAR(50) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_uuid CHAR(36) NOT NULL,
+    old_values JSONB DEFAULT NULL,
+    new_values JSONB DEFAULT NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_audit_logs_tenant_id_created_at ON audit_logs (tenant_id, created_at);
+CREATE INDEX idx_audit_logs_resource ON audit_logs (resource_type, resource_uuid);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs (actor_id);
+
+-- the log is append-only
+CREATE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tg_audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE PROCEDURE audit_logs_append_only();
@@ -0,0 +1,11 @@
+CREATE TABLE role_parents
+(
+    role_id INT NOT NULL,
+    parent_id INT NOT NULL,
+
+    CONSTRAINT fk_role_parents_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_parents_parent_id FOREIGN KEY (parent_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT chk_role_parents_self CHECK (role_id <> parent_id),
+
+    PRIMARY KEY (role_id, parent_id)
+);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ALTER COLUMN role_id SET NOT NULL;
\ No newline at end of file
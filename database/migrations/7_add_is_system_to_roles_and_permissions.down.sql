@@ -0,0 +1,3 @@
+ALTER TABLE roles DROP COLUMN IF EXISTS is_system;
+
+ALTER TABLE permissions DROP COLUMN IF EXISTS is_system;
\ No newline at end of file
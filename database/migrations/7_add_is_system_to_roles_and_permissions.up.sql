@@ -0,0 +1,3 @@
+ALTER TABLE roles ADD COLUMN is_system BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE permissions ADD COLUMN is_system BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
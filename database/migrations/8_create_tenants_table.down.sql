@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN role_id INT DEFAULT NULL;
+ALTER TABLE users ADD CONSTRAINT fk_users_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE RESTRICT;
+
+-- keep the role of the first tenant each user belongs to
+UPDATE users u SET role_id = (
+    SELECT tu.role_id FROM tenant_users tu WHERE tu.user_id = u.id ORDER BY tu.tenant_id LIMIT 1
+);
+
+DROP INDEX IF EXISTS roles_tenant_id_name_key;
+ALTER TABLE roles ADD CONSTRAINT roles_name_key UNIQUE (name);
+ALTER TABLE roles DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenant_users;
+DROP TABLE IF EXISTS tenants;
+DROP SEQUENCE IF EXISTS tenants_seq;
@@ -0,0 +1,49 @@
+CREATE SEQUENCE tenants_seq;
+
+CREATE TABLE tenants
+(
+	id INT NOT NULL DEFAULT NEXTVAL ('tenants_seq'),
+	uuid CHAR(36) NOT NULL UNIQUE,
+	slug VARCHAR(100) UNIQUE NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+	updated_at TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+
+	PRIMARY KEY (id)
+);
+
+-- a user may belong to many tenants, with one role in each of them
+CREATE TABLE tenant_users
+(
+    tenant_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role_id INT DEFAULT NULL,
+    created_at TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_tenant_users_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_tenant_users_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_tenant_users_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE RESTRICT,
+
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+-- roles without a tenant (the seeded system roles) are shared by every tenant
+ALTER TABLE roles ADD COLUMN tenant_id INT DEFAULT NULL;
+ALTER TABLE roles ADD CONSTRAINT fk_roles_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_name_key;
+CREATE UNIQUE INDEX roles_tenant_id_name_key ON roles ((COALESCE(tenant_id, 0)), name);
+
+-- existing users move to a default tenant with their current role
+INSERT INTO tenants (uuid, slug, name)
+SELECT md5(random()::text || clock_timestamp()::text)::uuid, 'default', 'Default'
+WHERE EXISTS (SELECT 1 FROM users);
+
+INSERT INTO tenant_users (tenant_id, user_id, role_id)
+SELECT t.id, u.id, u.role_id
+FROM users u
+CROSS JOIN tenants t
+WHERE t.slug = 'default';
+
+ALTER TABLE users DROP COLUMN role_id;
@@ -0,0 +1,42 @@
+CREATE SEQUENCE groups_seq;
+
+CREATE TABLE groups
+(
+	id INT NOT NULL DEFAULT NEXTVAL ('groups_seq'),
+	uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+	updated_at TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_groups_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT uq_groups_tenant_id_name UNIQUE (tenant_id, name),
+
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE group_users
+(
+    group_id INT NOT NULL,
+    user_id INT NOT NULL,
+
+    CONSTRAINT fk_group_users_group_id FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT fk_group_users_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- members of a group are granted every role of the group
+CREATE TABLE group_roles
+(
+    group_id INT NOT NULL,
+    role_id INT NOT NULL,
+
+    CONSTRAINT fk_group_roles_group_id FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT fk_group_roles_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (group_id, role_id)
+);
@@ -0,0 +1,223 @@
+-- the schema of the Postgres migrations 1 to 13 in one step, later migrations
+-- keep the numbering of database/migrations. MySQL has no partial index, the
+-- live_* columns are NULL for trashed rows so that they do not hold on to
+-- their unique values.
+CREATE TABLE permissions
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    parent_id INT NOT NULL DEFAULT 0,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1,
+    live_name VARCHAR(255) GENERATED ALWAYS AS (IF(deleted_at IS NULL, name, NULL)) VIRTUAL,
+
+    CONSTRAINT permissions_name_key UNIQUE (live_name),
+    INDEX idx_permissions_deleted_at (deleted_at),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE tenants
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+
+    PRIMARY KEY (id)
+);
+
+-- roles without a tenant (the seeded system roles) are shared by every tenant.
+-- A generated column cannot be based on tenant_id as it cascades, the names
+-- of shared roles are only kept unique by the application.
+CREATE TABLE roles
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    tenant_id INT DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1,
+    live_name VARCHAR(255) GENERATED ALWAYS AS (IF(deleted_at IS NULL, name, NULL)) VIRTUAL,
+
+    CONSTRAINT fk_roles_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT roles_tenant_id_name_key UNIQUE (tenant_id, live_name),
+    INDEX idx_roles_deleted_at (deleted_at),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE role_permissions
+(
+    role_id INT NOT NULL,
+    permission_id INT NOT NULL,
+
+    CONSTRAINT fk_role_permissions_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_permissions_permission_id FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (role_id, permission_id)
+);
+
+-- MySQL forbids checks on cascading columns, roles are kept from being their
+-- own parent by the application
+CREATE TABLE role_parents
+(
+    role_id INT NOT NULL,
+    parent_id INT NOT NULL,
+
+    CONSTRAINT fk_role_parents_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_parents_parent_id FOREIGN KEY (parent_id) REFERENCES roles(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (role_id, parent_id)
+);
+
+CREATE TABLE users
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    username VARCHAR(100) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    status INT NOT NULL DEFAULT 1,
+    last_login_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1,
+    live_username VARCHAR(100) GENERATED ALWAYS AS (IF(deleted_at IS NULL, username, NULL)) VIRTUAL,
+    live_email VARCHAR(255) GENERATED ALWAYS AS (IF(deleted_at IS NULL, email, NULL)) VIRTUAL,
+
+    CONSTRAINT users_username_key UNIQUE (live_username),
+    CONSTRAINT users_email_key UNIQUE (live_email),
+    INDEX idx_users_deleted_at (deleted_at),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE user_devices
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    user_id INT NOT NULL,
+    token VARCHAR(255) NOT NULL,
+    ip VARCHAR(255),
+    location VARCHAR(255),
+    platform VARCHAR(255),
+    user_agent VARCHAR(255),
+    app_version VARCHAR(255),
+    vendor VARCHAR(255),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_user_devices_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (id)
+);
+
+-- a user may belong to many tenants, with one role in each of them
+CREATE TABLE tenant_users
+(
+    tenant_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role_id INT DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_tenant_users_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_tenant_users_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_tenant_users_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE RESTRICT,
+    INDEX idx_tenant_users_deleted_at (deleted_at),
+
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+-- groups is a reserved word of MySQL
+CREATE TABLE `groups`
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_groups_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT uq_groups_tenant_id_name UNIQUE (tenant_id, name),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE group_users
+(
+    group_id INT NOT NULL,
+    user_id INT NOT NULL,
+
+    CONSTRAINT fk_group_users_group_id FOREIGN KEY (group_id) REFERENCES `groups`(id) ON DELETE CASCADE,
+    CONSTRAINT fk_group_users_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- members of a group are granted every role of the group
+CREATE TABLE group_roles
+(
+    group_id INT NOT NULL,
+    role_id INT NOT NULL,
+
+    CONSTRAINT fk_group_roles_group_id FOREIGN KEY (group_id) REFERENCES `groups`(id) ON DELETE CASCADE,
+    CONSTRAINT fk_group_roles_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (group_id, role_id)
+);
+
+-- temporary roles granted to a member of a tenant on top of their own role
+CREATE TABLE role_grants
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role_id INT NOT NULL,
+    valid_from DATETIME NOT NULL,
+    valid_until DATETIME NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_role_grants_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_grants_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_grants_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT ck_role_grants_validity CHECK (valid_until > valid_from),
+    INDEX idx_role_grants_tenant_id_user_id (tenant_id, user_id),
+    INDEX idx_role_grants_valid_until (valid_until),
+
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,32 @@
+-- changes made to users, roles and permissions, rows outlive the tenant, the
+-- actor and the resource they refer to
+CREATE TABLE audit_logs
+(
+    id INT NOT NULL AUTO_INCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL DEFAULT 0,
+    actor_id INT DEFAULT NULL,
+    action VARCHAR(50) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_uuid CHAR(36) NOT NULL,
+    old_values JSON DEFAULT NULL,
+    new_values JSON DEFAULT NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL,
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_audit_logs_tenant_id_created_at (tenant_id, created_at),
+    INDEX idx_audit_logs_resource (resource_type, resource_uuid),
+    INDEX idx_audit_logs_actor_id (actor_id),
+
+    PRIMARY KEY (id)
+);
+
+-- the log is append-only. With the binary log on, creating the triggers needs
+-- SUPER or log_bin_trust_function_creators=1 for the user running migrations.
+CREATE TRIGGER tg_audit_logs_no_update BEFORE UPDATE ON audit_logs
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only';
+
+CREATE TRIGGER tg_audit_logs_no_delete BEFORE DELETE ON audit_logs
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only';
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS role_grants;
+DROP TABLE IF EXISTS group_roles;
+DROP TABLE IF EXISTS group_users;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS tenant_users;
+DROP TABLE IF EXISTS user_devices;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS role_parents;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS tenants;
+DROP TABLE IF EXISTS permissions;
@@ -0,0 +1,202 @@
+-- the schema of the Postgres migrations 1 to 13 in one step, later migrations
+-- keep the numbering of database/migrations
+CREATE TABLE permissions
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    parent_id INT NOT NULL DEFAULT 0,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE tenants
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL
+);
+
+-- roles without a tenant (the seeded system roles) are shared by every tenant
+CREATE TABLE roles
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    tenant_id INT DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1,
+
+    CONSTRAINT fk_roles_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+
+CREATE TABLE role_permissions
+(
+    role_id INT NOT NULL,
+    permission_id INT NOT NULL,
+
+    CONSTRAINT fk_role_permissions_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_permissions_permission_id FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE role_parents
+(
+    role_id INT NOT NULL,
+    parent_id INT NOT NULL,
+
+    CONSTRAINT fk_role_parents_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_parents_parent_id FOREIGN KEY (parent_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT chk_role_parents_self CHECK (role_id <> parent_id),
+
+    PRIMARY KEY (role_id, parent_id)
+);
+
+CREATE TABLE users
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    username VARCHAR(100) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    status INT NOT NULL DEFAULT 1,
+    last_login_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+    version INT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE user_devices
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    user_id INT NOT NULL,
+    token VARCHAR(255) NOT NULL,
+    ip VARCHAR(255),
+    location VARCHAR(255),
+    platform VARCHAR(255),
+    user_agent VARCHAR(255),
+    app_version VARCHAR(255),
+    vendor VARCHAR(255),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_user_devices_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- a user may belong to many tenants, with one role in each of them
+CREATE TABLE tenant_users
+(
+    tenant_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role_id INT DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME DEFAULT NULL,
+    deleted_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_tenant_users_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_tenant_users_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_tenant_users_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE RESTRICT,
+
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE TABLE groups
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_groups_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT uq_groups_tenant_id_name UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE group_users
+(
+    group_id INT NOT NULL,
+    user_id INT NOT NULL,
+
+    CONSTRAINT fk_group_users_group_id FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT fk_group_users_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- members of a group are granted every role of the group
+CREATE TABLE group_roles
+(
+    group_id INT NOT NULL,
+    role_id INT NOT NULL,
+
+    CONSTRAINT fk_group_roles_group_id FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT fk_group_roles_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (group_id, role_id)
+);
+
+-- temporary roles granted to a member of a tenant on top of their own role
+CREATE TABLE role_grants
+(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid CHAR(36) NOT NULL UNIQUE,
+    tenant_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role_id INT NOT NULL,
+    valid_from DATETIME NOT NULL,
+    valid_until DATETIME NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INT DEFAULT NULL,
+
+    CONSTRAINT fk_role_grants_tenant_id FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_grants_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_grants_role_id FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT ck_role_grants_validity CHECK (valid_until > valid_from)
+);
+
+CREATE INDEX idx_role_grants_tenant_id_user_id ON role_grants (tenant_id, user_id);
+CREATE INDEX idx_role_grants_valid_until ON role_grants (valid_until);
+
+-- trashed rows do not hold on to their unique values
+CREATE UNIQUE INDEX users_username_key ON users (username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX roles_tenant_id_name_key ON roles (COALESCE(tenant_id, 0), name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX permissions_name_key ON permissions (name) WHERE deleted_at IS NULL;
+
+-- purging looks trashed rows up by age
+CREATE INDEX idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_tenant_users_deleted_at ON tenant_users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_roles_deleted_at ON roles (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_permissions_deleted_at ON permissions (deleted_at) WHERE deleted_at IS NOT NULL;
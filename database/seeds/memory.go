@@ -0,0 +1,133 @@
+package seeds
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// SeedMemory writes the rows of the seeders to an in-memory database, the
+// database starts empty on every boot so there is nothing to roll back
+func SeedMemory(ctx context.Context, db *memdb.DB) error {
+	log.Println("[Seeder]:", "Seeding the in-memory database")
+
+	permissions, err := declaredPermissions()
+	if err != nil {
+		return err
+	}
+
+	password, err := utils.HashPassword(seedPassword)
+	if err != nil {
+		return err
+	}
+
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+
+	return db.Update(ctx, func(t *memdb.Tables) error {
+		permissionIds := map[string]int{}
+		for _, permission := range permissions {
+			parentId := 0
+
+			if permission.Menu != "" {
+				menuId, ok := permissionIds[permission.Menu]
+				if !ok {
+					menuId, err = t.InsertPermission(&entity.Permission{
+						Uuid:      uuid.NewString(),
+						Name:      permission.Menu,
+						Type:      "menu",
+						IsSystem:  true,
+						CreatedAt: now,
+						UpdatedAt: now,
+					})
+					if err != nil {
+						return err
+					}
+					permissionIds[permission.Menu] = menuId
+				}
+
+				parentId = menuId
+			}
+
+			permissionIds[permission.Name], err = t.InsertPermission(&entity.Permission{
+				Uuid:      uuid.NewString(),
+				ParentId:  parentId,
+				Name:      permission.Name,
+				Type:      "action",
+				IsSystem:  true,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		roleIds := map[string]int{}
+		for role, permissions := range rolePermissions {
+			roleId, err := t.InsertRole(&entity.Role{
+				Uuid:      uuid.NewString(),
+				Name:      role,
+				IsSystem:  true,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+			roleIds[role] = roleId
+
+			for _, permission := range permissions {
+				err = t.AddRolePermission(roleId, permissionIds[permission])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		tenantId, err := t.InsertTenant(&entity.Tenant{
+			Uuid:      uuid.NewString(),
+			Slug:      DEFAULT_TENANT_SLUG,
+			Name:      "Default",
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, user := range userAccounts {
+			status, err := strconv.Atoi(user["status"])
+			if err != nil {
+				return err
+			}
+
+			userId, err := t.InsertUser(&entity.User{
+				Uuid:      uuid.NewString(),
+				Username:  user["username"],
+				Email:     user["email"],
+				Password:  password,
+				Name:      user["name"],
+				Status:    status,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = t.PutMember(memdb.Member{TenantId: tenantId, UserId: userId, RoleId: roleIds[user["role"]]})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
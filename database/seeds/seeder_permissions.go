@@ -1,78 +1,111 @@
 package seeds
 
 import (
-	"database/sql"
-	"fmt"
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/Adhiana46/echo-boilerplate/pkg/rbac"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
-type PermissionSeeder struct{}
-
-func (s *PermissionSeeder) Name() string {
-	return "Permission Seeder"
+// declaredPermission is a permission declared by the routes or the policies,
+// "users.create" is an action under the "users" menu
+type declaredPermission struct {
+	Name string
+	Menu string
 }
 
-func (s *PermissionSeeder) Up(db *sql.DB) error {
-	menus := []string{
-		"permissions",
-		"roles",
-		"users",
+// declaredPermissions returns the permissions seeded as the catalog, they
+// are only declared once the server is built
+func declaredPermissions() ([]declaredPermission, error) {
+	names := rbac.Declared()
+	if len(names) == 0 {
+		return nil, errors.New("no permission is declared, the server must be built before seeding")
 	}
 
-	actions := []string{
-		"create",
-		"read",
-		"update",
-		"delete",
+	permissions := []declaredPermission{}
+	for _, name := range names {
+		menu := ""
+		if idx := strings.Index(name, "."); idx > 0 {
+			menu = name[:idx]
+		}
+
+		permissions = append(permissions, declaredPermission{Name: name, Menu: menu})
 	}
 
+	return permissions, nil
+}
+
+type PermissionSeeder struct{}
+
+func (s *PermissionSeeder) Name() string {
+	return "Permission Seeder"
+}
+
+func (s *PermissionSeeder) Up(db *sqlx.DB) error {
 	sql := `
 		INSERT INTO permissions 
-		(uuid, parent_id, name, type, created_at, updated_at) 
+		(uuid, parent_id, name, type, is_system, created_at, updated_at) 
 		VALUES 
-		($1, $2, $3, $4, $5, $6)
-		RETURNING id`
+		(?, ?, ?, ?, TRUE, ?, ?)`
 
-	parentId := 0
-	for _, menu := range menus {
-		err := db.QueryRow(
-			sql,
+	permissions, err := declaredPermissions()
+	if err != nil {
+		return err
+	}
+
+	menuIds := map[string]int{}
+	for _, permission := range permissions {
+		parentId := 0
+
+		if permission.Menu != "" {
+			menuId, ok := menuIds[permission.Menu]
+			if !ok {
+				menuUuid := uuid.NewString()
+				menuId, err = insert(
+					db,
+					"permissions",
+					menuUuid,
+					sql,
+					menuUuid,
+					0,
+					permission.Menu,
+					"menu",
+					time.Now(),
+					time.Now(),
+				)
+
+				if err != nil {
+					return err
+				}
+				menuIds[permission.Menu] = menuId
+			}
+
+			parentId = menuId
+		}
+
+		_, err = db.Exec(
+			db.Rebind(sql),
 			uuid.NewString(),
-			0,
-			menu,
-			"menu",
+			parentId,
+			permission.Name,
+			"action",
 			time.Now(),
 			time.Now(),
-		).Scan(&parentId)
+		)
 
 		if err != nil {
 			return err
 		}
-
-		for _, action := range actions {
-			err = db.QueryRow(
-				sql,
-				uuid.NewString(),
-				parentId,
-				fmt.Sprintf("%s.%s", menu, action),
-				"action",
-				time.Now(),
-				time.Now(),
-			).Err()
-
-			if err != nil {
-				return err
-			}
-		}
 	}
 
 	return nil
 }
 
-func (p *PermissionSeeder) Down(db *sql.DB) error {
-	_, err := db.Exec("TRUNCATE permissions CASCADE")
+func (p *PermissionSeeder) Down(db *sqlx.DB) error {
+	_, err := db.Exec("DELETE FROM permissions")
 	if err != nil {
 		return err
 	}
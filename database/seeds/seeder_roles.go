@@ -1,84 +1,125 @@
 package seeds
 
 import (
-	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
+// rolePermissions are the permissions of the system roles, keyed by role
+var rolePermissions = map[string][]string{
+	"super-admin": {
+		"audit-logs",
+		"audit-logs.read",
+		"cache",
+		"cache.read",
+		"groups",
+		"groups.create",
+		"groups.read",
+		"groups.update",
+		"groups.delete",
+		"groups.members",
+		"groups.roles.grant",
+		"groups.roles.revoke",
+		"permissions",
+		"permissions.create",
+		"permissions.read",
+		"permissions.update",
+		"permissions.delete",
+		"permissions.restore",
+		"roles",
+		"roles.create",
+		"roles.read",
+		"roles.update",
+		"roles.delete",
+		"roles.restore",
+		"roles.permissions.grant",
+		"roles.permissions.revoke",
+		"roles.assign",
+		"roles.escalate",
+		"tenants",
+		"tenants.create",
+		"tenants.read",
+		"tenants.update",
+		"tenants.delete",
+		"tenants.users",
+		"users",
+		"users.create",
+		"users.read",
+		"users.update",
+		"users.delete",
+		"users.restore",
+	},
+	"admin": {
+		"groups",
+		"groups.create",
+		"groups.read",
+		"groups.update",
+		"groups.delete",
+		"groups.members",
+		"groups.roles.grant",
+		"groups.roles.revoke",
+		"roles",
+		"roles.create",
+		"roles.read",
+		"roles.update",
+		"roles.delete",
+		"roles.restore",
+		"roles.permissions.grant",
+		"roles.permissions.revoke",
+		"roles.assign",
+		"users",
+		"users.create",
+		"users.read",
+		"users.update",
+		"users.delete",
+		"users.restore",
+	},
+}
+
 type RoleSeeder struct{}
 
 func (s *RoleSeeder) Name() string {
 	return "Role Seeder"
 }
 
-func (s *RoleSeeder) Up(db *sql.DB) error {
-	roles := map[string][]string{
-		"super-admin": {
-			"permissions",
-			"permissions.create",
-			"permissions.read",
-			"permissions.update",
-			"permissions.delete",
-			"roles",
-			"roles.create",
-			"roles.read",
-			"roles.update",
-			"roles.delete",
-			"users",
-			"users.create",
-			"users.read",
-			"users.update",
-			"users.delete",
-		},
-		"admin": {
-			"roles",
-			"roles.create",
-			"roles.read",
-			"roles.update",
-			"roles.delete",
-			"users",
-			"users.create",
-			"users.read",
-			"users.update",
-			"users.delete",
-		},
-	}
-
+func (s *RoleSeeder) Up(db *sqlx.DB) error {
 	sqlRole := `
 		INSERT INTO roles 
-		(uuid, name, created_at, updated_at)
+		(uuid, name, is_system, created_at, updated_at)
 		VALUES
-		($1, $2, $3, $4)
-		RETURNING id
+		(?, ?, TRUE, ?, ?)
 	`
 	sqlRolePerm := `
 		INSERT INTO role_permissions
 		(role_id, permission_id)
-		SELECT $1, (SELECT id FROM permissions WHERE name = $2)
+		SELECT ?, (SELECT id FROM permissions WHERE name = ?)
 	`
 
-	roleId := 0
-	for role, permissions := range roles {
-		err := db.QueryRow(
+	for role, permissions := range rolePermissions {
+		roleUuid := uuid.NewString()
+		roleId, err := insert(
+			db,
+			"roles",
+			roleUuid,
 			sqlRole,
-			uuid.NewString(),
+			roleUuid,
 			role,
 			time.Now(),
 			time.Now(),
-		).Scan(&roleId)
+		)
 
 		if err != nil {
 			return err
 		}
 
 		for _, permission := range permissions {
-			err := db.QueryRow(
-				sqlRolePerm,
+			_, err := db.Exec(
+				db.Rebind(sqlRolePerm),
 				roleId,
 				permission,
-			).Err()
+			)
 
 			if err != nil {
 				return err
@@ -89,15 +130,16 @@ func (s *RoleSeeder) Up(db *sql.DB) error {
 	return nil
 }
 
-func (p *RoleSeeder) Down(db *sql.DB) error {
+func (p *RoleSeeder) Down(db *sqlx.DB) error {
 	var err error
 
-	_, err = db.Exec("TRUNCATE role_permissions")
+	// memberships hold on to their role, they go first
+	_, err = db.Exec("DELETE FROM tenant_users")
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec("TRUNCATE roles CASCADE")
+	_, err = db.Exec("DELETE FROM roles")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,55 @@
+package seeds
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// DEFAULT_TENANT_SLUG is the tenant seeded users belong to, it matches the
+// tenant created when migrating an existing database
+const DEFAULT_TENANT_SLUG = "default"
+
+type TenantSeeder struct{}
+
+func (s *TenantSeeder) Name() string {
+	return "Tenant Seeder"
+}
+
+// Up keeps the default tenant created when migrating an existing database
+func (s *TenantSeeder) Up(db *sqlx.DB) error {
+	sqlExists := `SELECT COUNT(id) FROM tenants WHERE slug = ?`
+	sql := `
+		INSERT INTO tenants
+		(uuid, slug, name, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?)
+	`
+
+	exists := 0
+	err := db.QueryRow(db.Rebind(sqlExists), DEFAULT_TENANT_SLUG).Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	_, err = db.Exec(
+		db.Rebind(sql),
+		uuid.NewString(),
+		DEFAULT_TENANT_SLUG,
+		"Default",
+		time.Now(),
+		time.Now(),
+	)
+
+	return err
+}
+
+func (s *TenantSeeder) Down(db *sqlx.DB) error {
+	_, err := db.Exec("DELETE FROM tenants")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,63 +1,85 @@
 package seeds
 
 import (
-	"database/sql"
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
+const seedPassword = "pass1234"
+
+// userAccounts are seeded with seedPassword in the default tenant
+var userAccounts = []map[string]string{
+	{
+		"username": "root",
+		"email":    "root@example.com",
+		"name":     "Super Admin",
+		"role":     "super-admin",
+		"status":   "1",
+	},
+	{
+		"username": "admin",
+		"email":    "admin@example.com",
+		"name":     "Admin",
+		"role":     "admin",
+		"status":   "1",
+	},
+}
+
 type UserSeeder struct{}
 
 func (s *UserSeeder) Name() string {
 	return "User Seeder"
 }
 
-func (s *UserSeeder) Up(db *sql.DB) error {
-	password, err := utils.HashPassword("pass1234")
+func (s *UserSeeder) Up(db *sqlx.DB) error {
+	password, err := utils.HashPassword(seedPassword)
 	if err != nil {
 		return err
 	}
 
-	users := []map[string]string{
-		{
-			"username": "root",
-			"email":    "root@example.com",
-			"password": password,
-			"name":     "Super Admin",
-			"role":     "super-admin",
-			"status":   "1",
-		},
-		{
-			"username": "admin",
-			"email":    "admin@example.com",
-			"password": password,
-			"name":     "Admin",
-			"role":     "admin",
-			"status":   "1",
-		},
-	}
-
 	sql := `
 		INSERT INTO users
-		(uuid, username, email, password, name, role_id, status, created_at, updated_at)
-		SELECT $1, $2, $3, $4, $5, (SELECT id FROM roles WHERE name = $6), $7, $8, $9
+		(uuid, username, email, password, name, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	// seeded users belong to the default tenant
+	sqlMember := `
+		INSERT INTO tenant_users
+		(tenant_id, user_id, role_id)
+		SELECT (SELECT id FROM tenants WHERE slug = ?), ?, (SELECT id FROM roles WHERE name = ? AND tenant_id IS NULL)
 	`
 
-	for _, user := range users {
-		err := db.QueryRow(
+	for _, user := range userAccounts {
+		userUuid := uuid.NewString()
+		userId, err := insert(
+			db,
+			"users",
+			userUuid,
 			sql,
-			uuid.NewString(),
+			userUuid,
 			user["username"],
 			user["email"],
-			user["password"],
+			password,
 			user["name"],
-			user["role"],
 			user["status"],
 			time.Now(),
 			time.Now(),
-		).Err()
+		)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = db.Exec(
+			db.Rebind(sqlMember),
+			DEFAULT_TENANT_SLUG,
+			userId,
+			user["role"],
+		)
 
 		if err != nil {
 			return err
@@ -67,8 +89,8 @@ func (s *UserSeeder) Up(db *sql.DB) error {
 	return nil
 }
 
-func (p *UserSeeder) Down(db *sql.DB) error {
-	_, err := db.Exec("TRUNCATE users")
+func (p *UserSeeder) Down(db *sqlx.DB) error {
+	_, err := db.Exec("DELETE FROM users")
 	if err != nil {
 		return err
 	}
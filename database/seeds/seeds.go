@@ -2,23 +2,26 @@ package seeds
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"sort"
+
+	"github.com/jmoiron/sqlx"
 )
 
 type Seed struct {
-	db *sql.DB
+	db *sqlx.DB
 }
 
 type SeederInterface interface {
 	Name() string
-	Up(db *sql.DB) error
-	Down(db *sql.DB) error
+	Up(db *sqlx.DB) error
+	Down(db *sqlx.DB) error
 }
 
-func NewSeeder(db *sql.DB) *Seed {
+// NewSeeder runs the seeders on any of the SQL databases, their queries are
+// written with ? placeholders and rebound for the driver of db
+func NewSeeder(db *sqlx.DB) *Seed {
 	return &Seed{
 		db: db,
 	}
@@ -27,6 +30,7 @@ func NewSeeder(db *sql.DB) *Seed {
 var seeds []SeederInterface = []SeederInterface{
 	&PermissionSeeder{},
 	&RoleSeeder{},
+	&TenantSeeder{},
 	&UserSeeder{},
 }
 
@@ -55,3 +59,17 @@ func (s *Seed) Down(ctx context.Context) error {
 
 	return nil
 }
+
+// insert runs an INSERT of a row identified by its uuid and returns the id the
+// database generated for it, RETURNING is not available everywhere
+func insert(db *sqlx.DB, table string, uuid string, query string, args ...any) (int, error) {
+	_, err := db.Exec(db.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id := 0
+	err = db.QueryRow(db.Rebind("SELECT id FROM "+table+" WHERE uuid = ?"), uuid).Scan(&id)
+
+	return id, err
+}
@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+// ActorResponse is a created_by or updated_by field, the id of the user
+// unless the request includes it, then a reference or null when the user is
+// gone
+type ActorResponse struct {
+	Id    int
+	Actor *entity.Actor
+}
+
+type ActorReferenceResponse struct {
+	Uuid string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+func NewActorResponse(id sql.NullInt64, actor *entity.Actor) ActorResponse {
+	return ActorResponse{
+		Id:    int(id.Int64),
+		Actor: actor,
+	}
+}
+
+func (a ActorResponse) MarshalJSON() ([]byte, error) {
+	if a.Actor == nil {
+		return json.Marshal(a.Id)
+	}
+
+	if a.Actor.Uuid == "" {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(ActorReferenceResponse{
+		Uuid: a.Actor.Uuid,
+		Name: a.Actor.Name,
+	})
+}
+
+// UnmarshalJSON reads back any of the shapes MarshalJSON writes, the access
+// token claims embed them. A reference has no id, the claims only hold ids,
+// see WithActorIds.
+func (a *ActorResponse) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*a = ActorResponse{Actor: &entity.Actor{}}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		ref := ActorReferenceResponse{}
+		if err := json.Unmarshal(data, &ref); err != nil {
+			return err
+		}
+
+		*a = ActorResponse{Actor: &entity.Actor{Uuid: ref.Uuid, Name: ref.Name}}
+		return nil
+	}
+
+	*a = ActorResponse{}
+
+	return json.Unmarshal(data, &a.Id)
+}
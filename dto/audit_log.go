@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+type AuditLogResponse struct {
+	Uuid         string          `json:"uuid"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceUuid string          `json:"resource_uuid"`
+	Actor        ActorResponse   `json:"actor"`
+	OldValues    json.RawMessage `json:"old_values"`
+	NewValues    json.RawMessage `json:"new_values"`
+	IP           string          `json:"ip"`
+	UserAgent    string          `json:"user_agent"`
+	RequestId    string          `json:"request_id"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+func NewAuditLogResponse(e *entity.AuditLog) *AuditLogResponse {
+	createdAt := ""
+	if e.CreatedAt.Valid {
+		createdAt = e.CreatedAt.Time.Format(time.RFC3339)
+	}
+
+	var oldValues, newValues json.RawMessage
+	if e.OldValues.Valid {
+		oldValues = json.RawMessage(e.OldValues.String)
+	}
+	if e.NewValues.Valid {
+		newValues = json.RawMessage(e.NewValues.String)
+	}
+
+	return &AuditLogResponse{
+		Uuid:         e.Uuid,
+		Action:       e.Action,
+		ResourceType: e.ResourceType,
+		ResourceUuid: e.ResourceUuid,
+		Actor:        NewActorResponse(e.ActorId, e.Actor),
+		OldValues:    oldValues,
+		NewValues:    newValues,
+		IP:           e.IP,
+		UserAgent:    e.UserAgent,
+		RequestId:    e.RequestId,
+		CreatedAt:    createdAt,
+	}
+}
+
+type AuditLogCollectionResponse struct {
+	Data       []*AuditLogResponse `json:"data"`
+	Pagination PaginationResponse  `json:"pagination"`
+}
+
+func NewAuditLogCollectionResponse(rows []*entity.AuditLog, pagination PaginationResponse) *AuditLogCollectionResponse {
+	response := &AuditLogCollectionResponse{
+		Data:       []*AuditLogResponse{},
+		Pagination: pagination,
+	}
+
+	for _, row := range rows {
+		response.Data = append(response.Data, NewAuditLogResponse(row))
+	}
+
+	return response
+}
+
+type GetListAuditLogRequest struct {
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+	SortBy string `query:"sortBy"`
+	Filter string `query:"filter"`
+	// Filters are the filter[field][op] parameters, parsed by the handler
+	Filters []queryspec.Filter
+	// Paginate=cursor opts in keyset pages, continued with Cursor
+	Paginate  string `query:"paginate" validate:"omitempty,oneof=offset cursor"`
+	Cursor    string `query:"cursor"`
+	SkipCount bool   `query:"skip_count"`
+	// Include=actor embeds the user who made the change
+	Include string `query:"include"`
+}
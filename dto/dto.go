@@ -1,8 +1,43 @@
 package dto
 
+import (
+	"math"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
 type PaginationResponse struct {
-	Size        int `json:"size"`
-	Total       int `json:"total"`
-	TotalPages  int `json:"total_pages"`
-	CurrentPage int `json:"current_page"`
+	Size int `json:"size"`
+	// Total and TotalPages are left out when the count is skipped
+	Total       *int   `json:"total,omitempty"`
+	TotalPages  *int   `json:"total_pages,omitempty"`
+	CurrentPage int    `json:"current_page"`
+	Next        string `json:"next,omitempty"`
+	Prev        string `json:"prev,omitempty"`
+}
+
+// NewPaginationResponse describes a page of the spec with size rows out of
+// total, total is nil when the count was skipped
+func NewPaginationResponse(spec *queryspec.Spec, size int, total *int) PaginationResponse {
+	pagination := PaginationResponse{
+		Size:        size,
+		Total:       total,
+		CurrentPage: spec.Page,
+		Next:        spec.Next,
+		Prev:        spec.Prev,
+	}
+
+	if total != nil {
+		totalPages := int(math.Ceil(float64(*total) / float64(spec.Limit)))
+		pagination.TotalPages = &totalPages
+	}
+
+	return pagination
+}
+
+// PurgeTrashResponse counts the rows removed for good from the trash
+type PurgeTrashResponse struct {
+	Users       int `json:"users"`
+	Roles       int `json:"roles"`
+	Permissions int `json:"permissions"`
 }
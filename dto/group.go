@@ -0,0 +1,148 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+type GroupResponse struct {
+	Uuid        string   `json:"uuid"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	CreatedAt   string   `json:"created_at"`
+	CreatedBy   int      `json:"created_by"`
+	UpdatedAt   string   `json:"updated_at"`
+	UpdatedBy   int      `json:"updated_by"`
+	Roles       []string `json:"roles,omitempty"`
+}
+
+func NewGroupResponse(e *entity.Group) *GroupResponse {
+	createdAt := ""
+	updatedAt := ""
+	roles := []string{}
+
+	if e.CreatedAt.Valid {
+		createdAt = e.CreatedAt.Time.Format(time.RFC3339)
+	}
+	if e.UpdatedAt.Valid {
+		updatedAt = e.UpdatedAt.Time.Format(time.RFC3339)
+	}
+
+	for _, role := range e.Roles {
+		roles = append(roles, role.Name)
+	}
+
+	return &GroupResponse{
+		Uuid:        e.Uuid,
+		Name:        e.Name,
+		Description: e.Description,
+		CreatedAt:   createdAt,
+		CreatedBy:   int(e.CreatedBy.Int64),
+		UpdatedAt:   updatedAt,
+		UpdatedBy:   int(e.UpdatedBy.Int64),
+		Roles:       roles,
+	}
+}
+
+type GroupCollectionResponse struct {
+	Data       []*GroupResponse   `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+func NewGroupCollectionResponse(rows []*entity.Group, pagination PaginationResponse) *GroupCollectionResponse {
+	response := &GroupCollectionResponse{
+		Data:       []*GroupResponse{},
+		Pagination: pagination,
+	}
+
+	for _, row := range rows {
+		response.Data = append(response.Data, NewGroupResponse(row))
+	}
+
+	return response
+}
+
+// UserGroupResponse is a group of a user with the roles it grants, it is
+// part of the token claims
+type UserGroupResponse struct {
+	Uuid  string          `json:"uuid"`
+	Name  string          `json:"name"`
+	Roles []*RoleResponse `json:"roles,omitempty"`
+}
+
+func NewUserGroupResponse(e *entity.Group) *UserGroupResponse {
+	roles := []*RoleResponse{}
+	for _, role := range e.Roles {
+		roles = append(roles, NewRoleResponse(role))
+	}
+
+	return &UserGroupResponse{
+		Uuid:  e.Uuid,
+		Name:  e.Name,
+		Roles: roles,
+	}
+}
+
+// HasPermission reports whether one of the roles of the group grants the
+// permission
+func (g *UserGroupResponse) HasPermission(name string) bool {
+	for _, role := range g.Roles {
+		if role.HasPermission(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type CreateGroupRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description" validate:""`
+	Roles       []string `json:"roles" validate:""`
+}
+
+type UpdateGroupRequest struct {
+	Uuid        string `json:"uuid" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description" validate:""`
+}
+
+type DeleteGroupRequest struct {
+	Uuid string `json:"uuid" validate:"required"`
+}
+
+type GetGroupRequest struct {
+	Uuid string `json:"uuid" validate:"required"`
+}
+
+type GetListGroupRequest struct {
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+	SortBy string `query:"sortBy"`
+	Filter string `query:"filter"`
+}
+
+type GroupUsersRequest struct {
+	Uuid  string   `json:"uuid" validate:"required"`
+	Users []string `json:"users" validate:"required,min=1"`
+}
+
+type GetGroupUsersRequest struct {
+	Uuid string `json:"uuid" validate:"required"`
+}
+
+type GroupMembershipResponse struct {
+	Group    string `json:"group"`
+	Affected int    `json:"affected"`
+}
+
+type GrantGroupRolesRequest struct {
+	Uuid  string   `json:"uuid" validate:"required"`
+	Roles []string `json:"roles" validate:"required,min=1"`
+}
+
+type RevokeGroupRoleRequest struct {
+	Uuid string `json:"uuid" validate:"required"`
+	Role string `json:"role" validate:"required"`
+}
@@ -4,22 +4,27 @@ import (
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 )
 
 type PermissionResponse struct {
-	Uuid      string `json:"uuid"`
-	ParentId  int    `json:"parent_id"`
-	Name      string `json:"name"`
-	Type      string `json:"type"`
-	CreatedAt string `json:"created_at"`
-	CreatedBy int    `json:"created_by"`
-	UpdatedAt string `json:"updated_at"`
-	UpdatedBy int    `json:"updated_by"`
+	Uuid      string        `json:"uuid"`
+	ParentId  int           `json:"parent_id"`
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	IsSystem  bool          `json:"is_system"`
+	CreatedAt string        `json:"created_at"`
+	CreatedBy ActorResponse `json:"created_by"`
+	UpdatedAt string        `json:"updated_at"`
+	UpdatedBy ActorResponse `json:"updated_by"`
+	Version   int           `json:"version"`
+	DeletedAt string        `json:"deleted_at,omitempty"`
 }
 
 func NewPermissionResponse(e *entity.Permission) *PermissionResponse {
 	createdAt := ""
 	updatedAt := ""
+	deletedAt := ""
 
 	if e.CreatedAt.Valid {
 		createdAt = e.CreatedAt.Time.Format(time.RFC3339)
@@ -27,16 +32,22 @@ func NewPermissionResponse(e *entity.Permission) *PermissionResponse {
 	if e.UpdatedAt.Valid {
 		updatedAt = e.UpdatedAt.Time.Format(time.RFC3339)
 	}
+	if e.DeletedAt.Valid {
+		deletedAt = e.DeletedAt.Time.Format(time.RFC3339)
+	}
 
 	return &PermissionResponse{
 		Uuid:      e.Uuid,
 		ParentId:  e.ParentId,
 		Name:      e.Name,
 		Type:      e.Type,
+		IsSystem:  e.IsSystem,
 		CreatedAt: createdAt,
-		CreatedBy: int(e.CreatedBy.Int64),
+		CreatedBy: NewActorResponse(e.CreatedBy, e.Creator),
 		UpdatedAt: updatedAt,
-		UpdatedBy: int(e.UpdatedBy.Int64),
+		UpdatedBy: NewActorResponse(e.UpdatedBy, e.Updater),
+		Version:   e.Version,
+		DeletedAt: deletedAt,
 	}
 }
 
@@ -69,19 +80,49 @@ type UpdatePermissionRequest struct {
 	ParentId int    `json:"parent_id" validate:"numeric"`
 	Name     string `json:"name" validate:"required"`
 	Type     string `json:"type" validate:"required"`
+	Version  int    `json:"version" validate:"omitempty,min=1"`
+	IfMatch  bool   `json:"-"`
 }
 
 type DeletePermissionRequest struct {
 	Uuid string `json:"uuid"`
 }
 
-type GetPermissionRequest struct {
+type RestorePermissionRequest struct {
 	Uuid string `json:"uuid"`
 }
 
+type GetPermissionRequest struct {
+	Uuid    string `json:"uuid"`
+	Include string `query:"include"`
+}
+
 type GetListPermissionRequest struct {
 	Page   int    `query:"page"`
 	Limit  int    `query:"limit"`
 	SortBy string `query:"sortBy"`
 	Filter string `query:"filter"`
+	// Filters are the filter[field][op] parameters, parsed by the handler
+	Filters []queryspec.Filter
+	// Paginate=cursor opts in keyset pages, continued with Cursor
+	Paginate  string `query:"paginate" validate:"omitempty,oneof=offset cursor"`
+	Cursor    string `query:"cursor"`
+	SkipCount bool   `query:"skip_count"`
+	// WithTrashed lists the deleted rows along with the others, OnlyTrashed
+	// lists them alone
+	WithTrashed bool `query:"with_trashed"`
+	OnlyTrashed bool `query:"only_trashed"`
+	// Include lists the relations to embed, e.g. include=role,created_by
+	Include string `query:"include"`
+}
+
+type SyncPermissionsResponse struct {
+	Created []string `json:"created"`
+}
+
+type VerifyPermissionsResponse struct {
+	// Unknown permissions are declared by the application but missing in the database
+	Unknown []string `json:"unknown"`
+	// Orphaned permissions are actions in the database that nothing declares
+	Orphaned []string `json:"orphaned"`
 }
@@ -4,16 +4,50 @@ import (
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 )
 
 type RoleResponse struct {
-	Uuid        string   `json:"uuid"`
-	Name        string   `json:"name"`
-	CreatedAt   string   `json:"created_at"`
-	CreatedBy   int      `json:"created_by"`
-	UpdatedAt   string   `json:"updated_at"`
-	UpdatedBy   int      `json:"updated_by"`
-	Permissions []string `json:"permissions,omitempty"`
+	Uuid                 string        `json:"uuid"`
+	Name                 string        `json:"name"`
+	IsSystem             bool          `json:"is_system"`
+	Shared               bool          `json:"shared"`
+	CreatedAt            string        `json:"created_at"`
+	CreatedBy            ActorResponse `json:"created_by"`
+	UpdatedAt            string        `json:"updated_at"`
+	UpdatedBy            ActorResponse `json:"updated_by"`
+	Version              int           `json:"version"`
+	DeletedAt            string        `json:"deleted_at,omitempty"`
+	Parents              []string      `json:"parents,omitempty"`
+	Permissions          []string      `json:"permissions,omitempty"`
+	InheritedPermissions []string      `json:"inherited_permissions,omitempty"`
+}
+
+// HasPermission reports whether the role grants the permission, either
+// directly or through one of the roles it extends.
+func (r *RoleResponse) HasPermission(name string) bool {
+	for _, perm := range r.Permissions {
+		if perm == name {
+			return true
+		}
+	}
+
+	for _, perm := range r.InheritedPermissions {
+		if perm == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithActorIds turns the actors of the role back into their ids, a reference
+// written in the token claims could not be read back to the id
+func (r *RoleResponse) WithActorIds() *RoleResponse {
+	r.CreatedBy.Actor = nil
+	r.UpdatedBy.Actor = nil
+
+	return r
 }
 
 type RoleCollectionResponse struct {
@@ -24,7 +58,10 @@ type RoleCollectionResponse struct {
 func NewRoleResponse(e *entity.Role) *RoleResponse {
 	createdAt := ""
 	updatedAt := ""
+	deletedAt := ""
+	parents := []string{}
 	permissions := []string{}
+	inheritedPermissions := []string{}
 
 	if e.CreatedAt.Valid {
 		createdAt = e.CreatedAt.Time.Format(time.RFC3339)
@@ -32,19 +69,36 @@ func NewRoleResponse(e *entity.Role) *RoleResponse {
 	if e.UpdatedAt.Valid {
 		updatedAt = e.UpdatedAt.Time.Format(time.RFC3339)
 	}
+	if e.DeletedAt.Valid {
+		deletedAt = e.DeletedAt.Time.Format(time.RFC3339)
+	}
+
+	for _, parent := range e.Parents {
+		parents = append(parents, parent.Name)
+	}
 
 	for _, perm := range e.Permissions {
 		permissions = append(permissions, perm.Name)
 	}
 
+	for _, perm := range e.InheritedPermissions {
+		inheritedPermissions = append(inheritedPermissions, perm.Name)
+	}
+
 	return &RoleResponse{
-		Uuid:        e.Uuid,
-		Name:        e.Name,
-		CreatedAt:   createdAt,
-		CreatedBy:   int(e.CreatedBy.Int64),
-		UpdatedAt:   updatedAt,
-		UpdatedBy:   int(e.UpdatedBy.Int64),
-		Permissions: permissions,
+		Uuid:                 e.Uuid,
+		Name:                 e.Name,
+		IsSystem:             e.IsSystem,
+		Shared:               e.TenantId == 0,
+		CreatedAt:            createdAt,
+		CreatedBy:            NewActorResponse(e.CreatedBy, e.Creator),
+		UpdatedAt:            updatedAt,
+		UpdatedBy:            NewActorResponse(e.UpdatedBy, e.Updater),
+		Version:              e.Version,
+		DeletedAt:            deletedAt,
+		Parents:              parents,
+		Permissions:          permissions,
+		InheritedPermissions: inheritedPermissions,
 	}
 }
 
@@ -55,24 +109,7 @@ func NewRoleCollectionResponse(rows []*entity.Role, pagination PaginationRespons
 	}
 
 	for _, row := range rows {
-		createdAt := ""
-		updatedAt := ""
-
-		if row.CreatedAt.Valid {
-			createdAt = row.CreatedAt.Time.Format(time.RFC3339)
-		}
-		if row.UpdatedAt.Valid {
-			updatedAt = row.UpdatedAt.Time.Format(time.RFC3339)
-		}
-
-		response.Data = append(response.Data, &RoleResponse{
-			Uuid:      row.Uuid,
-			Name:      row.Name,
-			CreatedAt: createdAt,
-			CreatedBy: int(row.CreatedBy.Int64),
-			UpdatedAt: updatedAt,
-			UpdatedBy: int(row.UpdatedBy.Int64),
-		})
+		response.Data = append(response.Data, NewRoleResponse(row))
 	}
 
 	return response
@@ -80,26 +117,67 @@ func NewRoleCollectionResponse(rows []*entity.Role, pagination PaginationRespons
 
 type CreateRoleRequest struct {
 	Name        string   `json:"name" validate:"required"`
+	Parents     []string `json:"parents" validate:""`
 	Permissions []string `json:"permissions" validate:""`
 }
 
 type UpdateRoleRequest struct {
 	Uuid        string   `json:"uuid" validate:"required"`
 	Name        string   `json:"name" validate:"required"`
+	Parents     []string `json:"parents" validate:""`
 	Permissions []string `json:"permissions" validate:""`
+	Version     int      `json:"version" validate:"omitempty,min=1"`
+	IfMatch     bool     `json:"-"`
 }
 
 type DeleteRoleRequest struct {
 	Uuid string `json:"uuid" validate:"required"`
 }
 
-type GetRoleRequest struct {
+type RestoreRoleRequest struct {
 	Uuid string `json:"uuid" validate:"required"`
 }
 
+type GetRoleRequest struct {
+	Uuid    string `json:"uuid" validate:"required"`
+	Include string `query:"include"`
+}
+
 type GetListRoleRequest struct {
 	Page   int    `query:"page"`
 	Limit  int    `query:"limit"`
 	SortBy string `query:"sortBy"`
 	Filter string `query:"filter"`
+	// Filters are the filter[field][op] parameters, parsed by the handler
+	Filters []queryspec.Filter
+	// Paginate=cursor opts in keyset pages, continued with Cursor
+	Paginate  string `query:"paginate" validate:"omitempty,oneof=offset cursor"`
+	Cursor    string `query:"cursor"`
+	SkipCount bool   `query:"skip_count"`
+	// WithTrashed lists the deleted rows along with the others, OnlyTrashed
+	// lists them alone
+	WithTrashed bool `query:"with_trashed"`
+	OnlyTrashed bool `query:"only_trashed"`
+	// Include lists the relations to embed, e.g. include=role,created_by
+	Include string `query:"include"`
+}
+
+type GrantRolePermissionsRequest struct {
+	Uuid        string   `json:"uuid" validate:"required"`
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
+type RevokeRolePermissionRequest struct {
+	Uuid       string `json:"uuid" validate:"required"`
+	Permission string `json:"permission" validate:"required"`
+}
+
+type AssignRoleUsersRequest struct {
+	Uuid  string   `json:"uuid" validate:"required"`
+	Users []string `json:"users" validate:"required,min=1"`
+}
+
+type RoleAssignmentResponse struct {
+	Role     string `json:"role"`
+	Affected int    `json:"affected"`
 }
@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+// RoleGrantResponse is a temporary role of a user, Status is computed when
+// the response is built
+type RoleGrantResponse struct {
+	Uuid       string        `json:"uuid"`
+	Role       *RoleResponse `json:"role,omitempty"`
+	ValidFrom  time.Time     `json:"valid_from"`
+	ValidUntil time.Time     `json:"valid_until"`
+	Reason     string        `json:"reason"`
+	Status     string        `json:"status"` // pending, active or expired
+	CreatedBy  int           `json:"created_by"`
+}
+
+func NewRoleGrantResponse(e *entity.RoleGrant) *RoleGrantResponse {
+	var roleResponse *RoleResponse = nil
+	if e.Role != nil {
+		roleResponse = NewRoleResponse(e.Role)
+	}
+
+	status := constants.GRANT_STATUS_ACTIVE
+	now := time.Now()
+	if now.Before(e.ValidFrom) {
+		status = constants.GRANT_STATUS_PENDING
+	} else if !now.Before(e.ValidUntil) {
+		status = constants.GRANT_STATUS_EXPIRED
+	}
+
+	return &RoleGrantResponse{
+		Uuid:       e.Uuid,
+		Role:       roleResponse,
+		ValidFrom:  e.ValidFrom,
+		ValidUntil: e.ValidUntil,
+		Reason:     e.Reason,
+		Status:     status,
+		CreatedBy:  int(e.CreatedBy.Int64),
+	}
+}
+
+// IsActive reports whether the grant is in effect at the given time, tokens
+// keep the grants they were issued with so the status is not trusted
+func (g *RoleGrantResponse) IsActive(at time.Time) bool {
+	return !at.Before(g.ValidFrom) && at.Before(g.ValidUntil)
+}
+
+type GrantRoleRequest struct {
+	Uuid       string     `json:"uuid" validate:"required"` // user
+	Role       string     `json:"role" validate:"required"`
+	ValidFrom  *time.Time `json:"valid_from" validate:"omitempty"` // now when empty
+	ValidUntil time.Time  `json:"valid_until" validate:"required"`
+	Reason     string     `json:"reason" validate:"max=255"`
+}
+
+type RevokeRoleGrantRequest struct {
+	Uuid  string `json:"uuid" validate:"required"` // user
+	Grant string `json:"grant" validate:"required"`
+}
@@ -0,0 +1,121 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+type TenantResponse struct {
+	Uuid      string `json:"uuid"`
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy int    `json:"created_by"`
+	UpdatedAt string `json:"updated_at"`
+	UpdatedBy int    `json:"updated_by"`
+}
+
+// TenantResponseWithID is the active tenant carried in the token claims
+type TenantResponseWithID struct {
+	ID   int    `json:"id"`
+	Uuid string `json:"uuid"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+func NewTenantResponse(e *entity.Tenant) *TenantResponse {
+	createdAt := ""
+	updatedAt := ""
+
+	if e.CreatedAt.Valid {
+		createdAt = e.CreatedAt.Time.Format(time.RFC3339)
+	}
+	if e.UpdatedAt.Valid {
+		updatedAt = e.UpdatedAt.Time.Format(time.RFC3339)
+	}
+
+	return &TenantResponse{
+		Uuid:      e.Uuid,
+		Slug:      e.Slug,
+		Name:      e.Name,
+		CreatedAt: createdAt,
+		CreatedBy: int(e.CreatedBy.Int64),
+		UpdatedAt: updatedAt,
+		UpdatedBy: int(e.UpdatedBy.Int64),
+	}
+}
+
+func NewTenantResponseWithID(e *entity.Tenant) *TenantResponseWithID {
+	if e == nil {
+		return nil
+	}
+
+	return &TenantResponseWithID{
+		ID:   e.Id,
+		Uuid: e.Uuid,
+		Slug: e.Slug,
+		Name: e.Name,
+	}
+}
+
+type TenantCollectionResponse struct {
+	Data       []*TenantResponse  `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+func NewTenantCollectionResponse(rows []*entity.Tenant, pagination PaginationResponse) *TenantCollectionResponse {
+	response := &TenantCollectionResponse{
+		Data:       []*TenantResponse{},
+		Pagination: pagination,
+	}
+
+	for _, row := range rows {
+		response.Data = append(response.Data, NewTenantResponse(row))
+	}
+
+	return response
+}
+
+type CreateTenantRequest struct {
+	Slug string `json:"slug" validate:"required"`
+	Name string `json:"name" validate:"required"`
+}
+
+type UpdateTenantRequest struct {
+	Uuid string `json:"uuid" validate:"required"`
+	Slug string `json:"slug" validate:"required"`
+	Name string `json:"name" validate:"required"`
+}
+
+type DeleteTenantRequest struct {
+	Uuid string `json:"uuid" validate:"required"`
+}
+
+type GetTenantRequest struct {
+	Uuid string `json:"uuid" validate:"required"`
+}
+
+type GetListTenantRequest struct {
+	Page   int    `query:"page"`
+	Limit  int    `query:"limit"`
+	SortBy string `query:"sortBy"`
+	Filter string `query:"filter"`
+}
+
+// AddTenantUsersRequest adds existing users to the tenant with the given role
+type AddTenantUsersRequest struct {
+	Uuid  string   `json:"uuid" validate:"required"`
+	Users []string `json:"users" validate:"required,min=1"`
+	Role  string   `json:"role" validate:"required"`
+}
+
+type RemoveTenantUsersRequest struct {
+	Uuid  string   `json:"uuid" validate:"required"`
+	Users []string `json:"users" validate:"required,min=1"`
+}
+
+type TenantMembershipResponse struct {
+	Tenant   string `json:"tenant"`
+	Affected int    `json:"affected"`
+}
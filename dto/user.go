@@ -1,38 +1,72 @@
 package dto
 
 import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 )
 
 type UserResponse struct {
-	Uuid        string        `json:"uuid"`
-	Username    string        `json:"username"`
-	Email       string        `json:"email"`
-	Name        string        `json:"name"`
-	Status      int           `json:"status"`
-	LastLoginAt string        `json:"last_login_at"`
-	CreatedAt   string        `json:"created_at"`
-	CreatedBy   int           `json:"created_by"`
-	UpdatedAt   string        `json:"updated_at"`
-	UpdatedBy   int           `json:"updated_by"`
-	Role        *RoleResponse `json:"role,omitempty"`
+	Uuid        string               `json:"uuid"`
+	Username    string               `json:"username"`
+	Email       string               `json:"email"`
+	Name        string               `json:"name"`
+	Status      int                  `json:"status"`
+	LastLoginAt string               `json:"last_login_at"`
+	CreatedAt   string               `json:"created_at"`
+	CreatedBy   ActorResponse        `json:"created_by"`
+	UpdatedAt   string               `json:"updated_at"`
+	UpdatedBy   ActorResponse        `json:"updated_by"`
+	Version     int                  `json:"version"`
+	DeletedAt   string               `json:"deleted_at,omitempty"`
+	Role        *RoleResponse        `json:"role,omitempty"`
+	Groups      []*UserGroupResponse `json:"groups,omitempty"`
+	Grants      []*RoleGrantResponse `json:"grants,omitempty"`
 }
 
 type UserResponseWithID struct {
-	ID          int           `json:"id"`
-	Uuid        string        `json:"uuid"`
-	Username    string        `json:"username"`
-	Email       string        `json:"email"`
-	Name        string        `json:"name"`
-	Status      int           `json:"status"`
-	LastLoginAt string        `json:"last_login_at"`
-	CreatedAt   string        `json:"created_at"`
-	CreatedBy   int           `json:"created_by"`
-	UpdatedAt   string        `json:"updated_at"`
-	UpdatedBy   int           `json:"updated_by"`
-	Role        *RoleResponse `json:"role,omitempty"`
+	ID          int                  `json:"id"`
+	Uuid        string               `json:"uuid"`
+	Username    string               `json:"username"`
+	Email       string               `json:"email"`
+	Name        string               `json:"name"`
+	Status      int                  `json:"status"`
+	LastLoginAt string               `json:"last_login_at"`
+	CreatedAt   string               `json:"created_at"`
+	CreatedBy   int                  `json:"created_by"`
+	UpdatedAt   string               `json:"updated_at"`
+	UpdatedBy   int                  `json:"updated_by"`
+	Role        *RoleResponse        `json:"role,omitempty"`
+	Groups      []*UserGroupResponse `json:"groups,omitempty"`
+	Grants      []*RoleGrantResponse `json:"grants,omitempty"`
+}
+
+// HasPermission reports whether the user is granted the permission by their
+// role, by the roles of one of their groups or by an active role grant
+func (u *UserResponseWithID) HasPermission(name string) bool {
+	if u.Role != nil && u.Role.HasPermission(name) {
+		return true
+	}
+
+	for _, group := range u.Groups {
+		if group.HasPermission(name) {
+			return true
+		}
+	}
+
+	now := time.Now()
+	for _, grant := range u.Grants {
+		if grant.IsActive(now) && grant.Role != nil && grant.Role.HasPermission(name) {
+			return true
+		}
+	}
+
+	return false
 }
 
 type UserCollectionResponse struct {
@@ -44,6 +78,7 @@ func NewUserResponse(e *entity.User) *UserResponse {
 	lastLoginAt := ""
 	createdAt := ""
 	updatedAt := ""
+	deletedAt := ""
 	var roleResponse *RoleResponse = nil
 
 	if e.LastLoginAt.Valid {
@@ -55,11 +90,24 @@ func NewUserResponse(e *entity.User) *UserResponse {
 	if e.UpdatedAt.Valid {
 		updatedAt = e.UpdatedAt.Time.Format(time.RFC3339)
 	}
+	if e.DeletedAt.Valid {
+		deletedAt = e.DeletedAt.Time.Format(time.RFC3339)
+	}
 
 	if e.Role != nil {
 		roleResponse = NewRoleResponse(e.Role)
 	}
 
+	groups := []*UserGroupResponse{}
+	for _, group := range e.Groups {
+		groups = append(groups, NewUserGroupResponse(group))
+	}
+
+	grants := []*RoleGrantResponse{}
+	for _, grant := range e.Grants {
+		grants = append(grants, NewRoleGrantResponse(grant))
+	}
+
 	return &UserResponse{
 		Uuid:        e.Uuid,
 		Username:    e.Username,
@@ -68,10 +116,14 @@ func NewUserResponse(e *entity.User) *UserResponse {
 		Status:      e.Status,
 		LastLoginAt: lastLoginAt,
 		CreatedAt:   createdAt,
-		CreatedBy:   int(e.CreatedBy.Int64),
+		CreatedBy:   NewActorResponse(e.CreatedBy, e.Creator),
 		UpdatedAt:   updatedAt,
-		UpdatedBy:   int(e.UpdatedBy.Int64),
+		UpdatedBy:   NewActorResponse(e.UpdatedBy, e.Updater),
+		Version:     e.Version,
+		DeletedAt:   deletedAt,
 		Role:        roleResponse,
+		Groups:      groups,
+		Grants:      grants,
 	}
 }
 
@@ -91,8 +143,30 @@ func NewUserResponseWithID(e *entity.User) *UserResponseWithID {
 		updatedAt = e.UpdatedAt.Time.Format(time.RFC3339)
 	}
 
+	// the user is carried in the token claims, the actors of its roles are
+	// kept as ids
 	if e.Role != nil {
-		roleResponse = NewRoleResponse(e.Role)
+		roleResponse = NewRoleResponse(e.Role).WithActorIds()
+	}
+
+	groups := []*UserGroupResponse{}
+	for _, group := range e.Groups {
+		groupResponse := NewUserGroupResponse(group)
+		for _, role := range groupResponse.Roles {
+			role.WithActorIds()
+		}
+
+		groups = append(groups, groupResponse)
+	}
+
+	grants := []*RoleGrantResponse{}
+	for _, grant := range e.Grants {
+		grantResponse := NewRoleGrantResponse(grant)
+		if grantResponse.Role != nil {
+			grantResponse.Role.WithActorIds()
+		}
+
+		grants = append(grants, grantResponse)
 	}
 
 	return &UserResponseWithID{
@@ -108,6 +182,8 @@ func NewUserResponseWithID(e *entity.User) *UserResponseWithID {
 		UpdatedAt:   updatedAt,
 		UpdatedBy:   int(e.UpdatedBy.Int64),
 		Role:        roleResponse,
+		Groups:      groups,
+		Grants:      grants,
 	}
 }
 
@@ -124,6 +200,94 @@ func NewUserCollectionResponse(rows []*entity.User, pagination PaginationRespons
 	return response
 }
 
+// UserSearchResponse is a user found by a search, Highlight has the fields
+// HTML escaped with the matched terms wrapped in <mark>
+type UserSearchResponse struct {
+	*UserResponse
+	Rank      float64             `json:"rank"`
+	Highlight UserSearchHighlight `json:"highlight"`
+}
+
+type UserSearchHighlight struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+}
+
+type UserSearchCollectionResponse struct {
+	Data       []*UserSearchResponse `json:"data"`
+	Pagination PaginationResponse    `json:"pagination"`
+}
+
+func NewUserSearchResponse(e *entity.UserSearchResult, search string) *UserSearchResponse {
+	matcher := searchMatcher(search)
+
+	return &UserSearchResponse{
+		UserResponse: NewUserResponse(&e.User),
+		Rank:         e.Rank,
+		Highlight: UserSearchHighlight{
+			Username: highlight(e.Username, matcher),
+			Email:    highlight(e.Email, matcher),
+			Name:     highlight(e.Name, matcher),
+		},
+	}
+}
+
+func NewUserSearchCollectionResponse(rows []*entity.UserSearchResult, search string, pagination PaginationResponse) *UserSearchCollectionResponse {
+	response := &UserSearchCollectionResponse{
+		Data:       []*UserSearchResponse{},
+		Pagination: pagination,
+	}
+
+	for _, row := range rows {
+		response.Data = append(response.Data, NewUserSearchResponse(row, search))
+	}
+
+	return response
+}
+
+// searchMatcher matches any term of the search case-insensitively, longest
+// terms first
+func searchMatcher(search string) *regexp.Regexp {
+	terms := strings.Fields(search)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		return len(terms[i]) > len(terms[j])
+	})
+
+	for i, term := range terms {
+		terms[i] = regexp.QuoteMeta(term)
+	}
+
+	return regexp.MustCompile("(?i)" + strings.Join(terms, "|"))
+}
+
+func highlight(text string, matcher *regexp.Regexp) string {
+	if matcher == nil {
+		return html.EscapeString(text)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range matcher.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>" + html.EscapeString(text[loc[0]:loc[1]]) + "</mark>")
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+
+	return b.String()
+}
+
+type SearchUserRequest struct {
+	Query string `json:"q" query:"q" validate:"required,min=2,max=100"`
+	Page  int    `json:"page" query:"page"`
+	Limit int    `json:"limit" query:"limit" validate:"omitempty,max=100"`
+}
+
 type CreateUserRequest struct {
 	Username             string `json:"username" validate:"required,min=3,max=30"`
 	Email                string `json:"email" validate:"required,email"`
@@ -143,20 +307,62 @@ type UpdateUserRequest struct {
 	Role                 string `json:"role" validate:"required"`
 	Password             string `json:"password" validate:"omitempty,min=6"`
 	PasswordConfirmation string `json:"password_confirmation" validate:"eqfield=Password"`
+	// CurrentPassword confirms a change of one's own password
+	CurrentPassword string `json:"current_password"`
+	// Version is the version the update is based on, taken from the If-Match
+	// header when it is sent
+	Version int  `json:"version" validate:"omitempty,min=1"`
+	IfMatch bool `json:"-"`
 }
 
 type DeleteUserRequest struct {
 	Uuid string `json:"uuid" validate:"required"`
 }
 
-type GetUserRequest struct {
+type RestoreUserRequest struct {
 	Uuid string `json:"uuid" validate:"required"`
 }
 
+type GetUserRequest struct {
+	Uuid    string `json:"uuid" validate:"required"`
+	Include string `query:"include"`
+}
+
+type ExplainPermissionRequest struct {
+	Uuid       string `json:"uuid" validate:"required"`
+	Permission string `json:"permission" validate:"required"`
+}
+
+// PermissionGrantResponse is one way the permission is granted, Path lists
+// the roles from the granted role to the role holding the permission
+type PermissionGrantResponse struct {
+	Source string   `json:"source"` // role, group or temporary
+	Group  string   `json:"group,omitempty"`
+	Path   []string `json:"path"`
+}
+
+type ExplainPermissionResponse struct {
+	User       string                     `json:"user"`
+	Permission string                     `json:"permission"`
+	Granted    bool                       `json:"granted"`
+	Grants     []*PermissionGrantResponse `json:"grants"`
+}
+
 type GetListUserRequest struct {
 	Page   int    `query:"page"`
 	Limit  int    `query:"limit"`
 	SortBy string `query:"sortBy"`
 	Filter string `query:"filter"`
-	// TODO: more filters
+	// Filters are the filter[field][op] parameters, parsed by the handler
+	Filters []queryspec.Filter
+	// Paginate=cursor opts in keyset pages, continued with Cursor
+	Paginate  string `query:"paginate" validate:"omitempty,oneof=offset cursor"`
+	Cursor    string `query:"cursor"`
+	SkipCount bool   `query:"skip_count"`
+	// WithTrashed lists the deleted rows along with the others, OnlyTrashed
+	// lists them alone
+	WithTrashed bool `query:"with_trashed"`
+	OnlyTrashed bool `query:"only_trashed"`
+	// Include lists the relations to embed, e.g. include=role,created_by
+	Include string `query:"include"`
 }
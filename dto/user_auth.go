@@ -5,17 +5,19 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func NewUserClaims(eUser *entity.User, eDevice *entity.UserDevice, regClaims jwt.RegisteredClaims) *UserClaims {
+func NewUserClaims(eUser *entity.User, eDevice *entity.UserDevice, eTenant *entity.Tenant, regClaims jwt.RegisteredClaims) *UserClaims {
 	return &UserClaims{
 		User:             NewUserResponseWithID(eUser),
 		Device:           NewUserDevice(eDevice),
+		Tenant:           NewTenantResponseWithID(eTenant),
 		RegisteredClaims: regClaims,
 	}
 }
 
 type UserClaims struct {
-	User   *UserResponseWithID `json:"user,omitempty"`
-	Device *UserDevice         `json:"device,omitempty"`
+	User   *UserResponseWithID   `json:"user,omitempty"`
+	Device *UserDevice           `json:"device,omitempty"`
+	Tenant *TenantResponseWithID `json:"tenant,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -47,12 +49,14 @@ type SignInRequest struct {
 	Username string      `json:"username" validate:"required"`
 	Password string      `json:"password" validate:"required"`
 	Device   *UserDevice `json:"device" validate:"omitempty"`
+	Tenant   string      `json:"tenant"` // uuid or slug, the first tenant of the user when empty
 }
 
 type SignInResponse struct {
-	AccessToken  string     `json:"access_token"`
-	RefreshToken string     `json:"refresh_token"`
-	Device       UserDevice `json:"device"`
+	AccessToken  string          `json:"access_token"`
+	RefreshToken string          `json:"refresh_token"`
+	Device       UserDevice      `json:"device"`
+	Tenant       *TenantResponse `json:"tenant"`
 }
 
 type SignOutRequest struct {
@@ -69,7 +73,26 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// SwitchTenantRequest issues tokens for another tenant of the current user
+type SwitchTenantRequest struct {
+	Tenant string `json:"tenant" validate:"required"`
+}
+
+type SwitchTenantResponse struct {
+	AccessToken  string          `json:"access_token"`
+	RefreshToken string          `json:"refresh_token"`
+	Tenant       *TenantResponse `json:"tenant"`
+}
+
 type RefreshTokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type CheckPermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
+// CheckPermissionsResponse maps every requested permission or expression to
+// whether the current user is granted it
+type CheckPermissionsResponse map[string]bool
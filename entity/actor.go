@@ -0,0 +1,15 @@
+package entity
+
+// Actor is the user behind a created_by or updated_by column
+type Actor struct {
+	Id   int    `db:"id" json:"id"`
+	Uuid string `db:"uuid" json:"uuid"`
+	Name string `db:"name" json:"name"`
+}
+
+// Audited is a row with created_by and updated_by columns whose actors can be
+// loaded on request
+type Audited interface {
+	ActorIds() (createdBy int, updatedBy int)
+	SetActors(creator *Actor, updater *Actor)
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"database/sql"
+)
+
+// AuditLog is a change made to a resource, OldValues and NewValues hold the
+// JSON of the changed fields before and after it. OldValues is null for a
+// create and NewValues for a delete.
+type AuditLog struct {
+	Id           int            `db:"id" json:"id"`
+	Uuid         string         `db:"uuid" json:"uuid"`
+	TenantId     int            `db:"tenant_id" json:"tenant_id"` // 0 for changes made outside a tenant
+	ActorId      sql.NullInt64  `db:"actor_id" json:"actor_id"`
+	Action       string         `db:"action" json:"action"`
+	ResourceType string         `db:"resource_type" json:"resource_type"`
+	ResourceUuid string         `db:"resource_uuid" json:"resource_uuid"`
+	OldValues    sql.NullString `db:"old_values" json:"old_values"`
+	NewValues    sql.NullString `db:"new_values" json:"new_values"`
+	IP           string         `db:"ip" json:"ip"`
+	UserAgent    string         `db:"user_agent" json:"user_agent"`
+	RequestId    string         `db:"request_id" json:"request_id"`
+	CreatedAt    sql.NullTime   `db:"created_at" json:"created_at"`
+	Actor        *Actor         `json:"actor"`
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"database/sql"
+)
+
+type Group struct {
+	Id          int           `db:"id" json:"id"`
+	Uuid        string        `db:"uuid" json:"uuid"`
+	TenantId    int           `db:"tenant_id" json:"tenant_id"`
+	Name        string        `db:"name" json:"name"`
+	Description string        `db:"description" json:"description"`
+	CreatedAt   sql.NullTime  `db:"created_at" json:"created_at"`
+	CreatedBy   sql.NullInt64 `db:"created_by" json:"created_by"`
+	UpdatedAt   sql.NullTime  `db:"updated_at" json:"updated_at"`
+	UpdatedBy   sql.NullInt64 `db:"updated_by" json:"updated_by"`
+
+	// Roles are granted to every member of the group
+	Roles []*Role `json:"roles"`
+}
+
+// HasPermission reports whether one of the roles of the group grants the
+// permission
+func (e *Group) HasPermission(name string) bool {
+	for _, role := range e.Roles {
+		if role.HasPermission(name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -10,8 +10,23 @@ type Permission struct {
 	ParentId  int           `db:"parent_id" json:"parent_id"`
 	Name      string        `db:"name" json:"name"`
 	Type      string        `db:"type" json:"type"`
+	IsSystem  bool          `db:"is_system" json:"is_system"`
 	CreatedAt sql.NullTime  `db:"created_at" json:"created_at"`
 	CreatedBy sql.NullInt64 `db:"created_by" json:"created_by"`
 	UpdatedAt sql.NullTime  `db:"updated_at" json:"updated_at"`
 	UpdatedBy sql.NullInt64 `db:"updated_by" json:"updated_by"`
+	Version   int           `db:"version" json:"version"`
+	DeletedAt sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+	DeletedBy sql.NullInt64 `db:"deleted_by" json:"deleted_by"`
+	Creator   *Actor        `json:"creator"`
+	Updater   *Actor        `json:"updater"`
+}
+
+func (e *Permission) ActorIds() (int, int) {
+	return int(e.CreatedBy.Int64), int(e.UpdatedBy.Int64)
+}
+
+func (e *Permission) SetActors(creator *Actor, updater *Actor) {
+	e.Creator = creator
+	e.Updater = updater
 }
@@ -8,9 +8,63 @@ type Role struct {
 	Id          int           `db:"id" json:"id"`
 	Uuid        string        `db:"uuid" json:"uuid"`
 	Name        string        `db:"name" json:"name"`
+	IsSystem    bool          `db:"is_system" json:"is_system"`
+	TenantId    int           `db:"tenant_id" json:"tenant_id"` // 0 for roles shared by every tenant
 	CreatedAt   sql.NullTime  `db:"created_at" json:"created_at"`
 	CreatedBy   sql.NullInt64 `db:"created_by" json:"created_by"`
 	UpdatedAt   sql.NullTime  `db:"updated_at" json:"updated_at"`
 	UpdatedBy   sql.NullInt64 `db:"updated_by" json:"updated_by"`
+	Version     int           `db:"version" json:"version"`
+	DeletedAt   sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+	DeletedBy   sql.NullInt64 `db:"deleted_by" json:"deleted_by"`
 	Permissions []*Permission `json:"permissions"`
+
+	// Parents are the roles this role extends, InheritedPermissions are the
+	// permissions resolved transitively from them (excluding direct ones).
+	Parents              []*Role       `json:"parents"`
+	InheritedPermissions []*Permission `json:"inherited_permissions"`
+
+	Creator *Actor `json:"creator"`
+	Updater *Actor `json:"updater"`
+}
+
+// HasPermission reports whether the role grants the permission directly or
+// through one of its ancestors
+func (e *Role) HasPermission(name string) bool {
+	for _, perm := range e.Permissions {
+		if perm.Name == name {
+			return true
+		}
+	}
+
+	for _, perm := range e.InheritedPermissions {
+		if perm.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PermissionNames returns the names of the direct and inherited permissions
+func (e *Role) PermissionNames() []string {
+	names := []string{}
+	for _, perm := range e.Permissions {
+		names = append(names, perm.Name)
+	}
+
+	for _, perm := range e.InheritedPermissions {
+		names = append(names, perm.Name)
+	}
+
+	return names
+}
+
+func (e *Role) ActorIds() (int, int) {
+	return int(e.CreatedBy.Int64), int(e.UpdatedBy.Int64)
+}
+
+func (e *Role) SetActors(creator *Actor, updater *Actor) {
+	e.Creator = creator
+	e.Updater = updater
 }
@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RoleGrant grants a role to a member of a tenant for a limited time, on top
+// of their own role
+type RoleGrant struct {
+	Id         int           `db:"id" json:"id"`
+	Uuid       string        `db:"uuid" json:"uuid"`
+	TenantId   int           `db:"tenant_id" json:"tenant_id"`
+	UserId     int           `db:"user_id" json:"user_id"`
+	RoleId     int           `db:"role_id" json:"role_id"`
+	ValidFrom  time.Time     `db:"valid_from" json:"valid_from"`
+	ValidUntil time.Time     `db:"valid_until" json:"valid_until"`
+	Reason     string        `db:"reason" json:"reason"`
+	CreatedAt  sql.NullTime  `db:"created_at" json:"created_at"`
+	CreatedBy  sql.NullInt64 `db:"created_by" json:"created_by"`
+	Role       *Role         `json:"role"`
+}
+
+// IsActive reports whether the grant is in effect at the given time
+func (e *RoleGrant) IsActive(at time.Time) bool {
+	return !at.Before(e.ValidFrom) && at.Before(e.ValidUntil)
+}
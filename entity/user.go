@@ -2,6 +2,7 @@ package entity
 
 import (
 	"database/sql"
+	"time"
 )
 
 type User struct {
@@ -9,7 +10,7 @@ type User struct {
 	Uuid        string        `db:"uuid" json:"uuid"`
 	Username    string        `db:"username" json:"username"`
 	Email       string        `db:"email" json:"email"`
-	Password    string        `db:"password" json:"password"`
+	Password    string        `db:"password" json:"-"` // only loaded by FindByUsernameOrEmail
 	Name        string        `db:"name" json:"name"`
 	RoleId      int           `db:"role_id" json:"role_id"`
 	Status      int           `db:"status" json:"status"`
@@ -18,5 +19,89 @@ type User struct {
 	CreatedBy   sql.NullInt64 `db:"created_by" json:"created_by"`
 	UpdatedAt   sql.NullTime  `db:"updated_at" json:"updated_at"`
 	UpdatedBy   sql.NullInt64 `db:"updated_by" json:"updated_by"`
+	Version     int           `db:"version" json:"version"`
+	DeletedAt   sql.NullTime  `db:"deleted_at" json:"deleted_at"` // removed from the active tenant
+	DeletedBy   sql.NullInt64 `db:"deleted_by" json:"deleted_by"`
 	Role        *Role         `json:"role"`
+	Groups      []*Group      `json:"groups"`
+	Grants      []*RoleGrant  `json:"grants"`
+	Creator     *Actor        `json:"creator"`
+	Updater     *Actor        `json:"updater"`
+}
+
+// HasPermission reports whether the user is granted the permission by their
+// role, by the roles of one of their groups or by an active role grant
+func (e *User) HasPermission(name string) bool {
+	if e.Role != nil && e.Role.HasPermission(name) {
+		return true
+	}
+
+	for _, group := range e.Groups {
+		if group.HasPermission(name) {
+			return true
+		}
+	}
+
+	now := time.Now()
+	for _, grant := range e.Grants {
+		if grant.IsActive(now) && grant.Role != nil && grant.Role.HasPermission(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PurgedMember is a member deleted for good from the trash of a tenant
+type PurgedMember struct {
+	TenantId int `db:"tenant_id" json:"tenant_id"`
+	User
+}
+
+// UserSearchResult is a user found by a search, results are ordered by Rank
+type UserSearchResult struct {
+	User
+	Rank float64 `db:"rank" json:"rank"`
+}
+
+// PermissionNames lists once every permission the user is granted, by their
+// role, the roles of their groups and their active role grants
+func (e *User) PermissionNames() []string {
+	roles := []*Role{e.Role}
+	for _, group := range e.Groups {
+		roles = append(roles, group.Roles...)
+	}
+
+	now := time.Now()
+	for _, grant := range e.Grants {
+		if grant.IsActive(now) {
+			roles = append(roles, grant.Role)
+		}
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, role := range roles {
+		if role == nil {
+			continue
+		}
+
+		for _, name := range role.PermissionNames() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+func (e *User) ActorIds() (int, int) {
+	return int(e.CreatedBy.Int64), int(e.UpdatedBy.Int64)
+}
+
+func (e *User) SetActors(creator *Actor, updater *Actor) {
+	e.Creator = creator
+	e.Updater = updater
 }
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+var (
+	memoryPersistInstance     *memoryAuditLogPersistent
+	memoryPersistInstanceOnce sync.Once
+)
+
+// memoryAuditLogPersistent keeps the audit logs in a memdb.DB, it sees the
+// logs of the active tenant
+type memoryAuditLogPersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryAuditLogPersistent(db *memdb.DB) audit.AuditLogPersistent {
+	memoryPersistInstanceOnce.Do(func() {
+		memoryPersistInstance = &memoryAuditLogPersistent{
+			db: db,
+		}
+	})
+
+	return memoryPersistInstance
+}
+
+func (r *memoryAuditLogPersistent) Create(ctx context.Context, e *entity.AuditLog) (*entity.AuditLog, error) {
+	id := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		id, err = t.InsertAuditLog(e)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var found *entity.AuditLog
+	err = r.db.View(ctx, func(t *memdb.Tables) error {
+		found = memdb.Row(t.AuditLogs, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil || found.TenantId != utils.GetTenantIdFromContext(ctx) {
+		return nil, sql.ErrNoRows
+	}
+
+	return found, nil
+}
+
+func (r *memoryAuditLogPersistent) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.AuditLog, error) {
+	rows := []*entity.AuditLog{}
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		rows, err = queryspec.SelectRows(auditLogResource, r.tenantRows(ctx, t), spec, queryspec.ColumnOf[*entity.AuditLog])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return queryspec.Page(auditLogResource, spec, rows)
+}
+
+func (r *memoryAuditLogPersistent) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		numrows, err = queryspec.CountRows(auditLogResource, r.tenantRows(ctx, t), spec, queryspec.ColumnOf[*entity.AuditLog])
+		return err
+	})
+
+	return numrows, err
+}
+
+// tenantRows returns the logs of the active tenant ordered by id
+func (r *memoryAuditLogPersistent) tenantRows(ctx context.Context, t *memdb.Tables) []*entity.AuditLog {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	rows := []*entity.AuditLog{}
+	for _, row := range memdb.Rows(t.AuditLogs) {
+		if row.TenantId == tenantId {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
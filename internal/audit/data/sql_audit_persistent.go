@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/Masterminds/squirrel"
+)
+
+var (
+	sqlPersistInstance     *sqlAuditLogPersistent
+	sqlPersistInstanceOnce sync.Once
+)
+
+// auditLogResource whitelists the fields of the audit log list, newest first
+// unless sorted otherwise
+var auditLogResource = &queryspec.Resource{
+	Sortable: map[string]string{
+		"action":        "action",
+		"resource_type": "resource_type",
+		"created_at":    "created_at",
+	},
+	Filterable: map[string]queryspec.Field{
+		"action":        {Column: "action", Type: queryspec.TypeString, Ops: queryspec.EnumOps},
+		"resource_type": {Column: "resource_type", Type: queryspec.TypeString, Ops: queryspec.EnumOps},
+		"resource_uuid": {Column: "resource_uuid", Type: queryspec.TypeString, Ops: queryspec.EnumOps},
+		"actor":         {Column: "actor_id", Type: queryspec.TypeInt, Ops: queryspec.Nullable(queryspec.EnumOps)},
+		"ip":            {Column: "ip", Type: queryspec.TypeString, Ops: queryspec.TextOps},
+		"request_id":    {Column: "request_id", Type: queryspec.TypeString, Ops: []string{queryspec.OpEq}},
+		"created_at":    {Column: "created_at", Type: queryspec.TypeTime, Ops: queryspec.RangeOps},
+	},
+	Searchable:   []string{"resource_uuid", "request_id"},
+	DefaultSorts: []queryspec.Sort{{Field: "created_at", Dir: queryspec.DirDesc}},
+	Key:          "id",
+}
+
+// auditLogTable maps the audit logs for the CRUD queries, the application
+// writes every column itself
+var auditLogTable = crud.Table{
+	Name:       "audit_logs",
+	Columns:    []string{"id", "uuid", "tenant_id", "actor_id", "action", "resource_type", "resource_uuid", "old_values", "new_values", "ip", "user_agent", "request_id", "created_at"},
+	Insertable: []string{"uuid", "tenant_id", "actor_id", "action", "resource_type", "resource_uuid", "old_values", "new_values", "ip", "user_agent", "request_id", "created_at"},
+	Resource:   auditLogResource,
+	Scope: func(ctx context.Context) squirrel.Sqlizer {
+		return squirrel.Eq{"tenant_id": utils.GetTenantIdFromContext(ctx)}
+	},
+}
+
+// sqlAuditLogPersistent sees the logs of the active tenant, its queries are
+// built by crud and run on every SQL dialect
+type sqlAuditLogPersistent struct {
+	*crud.Repository[entity.AuditLog]
+}
+
+func NewSqlAuditLogPersistent(db *txmanager.DB) audit.AuditLogPersistent {
+	sqlPersistInstanceOnce.Do(func() {
+		sqlPersistInstance = &sqlAuditLogPersistent{
+			Repository: crud.New[entity.AuditLog](db, auditLogTable),
+		}
+	})
+
+	return sqlPersistInstance
+}
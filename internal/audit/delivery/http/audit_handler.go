@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	handlerInstance     *handler
+	handlerInstanceOnce sync.Once
+)
+
+type Handler interface {
+	GetAll() func(echo.Context) error
+}
+
+type handler struct {
+	uc audit.AuditLogUsecase
+}
+
+func NewAuditLogHttpHandler(uc audit.AuditLogUsecase) Handler {
+	handlerInstanceOnce.Do(func() {
+		handlerInstance = &handler{
+			uc: uc,
+		}
+	})
+
+	return handlerInstance
+}
+
+func (h *handler) GetAll() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GetListAuditLogRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		filters, err := queryspec.ParseFilters(c.QueryParams())
+		if err != nil {
+			return err
+		}
+		input.Filters = filters
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.GetList(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res.Data, res.Pagination))
+	}
+}
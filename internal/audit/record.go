@@ -0,0 +1,266 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Change is a change made to a resource. Before and After are the resource,
+// an entity or a map, before and after the change, nil for a resource that
+// did not exist on that side. Secrets names the secret fields changed along
+// with the resource that it does not hold, such as a new password.
+type Change struct {
+	Action       string
+	ResourceType string
+	ResourceUuid string
+	Before       any
+	After        any
+	Secrets      []string
+}
+
+// ignoredFields are derived from other fields or loaded for display, they
+// are left out of the log
+var ignoredFields = []string{"id", "creator", "updater", "inherited_permissions", "groups", "grants"}
+
+// secretFields are redacted wherever they appear in a field name
+var secretFields = []string{"password", "secret", "token"}
+
+// sizes of the request columns, the values come from request headers and are
+// cut to fit
+const (
+	maxIPLength        = 45
+	maxRequestIdLength = 100
+)
+
+// Record writes the log of the change along with the user, the tenant and
+// the request of the context. Only the fields that changed are kept when both
+// sides are given, secrets are redacted. It runs in the transaction of the
+// context, a change is not saved without its log.
+func Record(ctx context.Context, repo AuditLogRepository, change Change) error {
+	before, after := snapshot(change.Before), snapshot(change.After)
+	if before != nil && after != nil {
+		before, after = diff(before, after)
+	}
+	before, after = withSecrets(before, change.Secrets), withSecrets(after, change.Secrets)
+
+	oldValues, err := encode(before)
+	if err != nil {
+		return err
+	}
+
+	newValues, err := encode(after)
+	if err != nil {
+		return err
+	}
+
+	e := &entity.AuditLog{
+		Uuid:         uuid.NewString(),
+		TenantId:     utils.GetTenantIdFromContext(ctx),
+		Action:       change.Action,
+		ResourceType: change.ResourceType,
+		ResourceUuid: change.ResourceUuid,
+		OldValues:    oldValues,
+		NewValues:    newValues,
+		CreatedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+	}
+
+	if user := utils.GetUserFromContext(ctx); user != nil {
+		e.ActorId = sql.NullInt64{
+			Int64: int64(user.ID),
+			Valid: true,
+		}
+	}
+
+	if info := utils.GetRequestInfoFromContext(ctx); info != nil {
+		e.IP = truncate(info.IP, maxIPLength)
+		e.UserAgent = info.UserAgent
+		e.RequestId = truncate(info.Id, maxRequestIdLength)
+	}
+
+	_, err = repo.Create(ctx, e)
+
+	return err
+}
+
+// snapshot returns the fields of the resource by their JSON name, nil for no
+// resource
+func snapshot(resource any) map[string]any {
+	if resource == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(resource)
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Map) && v.IsNil() {
+		return nil
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	fields := map[string]any{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil
+	}
+
+	for _, name := range ignoredFields {
+		delete(fields, name)
+	}
+
+	for name, value := range fields {
+		fields[name] = compact(value)
+	}
+
+	return fields
+}
+
+// compact turns the sql.Null* values into their value or null and the
+// related resources into their name, or their uuid when they have no name
+func compact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if valid, ok := v["Valid"]; ok && len(v) == 2 {
+			if valid != true {
+				return nil
+			}
+
+			for name, inner := range v {
+				if name != "Valid" {
+					return inner
+				}
+			}
+		}
+
+		for _, name := range []string{"name", "uuid"} {
+			if ref, ok := v[name]; ok {
+				return ref
+			}
+		}
+
+		compacted := map[string]any{}
+		for name, inner := range v {
+			compacted[name] = compact(inner)
+		}
+
+		return compacted
+	case []any:
+		compacted := make([]any, 0, len(v))
+		for _, inner := range v {
+			compacted = append(compacted, compact(inner))
+		}
+
+		return compacted
+	}
+
+	return value
+}
+
+// diff keeps the fields whose value differs between the two sides
+func diff(before map[string]any, after map[string]any) (map[string]any, map[string]any) {
+	changedBefore, changedAfter := map[string]any{}, map[string]any{}
+
+	for name, value := range before {
+		if !reflect.DeepEqual(value, after[name]) {
+			changedBefore[name] = value
+			changedAfter[name] = after[name]
+		}
+	}
+
+	for name, value := range after {
+		if _, ok := before[name]; !ok {
+			changedBefore[name] = nil
+			changedAfter[name] = value
+		}
+	}
+
+	return changedBefore, changedAfter
+}
+
+// withSecrets adds the changed secrets, redacted, to the fields of a side
+func withSecrets(fields map[string]any, secrets []string) map[string]any {
+	if fields == nil {
+		return nil
+	}
+
+	for _, name := range secrets {
+		fields[name] = constants.AUDIT_REDACTED
+	}
+
+	return fields
+}
+
+// encode redacts the secrets and returns the JSON of the fields, null for
+// no fields
+func encode(fields map[string]any) (sql.NullString, error) {
+	if fields == nil {
+		return sql.NullString{}, nil
+	}
+
+	redact(fields)
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func redact(fields map[string]any) {
+	for name, value := range fields {
+		if isSecret(name) {
+			fields[name] = constants.AUDIT_REDACTED
+			continue
+		}
+
+		redactValue(value)
+	}
+}
+
+// redactValue redacts the objects nested in a value, at any depth of arrays
+func redactValue(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		redact(v)
+	case []any:
+		for _, item := range v {
+			redactValue(item)
+		}
+	}
+}
+
+func isSecret(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretFields {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+
+	return value
+}
@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+// recorder keeps the logs written by Record
+type recorder struct {
+	logs []*entity.AuditLog
+}
+
+func (r *recorder) Create(ctx context.Context, e *entity.AuditLog) (*entity.AuditLog, error) {
+	r.logs = append(r.logs, e)
+	return e, nil
+}
+
+func (r *recorder) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.AuditLog, error) {
+	return r.logs, nil
+}
+
+func (r *recorder) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	return len(r.logs), nil
+}
+
+func TestEncodeRedactsSecrets(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]any
+		want   string
+	}{
+		{
+			name:   "top level",
+			fields: map[string]any{"name": "alice", "password": "hash"},
+			want:   `{"name":"alice","password":"[REDACTED]"}`,
+		},
+		{
+			name:   "field name containing a secret",
+			fields: map[string]any{"refresh_token": "t", "Client_Secret": "s"},
+			want:   `{"Client_Secret":"[REDACTED]","refresh_token":"[REDACTED]"}`,
+		},
+		{
+			name:   "nested object",
+			fields: map[string]any{"device": map[string]any{"token": "t", "platform": "android"}},
+			want:   `{"device":{"platform":"android","token":"[REDACTED]"}}`,
+		},
+		{
+			name: "objects in an array",
+			fields: map[string]any{"devices": []any{
+				map[string]any{"token": "t1"},
+				map[string]any{"token": "t2", "platform": "ios"},
+			}},
+			want: `{"devices":[{"token":"[REDACTED]"},{"platform":"ios","token":"[REDACTED]"}]}`,
+		},
+		{
+			name:   "objects in nested arrays",
+			fields: map[string]any{"batches": []any{[]any{map[string]any{"secret": "s"}}, "plain"}},
+			want:   `{"batches":[[{"secret":"[REDACTED]"}],"plain"]}`,
+		},
+		{
+			name:   "whole array under a secret name",
+			fields: map[string]any{"tokens": []any{"t1", "t2"}},
+			want:   `{"tokens":"[REDACTED]"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encode(tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.String != tt.want {
+				t.Errorf("got %s, want %s", got.String, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeNoFields(t *testing.T) {
+	got, err := encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Valid {
+		t.Errorf("got %s, want null", got.String)
+	}
+}
+
+func TestRecordSecrets(t *testing.T) {
+	type account struct {
+		Name     string `json:"name"`
+		Password string `json:"-"`
+	}
+
+	tests := []struct {
+		name    string
+		change  Change
+		wantOld string
+		wantNew string
+	}{
+		{
+			name: "secret changed alone",
+			change: Change{
+				Before:  &account{Name: "alice", Password: "old"},
+				After:   &account{Name: "alice", Password: "new"},
+				Secrets: []string{"password"},
+			},
+			wantOld: `{"password":"[REDACTED]"}`,
+			wantNew: `{"password":"[REDACTED]"}`,
+		},
+		{
+			name: "secret changed with other fields",
+			change: Change{
+				Before:  &account{Name: "alice"},
+				After:   &account{Name: "alicia"},
+				Secrets: []string{"password"},
+			},
+			wantOld: `{"name":"alice","password":"[REDACTED]"}`,
+			wantNew: `{"name":"alicia","password":"[REDACTED]"}`,
+		},
+		{
+			name: "hidden secret changed without being named",
+			change: Change{
+				Before: &account{Name: "alice", Password: "old"},
+				After:  &account{Name: "alice", Password: "new"},
+			},
+			wantOld: `{}`,
+			wantNew: `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &recorder{}
+			err := Record(context.Background(), repo, tt.change)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(repo.logs) != 1 {
+				t.Fatalf("got %d logs, want 1", len(repo.logs))
+			}
+
+			log := repo.logs[0]
+			if log.OldValues.String != tt.wantOld || log.NewValues.String != tt.wantNew {
+				t.Errorf("got %s -> %s, want %s -> %s", log.OldValues.String, log.NewValues.String, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, e *entity.AuditLog) (*entity.AuditLog, error)
+	FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.AuditLog, error)
+
+	CountAll(ctx context.Context, spec *queryspec.Spec) (int, error)
+}
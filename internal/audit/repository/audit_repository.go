@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+var (
+	auditLogRepoInstance     *auditLogRepository
+	auditLogRepoInstanceOnce sync.Once
+)
+
+type auditLogRepository struct {
+	persistent audit.AuditLogPersistent
+}
+
+func NewAuditLogRepository(persistent audit.AuditLogPersistent) audit.AuditLogRepository {
+	auditLogRepoInstanceOnce.Do(func() {
+		auditLogRepoInstance = &auditLogRepository{
+			persistent: persistent,
+		}
+	})
+
+	return auditLogRepoInstance
+}
+
+func (r *auditLogRepository) Create(ctx context.Context, e *entity.AuditLog) (*entity.AuditLog, error) {
+	return r.persistent.Create(ctx, e)
+}
+
+func (r *auditLogRepository) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.AuditLog, error) {
+	return r.persistent.FindAll(ctx, spec)
+}
+
+func (r *auditLogRepository) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	return r.persistent.CountAll(ctx, spec)
+}
@@ -0,0 +1,11 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+)
+
+type AuditLogUsecase interface {
+	GetList(ctx context.Context, input *dto.GetListAuditLogRequest) (*dto.AuditLogCollectionResponse, error)
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+var (
+	auditLogUcInstance     *auditLogUsecase
+	auditLogUcInstanceOnce sync.Once
+)
+
+// auditLogIncludes are the relations audit log responses can embed
+var auditLogIncludes = []string{"actor"}
+
+type auditLogUsecase struct {
+	repo     audit.AuditLogRepository
+	userRepo user.UserRepository
+}
+
+func NewAuditLogUsecase(repo audit.AuditLogRepository, userRepo user.UserRepository) audit.AuditLogUsecase {
+	auditLogUcInstanceOnce.Do(func() {
+		auditLogUcInstance = &auditLogUsecase{
+			repo:     repo,
+			userRepo: userRepo,
+		}
+	})
+
+	return auditLogUcInstance
+}
+
+func (uc *auditLogUsecase) GetList(ctx context.Context, input *dto.GetListAuditLogRequest) (*dto.AuditLogCollectionResponse, error) {
+	includes, err := queryspec.ParseIncludes(input.Include, auditLogIncludes...)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := queryspec.New(input.Page, input.Limit, input.SortBy, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+	spec.Filters = input.Filters
+	spec.SkipCount = input.SkipCount
+
+	if input.Paginate == queryspec.PaginateCursor || input.Cursor != "" {
+		err = spec.UseCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := uc.repo.FindAll(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if includes.Has("actor") {
+		err = uc.expandActors(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var total *int
+	if !spec.SkipCount {
+		numrows, err := uc.repo.CountAll(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		total = &numrows
+	}
+
+	return dto.NewAuditLogCollectionResponse(rows, dto.NewPaginationResponse(spec, len(rows), total)), nil
+}
+
+// expandActors loads the users who made the changes with a single query, an
+// actor that no longer exists is set with its id alone
+func (uc *auditLogUsecase) expandActors(ctx context.Context, rows []*entity.AuditLog) error {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, row := range rows {
+		id := int(row.ActorId.Int64)
+		if row.ActorId.Valid && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	actors, err := uc.userRepo.FindActorsByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byId := map[int]*entity.Actor{}
+	for _, actor := range actors {
+		byId[actor.Id] = actor
+	}
+
+	for _, row := range rows {
+		if !row.ActorId.Valid {
+			continue
+		}
+
+		row.Actor = byId[int(row.ActorId.Int64)]
+		if row.Actor == nil {
+			row.Actor = &entity.Actor{Id: int(row.ActorId.Int64)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+var (
+	memoryPersistInstance     *memoryGrantPersistent
+	memoryPersistInstanceOnce sync.Once
+)
+
+// memoryGrantPersistent keeps the role grants in a memdb.DB, it sees the
+// grants of the active tenant except DestroyExpired which sweeps every tenant
+type memoryGrantPersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryGrantPersistent(db *memdb.DB) grant.GrantPersistent {
+	memoryPersistInstanceOnce.Do(func() {
+		memoryPersistInstance = &memoryGrantPersistent{
+			db: db,
+		}
+	})
+
+	return memoryPersistInstance
+}
+
+func (r *memoryGrantPersistent) Create(ctx context.Context, e *entity.RoleGrant) (*entity.RoleGrant, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	if tenantId == 0 {
+		return nil, errors.NewForbiddenError("No active tenant")
+	}
+
+	row := *e
+	row.TenantId = tenantId
+
+	insertedId := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		insertedId, err = t.InsertRoleGrant(&row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, insertedId)
+}
+
+func (r *memoryGrantPersistent) Destroy(ctx context.Context, e *entity.RoleGrant) error {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		row, ok := t.RoleGrants[e.Id]
+		if !ok || row.TenantId != tenantId {
+			return sql.ErrNoRows
+		}
+
+		delete(t.RoleGrants, e.Id)
+
+		return nil
+	})
+}
+
+func (r *memoryGrantPersistent) FindById(ctx context.Context, id int) (*entity.RoleGrant, error) {
+	return r.find(ctx, func(row *entity.RoleGrant) bool {
+		return row.Id == id
+	})
+}
+
+func (r *memoryGrantPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.RoleGrant, error) {
+	return r.find(ctx, func(row *entity.RoleGrant) bool {
+		return row.Uuid == uuid
+	})
+}
+
+// FindAllByUserId returns the pending, active and not yet swept grants of the
+// user in the active tenant
+func (r *memoryGrantPersistent) FindAllByUserId(ctx context.Context, userId int) ([]*entity.RoleGrant, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	rows := []*entity.RoleGrant{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.RoleGrants) {
+			role, ok := t.Roles[row.RoleId]
+			if row.UserId == userId && row.TenantId == tenantId && ok && !role.DeletedAt.Valid {
+				rows = append(rows, row)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].ValidFrom.Before(rows[j].ValidFrom)
+	})
+
+	return rows, nil
+}
+
+// DestroyExpired removes the grants of every tenant that expired before the
+// given time and returns them
+func (r *memoryGrantPersistent) DestroyExpired(ctx context.Context, before time.Time) ([]*entity.RoleGrant, error) {
+	rows := []*entity.RoleGrant{}
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.RoleGrants) {
+			if row.ValidUntil.After(before) {
+				continue
+			}
+
+			delete(t.RoleGrants, row.Id)
+			rows = append(rows, row)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (r *memoryGrantPersistent) find(ctx context.Context, match func(row *entity.RoleGrant) bool) (*entity.RoleGrant, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	var found *entity.RoleGrant
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.RoleGrants) {
+			if row.TenantId == tenantId && match(row) {
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	sqlPersistInstance     *sqlGrantPersistent
+	sqlPersistInstanceOnce sync.Once
+)
+
+// grantTable maps the grants of the active tenant for the CRUD queries,
+// grants are never updated
+var grantTable = crud.Table{
+	Name:       "role_grants",
+	Columns:    []string{"id", "uuid", "tenant_id", "user_id", "role_id", "valid_from", "valid_until", "reason", "created_at", "created_by"},
+	Insertable: []string{"uuid", "user_id", "role_id", "valid_from", "valid_until", "reason", "created_at", "created_by"},
+	Owner: func(ctx context.Context) map[string]any {
+		return map[string]any{"tenant_id": utils.GetTenantIdFromContext(ctx)}
+	},
+	Scope: func(ctx context.Context) squirrel.Sqlizer {
+		return squirrel.Eq{"tenant_id": utils.GetTenantIdFromContext(ctx)}
+	},
+}
+
+// sqlGrantPersistent only sees the grants of the active tenant, except
+// DestroyExpired which sweeps every tenant. Roles of the grants are not loaded.
+type sqlGrantPersistent struct {
+	*crud.Repository[entity.RoleGrant]
+}
+
+func NewSqlGrantPersistent(db *txmanager.DB) grant.GrantPersistent {
+	sqlPersistInstanceOnce.Do(func() {
+		sqlPersistInstance = &sqlGrantPersistent{
+			Repository: crud.New[entity.RoleGrant](db, grantTable),
+		}
+	})
+
+	return sqlPersistInstance
+}
+
+func (r *sqlGrantPersistent) Create(ctx context.Context, e *entity.RoleGrant) (*entity.RoleGrant, error) {
+	if utils.GetTenantIdFromContext(ctx) == 0 {
+		return nil, errors.NewForbiddenError("No active tenant")
+	}
+
+	return r.Repository.Create(ctx, e)
+}
+
+func (r *sqlGrantPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.RoleGrant, error) {
+	return r.FindBy(ctx, "uuid", uuid)
+}
+
+// FindAllByUserId returns the pending, active and not yet swept grants of the
+// user in the active tenant
+func (r *sqlGrantPersistent) FindAllByUserId(ctx context.Context, userId int) ([]*entity.RoleGrant, error) {
+	q := r.Query(ctx).
+		Where(squirrel.Eq{"user_id": userId}).
+		Where("role_id IN (SELECT id FROM roles WHERE deleted_at IS NULL)").
+		OrderBy("valid_from", "id")
+
+	return r.Select(ctx, q)
+}
+
+// DestroyExpired removes the grants of every tenant that expired before the
+// given time and returns them, they are read first as only Postgres could
+// return the deleted rows
+func (r *sqlGrantPersistent) DestroyExpired(ctx context.Context, before time.Time) ([]*entity.RoleGrant, error) {
+	sqlExpired := `
+		SELECT id, uuid, tenant_id, user_id, role_id, valid_from, valid_until, reason, created_at, created_by
+		FROM role_grants
+		WHERE valid_until <= ?
+	`
+
+	tx, err := r.DB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*entity.RoleGrant{}
+	err = tx.SelectContext(ctx, &rows, sqlExpired, before)
+	if err != nil || len(rows) == 0 {
+		tx.Rollback()
+		return rows, err
+	}
+
+	ids := make([]int, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.Id)
+	}
+
+	query, args, err := sqlx.In(`DELETE FROM role_grants WHERE id IN (?)`, ids)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
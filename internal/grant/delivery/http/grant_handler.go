@@ -0,0 +1,79 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	handlerInstance     *handler
+	handlerInstanceOnce sync.Once
+)
+
+type Handler interface {
+	Store() func(echo.Context) error
+	Delete() func(echo.Context) error
+}
+
+type handler struct {
+	uc grant.GrantUsecase
+}
+
+func NewGrantHttpHandler(uc grant.GrantUsecase) Handler {
+	handlerInstanceOnce.Do(func() {
+		handlerInstance = &handler{
+			uc: uc,
+		}
+	})
+
+	return handlerInstance
+}
+
+func (h *handler) Store() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GrantRoleRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.GrantRole(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, utils.JsonSuccess(http.StatusCreated, "", res, nil))
+	}
+}
+
+func (h *handler) Delete() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.RevokeRoleGrantRequest{
+			Uuid:  strings.Trim(c.Param("uuid"), "/"),
+			Grant: strings.Trim(c.Param("grant"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RevokeGrant(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
@@ -0,0 +1,17 @@
+package grant
+
+import (
+	"context"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+type GrantRepository interface {
+	Create(ctx context.Context, e *entity.RoleGrant) (*entity.RoleGrant, error)
+	Destroy(ctx context.Context, e *entity.RoleGrant) error
+	FindById(ctx context.Context, id int) (*entity.RoleGrant, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.RoleGrant, error)
+	FindAllByUserId(ctx context.Context, userId int) ([]*entity.RoleGrant, error)
+	DestroyExpired(ctx context.Context, before time.Time) ([]*entity.RoleGrant, error)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+)
+
+var (
+	grantRepoInstance     *grantRepository
+	grantRepoInstanceOnce sync.Once
+)
+
+type grantRepository struct {
+	persistent grant.GrantPersistent
+}
+
+func NewGrantRepository(persistent grant.GrantPersistent) grant.GrantRepository {
+	grantRepoInstanceOnce.Do(func() {
+		grantRepoInstance = &grantRepository{
+			persistent: persistent,
+		}
+	})
+
+	return grantRepoInstance
+}
+
+func (r *grantRepository) Create(ctx context.Context, e *entity.RoleGrant) (*entity.RoleGrant, error) {
+	return r.persistent.Create(ctx, e)
+}
+
+func (r *grantRepository) Destroy(ctx context.Context, e *entity.RoleGrant) error {
+	return r.persistent.Destroy(ctx, e)
+}
+
+func (r *grantRepository) FindById(ctx context.Context, id int) (*entity.RoleGrant, error) {
+	return r.persistent.FindById(ctx, id)
+}
+
+func (r *grantRepository) FindByUuid(ctx context.Context, uuid string) (*entity.RoleGrant, error) {
+	return r.persistent.FindByUuid(ctx, uuid)
+}
+
+func (r *grantRepository) FindAllByUserId(ctx context.Context, userId int) ([]*entity.RoleGrant, error) {
+	return r.persistent.FindAllByUserId(ctx, userId)
+}
+
+func (r *grantRepository) DestroyExpired(ctx context.Context, before time.Time) ([]*entity.RoleGrant, error) {
+	return r.persistent.DestroyExpired(ctx, before)
+}
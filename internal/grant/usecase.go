@@ -0,0 +1,17 @@
+package grant
+
+import (
+	"context"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+)
+
+type GrantUsecase interface {
+	GrantRole(ctx context.Context, input *dto.GrantRoleRequest) (*dto.RoleGrantResponse, error)
+	RevokeGrant(ctx context.Context, input *dto.RevokeRoleGrantRequest) (*dto.RoleGrantResponse, error)
+
+	// SweepExpired removes the grants of every tenant that expired before the
+	// given time and publishes an event for each of them
+	SweepExpired(ctx context.Context, before time.Time) (int, error)
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/event"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	grantUcInstance     *grantUsecase
+	grantUcInstanceOnce sync.Once
+)
+
+type grantUsecase struct {
+	grantRepo grant.GrantRepository
+	roleRepo  role.RoleRepository
+	userRepo  user.UserRepository
+	auditRepo audit.AuditLogRepository
+	txManager txmanager.TxManager
+}
+
+func NewGrantUsecase(grantRepo grant.GrantRepository, roleRepo role.RoleRepository, userRepo user.UserRepository, auditRepo audit.AuditLogRepository, txManager txmanager.TxManager) grant.GrantUsecase {
+	grantUcInstanceOnce.Do(func() {
+		grantUcInstance = &grantUsecase{
+			grantRepo: grantRepo,
+			roleRepo:  roleRepo,
+			userRepo:  userRepo,
+			auditRepo: auditRepo,
+			txManager: txManager,
+		}
+	})
+
+	return grantUcInstance
+}
+
+// GrantRole grants a role to a member of the active tenant until ValidUntil,
+// starting now or at ValidFrom
+func (uc *grantUsecase) GrantRole(ctx context.Context, input *dto.GrantRoleRequest) (*dto.RoleGrantResponse, error) {
+	now := time.Now()
+	validFrom := now
+	if input.ValidFrom != nil {
+		validFrom = *input.ValidFrom
+	}
+
+	// validation logic
+	if !input.ValidUntil.After(validFrom) {
+		return nil, errors.NewBadRequestError("valid_until must be after valid_from")
+	}
+	if !input.ValidUntil.After(now) {
+		return nil, errors.NewBadRequestError("valid_until must be in the future")
+	}
+
+	var res *dto.RoleGrantResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		u, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		r, err := uc.roleRepo.FindByName(ctx, input.Role)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", input.Role))
+			}
+			return err
+		}
+
+		err = role.EnsureAssignable(ctx, r)
+		if err != nil {
+			return err
+		}
+
+		e, err := uc.grantRepo.Create(ctx, &entity.RoleGrant{
+			Uuid:       uuid.NewString(),
+			UserId:     u.Id,
+			RoleId:     r.Id,
+			ValidFrom:  validFrom,
+			ValidUntil: input.ValidUntil,
+			Reason:     input.Reason,
+			CreatedAt: sql.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+			CreatedBy: uc.actorId(ctx),
+		})
+		if err != nil {
+			return err
+		}
+
+		e.Role = r
+
+		res = dto.NewRoleGrantResponse(e)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_ROLE_GRANT, u.Uuid, nil, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (uc *grantUsecase) RevokeGrant(ctx context.Context, input *dto.RevokeRoleGrantRequest) (*dto.RoleGrantResponse, error) {
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		u, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		e, err := uc.grantRepo.FindByUuid(ctx, input.Grant)
+		if err != nil {
+			return err
+		}
+
+		if e.UserId != u.Id {
+			return sql.ErrNoRows
+		}
+
+		err = uc.grantRepo.Destroy(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return uc.record(ctx, constants.AUDIT_ACTION_ROLE_REVOKE, u.Uuid, e, nil)
+	})
+
+	return nil, err
+}
+
+func (uc *grantUsecase) SweepExpired(ctx context.Context, before time.Time) (int, error) {
+	rows, err := uc.grantRepo.DestroyExpired(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		event.Publish(ctx, constants.EVENT_ROLE_GRANT_EXPIRED, row)
+	}
+
+	return len(rows), nil
+}
+
+// record logs a grant or a revoke on the user holding the grant, before is
+// nil for a granted role and after for a revoked one
+func (uc *grantUsecase) record(ctx context.Context, action string, userUuid string, before any, after any) error {
+	return audit.Record(ctx, uc.auditRepo, audit.Change{
+		Action:       action,
+		ResourceType: constants.AUDIT_RESOURCE_USER,
+		ResourceUuid: userUuid,
+		Before:       before,
+		After:        after,
+	})
+}
+
+// actorId returns the id of the authenticated user for audit columns
+func (uc *grantUsecase) actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
@@ -0,0 +1,375 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/group"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+var (
+	memoryPersistInstance     *memoryGroupPersistent
+	memoryPersistInstanceOnce sync.Once
+)
+
+// memoryGroupPersistent keeps the groups in a memdb.DB, it sees the groups of
+// the active tenant and loads their roles without permissions
+type memoryGroupPersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryGroupPersistent(db *memdb.DB) group.GroupPersistent {
+	memoryPersistInstanceOnce.Do(func() {
+		memoryPersistInstance = &memoryGroupPersistent{
+			db: db,
+		}
+	})
+
+	return memoryPersistInstance
+}
+
+func (r *memoryGroupPersistent) Create(ctx context.Context, e *entity.Group) (*entity.Group, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	if tenantId == 0 {
+		return nil, errors.NewForbiddenError("No active tenant")
+	}
+
+	row := *e
+	row.TenantId = tenantId
+
+	groupId := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		groupId, err = t.InsertGroup(&row)
+		if err != nil {
+			return err
+		}
+
+		return insertRoles(t, groupId, e.Roles)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, groupId)
+}
+
+func (r *memoryGroupPersistent) Update(ctx context.Context, e *entity.Group) (*entity.Group, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Groups, e.Id)
+		if row == nil || row.TenantId != utils.GetTenantIdFromContext(ctx) {
+			return nil
+		}
+
+		row.Name = e.Name
+		row.Description = e.Description
+		row.UpdatedAt = e.UpdatedAt
+		row.UpdatedBy = e.UpdatedBy
+
+		return t.SaveGroup(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+func (r *memoryGroupPersistent) Destroy(ctx context.Context, e *entity.Group) error {
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		row, ok := t.Groups[e.Id]
+		if !ok || row.TenantId != utils.GetTenantIdFromContext(ctx) {
+			return sql.ErrNoRows
+		}
+
+		t.DeleteGroup(e.Id)
+
+		return nil
+	})
+}
+
+func (r *memoryGroupPersistent) FindById(ctx context.Context, id int) (*entity.Group, error) {
+	return r.find(ctx, func(row *entity.Group) bool {
+		return row.Id == id
+	})
+}
+
+func (r *memoryGroupPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Group, error) {
+	return r.find(ctx, func(row *entity.Group) bool {
+		return row.Uuid == uuid
+	})
+}
+
+func (r *memoryGroupPersistent) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Group, error) {
+	rows := []*entity.Group{}
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		rows, err = queryspec.SelectRows(groupResource, tenantGroups(ctx, t), spec, queryspec.ColumnOf[*entity.Group])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// FindAllByUserId returns the groups of the active tenant the user belongs to
+func (r *memoryGroupPersistent) FindAllByUserId(ctx context.Context, userId int) ([]*entity.Group, error) {
+	rows := []*entity.Group{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range tenantGroups(ctx, t) {
+			if t.GroupUsers[memdb.GroupUser{GroupId: row.Id, UserId: userId}] {
+				row.Roles = groupRoles(t, row.Id)
+				rows = append(rows, row)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// FindAllMembers returns the members of the group with their role in the
+// active tenant
+func (r *memoryGroupPersistent) FindAllMembers(ctx context.Context, id int) ([]*entity.User, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	rows := []*entity.User{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		g, ok := t.Groups[id]
+		if !ok || g.TenantId != tenantId {
+			return nil
+		}
+
+		for _, row := range memdb.Rows(t.Users) {
+			m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: row.Id}]
+			if !t.GroupUsers[memdb.GroupUser{GroupId: id, UserId: row.Id}] || !ok || m.DeletedAt.Valid {
+				continue
+			}
+
+			row.Password = ""
+			row.RoleId = m.RoleId
+			rows = append(rows, row)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// AddUsers adds the users in uuids to the group, only members of the tenant of
+// the group can join it. It returns the number of users added.
+func (r *memoryGroupPersistent) AddUsers(ctx context.Context, id int, uuids []string) (int, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	wanted := uuidSet(uuids)
+
+	affected := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		g, ok := t.Groups[id]
+		if !ok || g.TenantId != tenantId {
+			return nil
+		}
+
+		for _, row := range memdb.Rows(t.Users) {
+			m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: row.Id}]
+			if !wanted[row.Uuid] || !ok || m.DeletedAt.Valid {
+				continue
+			}
+
+			added, err := t.AddGroupUser(id, row.Id)
+			if err != nil {
+				return err
+			}
+			if added {
+				affected++
+			}
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+func (r *memoryGroupPersistent) RemoveUsers(ctx context.Context, id int, uuids []string) (int, error) {
+	wanted := uuidSet(uuids)
+
+	affected := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		g, ok := t.Groups[id]
+		if !ok || g.TenantId != utils.GetTenantIdFromContext(ctx) {
+			return nil
+		}
+
+		for _, row := range t.Users {
+			key := memdb.GroupUser{GroupId: id, UserId: row.Id}
+			if wanted[row.Uuid] && t.GroupUsers[key] {
+				delete(t.GroupUsers, key)
+				affected++
+			}
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+// GrantRoles adds the roles to the group, roles already granted are kept
+func (r *memoryGroupPersistent) GrantRoles(ctx context.Context, e *entity.Group, roles []*entity.Role) (*entity.Group, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		err := touchGroup(ctx, t, e)
+		if err != nil {
+			return err
+		}
+
+		return insertRoles(t, e.Id, roles)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+func (r *memoryGroupPersistent) RevokeRole(ctx context.Context, e *entity.Group, role *entity.Role) (*entity.Group, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		err := touchGroup(ctx, t, e)
+		if err != nil {
+			return err
+		}
+
+		delete(t.GroupRoles, memdb.GroupRole{GroupId: e.Id, RoleId: role.Id})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+func (r *memoryGroupPersistent) CountByName(ctx context.Context, name string) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range tenantGroups(ctx, t) {
+			if row.Name == name {
+				numrows++
+			}
+		}
+
+		return nil
+	})
+
+	return numrows, err
+}
+
+func (r *memoryGroupPersistent) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		numrows, err = queryspec.CountRows(groupResource, tenantGroups(ctx, t), spec, queryspec.ColumnOf[*entity.Group])
+		return err
+	})
+
+	return numrows, err
+}
+
+func (r *memoryGroupPersistent) find(ctx context.Context, match func(row *entity.Group) bool) (*entity.Group, error) {
+	var found *entity.Group
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range tenantGroups(ctx, t) {
+			if match(row) {
+				row.Roles = groupRoles(t, row.Id)
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// touchGroup updates the audit columns of the group, it fails with
+// sql.ErrNoRows when the group is not in the active tenant
+func touchGroup(ctx context.Context, t *memdb.Tables, e *entity.Group) error {
+	row := memdb.Row(t.Groups, e.Id)
+	if row == nil || row.TenantId != utils.GetTenantIdFromContext(ctx) {
+		return sql.ErrNoRows
+	}
+
+	row.UpdatedAt = e.UpdatedAt
+	row.UpdatedBy = e.UpdatedBy
+
+	return t.SaveGroup(row)
+}
+
+func insertRoles(t *memdb.Tables, groupId int, roles []*entity.Role) error {
+	for _, role := range roles {
+		err := t.AddGroupRole(groupId, role.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tenantGroups returns the groups of the active tenant ordered by id
+func tenantGroups(ctx context.Context, t *memdb.Tables) []*entity.Group {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	groups := []*entity.Group{}
+	for _, row := range memdb.Rows(t.Groups) {
+		if row.TenantId == tenantId {
+			groups = append(groups, row)
+		}
+	}
+
+	return groups
+}
+
+// groupRoles returns the live roles granted to the group, ordered by name and
+// without their permissions
+func groupRoles(t *memdb.Tables, groupId int) []*entity.Role {
+	roles := []*entity.Role{}
+	for _, row := range memdb.Rows(t.Roles) {
+		if t.GroupRoles[memdb.GroupRole{GroupId: groupId, RoleId: row.Id}] && !row.DeletedAt.Valid {
+			roles = append(roles, row)
+		}
+	}
+
+	sort.SliceStable(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+
+	return roles
+}
+
+func uuidSet(uuids []string) map[string]bool {
+	set := map[string]bool{}
+	for _, uuid := range uuids {
+		set[uuid] = true
+	}
+
+	return set
+}
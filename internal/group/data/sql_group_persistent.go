@@ -0,0 +1,344 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/group"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	sqlPersistInstance     *sqlGroupPersistent
+	sqlPersistInstanceOnce sync.Once
+)
+
+// groupResource whitelists the fields of the group list
+var groupResource = &queryspec.Resource{
+	Sortable: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Filterable: map[string]queryspec.Field{},
+	Searchable: []string{"name"},
+	Key:        "id",
+}
+
+// groupTable maps the groups of the active tenant for the CRUD queries
+var groupTable = crud.Table{
+	Name:       `"groups"`,
+	Columns:    []string{"id", "uuid", "tenant_id", "name", "description", "created_at", "created_by", "updated_at", "updated_by"},
+	Insertable: []string{"uuid", "name", "description"},
+	Updatable:  []string{"name", "description"},
+	Audited:    true,
+	Resource:   groupResource,
+	Owner: func(ctx context.Context) map[string]any {
+		return map[string]any{"tenant_id": utils.GetTenantIdFromContext(ctx)}
+	},
+	Scope: func(ctx context.Context) squirrel.Sqlizer {
+		return squirrel.Eq{"tenant_id": utils.GetTenantIdFromContext(ctx)}
+	},
+}
+
+// sqlGroupPersistent only sees the groups of the active tenant, the roles of a
+// group are loaded without their permissions. groups is quoted as it is a
+// reserved word of MySQL.
+type sqlGroupPersistent struct {
+	*crud.Repository[entity.Group]
+}
+
+func NewSqlGroupPersistent(db *txmanager.DB) group.GroupPersistent {
+	sqlPersistInstanceOnce.Do(func() {
+		sqlPersistInstance = &sqlGroupPersistent{
+			Repository: crud.New[entity.Group](db, groupTable),
+		}
+	})
+
+	return sqlPersistInstance
+}
+
+func (r *sqlGroupPersistent) Create(ctx context.Context, e *entity.Group) (*entity.Group, error) {
+	if utils.GetTenantIdFromContext(ctx) == 0 {
+		return nil, errors.NewForbiddenError("No active tenant")
+	}
+
+	groupId := 0
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		var err error
+		groupId, err = r.Insert(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return r.insertRoles(ctx, groupId, e.Roles)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), groupId)
+}
+
+func (r *sqlGroupPersistent) Update(ctx context.Context, e *entity.Group) (*entity.Group, error) {
+	err := r.Save(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+func (r *sqlGroupPersistent) FindById(ctx context.Context, id int) (*entity.Group, error) {
+	e, err := r.Repository.FindById(ctx, id)
+
+	return r.withRoles(ctx, e, err)
+}
+
+func (r *sqlGroupPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Group, error) {
+	e, err := r.FindBy(ctx, "uuid", uuid)
+
+	return r.withRoles(ctx, e, err)
+}
+
+// FindAllByUserId returns the groups of the active tenant the user belongs to
+func (r *sqlGroupPersistent) FindAllByUserId(ctx context.Context, userId int) ([]*entity.Group, error) {
+	sql := `
+		SELECT g.id, g.uuid, g.tenant_id, g.name, g.description, g.created_at, g.created_by, g.updated_at, g.updated_by
+		FROM "groups" g
+		INNER JOIN group_users gu ON gu.group_id = g.id
+		WHERE gu.user_id = ? AND g.tenant_id = ?
+		ORDER BY g.id
+	`
+
+	rows := []*entity.Group{}
+	err := r.DB().SelectContext(ctx, &rows, sql, userId, utils.GetTenantIdFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		err = r.loadRoles(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rows, nil
+}
+
+// FindAllMembers returns the members of the group with their role in the
+// active tenant
+func (r *sqlGroupPersistent) FindAllMembers(ctx context.Context, id int) ([]*entity.User, error) {
+	sql := `
+		SELECT u.id, u.uuid, u.username, u.email, u.name, COALESCE(tu.role_id, 0) AS role_id, u.status, u.last_login_at, u.created_at, u.created_by, u.updated_at, u.updated_by
+		FROM users u
+		INNER JOIN group_users gu ON gu.user_id = u.id
+		INNER JOIN "groups" g ON g.id = gu.group_id
+		INNER JOIN tenant_users tu ON tu.user_id = u.id AND tu.tenant_id = g.tenant_id AND tu.deleted_at IS NULL
+		WHERE gu.group_id = ? AND g.tenant_id = ?
+		ORDER BY u.id
+	`
+
+	rows := []*entity.User{}
+	err := r.DB().SelectContext(ctx, &rows, sql, id, utils.GetTenantIdFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// AddUsers adds the users in uuids to the group, only members of the tenant of
+// the group can join it. It returns the number of users added.
+func (r *sqlGroupPersistent) AddUsers(ctx context.Context, id int, uuids []string) (int, error) {
+	if len(uuids) == 0 {
+		return 0, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT tu.user_id
+		FROM "groups" g
+		INNER JOIN tenant_users tu ON tu.tenant_id = g.tenant_id AND tu.deleted_at IS NULL
+		INNER JOIN users u ON u.id = tu.user_id
+		WHERE g.id = ? AND g.tenant_id = ? AND u.uuid IN (?)
+		AND NOT EXISTS (SELECT 1 FROM group_users gu WHERE gu.group_id = g.id AND gu.user_id = tu.user_id)
+	`, id, utils.GetTenantIdFromContext(ctx), uuids)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.DB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	userIds := []int{}
+	err = tx.SelectContext(ctx, &userIds, query, args...)
+	if err != nil || len(userIds) == 0 {
+		tx.Rollback()
+		return 0, err
+	}
+
+	qInsertGroupUsers := squirrel.Insert("group_users").Columns("group_id", "user_id")
+	for _, userId := range userIds {
+		qInsertGroupUsers = qInsertGroupUsers.Values(id, userId)
+	}
+
+	sqlInsertGroupUsers, args, err := qInsertGroupUsers.ToSql()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlInsertGroupUsers, args...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return len(userIds), tx.Commit()
+}
+
+func (r *sqlGroupPersistent) RemoveUsers(ctx context.Context, id int, uuids []string) (int, error) {
+	query, args, err := sqlx.In(`
+		DELETE FROM group_users
+		WHERE group_id IN (SELECT id FROM "groups" WHERE id = ? AND tenant_id = ?)
+		AND user_id IN (SELECT id FROM users WHERE uuid IN (?))
+	`, id, utils.GetTenantIdFromContext(ctx), uuids)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.DB().ExecContext(ctx, r.DB().Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return int(affected), err
+}
+
+// GrantRoles adds the roles to the group, roles already granted are kept
+func (r *sqlGroupPersistent) GrantRoles(ctx context.Context, e *entity.Group, roles []*entity.Role) (*entity.Group, error) {
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		err := r.Touch(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return r.insertRoles(ctx, e.Id, roles)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+func (r *sqlGroupPersistent) RevokeRole(ctx context.Context, e *entity.Group, role *entity.Role) (*entity.Group, error) {
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		err := r.Touch(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		_, err = r.DB().ExecContext(ctx, `DELETE FROM group_roles WHERE group_id = ? AND role_id = ?`, e.Id, role.Id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+// insertRoles inserts group_roles rows, existing rows are kept
+func (r *sqlGroupPersistent) insertRoles(ctx context.Context, groupId int, roles []*entity.Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	granted := []int{}
+	err := r.DB().SelectContext(ctx, &granted, `SELECT role_id FROM group_roles WHERE group_id = ?`, groupId)
+	if err != nil {
+		return err
+	}
+
+	skip := map[int]bool{}
+	for _, roleId := range granted {
+		skip[roleId] = true
+	}
+
+	qInsertGroupRoles := squirrel.Insert("group_roles").Columns("group_id", "role_id")
+	inserted := 0
+	for _, role := range roles {
+		if skip[role.Id] {
+			continue
+		}
+		skip[role.Id] = true
+		qInsertGroupRoles = qInsertGroupRoles.Values(groupId, role.Id)
+		inserted++
+	}
+	if inserted == 0 {
+		return nil
+	}
+
+	sqlInsertGroupRoles, args, err := qInsertGroupRoles.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB().ExecContext(ctx, sqlInsertGroupRoles, args...)
+
+	return err
+}
+
+// withRoles loads the roles of the group found
+func (r *sqlGroupPersistent) withRoles(ctx context.Context, e *entity.Group, err error) (*entity.Group, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.loadRoles(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// loadRoles fills the roles granted to the group, without their permissions.
+// Trashed roles grant nothing and are left out.
+func (r *sqlGroupPersistent) loadRoles(ctx context.Context, e *entity.Group) error {
+	sql := `
+		SELECT id, uuid, name, is_system, COALESCE(tenant_id, 0) AS tenant_id, created_at, created_by, updated_at, updated_by
+		FROM roles
+		WHERE id IN (SELECT role_id FROM group_roles WHERE group_id = ?) AND deleted_at IS NULL
+		ORDER BY name
+	`
+
+	roles := []*entity.Role{}
+	err := r.DB().SelectContext(ctx, &roles, sql, e.Id)
+	if err != nil {
+		return err
+	}
+
+	e.Roles = roles
+
+	return nil
+}
+
+func (r *sqlGroupPersistent) CountByName(ctx context.Context, name string) (int, error) {
+	return r.CountBy(ctx, "name", name)
+}
@@ -0,0 +1,255 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/internal/group"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	handlerInstance     *handler
+	handlerInstanceOnce sync.Once
+)
+
+type Handler interface {
+	Store() func(echo.Context) error
+	Update() func(echo.Context) error
+	Delete() func(echo.Context) error
+	GetByUuid() func(echo.Context) error
+	GetAll() func(echo.Context) error
+	GetUsers() func(echo.Context) error
+	AddUsers() func(echo.Context) error
+	RemoveUsers() func(echo.Context) error
+	GrantRoles() func(echo.Context) error
+	RevokeRole() func(echo.Context) error
+}
+
+type handler struct {
+	uc group.GroupUsecase
+}
+
+func NewGroupHttpHandler(uc group.GroupUsecase) Handler {
+	handlerInstanceOnce.Do(func() {
+		handlerInstance = &handler{
+			uc: uc,
+		}
+	})
+
+	return handlerInstance
+}
+
+func (h *handler) Store() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.CreateGroupRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.CreateGroup(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, utils.JsonSuccess(http.StatusCreated, "", res, nil))
+	}
+}
+
+func (h *handler) Update() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.UpdateGroupRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.UpdateGroup(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) Delete() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.DeleteGroupRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.DeleteGroup(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) GetByUuid() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GetGroupRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.Get(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) GetAll() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GetListGroupRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.GetList(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res.Data, res.Pagination))
+	}
+}
+
+func (h *handler) GetUsers() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GetGroupUsersRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.GetUsers(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) AddUsers() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GroupUsersRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.AddUsers(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) RemoveUsers() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GroupUsersRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RemoveUsers(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) GrantRoles() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GrantGroupRolesRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.GrantRoles(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) RevokeRole() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.RevokeGroupRoleRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+			Role: strings.Trim(c.Param("name"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RevokeRole(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
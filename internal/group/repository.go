@@ -0,0 +1,26 @@
+package group
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+type GroupRepository interface {
+	Create(ctx context.Context, e *entity.Group) (*entity.Group, error)
+	Update(ctx context.Context, e *entity.Group) (*entity.Group, error)
+	Destroy(ctx context.Context, e *entity.Group) error
+	FindById(ctx context.Context, id int) (*entity.Group, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.Group, error)
+	FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Group, error)
+	FindAllByUserId(ctx context.Context, userId int) ([]*entity.Group, error)
+	FindAllMembers(ctx context.Context, id int) ([]*entity.User, error)
+	AddUsers(ctx context.Context, id int, uuids []string) (int, error)
+	RemoveUsers(ctx context.Context, id int, uuids []string) (int, error)
+	GrantRoles(ctx context.Context, e *entity.Group, roles []*entity.Role) (*entity.Group, error)
+	RevokeRole(ctx context.Context, e *entity.Group, role *entity.Role) (*entity.Group, error)
+
+	CountByName(ctx context.Context, name string) (int, error)
+	CountAll(ctx context.Context, spec *queryspec.Spec) (int, error)
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/group"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+var (
+	groupRepoInstance     *groupRepository
+	groupRepoInstanceOnce sync.Once
+)
+
+type groupRepository struct {
+	persistent group.GroupPersistent
+}
+
+func NewGroupRepository(persistent group.GroupPersistent) group.GroupRepository {
+	groupRepoInstanceOnce.Do(func() {
+		groupRepoInstance = &groupRepository{
+			persistent: persistent,
+		}
+	})
+
+	return groupRepoInstance
+}
+
+func (r *groupRepository) Create(ctx context.Context, e *entity.Group) (*entity.Group, error) {
+	return r.persistent.Create(ctx, e)
+}
+
+func (r *groupRepository) Update(ctx context.Context, e *entity.Group) (*entity.Group, error) {
+	return r.persistent.Update(ctx, e)
+}
+
+func (r *groupRepository) Destroy(ctx context.Context, e *entity.Group) error {
+	return r.persistent.Destroy(ctx, e)
+}
+
+func (r *groupRepository) FindById(ctx context.Context, id int) (*entity.Group, error) {
+	return r.persistent.FindById(ctx, id)
+}
+
+func (r *groupRepository) FindByUuid(ctx context.Context, uuid string) (*entity.Group, error) {
+	return r.persistent.FindByUuid(ctx, uuid)
+}
+
+func (r *groupRepository) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Group, error) {
+	return r.persistent.FindAll(ctx, spec)
+}
+
+func (r *groupRepository) FindAllByUserId(ctx context.Context, userId int) ([]*entity.Group, error) {
+	return r.persistent.FindAllByUserId(ctx, userId)
+}
+
+func (r *groupRepository) FindAllMembers(ctx context.Context, id int) ([]*entity.User, error) {
+	return r.persistent.FindAllMembers(ctx, id)
+}
+
+func (r *groupRepository) AddUsers(ctx context.Context, id int, uuids []string) (int, error) {
+	return r.persistent.AddUsers(ctx, id, uuids)
+}
+
+func (r *groupRepository) RemoveUsers(ctx context.Context, id int, uuids []string) (int, error) {
+	return r.persistent.RemoveUsers(ctx, id, uuids)
+}
+
+func (r *groupRepository) GrantRoles(ctx context.Context, e *entity.Group, roles []*entity.Role) (*entity.Group, error) {
+	return r.persistent.GrantRoles(ctx, e, roles)
+}
+
+func (r *groupRepository) RevokeRole(ctx context.Context, e *entity.Group, role *entity.Role) (*entity.Group, error) {
+	return r.persistent.RevokeRole(ctx, e, role)
+}
+
+func (r *groupRepository) CountByName(ctx context.Context, name string) (int, error) {
+	return r.persistent.CountByName(ctx, name)
+}
+
+func (r *groupRepository) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	return r.persistent.CountAll(ctx, spec)
+}
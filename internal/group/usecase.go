@@ -0,0 +1,21 @@
+package group
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+)
+
+type GroupUsecase interface {
+	CreateGroup(ctx context.Context, input *dto.CreateGroupRequest) (*dto.GroupResponse, error)
+	UpdateGroup(ctx context.Context, input *dto.UpdateGroupRequest) (*dto.GroupResponse, error)
+	DeleteGroup(ctx context.Context, input *dto.DeleteGroupRequest) (*dto.GroupResponse, error)
+	Get(ctx context.Context, input *dto.GetGroupRequest) (*dto.GroupResponse, error)
+	GetList(ctx context.Context, input *dto.GetListGroupRequest) (*dto.GroupCollectionResponse, error)
+
+	GetUsers(ctx context.Context, input *dto.GetGroupUsersRequest) ([]*dto.UserResponse, error)
+	AddUsers(ctx context.Context, input *dto.GroupUsersRequest) (*dto.GroupMembershipResponse, error)
+	RemoveUsers(ctx context.Context, input *dto.GroupUsersRequest) (*dto.GroupMembershipResponse, error)
+	GrantRoles(ctx context.Context, input *dto.GrantGroupRolesRequest) (*dto.GroupResponse, error)
+	RevokeRole(ctx context.Context, input *dto.RevokeGroupRoleRequest) (*dto.GroupResponse, error)
+}
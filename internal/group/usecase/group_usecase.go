@@ -0,0 +1,375 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/group"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	groupUcInstance     *groupUsecase
+	groupUcInstanceOnce sync.Once
+)
+
+type groupUsecase struct {
+	groupRepo group.GroupRepository
+	roleRepo  role.RoleRepository
+	userRepo  user.UserRepository
+}
+
+func NewGroupUsecase(groupRepo group.GroupRepository, roleRepo role.RoleRepository, userRepo user.UserRepository) group.GroupUsecase {
+	groupUcInstanceOnce.Do(func() {
+		groupUcInstance = &groupUsecase{
+			groupRepo: groupRepo,
+			roleRepo:  roleRepo,
+			userRepo:  userRepo,
+		}
+	})
+
+	return groupUcInstance
+}
+
+func (uc *groupUsecase) CreateGroup(ctx context.Context, input *dto.CreateGroupRequest) (*dto.GroupResponse, error) {
+	// validation logic
+	numrows, err := uc.groupRepo.CountByName(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if numrows > 0 {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("Group with name '%s' already exists", input.Name))
+	}
+
+	roles, err := uc.findRoles(ctx, input.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.ensureAssignable(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := uc.groupRepo.Create(ctx, &entity.Group{
+		Uuid:        uuid.NewString(),
+		Name:        input.Name,
+		Description: input.Description,
+		CreatedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		CreatedBy: uc.actorId(ctx),
+		UpdatedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		UpdatedBy: uc.actorId(ctx),
+		Roles:     roles,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewGroupResponse(e), nil
+}
+
+func (uc *groupUsecase) UpdateGroup(ctx context.Context, input *dto.UpdateGroupRequest) (*dto.GroupResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	// validation logic
+	if e.Name != input.Name {
+		numrows, err := uc.groupRepo.CountByName(ctx, input.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if numrows > 0 {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Group with name '%s' already exists", input.Name))
+		}
+	}
+
+	e.Name = input.Name
+	e.Description = input.Description
+	e.UpdatedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	e.UpdatedBy = uc.actorId(ctx)
+
+	updatedE, err := uc.groupRepo.Update(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewGroupResponse(updatedE), nil
+}
+
+func (uc *groupUsecase) DeleteGroup(ctx context.Context, input *dto.DeleteGroupRequest) (*dto.GroupResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{GroupIds: []int{e.Id}})
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.groupRepo.Destroy(ctx, e)
+
+	return nil, err
+}
+
+func (uc *groupUsecase) Get(ctx context.Context, input *dto.GetGroupRequest) (*dto.GroupResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewGroupResponse(e), nil
+}
+
+func (uc *groupUsecase) GetList(ctx context.Context, input *dto.GetListGroupRequest) (*dto.GroupCollectionResponse, error) {
+	spec, err := queryspec.New(input.Page, input.Limit, input.SortBy, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := uc.groupRepo.FindAll(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	numrows, err := uc.groupRepo.CountAll(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewGroupCollectionResponse(rows, dto.NewPaginationResponse(spec, len(rows), &numrows)), nil
+}
+
+func (uc *groupUsecase) GetUsers(ctx context.Context, input *dto.GetGroupUsersRequest) ([]*dto.UserResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := uc.groupRepo.FindAllMembers(ctx, e.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*dto.UserResponse{}
+	for _, row := range rows {
+		res = append(res, dto.NewUserResponse(row))
+	}
+
+	return res, nil
+}
+
+// AddUsers adds members of the tenant to the group, they are granted every
+// role of the group
+func (uc *groupUsecase) AddUsers(ctx context.Context, input *dto.GroupUsersRequest) (*dto.GroupMembershipResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := uc.loadRoles(ctx, e.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.ensureAssignable(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := uc.groupRepo.AddUsers(ctx, e.Id, input.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.GroupMembershipResponse{
+		Group:    e.Name,
+		Affected: affected,
+	}, nil
+}
+
+func (uc *groupUsecase) RemoveUsers(ctx context.Context, input *dto.GroupUsersRequest) (*dto.GroupMembershipResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{
+		GroupId:          e.Id,
+		GroupMemberUuids: input.Users,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := uc.groupRepo.RemoveUsers(ctx, e.Id, input.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.GroupMembershipResponse{
+		Group:    e.Name,
+		Affected: affected,
+	}, nil
+}
+
+func (uc *groupUsecase) GrantRoles(ctx context.Context, input *dto.GrantGroupRolesRequest) (*dto.GroupResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := uc.findRoles(ctx, input.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.ensureAssignable(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	e.UpdatedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	e.UpdatedBy = uc.actorId(ctx)
+
+	updatedE, err := uc.groupRepo.GrantRoles(ctx, e, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewGroupResponse(updatedE), nil
+}
+
+func (uc *groupUsecase) RevokeRole(ctx context.Context, input *dto.RevokeGroupRoleRequest) (*dto.GroupResponse, error) {
+	e, err := uc.groupRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := uc.roleRepo.FindByName(ctx, input.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", input.Role))
+		}
+		return nil, err
+	}
+
+	if r.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+		remaining := []*entity.Role{}
+		for _, granted := range e.Roles {
+			if granted.Id != r.Id {
+				remaining = append(remaining, granted)
+			}
+		}
+
+		remaining, err = uc.loadRoles(ctx, remaining)
+		if err != nil {
+			return nil, err
+		}
+
+		if !(&entity.Group{Roles: remaining}).HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{GroupIds: []int{e.Id}})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	e.UpdatedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	e.UpdatedBy = uc.actorId(ctx)
+
+	updatedE, err := uc.groupRepo.RevokeRole(ctx, e, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewGroupResponse(updatedE), nil
+}
+
+// actorId returns the id of the authenticated user for audit columns
+func (uc *groupUsecase) actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
+
+// findRoles resolves role names with their permissions, unknown names are
+// rejected
+func (uc *groupUsecase) findRoles(ctx context.Context, names []string) ([]*entity.Role, error) {
+	roles := []*entity.Role{}
+	for _, name := range names {
+		r, err := uc.roleRepo.FindByName(ctx, name)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", name))
+			}
+			return nil, err
+		}
+
+		roles = append(roles, r)
+	}
+
+	return roles, nil
+}
+
+// loadRoles reloads the roles of a group with their permissions
+func (uc *groupUsecase) loadRoles(ctx context.Context, roles []*entity.Role) ([]*entity.Role, error) {
+	loaded := []*entity.Role{}
+	for _, r := range roles {
+		full, err := uc.roleRepo.FindById(ctx, r.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		loaded = append(loaded, full)
+	}
+
+	return loaded, nil
+}
+
+func (uc *groupUsecase) ensureAssignable(ctx context.Context, roles []*entity.Role) error {
+	for _, r := range roles {
+		err := role.EnsureAssignable(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
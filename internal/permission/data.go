@@ -3,18 +3,26 @@ package permission
 import (
 	"context"
 
+	"time"
+
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 )
 
 type PermissionPersistent interface {
 	Create(ctx context.Context, e *entity.Permission) (*entity.Permission, error)
 	Update(ctx context.Context, e *entity.Permission) (*entity.Permission, error)
 	Destroy(ctx context.Context, e *entity.Permission) error
+	Restore(ctx context.Context, e *entity.Permission) (*entity.Permission, error)
+	Purge(ctx context.Context, before time.Time) ([]*entity.Permission, error)
 	FindById(ctx context.Context, id int) (*entity.Permission, error)
 	FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error)
-	FindAll(ctx context.Context, offset int, limit int, sorts map[string]string, search string) ([]*entity.Permission, error)
+	FindTrashedByUuid(ctx context.Context, uuid string) (*entity.Permission, error)
+	FindByName(ctx context.Context, name string) (*entity.Permission, error)
+	FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Permission, error)
 	FindAllByNames(ctx context.Context, names []string) ([]*entity.Permission, error)
+	FindAllByType(ctx context.Context, permissionType string) ([]*entity.Permission, error)
 
 	CountByName(ctx context.Context, name string) (int, error)
-	CountAll(ctx context.Context, search string) (int, error)
+	CountAll(ctx context.Context, spec *queryspec.Spec) (int, error)
 }
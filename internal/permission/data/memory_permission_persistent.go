@@ -0,0 +1,313 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+var (
+	memoryPersistInstance     *memoryPermissionPersistent
+	memoryPersistInstanceOnce sync.Once
+)
+
+// memoryPermissionPersistent keeps the permissions in a memdb.DB, the audit
+// columns the entity leaves unset are filled like the CRUD queries do
+type memoryPermissionPersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryPermissionPersistent(db *memdb.DB) permission.PermissionPersistent {
+	memoryPersistInstanceOnce.Do(func() {
+		memoryPersistInstance = &memoryPermissionPersistent{
+			db: db,
+		}
+	})
+
+	return memoryPersistInstance
+}
+
+func (r *memoryPermissionPersistent) Create(ctx context.Context, e *entity.Permission) (*entity.Permission, error) {
+	now, actor := time.Now(), actorId(ctx)
+
+	row := *e
+	row.CreatedAt = timeOr(e.CreatedAt, now)
+	row.CreatedBy = actorOr(e.CreatedBy, actor)
+	row.UpdatedAt = timeOr(e.UpdatedAt, now)
+	row.UpdatedBy = actorOr(e.UpdatedBy, actor)
+	row.DeletedAt = sql.NullTime{}
+	row.DeletedBy = sql.NullInt64{}
+
+	id := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		id, err = t.InsertPermission(&row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, id)
+}
+
+// Update saves the permission at the version it was read with, a permission
+// changed since fails with errors.ErrVersionConflict and a missing one with
+// sql.ErrNoRows
+func (r *memoryPermissionPersistent) Update(ctx context.Context, e *entity.Permission) (*entity.Permission, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Permissions, e.Id)
+		if row == nil || row.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+		if row.Version != e.Version {
+			return errors.ErrVersionConflict
+		}
+
+		row.ParentId = e.ParentId
+		row.Name = e.Name
+		row.Type = e.Type
+		row.UpdatedAt = timeOr(e.UpdatedAt, time.Now())
+		row.UpdatedBy = actorOr(e.UpdatedBy, actorId(ctx))
+		row.Version++
+
+		return t.SavePermission(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// Destroy moves the permission to the trash
+func (r *memoryPermissionPersistent) Destroy(ctx context.Context, e *entity.Permission) error {
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Permissions, e.Id)
+		if row == nil || row.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+
+		row.DeletedAt = timeOr(e.DeletedAt, time.Now())
+		row.DeletedBy = actorOr(e.DeletedBy, actorId(ctx))
+
+		return t.SavePermission(row)
+	})
+}
+
+func (r *memoryPermissionPersistent) Restore(ctx context.Context, e *entity.Permission) (*entity.Permission, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Permissions, e.Id)
+		if row == nil || !row.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+
+		row.DeletedAt = sql.NullTime{}
+		row.DeletedBy = sql.NullInt64{}
+		row.UpdatedAt = timeOr(e.UpdatedAt, time.Now())
+		row.UpdatedBy = actorOr(e.UpdatedBy, actorId(ctx))
+
+		return t.SavePermission(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// Purge deletes for good the permissions trashed before the given time, their
+// children are left without a parent. It returns the purged permissions.
+func (r *memoryPermissionPersistent) Purge(ctx context.Context, before time.Time) ([]*entity.Permission, error) {
+	purged := []*entity.Permission{}
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		ids := map[int]bool{}
+		for _, row := range memdb.Rows(t.Permissions) {
+			if row.DeletedAt.Valid && row.DeletedAt.Time.Before(before) {
+				ids[row.Id] = true
+				purged = append(purged, row)
+			}
+		}
+
+		for _, row := range memdb.Rows(t.Permissions) {
+			if !ids[row.ParentId] {
+				continue
+			}
+
+			row.ParentId = 0
+			if err := t.SavePermission(row); err != nil {
+				return err
+			}
+		}
+
+		for id := range ids {
+			t.DeletePermission(id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+func (r *memoryPermissionPersistent) FindById(ctx context.Context, id int) (*entity.Permission, error) {
+	return r.find(ctx, false, func(row *entity.Permission) bool {
+		return row.Id == id
+	})
+}
+
+func (r *memoryPermissionPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
+	return r.find(ctx, false, func(row *entity.Permission) bool {
+		return row.Uuid == uuid
+	})
+}
+
+func (r *memoryPermissionPersistent) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
+	return r.find(ctx, true, func(row *entity.Permission) bool {
+		return row.Uuid == uuid
+	})
+}
+
+func (r *memoryPermissionPersistent) FindByName(ctx context.Context, name string) (*entity.Permission, error) {
+	return r.find(ctx, false, func(row *entity.Permission) bool {
+		return row.Name == name
+	})
+}
+
+func (r *memoryPermissionPersistent) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Permission, error) {
+	rows := []*entity.Permission{}
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		rows, err = queryspec.SelectRows(permissionResource, memdb.Rows(t.Permissions), spec, queryspec.ColumnOf[*entity.Permission])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return queryspec.Page(permissionResource, spec, rows)
+}
+
+func (r *memoryPermissionPersistent) FindAllByNames(ctx context.Context, names []string) ([]*entity.Permission, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	return r.findAll(ctx, func(row *entity.Permission) bool {
+		return wanted[row.Name]
+	})
+}
+
+func (r *memoryPermissionPersistent) FindAllByType(ctx context.Context, permissionType string) ([]*entity.Permission, error) {
+	rows, err := r.findAll(ctx, func(row *entity.Permission) bool {
+		return row.Type == permissionType
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows, nil
+}
+
+func (r *memoryPermissionPersistent) CountByName(ctx context.Context, name string) (int, error) {
+	rows, err := r.findAll(ctx, func(row *entity.Permission) bool {
+		return row.Name == name
+	})
+
+	return len(rows), err
+}
+
+func (r *memoryPermissionPersistent) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		numrows, err = queryspec.CountRows(permissionResource, memdb.Rows(t.Permissions), spec, queryspec.ColumnOf[*entity.Permission])
+		return err
+	})
+
+	return numrows, err
+}
+
+// find returns the first live permission, or trashed one, matching
+func (r *memoryPermissionPersistent) find(ctx context.Context, trashed bool, match func(row *entity.Permission) bool) (*entity.Permission, error) {
+	var found *entity.Permission
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Permissions) {
+			if row.DeletedAt.Valid == trashed && match(row) {
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// findAll returns the live permissions matching, ordered by id
+func (r *memoryPermissionPersistent) findAll(ctx context.Context, match func(row *entity.Permission) bool) ([]*entity.Permission, error) {
+	rows := []*entity.Permission{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Permissions) {
+			if !row.DeletedAt.Valid && match(row) {
+				rows = append(rows, row)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// timeOr returns the time of the entity when it is set, now otherwise
+func timeOr(t sql.NullTime, now time.Time) sql.NullTime {
+	if t.Valid {
+		return t
+	}
+
+	return sql.NullTime{Time: now, Valid: true}
+}
+
+// actorOr returns the user of the entity when it is set, the actor otherwise
+func actorOr(id sql.NullInt64, actor sql.NullInt64) sql.NullInt64 {
+	if id.Valid {
+		return id
+	}
+
+	return actor
+}
+
+// actorId returns the id of the authenticated user, unset outside requests
+func actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
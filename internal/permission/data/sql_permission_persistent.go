@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	sqlPersistInstance     *sqlPermissionPersistent
+	sqlPersistInstanceOnce sync.Once
+)
+
+// permissionResource whitelists the fields of the permission list
+var permissionResource = &queryspec.Resource{
+	Sortable: map[string]string{
+		"name":       "name",
+		"type":       "type",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Filterable: map[string]queryspec.Field{
+		"name":       {Column: "name", Type: queryspec.TypeString, Ops: queryspec.TextOps},
+		"type":       {Column: "type", Type: queryspec.TypeString, Ops: queryspec.EnumOps},
+		"is_system":  {Column: "is_system", Type: queryspec.TypeBool, Ops: []string{queryspec.OpEq}},
+		"created_at": {Column: "created_at", Type: queryspec.TypeTime, Ops: queryspec.RangeOps},
+		"updated_at": {Column: "updated_at", Type: queryspec.TypeTime, Ops: queryspec.RangeOps},
+	},
+	Searchable: []string{"name"},
+	SoftDelete: "deleted_at",
+	Key:        "id",
+}
+
+// permissionTable maps the permissions for the CRUD queries
+var permissionTable = crud.Table{
+	Name:       "permissions",
+	Columns:    []string{"id", "uuid", "parent_id", "name", "type", "is_system", "created_at", "created_by", "updated_at", "updated_by", "version", "deleted_at", "deleted_by"},
+	Insertable: []string{"uuid", "parent_id", "name", "type", "is_system"},
+	Updatable:  []string{"parent_id", "name", "type"},
+	Version:    "version",
+	Audited:    true,
+	Resource:   permissionResource,
+}
+
+// sqlPermissionPersistent is not filtered by tenant, permissions are the
+// catalog declared by the application and shared by every tenant. Its queries
+// are built by crud and run on every SQL dialect.
+type sqlPermissionPersistent struct {
+	*crud.Repository[entity.Permission]
+}
+
+func NewSqlPermissionPersistent(db *txmanager.DB) permission.PermissionPersistent {
+	sqlPersistInstanceOnce.Do(func() {
+		sqlPersistInstance = &sqlPermissionPersistent{
+			Repository: crud.New[entity.Permission](db, permissionTable),
+		}
+	})
+
+	return sqlPersistInstance
+}
+
+// Purge deletes for good the permissions trashed before the given time, their
+// children are left without a parent. It returns the purged permissions.
+func (r *sqlPermissionPersistent) Purge(ctx context.Context, before time.Time) ([]*entity.Permission, error) {
+	// MySQL cannot update a table filtered by a subquery on that same table,
+	// the purged ids are read first
+	sqlOrphans := `UPDATE permissions SET parent_id = 0 WHERE parent_id IN (?)`
+	sqlPurge := `DELETE FROM permissions WHERE id IN (?)`
+
+	purged := []*entity.Permission{}
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		var err error
+		purged, err = r.Select(ctx, squirrel.Select(permissionTable.Columns...).
+			From(permissionTable.Name).
+			Where(squirrel.Lt{"deleted_at": before}).
+			OrderBy("id"))
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(purged))
+		for _, row := range purged {
+			ids = append(ids, row.Id)
+		}
+
+		for _, query := range []string{sqlOrphans, sqlPurge} {
+			query, args, err := sqlx.In(query, ids)
+			if err != nil {
+				return err
+			}
+
+			_, err = r.DB().ExecContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+func (r *sqlPermissionPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
+	return r.FindBy(ctx, "uuid", uuid)
+}
+
+func (r *sqlPermissionPersistent) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
+	return r.FindTrashedBy(ctx, "uuid", uuid)
+}
+
+func (r *sqlPermissionPersistent) FindByName(ctx context.Context, name string) (*entity.Permission, error) {
+	return r.FindBy(ctx, "name", name)
+}
+
+func (r *sqlPermissionPersistent) FindAllByNames(ctx context.Context, names []string) ([]*entity.Permission, error) {
+	return r.FindAllIn(ctx, "name", names)
+}
+
+func (r *sqlPermissionPersistent) FindAllByType(ctx context.Context, permissionType string) ([]*entity.Permission, error) {
+	return r.Select(ctx, r.Query(ctx).Where(squirrel.Eq{"type": permissionType}).OrderBy("name"))
+}
+
+func (r *sqlPermissionPersistent) CountByName(ctx context.Context, name string) (int, error) {
+	return r.CountBy(ctx, "name", name)
+}
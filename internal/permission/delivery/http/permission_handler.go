@@ -7,6 +7,7 @@ import (
 
 	"github.com/Adhiana46/echo-boilerplate/dto"
 	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
@@ -20,6 +21,7 @@ type Handler interface {
 	Store() func(echo.Context) error
 	Update() func(echo.Context) error
 	Delete() func(echo.Context) error
+	Restore() func(echo.Context) error
 	GetByUuid() func(echo.Context) error
 	GetAll() func(echo.Context) error
 }
@@ -69,6 +71,15 @@ func (h *handler) Update() func(echo.Context) error {
 
 		input.Uuid = strings.Trim(c.Param("uuid"), "/")
 
+		version, ok, err := utils.ParseIfMatch(c.Request().Header.Get("If-Match"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			input.Version = version
+			input.IfMatch = true
+		}
+
 		if err := c.Validate(input); err != nil {
 			return err
 		}
@@ -78,6 +89,8 @@ func (h *handler) Update() func(echo.Context) error {
 			return err
 		}
 
+		c.Response().Header().Set("ETag", utils.ETag(res.Version))
+
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
 	}
 }
@@ -101,10 +114,30 @@ func (h *handler) Delete() func(echo.Context) error {
 	}
 }
 
+func (h *handler) Restore() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.RestorePermissionRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RestorePermission(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
 func (h *handler) GetByUuid() func(echo.Context) error {
 	return func(c echo.Context) error {
 		input := dto.GetPermissionRequest{
-			Uuid: strings.Trim(c.Param("uuid"), "/"),
+			Uuid:    strings.Trim(c.Param("uuid"), "/"),
+			Include: c.QueryParam("include"),
 		}
 
 		if err := c.Validate(input); err != nil {
@@ -116,6 +149,8 @@ func (h *handler) GetByUuid() func(echo.Context) error {
 			return err
 		}
 
+		c.Response().Header().Set("ETag", utils.ETag(res.Version))
+
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
 	}
 }
@@ -128,6 +163,12 @@ func (h *handler) GetAll() func(echo.Context) error {
 			return err
 		}
 
+		filters, err := queryspec.ParseFilters(c.QueryParams())
+		if err != nil {
+			return err
+		}
+		input.Filters = filters
+
 		if err := c.Validate(input); err != nil {
 			return err
 		}
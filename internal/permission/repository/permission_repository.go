@@ -2,10 +2,16 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
+	"github.com/Adhiana46/echo-boilerplate/config"
+	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/entity"
 	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	cachePkg "github.com/Adhiana46/echo-boilerplate/pkg/cache"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 )
 
 var (
@@ -13,51 +19,114 @@ var (
 	permissionRepoInstanceOnce sync.Once
 )
 
+// permissionRepository reads single permissions through the cache and drops
+// them on every write, the other methods of the embedded persistent are
+// served as they are
 type permissionRepository struct {
-	persistent permission.PermissionPersistent
+	permission.PermissionPersistent
+	cache *cachePkg.Aside
 }
 
-func NewPermissionRepository(persistent permission.PermissionPersistent) permission.PermissionRepository {
+func NewPermissionRepository(persistent permission.PermissionPersistent, cache cachePkg.Cache, cfg *config.CacheConfig) permission.PermissionRepository {
 	permissionRepoInstanceOnce.Do(func() {
 		permissionRepoInstance = &permissionRepository{
-			persistent: persistent,
+			PermissionPersistent: persistent,
+			cache:                cachePkg.NewAside(cache, constants.CACHE_NAMESPACE_PERMISSIONS, cfg.PermissionTTL),
 		}
 	})
 	return permissionRepoInstance
 }
 
 func (r *permissionRepository) Create(ctx context.Context, e *entity.Permission) (*entity.Permission, error) {
-	return r.persistent.Create(ctx, e)
+	permissionEntity, err := r.PermissionPersistent.Create(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return permissionEntity, nil
 }
 
 func (r *permissionRepository) Update(ctx context.Context, e *entity.Permission) (*entity.Permission, error) {
-	return r.persistent.Update(ctx, e)
+	permissionEntity, err := r.PermissionPersistent.Update(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return permissionEntity, nil
 }
 
 func (r *permissionRepository) Destroy(ctx context.Context, e *entity.Permission) error {
-	return r.persistent.Destroy(ctx, e)
+	err := r.PermissionPersistent.Destroy(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx)
+
+	return nil
+}
+
+func (r *permissionRepository) Restore(ctx context.Context, e *entity.Permission) (*entity.Permission, error) {
+	permissionEntity, err := r.PermissionPersistent.Restore(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return permissionEntity, nil
+}
+
+func (r *permissionRepository) Purge(ctx context.Context, before time.Time) ([]*entity.Permission, error) {
+	purged, err := r.PermissionPersistent.Purge(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(purged) > 0 {
+		r.invalidate(ctx)
+	}
+
+	return purged, nil
 }
 
 func (r *permissionRepository) FindById(ctx context.Context, id int) (*entity.Permission, error) {
-	return r.persistent.FindById(ctx, id)
+	return r.cached(ctx, fmt.Sprintf("id:%d", id), func(ctx context.Context) (*entity.Permission, error) {
+		return r.PermissionPersistent.FindById(ctx, id)
+	})
 }
 
 func (r *permissionRepository) FindByUuid(ctx context.Context, uuid string) (*entity.Permission, error) {
-	return r.persistent.FindByUuid(ctx, uuid)
+	return r.cached(ctx, "uuid:"+uuid, func(ctx context.Context) (*entity.Permission, error) {
+		return r.PermissionPersistent.FindByUuid(ctx, uuid)
+	})
 }
 
-func (r *permissionRepository) FindAll(ctx context.Context, offset int, limit int, sorts map[string]string, search string) ([]*entity.Permission, error) {
-	return r.persistent.FindAll(ctx, offset, limit, sorts, search)
+func (r *permissionRepository) FindByName(ctx context.Context, name string) (*entity.Permission, error) {
+	return r.cached(ctx, "name:"+name, func(ctx context.Context) (*entity.Permission, error) {
+		return r.PermissionPersistent.FindByName(ctx, name)
+	})
 }
 
-func (r *permissionRepository) FindAllByNames(ctx context.Context, names []string) ([]*entity.Permission, error) {
-	return r.persistent.FindAllByNames(ctx, names)
-}
+// cached reads the permission through the cache. Reads inside a transaction
+// skip it, they may see writes not committed yet. Misses are loaded from the
+// primary so a lagging replica is never cached.
+func (r *permissionRepository) cached(ctx context.Context, key string, load func(ctx context.Context) (*entity.Permission, error)) (*entity.Permission, error) {
+	if txmanager.InTx(ctx) {
+		return load(ctx)
+	}
 
-func (r *permissionRepository) CountByName(ctx context.Context, name string) (int, error) {
-	return r.persistent.CountByName(ctx, name)
+	return cachePkg.Fetch(r.cache, key, func() (*entity.Permission, error) {
+		return load(txmanager.OnPrimary(ctx))
+	})
 }
 
-func (r *permissionRepository) CountAll(ctx context.Context, search string) (int, error) {
-	return r.persistent.CountAll(ctx, search)
+// invalidate drops the cached permissions, and the roles caching them, once
+// the write is committed
+func (r *permissionRepository) invalidate(ctx context.Context) {
+	txmanager.AfterCommit(ctx, r.cache.Invalidate)
 }
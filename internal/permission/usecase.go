@@ -2,6 +2,7 @@ package permission
 
 import (
 	"context"
+	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/dto"
 )
@@ -10,6 +11,14 @@ type PermissionUsecase interface {
 	CreatePermission(ctx context.Context, input *dto.CreatePermissionRequest) (*dto.PermissionResponse, error)
 	UpdatePermission(ctx context.Context, input *dto.UpdatePermissionRequest) (*dto.PermissionResponse, error)
 	DeletePermission(ctx context.Context, input *dto.DeletePermissionRequest) (*dto.PermissionResponse, error)
+	RestorePermission(ctx context.Context, input *dto.RestorePermissionRequest) (*dto.PermissionResponse, error)
 	Get(ctx context.Context, input *dto.GetPermissionRequest) (*dto.PermissionResponse, error)
 	GetList(ctx context.Context, input *dto.GetListPermissionRequest) (*dto.PermissionCollectionResponse, error)
+
+	SyncPermissions(ctx context.Context, names []string) (*dto.SyncPermissionsResponse, error)
+	VerifyPermissions(ctx context.Context, names []string) (*dto.VerifyPermissionsResponse, error)
+
+	// PurgeTrashed deletes for good the permissions trashed before the given
+	// time and logs each of them
+	PurgeTrashed(ctx context.Context, before time.Time) (int, error)
 }
@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/policy"
+)
+
+// permissionPolicy is evaluated against the loaded permission, so users can
+// read the permissions they are granted without holding permissions.read
+var permissionPolicy = policy.New[*entity.Permission]("permission").
+	Allow("read", policy.HasPermission[*entity.Permission]("permissions.read"), isGranted).
+	Allow("update", policy.HasPermission[*entity.Permission]("permissions.update")).
+	Allow("delete", policy.HasPermission[*entity.Permission]("permissions.delete")).
+	Allow("restore", policy.HasPermission[*entity.Permission]("permissions.restore"))
+
+var isGranted = policy.Attribute(func(actor *dto.UserResponseWithID, e *entity.Permission) bool {
+	return actor.HasPermission(e.Name)
+})
@@ -4,15 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"math"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/dto"
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
 	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
 	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/google/uuid"
 )
@@ -22,14 +26,23 @@ var (
 	permissionUcInstanceOnce sync.Once
 )
 
+// permissionIncludes are the relations permission responses can embed
+var permissionIncludes = []string{"created_by", "updated_by"}
+
 type permissionUsecase struct {
-	repo permission.PermissionRepository
+	repo      permission.PermissionRepository
+	userRepo  user.UserRepository
+	auditRepo audit.AuditLogRepository
+	txManager txmanager.TxManager
 }
 
-func NewPermissionUsecase(repo permission.PermissionRepository) permission.PermissionUsecase {
+func NewPermissionUsecase(repo permission.PermissionRepository, userRepo user.UserRepository, auditRepo audit.AuditLogRepository, txManager txmanager.TxManager) permission.PermissionUsecase {
 	permissionUcInstanceOnce.Do(func() {
 		permissionUcInstance = &permissionUsecase{
-			repo: repo,
+			repo:      repo,
+			userRepo:  userRepo,
+			auditRepo: auditRepo,
+			txManager: txManager,
 		}
 	})
 	return permissionUcInstance
@@ -61,28 +74,38 @@ func (uc *permissionUsecase) CreatePermission(ctx context.Context, input *dto.Cr
 		}
 	}
 
-	e, err := uc.repo.Create(ctx, &entity.Permission{
-		Uuid:     uuid.NewString(),
-		ParentId: input.ParentId,
-		Name:     input.Name,
-		Type:     input.Type,
-		CreatedAt: sql.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		CreatedBy: createdBy,
-		UpdatedAt: sql.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		UpdatedBy: updatedBy,
-	})
+	var res *dto.PermissionResponse
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.repo.Create(ctx, &entity.Permission{
+			Uuid:     uuid.NewString(),
+			ParentId: input.ParentId,
+			Name:     input.Name,
+			Type:     input.Type,
+			CreatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+			CreatedBy: createdBy,
+			UpdatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+			UpdatedBy: updatedBy,
+		})
 
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewPermissionResponse(e)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_CREATE, e.Uuid, nil, e)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewPermissionResponse(e), nil
+	return res, nil
 }
 
 func (uc *permissionUsecase) UpdatePermission(ctx context.Context, input *dto.UpdatePermissionRequest) (*dto.PermissionResponse, error) {
@@ -91,6 +114,16 @@ func (uc *permissionUsecase) UpdatePermission(ctx context.Context, input *dto.Up
 		return nil, err
 	}
 
+	err = permissionPolicy.Authorize(ctx, "update", e)
+	if err != nil {
+		return nil, err
+	}
+
+	err = utils.CheckVersion(e.Version, input.Version, input.IfMatch)
+	if err != nil {
+		return nil, err
+	}
+
 	// validation logic
 	if e.Name != input.Name {
 		numrows, err := uc.repo.CountByName(ctx, input.Name)
@@ -103,6 +136,10 @@ func (uc *permissionUsecase) UpdatePermission(ctx context.Context, input *dto.Up
 		}
 	}
 
+	if e.IsSystem && (e.Name != input.Name || e.Type != input.Type || e.ParentId != input.ParentId) {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("System permission '%s' cannot be renamed or moved", e.Name))
+	}
+
 	updatedBy := sql.NullInt64{}
 	user := utils.GetUserFromContext(ctx)
 	if user != nil {
@@ -112,6 +149,8 @@ func (uc *permissionUsecase) UpdatePermission(ctx context.Context, input *dto.Up
 		}
 	}
 
+	before := *e
+
 	e.ParentId = input.ParentId
 	e.Name = input.Name
 	e.Type = input.Type
@@ -121,12 +160,25 @@ func (uc *permissionUsecase) UpdatePermission(ctx context.Context, input *dto.Up
 	}
 	e.UpdatedBy = updatedBy
 
-	updatedE, err := uc.repo.Update(ctx, e)
+	var res *dto.PermissionResponse
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		updatedE, err := uc.repo.Update(ctx, e)
+		if err == errors.ErrVersionConflict {
+			return errors.NewVersionConflictError(e.Version, input.IfMatch)
+		}
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewPermissionResponse(updatedE)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_UPDATE, e.Uuid, &before, updatedE)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewPermissionResponse(updatedE), nil
+	return res, nil
 }
 
 func (uc *permissionUsecase) DeletePermission(ctx context.Context, input *dto.DeletePermissionRequest) (*dto.PermissionResponse, error) {
@@ -135,56 +187,308 @@ func (uc *permissionUsecase) DeletePermission(ctx context.Context, input *dto.De
 		return nil, err
 	}
 
-	err = uc.repo.Destroy(ctx, e)
+	err = permissionPolicy.Authorize(ctx, "delete", e)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.IsSystem {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("System permission '%s' cannot be deleted", e.Name))
+	}
+
+	before := *e
+
+	e.DeletedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	e.DeletedBy = uc.actorId(ctx)
+
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		err := uc.repo.Destroy(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return uc.record(ctx, constants.AUDIT_ACTION_DELETE, e.Uuid, &before, nil)
+	})
 
 	return nil, err
 }
 
+func (uc *permissionUsecase) RestorePermission(ctx context.Context, input *dto.RestorePermissionRequest) (*dto.PermissionResponse, error) {
+	e, err := uc.repo.FindTrashedByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = permissionPolicy.Authorize(ctx, "restore", e)
+	if err != nil {
+		return nil, err
+	}
+
+	// the name may have been taken while the permission was in the trash
+	numrows, err := uc.repo.CountByName(ctx, e.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if numrows > 0 {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("Permission with name '%s' already exists", e.Name))
+	}
+
+	before := *e
+
+	e.UpdatedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	e.UpdatedBy = uc.actorId(ctx)
+
+	var res *dto.PermissionResponse
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		restoredE, err := uc.repo.Restore(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewPermissionResponse(restoredE)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_RESTORE, e.Uuid, &before, restoredE)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PurgeTrashed logs every purged permission along with the purge
+func (uc *permissionUsecase) PurgeTrashed(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := uc.repo.Purge(ctx, before)
+		if err != nil {
+			return err
+		}
+		purged = len(rows)
+
+		for _, row := range rows {
+			err = uc.record(ctx, constants.AUDIT_ACTION_PURGE, row.Uuid, row, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return purged, err
+}
+
 func (uc *permissionUsecase) Get(ctx context.Context, input *dto.GetPermissionRequest) (*dto.PermissionResponse, error) {
+	includes, err := queryspec.ParseIncludes(input.Include, permissionIncludes...)
+	if err != nil {
+		return nil, err
+	}
+
 	e, err := uc.repo.FindByUuid(ctx, input.Uuid)
 	if err != nil {
 		return nil, err
 	}
 
+	err = permissionPolicy.Authorize(ctx, "read", e)
+	if err != nil {
+		return nil, err
+	}
+
+	err = user.ExpandActors(ctx, uc.userRepo, includes, []*entity.Permission{e})
+	if err != nil {
+		return nil, err
+	}
+
 	return dto.NewPermissionResponse(e), nil
 }
 
 func (uc *permissionUsecase) GetList(ctx context.Context, input *dto.GetListPermissionRequest) (*dto.PermissionCollectionResponse, error) {
-	var err error
-	offset := 0                                 // default
-	limit := constants.DEFAULT_PAGINATION_LIMIT // default
-	sorts := map[string]string{}
-	filter := input.Filter
-
-	if input.Limit > 0 {
-		limit = input.Limit
+	includes, err := queryspec.ParseIncludes(input.Include, permissionIncludes...)
+	if err != nil {
+		return nil, err
 	}
 
-	if input.Page > 0 {
-		offset = (input.Page - 1) * limit
+	spec, err := queryspec.New(input.Page, input.Limit, input.SortBy, input.Filter)
+	if err != nil {
+		return nil, err
 	}
+	spec.Filters = input.Filters
+	spec.SkipCount = input.SkipCount
+	spec.WithTrashed = input.WithTrashed
+	spec.OnlyTrashed = input.OnlyTrashed
 
-	if input.SortBy != "" {
-		sorts, err = utils.QuerySortToMap(input.SortBy)
+	if input.Paginate == queryspec.PaginateCursor || input.Cursor != "" {
+		err = spec.UseCursor(input.Cursor)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	rows, err := uc.repo.FindAll(ctx, offset, limit, sorts, filter)
+	rows, err := uc.repo.FindAll(ctx, spec)
 	if err != nil {
 		return nil, err
 	}
 
-	numrows, err := uc.repo.CountAll(ctx, filter)
+	err = user.ExpandActors(ctx, uc.userRepo, includes, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var total *int
+	if !spec.SkipCount {
+		numrows, err := uc.repo.CountAll(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		total = &numrows
+	}
+
+	return dto.NewPermissionCollectionResponse(rows, dto.NewPaginationResponse(spec, len(rows), total)), nil
+}
+
+// SyncPermissions creates the missing permissions, "users.create" is created
+// as an action under the "users" menu which is created too when missing.
+func (uc *permissionUsecase) SyncPermissions(ctx context.Context, names []string) (*dto.SyncPermissionsResponse, error) {
+	result := &dto.SyncPermissionsResponse{
+		Created: []string{},
+	}
+
+	for _, name := range names {
+		parentId := 0
+
+		if idx := strings.Index(name, "."); idx > 0 {
+			parent, created, err := uc.ensurePermission(ctx, name[:idx], 0, constants.PERMISSION_TYPE_MENU)
+			if err != nil {
+				return nil, err
+			}
+			if created {
+				result.Created = append(result.Created, parent.Name)
+			}
+
+			parentId = parent.Id
+		}
+
+		_, created, err := uc.ensurePermission(ctx, name, parentId, constants.PERMISSION_TYPE_ACTION)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			result.Created = append(result.Created, name)
+		}
+	}
+
+	return result, nil
+}
+
+func (uc *permissionUsecase) VerifyPermissions(ctx context.Context, names []string) (*dto.VerifyPermissionsResponse, error) {
+	result := &dto.VerifyPermissionsResponse{
+		Unknown:  []string{},
+		Orphaned: []string{},
+	}
+
+	declared := map[string]bool{}
+	for _, name := range names {
+		declared[name] = true
+	}
+
+	existing := map[string]bool{}
+	if len(names) > 0 {
+		rows, err := uc.repo.FindAllByNames(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			existing[row.Name] = true
+		}
+	}
+
+	for _, name := range names {
+		if !existing[name] {
+			result.Unknown = append(result.Unknown, name)
+		}
+	}
+
+	actions, err := uc.repo.FindAllByType(ctx, constants.PERMISSION_TYPE_ACTION)
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewPermissionCollectionResponse(rows, dto.PaginationResponse{
-		Size:        len(rows),
-		Total:       numrows,
-		TotalPages:  int(math.Ceil(float64(numrows) / float64(limit))),
-		CurrentPage: input.Page,
-	}), nil
+	for _, action := range actions {
+		if !declared[action.Name] {
+			result.Orphaned = append(result.Orphaned, action.Name)
+		}
+	}
+
+	return result, nil
+}
+
+func (uc *permissionUsecase) ensurePermission(ctx context.Context, name string, parentId int, permissionType string) (*entity.Permission, bool, error) {
+	e, err := uc.repo.FindByName(ctx, name)
+	if err == nil {
+		return e, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err = uc.repo.Create(ctx, &entity.Permission{
+			Uuid:     uuid.NewString(),
+			ParentId: parentId,
+			Name:     name,
+			Type:     permissionType,
+			CreatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+			UpdatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		return uc.record(ctx, constants.AUDIT_ACTION_CREATE, e.Uuid, nil, e)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return e, true, nil
+}
+
+// actorId returns the id of the authenticated user for audit columns
+func (uc *permissionUsecase) actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
+
+// record logs a change of the permission, before is nil for a created one and
+// after for a deleted one
+func (uc *permissionUsecase) record(ctx context.Context, action string, resourceUuid string, before any, after any) error {
+	return audit.Record(ctx, uc.auditRepo, audit.Change{
+		Action:       action,
+		ResourceType: constants.AUDIT_RESOURCE_PERMISSION,
+		ResourceUuid: resourceUuid,
+		Before:       before,
+		After:        after,
+	})
 }
@@ -0,0 +1,602 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+var (
+	memoryPersistInstance     *memoryRolePersistent
+	memoryPersistInstanceOnce sync.Once
+)
+
+// memoryRolePersistent keeps the roles in a memdb.DB, it sees the same roles
+// as sqlRolePersistent: those of the active tenant and the shared ones
+type memoryRolePersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryRolePersistent(db *memdb.DB) role.RolePersistent {
+	memoryPersistInstanceOnce.Do(func() {
+		memoryPersistInstance = &memoryRolePersistent{
+			db: db,
+		}
+	})
+
+	return memoryPersistInstance
+}
+
+func (r *memoryRolePersistent) Create(ctx context.Context, e *entity.Role) (*entity.Role, error) {
+	row := *e
+	row.TenantId = utils.GetTenantIdFromContext(ctx)
+	row.DeletedAt = sql.NullTime{}
+	row.DeletedBy = sql.NullInt64{}
+
+	roleId := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		roleId, err = t.InsertRole(&row)
+		if err != nil {
+			return err
+		}
+
+		err = insertPermissions(t, roleId, e.Permissions)
+		if err != nil {
+			return err
+		}
+
+		return insertParents(t, roleId, e.Parents)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, roleId)
+}
+
+// Update saves the role at the version it was read with, a role changed since
+// fails with errors.ErrVersionConflict and one missing from the active tenant
+// with sql.ErrNoRows
+func (r *memoryRolePersistent) Update(ctx context.Context, e *entity.Role) (*entity.Role, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Roles, e.Id)
+		if row == nil || row.TenantId != tenantId || row.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+		if row.Version != e.Version {
+			return errors.ErrVersionConflict
+		}
+
+		row.Name = e.Name
+		row.UpdatedAt = e.UpdatedAt
+		row.UpdatedBy = e.UpdatedBy
+		row.Version++
+
+		err := t.SaveRole(row)
+		if err != nil {
+			return err
+		}
+
+		for key := range t.RolePermissions {
+			if key.RoleId == e.Id {
+				delete(t.RolePermissions, key)
+			}
+		}
+
+		err = insertPermissions(t, e.Id, e.Permissions)
+		if err != nil {
+			return err
+		}
+
+		for key := range t.RoleParents {
+			if key.RoleId == e.Id {
+				delete(t.RoleParents, key)
+			}
+		}
+
+		return insertParents(t, e.Id, e.Parents)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// GrantPermissions adds permissions to the role without touching the other
+// grants, permissions already granted are skipped
+func (r *memoryRolePersistent) GrantPermissions(ctx context.Context, e *entity.Role, permissions []*entity.Permission) (*entity.Role, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		err := touch(ctx, t, e)
+		if err != nil {
+			return err
+		}
+
+		return insertPermissions(t, e.Id, permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// RevokePermission removes a single permission from the role
+func (r *memoryRolePersistent) RevokePermission(ctx context.Context, e *entity.Role, permission *entity.Permission) (*entity.Role, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		err := touch(ctx, t, e)
+		if err != nil {
+			return err
+		}
+
+		delete(t.RolePermissions, memdb.RolePermission{RoleId: e.Id, PermissionId: permission.Id})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// touch saves updated_at and updated_by of the role and bumps its version, it
+// fails with sql.ErrNoRows when the role does not belong to the active tenant
+// or is in the trash
+func touch(ctx context.Context, t *memdb.Tables, e *entity.Role) error {
+	row := memdb.Row(t.Roles, e.Id)
+	if row == nil || row.TenantId != utils.GetTenantIdFromContext(ctx) || row.DeletedAt.Valid {
+		return sql.ErrNoRows
+	}
+
+	row.UpdatedAt = e.UpdatedAt
+	row.UpdatedBy = e.UpdatedBy
+	row.Version++
+
+	return t.SaveRole(row)
+}
+
+func insertPermissions(t *memdb.Tables, roleId int, permissions []*entity.Permission) error {
+	for _, perm := range permissions {
+		err := t.AddRolePermission(roleId, perm.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertParents(t *memdb.Tables, roleId int, parents []*entity.Role) error {
+	for _, parent := range parents {
+		err := t.AddRoleParent(roleId, parent.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Destroy moves the role to the trash, members keep it for a restore but a
+// trashed role grants nothing
+func (r *memoryRolePersistent) Destroy(ctx context.Context, e *entity.Role) error {
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Roles, e.Id)
+		if row == nil || row.TenantId != utils.GetTenantIdFromContext(ctx) || row.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+
+		row.DeletedAt = e.DeletedAt
+		row.DeletedBy = e.DeletedBy
+
+		return t.SaveRole(row)
+	})
+}
+
+func (r *memoryRolePersistent) Restore(ctx context.Context, e *entity.Role) (*entity.Role, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Roles, e.Id)
+		if row == nil || row.TenantId != utils.GetTenantIdFromContext(ctx) || !row.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+
+		row.DeletedAt = sql.NullTime{}
+		row.DeletedBy = sql.NullInt64{}
+		row.UpdatedAt = e.UpdatedAt
+		row.UpdatedBy = e.UpdatedBy
+
+		return t.SaveRole(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// Purge deletes for good the roles trashed before the given time, members
+// still holding them are left without a role. It is not filtered by tenant
+// and returns the purged roles, without their relations.
+func (r *memoryRolePersistent) Purge(ctx context.Context, before time.Time) ([]*entity.Role, error) {
+	purged := []*entity.Role{}
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		ids := map[int]bool{}
+		for _, row := range memdb.Rows(t.Roles) {
+			if row.DeletedAt.Valid && row.DeletedAt.Time.Before(before) {
+				ids[row.Id] = true
+				purged = append(purged, row)
+			}
+		}
+
+		for _, m := range t.Members {
+			if ids[m.RoleId] {
+				m.RoleId = 0
+			}
+		}
+
+		for id := range ids {
+			err := t.DeleteRole(id)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+func (r *memoryRolePersistent) FindById(ctx context.Context, id int) (*entity.Role, error) {
+	return r.find(ctx, false, func(row *entity.Role) bool {
+		return row.Id == id
+	})
+}
+
+func (r *memoryRolePersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
+	return r.find(ctx, false, func(row *entity.Role) bool {
+		return row.Uuid == uuid
+	})
+}
+
+func (r *memoryRolePersistent) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
+	return r.find(ctx, true, func(row *entity.Role) bool {
+		return row.Uuid == uuid
+	})
+}
+
+// FindByName prefers the role of the active tenant to a shared role of the
+// same name
+func (r *memoryRolePersistent) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	rows, err := r.findAll(ctx, func(row *entity.Role) bool {
+		return row.Name == name
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	e := rows[0]
+	for _, row := range rows {
+		if row.TenantId != 0 {
+			e = row
+		}
+	}
+
+	err = r.LoadRelations(ctx, []*entity.Role{e})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *memoryRolePersistent) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Role, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	rows := []*entity.Role{}
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		visible := []*entity.Role{}
+		for _, row := range memdb.Rows(t.Roles) {
+			if row.TenantId == 0 || row.TenantId == tenantId {
+				visible = append(visible, row)
+			}
+		}
+
+		rows, err = queryspec.SelectRows(roleResource, visible, spec, queryspec.ColumnOf[*entity.Role])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return queryspec.Page(roleResource, spec, rows)
+}
+
+func (r *memoryRolePersistent) FindAllByNames(ctx context.Context, names []string) ([]*entity.Role, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	return r.findAll(ctx, func(row *entity.Role) bool {
+		return wanted[row.Name]
+	})
+}
+
+// FindAllByIds returns the live roles among the ids, without their relations
+func (r *memoryRolePersistent) FindAllByIds(ctx context.Context, ids []int) ([]*entity.Role, error) {
+	wanted := map[int]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	return r.findAll(ctx, func(row *entity.Role) bool {
+		return wanted[row.Id]
+	})
+}
+
+// FindAncestorIds returns the ids of every role the given role extends,
+// directly or transitively. Trashed roles are kept since they may come back.
+func (r *memoryRolePersistent) FindAncestorIds(ctx context.Context, id int) ([]int, error) {
+	ids := []int{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for ancestorId := range ancestors(t, id, true) {
+			ids = append(ids, ancestorId)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// FindPermissionPaths returns every chain of roles, starting at the given
+// role, that ends at a role granting the permission directly
+func (r *memoryRolePersistent) FindPermissionPaths(ctx context.Context, id int, permission string) ([][]string, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	visible := func(row *entity.Role) bool {
+		return (row.TenantId == 0 || row.TenantId == tenantId) && !row.DeletedAt.Valid
+	}
+
+	paths := [][]string{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		start, ok := t.Roles[id]
+		if !ok || !visible(start) {
+			return nil
+		}
+
+		grants := func(roleId int) bool {
+			for key := range t.RolePermissions {
+				perm, ok := t.Permissions[key.PermissionId]
+				if key.RoleId == roleId && ok && perm.Name == permission && !perm.DeletedAt.Valid {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		var walk func(roleId int, path []string)
+		walk = func(roleId int, path []string) {
+			if grants(roleId) {
+				paths = append(paths, append([]string{}, path...))
+			}
+
+			for _, parent := range parentsOf(t, roleId, false) {
+				if contains(path, parent.Name) || !visible(parent) {
+					continue
+				}
+
+				walk(parent.Id, append(path, parent.Name))
+			}
+		}
+		walk(start.Id, []string{start.Name})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// LoadRelations fills the direct permissions, the direct parents and the
+// permissions inherited from every ancestor of the roles. Trashed roles and
+// permissions are left out, along with what comes through them.
+func (r *memoryRolePersistent) LoadRelations(ctx context.Context, roles []*entity.Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	return r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, e := range roles {
+			e.Permissions = permissionsOf(t, e.Id)
+			e.Parents = parentsOf(t, e.Id, false)
+			e.InheritedPermissions = []*entity.Permission{}
+
+			owned := map[int]bool{}
+			for key := range t.RolePermissions {
+				if key.RoleId == e.Id {
+					owned[key.PermissionId] = true
+				}
+			}
+
+			inherited := map[int]*entity.Permission{}
+			for ancestorId := range ancestors(t, e.Id, false) {
+				for _, perm := range permissionsOf(t, ancestorId) {
+					if !owned[perm.Id] {
+						inherited[perm.Id] = perm
+					}
+				}
+			}
+
+			for _, perm := range memdb.Rows(inherited) {
+				e.InheritedPermissions = append(e.InheritedPermissions, perm)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *memoryRolePersistent) CountByName(ctx context.Context, name string) (int, error) {
+	rows, err := r.findAll(ctx, func(row *entity.Role) bool {
+		return row.Name == name
+	})
+
+	return len(rows), err
+}
+
+func (r *memoryRolePersistent) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		visible := []*entity.Role{}
+		for _, row := range memdb.Rows(t.Roles) {
+			if row.TenantId == 0 || row.TenantId == tenantId {
+				visible = append(visible, row)
+			}
+		}
+
+		numrows, err = queryspec.CountRows(roleResource, visible, spec, queryspec.ColumnOf[*entity.Role])
+		return err
+	})
+
+	return numrows, err
+}
+
+// find returns the first live role, or trashed one, of the active tenant or
+// shared that matches, with its relations
+func (r *memoryRolePersistent) find(ctx context.Context, trashed bool, match func(row *entity.Role) bool) (*entity.Role, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	var found *entity.Role
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Roles) {
+			if (row.TenantId == 0 || row.TenantId == tenantId) && row.DeletedAt.Valid == trashed && match(row) {
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.LoadRelations(ctx, []*entity.Role{found})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// findAll returns the live roles of the active tenant and the shared ones that
+// match, ordered by id and without their relations
+func (r *memoryRolePersistent) findAll(ctx context.Context, match func(row *entity.Role) bool) ([]*entity.Role, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	rows := []*entity.Role{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Roles) {
+			if (row.TenantId == 0 || row.TenantId == tenantId) && !row.DeletedAt.Valid && match(row) {
+				rows = append(rows, row)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// permissionsOf returns the live permissions granted to the role directly,
+// ordered by id
+func permissionsOf(t *memdb.Tables, roleId int) []*entity.Permission {
+	perms := map[int]*entity.Permission{}
+	for key := range t.RolePermissions {
+		perm, ok := t.Permissions[key.PermissionId]
+		if key.RoleId == roleId && ok && !perm.DeletedAt.Valid {
+			perms[perm.Id] = perm
+		}
+	}
+
+	return memdb.Rows(perms)
+}
+
+// parentsOf returns the parents of the role ordered by id, the trashed ones
+// only when asked
+func parentsOf(t *memdb.Tables, roleId int, withTrashed bool) []*entity.Role {
+	parents := map[int]*entity.Role{}
+	for key := range t.RoleParents {
+		parent, ok := t.Roles[key.ParentId]
+		if key.RoleId == roleId && ok && (withTrashed || !parent.DeletedAt.Valid) {
+			parents[parent.Id] = parent
+		}
+	}
+
+	return memdb.Rows(parents)
+}
+
+// ancestors returns the ids of the roles the role extends transitively,
+// stopping on cycles. Without the trashed ones, nothing comes through them.
+func ancestors(t *memdb.Tables, roleId int, withTrashed bool) map[int]bool {
+	seen := map[int]bool{}
+
+	queue := []int{roleId}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		for _, parent := range parentsOf(t, id, withTrashed) {
+			if seen[parent.Id] {
+				continue
+			}
+
+			seen[parent.Id] = true
+			queue = append(queue, parent.Id)
+		}
+	}
+
+	return seen
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,579 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	sqlPersistInstance     *sqlRolePersistent
+	sqlPersistInstanceOnce sync.Once
+)
+
+// roleResource whitelists the fields of the role list
+var roleResource = &queryspec.Resource{
+	Sortable: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Filterable: map[string]queryspec.Field{
+		"name":       {Column: "name", Type: queryspec.TypeString, Ops: queryspec.TextOps},
+		"is_system":  {Column: "is_system", Type: queryspec.TypeBool, Ops: []string{queryspec.OpEq}},
+		"created_at": {Column: "created_at", Type: queryspec.TypeTime, Ops: queryspec.RangeOps},
+		"updated_at": {Column: "updated_at", Type: queryspec.TypeTime, Ops: queryspec.RangeOps},
+	},
+	Searchable: []string{"name"},
+	SoftDelete: "deleted_at",
+	Key:        "id",
+}
+
+// roleTable maps the roles for the CRUD queries. A tenant sees its roles and
+// the shared ones (tenant_id NULL) but only changes its own, shared roles can
+// only be changed outside of a tenant (seeders, commands).
+var roleTable = crud.Table{
+	Name:       "roles",
+	Columns:    []string{"id", "uuid", "name", "is_system", "COALESCE(tenant_id, 0) AS tenant_id", "created_at", "created_by", "updated_at", "updated_by", "version", "deleted_at", "deleted_by"},
+	Insertable: []string{"uuid", "name", "is_system"},
+	Updatable:  []string{"name"},
+	Version:    "version",
+	Audited:    true,
+	Resource:   roleResource,
+	Owner: func(ctx context.Context) map[string]any {
+		tenantId := utils.GetTenantIdFromContext(ctx)
+
+		return map[string]any{"tenant_id": sql.NullInt64{Int64: int64(tenantId), Valid: tenantId != 0}}
+	},
+	Scope: func(ctx context.Context) squirrel.Sqlizer {
+		return squirrel.Expr("COALESCE(tenant_id, 0) IN (0, ?)", utils.GetTenantIdFromContext(ctx))
+	},
+	Writable: func(ctx context.Context) squirrel.Sqlizer {
+		return squirrel.Expr("COALESCE(tenant_id, 0) = ?", utils.GetTenantIdFromContext(ctx))
+	},
+}
+
+// sqlRolePersistent loads the roles with their permissions and parents, the
+// permission paths are walked in Go
+type sqlRolePersistent struct {
+	*crud.Repository[entity.Role]
+}
+
+func NewSqlRolePersistent(db *txmanager.DB) role.RolePersistent {
+	sqlPersistInstanceOnce.Do(func() {
+		sqlPersistInstance = &sqlRolePersistent{
+			Repository: crud.New[entity.Role](db, roleTable),
+		}
+	})
+
+	return sqlPersistInstance
+}
+
+func (r *sqlRolePersistent) Create(ctx context.Context, e *entity.Role) (*entity.Role, error) {
+	roleId := 0
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		var err error
+		roleId, err = r.Insert(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		err = r.insertPermissions(ctx, roleId, e.Permissions)
+		if err != nil {
+			return err
+		}
+
+		return r.insertParents(ctx, roleId, e.Parents)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), roleId)
+}
+
+// Update saves the role at the version it was read with, a role changed since
+// fails with errors.ErrVersionConflict and one missing from the active tenant
+// with sql.ErrNoRows
+func (r *sqlRolePersistent) Update(ctx context.Context, e *entity.Role) (*entity.Role, error) {
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		err := r.Save(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		for _, query := range []string{`DELETE FROM role_permissions WHERE role_id = ?`, `DELETE FROM role_parents WHERE role_id = ?`} {
+			_, err = r.DB().ExecContext(ctx, query, e.Id)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = r.insertPermissions(ctx, e.Id, e.Permissions)
+		if err != nil {
+			return err
+		}
+
+		return r.insertParents(ctx, e.Id, e.Parents)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+// GrantPermissions adds permissions to the role without touching the other
+// grants, permissions already granted are skipped
+func (r *sqlRolePersistent) GrantPermissions(ctx context.Context, e *entity.Role, permissions []*entity.Permission) (*entity.Role, error) {
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		err := r.Touch(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return r.insertPermissions(ctx, e.Id, permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+// RevokePermission removes a single permission from the role
+func (r *sqlRolePersistent) RevokePermission(ctx context.Context, e *entity.Role, permission *entity.Permission) (*entity.Role, error) {
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		err := r.Touch(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		_, err = r.DB().ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = ? AND permission_id = ?`, e.Id, permission.Id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+// insertPermissions inserts the role_permissions rows the role does not have
+// yet, the rows are compared in Go since the dialects disagree on upserts
+func (r *sqlRolePersistent) insertPermissions(ctx context.Context, roleId int, permissions []*entity.Permission) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	granted := []int{}
+	err := r.DB().SelectContext(ctx, &granted, `SELECT permission_id FROM role_permissions WHERE role_id = ?`, roleId)
+	if err != nil {
+		return err
+	}
+
+	skip := map[int]bool{}
+	for _, id := range granted {
+		skip[id] = true
+	}
+
+	q := squirrel.Insert("role_permissions").Columns("role_id", "permission_id")
+	inserts := 0
+	for _, perm := range permissions {
+		if skip[perm.Id] {
+			continue
+		}
+		skip[perm.Id] = true
+
+		q = q.Values(roleId, perm.Id)
+		inserts++
+	}
+
+	if inserts == 0 {
+		return nil
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB().ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (r *sqlRolePersistent) insertParents(ctx context.Context, roleId int, parents []*entity.Role) error {
+	if len(parents) == 0 {
+		return nil
+	}
+
+	q := squirrel.Insert("role_parents").Columns("role_id", "parent_id")
+	for _, parent := range parents {
+		q = q.Values(roleId, parent.Id)
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB().ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// Restore takes the role out of the trash
+func (r *sqlRolePersistent) Restore(ctx context.Context, e *entity.Role) (*entity.Role, error) {
+	_, err := r.Repository.Restore(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+// Purge deletes for good the roles trashed before the given time, members
+// still holding them are left without a role. It is not filtered by tenant
+// and returns the purged roles, without their relations.
+func (r *sqlRolePersistent) Purge(ctx context.Context, before time.Time) ([]*entity.Role, error) {
+	sqlUnassign := `
+		UPDATE tenant_users
+		SET role_id = NULL
+		WHERE role_id IN (SELECT id FROM roles WHERE deleted_at < ?)
+	`
+	sqlPurge := `DELETE FROM roles WHERE deleted_at < ?`
+
+	purged := []*entity.Role{}
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		var err error
+		purged, err = r.Select(ctx, squirrel.Select(roleTable.Columns...).
+			From(roleTable.Name).
+			Where(squirrel.Lt{"deleted_at": before}).
+			OrderBy("id"))
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+
+		for _, query := range []string{sqlUnassign, sqlPurge} {
+			_, err = r.DB().ExecContext(ctx, query, before)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+func (r *sqlRolePersistent) FindById(ctx context.Context, id int) (*entity.Role, error) {
+	e, err := r.Repository.FindById(ctx, id)
+
+	return r.withRelations(ctx, e, err)
+}
+
+func (r *sqlRolePersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
+	e, err := r.FindBy(ctx, "uuid", uuid)
+
+	return r.withRelations(ctx, e, err)
+}
+
+func (r *sqlRolePersistent) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
+	e, err := r.FindTrashedBy(ctx, "uuid", uuid)
+
+	return r.withRelations(ctx, e, err)
+}
+
+// FindByName prefers the role of the active tenant over a shared one
+func (r *sqlRolePersistent) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	q := r.Query(ctx).
+		Where(squirrel.Eq{"name": name}).
+		OrderBy("COALESCE(tenant_id, 0) DESC").
+		Limit(1)
+
+	e, err := r.Get(ctx, q)
+
+	return r.withRelations(ctx, e, err)
+}
+
+// withRelations loads the relations of a role found by a query
+func (r *sqlRolePersistent) withRelations(ctx context.Context, e *entity.Role, err error) (*entity.Role, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.LoadRelations(ctx, []*entity.Role{e})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *sqlRolePersistent) FindAllByNames(ctx context.Context, names []string) ([]*entity.Role, error) {
+	return r.FindAllIn(ctx, "name", names)
+}
+
+// FindAllByIds returns the live roles among the ids, without their relations
+func (r *sqlRolePersistent) FindAllByIds(ctx context.Context, ids []int) ([]*entity.Role, error) {
+	return r.FindAllIn(ctx, "id", ids)
+}
+
+// FindAncestorIds returns the ids of every role the given role extends,
+// directly or transitively. Trashed roles are kept since they may come back.
+func (r *sqlRolePersistent) FindAncestorIds(ctx context.Context, id int) ([]int, error) {
+	sql := `
+		WITH RECURSIVE ancestors (id) AS (
+			SELECT parent_id FROM role_parents WHERE role_id = ?
+			UNION
+			SELECT rp.parent_id FROM role_parents rp INNER JOIN ancestors a ON rp.role_id = a.id
+		)
+		SELECT id FROM ancestors
+	`
+
+	ids := []int{}
+	err := r.DB().SelectContext(ctx, &ids, sql, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// roleEdge is a live parent of the role Id
+type roleEdge struct {
+	Id         int    `db:"id"`
+	ParentId   int    `db:"parent_id"`
+	ParentName string `db:"parent_name"`
+}
+
+// FindPermissionPaths returns every chain of roles, starting at the given
+// role, that ends at a role granting the permission directly. The links
+// between the live roles of the active tenant are read at once and the chains
+// are walked in Go, a chain does not go through the same role twice.
+func (r *sqlRolePersistent) FindPermissionPaths(ctx context.Context, id int, permission string) ([][]string, error) {
+	sqlGranting := `
+		SELECT rp.role_id
+		FROM role_permissions rp
+		INNER JOIN permissions p ON p.id = rp.permission_id
+		WHERE p.name = ? AND p.deleted_at IS NULL
+	`
+
+	// the live roles the active tenant sees
+	visible := squirrel.Select("id").
+		From("roles").
+		Where(squirrel.Eq{"deleted_at": nil}).
+		Where(roleTable.Scope(ctx))
+
+	query, args, err := squirrel.Select("name").
+		From("roles").
+		Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		Where(roleTable.Scope(ctx)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	start := ""
+	err = r.DB().QueryRowContext(ctx, query, args...).Scan(&start)
+	if err == sql.ErrNoRows {
+		return [][]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err = squirrel.Select("rp.role_id AS id", "r.id AS parent_id", "r.name AS parent_name").
+		From("role_parents rp").
+		Join("roles r ON r.id = rp.parent_id").
+		Where(squirrel.Expr("rp.role_id IN (?)", visible)).
+		Where(squirrel.Expr("r.id IN (?)", visible)).
+		OrderBy("rp.role_id", "r.id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	edges := []*roleEdge{}
+	err = r.DB().SelectContext(ctx, &edges, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	granting := []int{}
+	err = r.DB().SelectContext(ctx, &granting, sqlGranting, permission)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := map[int][]*roleEdge{}
+	for _, edge := range edges {
+		parents[edge.Id] = append(parents[edge.Id], edge)
+	}
+
+	grants := map[int]bool{}
+	for _, roleId := range granting {
+		grants[roleId] = true
+	}
+
+	paths := [][]string{}
+
+	var walk func(roleId int, path []string)
+	walk = func(roleId int, path []string) {
+		if grants[roleId] {
+			paths = append(paths, append([]string{}, path...))
+		}
+
+		for _, edge := range parents[roleId] {
+			if contains(path, edge.ParentName) {
+				continue
+			}
+
+			walk(edge.ParentId, append(path, edge.ParentName))
+		}
+	}
+	walk(id, []string{start})
+
+	return paths, nil
+}
+
+// ownedPermission is a permission of the role OwnerId
+type ownedPermission struct {
+	OwnerId int `db:"owner_id"`
+	entity.Permission
+}
+
+// ownedParent is a parent of the role OwnerId
+type ownedParent struct {
+	OwnerId int `db:"owner_id"`
+	entity.Role
+}
+
+// LoadRelations fills the direct permissions, the direct parents and the
+// permissions inherited from every ancestor of the roles, see loadRelations
+func (r *sqlRolePersistent) LoadRelations(ctx context.Context, roles []*entity.Role) error {
+	return loadRelations(ctx, r.DB(), roles)
+}
+
+// loadRelations fills the direct permissions, the direct parents and the
+// permissions inherited from every ancestor of the roles with three queries
+// whatever their number. Ancestors are resolved with a single recursive
+// query; UNION (not UNION ALL) stops on cycles. Trashed roles and permissions
+// are left out, along with what comes through them.
+func loadRelations(ctx context.Context, db *txmanager.DB, roles []*entity.Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	sqlPerms := `
+		SELECT rp.role_id AS owner_id, p.id, p.uuid, p.parent_id, p.name, p.type, p.is_system, p.created_at, p.created_by, p.updated_at, p.updated_by, p.version
+		FROM role_permissions rp
+		INNER JOIN permissions p ON p.id = rp.permission_id AND p.deleted_at IS NULL
+		WHERE rp.role_id IN (?)
+		ORDER BY rp.role_id, p.id
+	`
+	sqlParents := `
+		SELECT rp.role_id AS owner_id, r.id, r.uuid, r.name, r.is_system, COALESCE(r.tenant_id, 0) AS tenant_id, r.created_at, r.created_by, r.updated_at, r.updated_by, r.version
+		FROM role_parents rp
+		INNER JOIN roles r ON r.id = rp.parent_id AND r.deleted_at IS NULL
+		WHERE rp.role_id IN (?)
+		ORDER BY rp.role_id, r.id
+	`
+	sqlInheritedPerms := `
+		WITH RECURSIVE ancestors (role_id, id) AS (
+			SELECT rp.role_id, rp.parent_id
+			FROM role_parents rp
+			INNER JOIN roles r ON r.id = rp.parent_id AND r.deleted_at IS NULL
+			WHERE rp.role_id IN (?)
+			UNION
+			SELECT a.role_id, rp.parent_id
+			FROM role_parents rp
+			INNER JOIN ancestors a ON rp.role_id = a.id
+			INNER JOIN roles r ON r.id = rp.parent_id AND r.deleted_at IS NULL
+		)
+		SELECT DISTINCT a.role_id AS owner_id, p.id, p.uuid, p.parent_id, p.name, p.type, p.is_system, p.created_at, p.created_by, p.updated_at, p.updated_by, p.version
+		FROM ancestors a
+		INNER JOIN role_permissions rp ON rp.role_id = a.id
+		INNER JOIN permissions p ON p.id = rp.permission_id AND p.deleted_at IS NULL
+		WHERE NOT EXISTS (
+			SELECT 1 FROM role_permissions own WHERE own.role_id = a.role_id AND own.permission_id = p.id
+		)
+		ORDER BY owner_id, p.id
+	`
+
+	ids := []int{}
+	byId := map[int]*entity.Role{}
+	for _, e := range roles {
+		e.Permissions = []*entity.Permission{}
+		e.Parents = []*entity.Role{}
+		e.InheritedPermissions = []*entity.Permission{}
+
+		ids = append(ids, e.Id)
+		byId[e.Id] = e
+	}
+
+	perms := []*ownedPermission{}
+	err := selectIn(ctx, db, &perms, sqlPerms, ids)
+	if err != nil {
+		return err
+	}
+
+	parents := []*ownedParent{}
+	err = selectIn(ctx, db, &parents, sqlParents, ids)
+	if err != nil {
+		return err
+	}
+
+	inheritedPerms := []*ownedPermission{}
+	err = selectIn(ctx, db, &inheritedPerms, sqlInheritedPerms, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range perms {
+		perm := row.Permission
+		byId[row.OwnerId].Permissions = append(byId[row.OwnerId].Permissions, &perm)
+	}
+
+	for _, row := range parents {
+		parent := row.Role
+		byId[row.OwnerId].Parents = append(byId[row.OwnerId].Parents, &parent)
+	}
+
+	for _, row := range inheritedPerms {
+		perm := row.Permission
+		byId[row.OwnerId].InheritedPermissions = append(byId[row.OwnerId].InheritedPermissions, &perm)
+	}
+
+	return nil
+}
+
+// selectIn runs a query whose single placeholder is a list of ids
+func selectIn(ctx context.Context, db *txmanager.DB, dest any, query string, ids []int) error {
+	query, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return err
+	}
+
+	return db.SelectContext(ctx, dest, query, args...)
+}
+
+func (r *sqlRolePersistent) CountByName(ctx context.Context, name string) (int, error) {
+	return r.CountBy(ctx, "name", name)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Adhiana46/echo-boilerplate/dto"
 	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
@@ -15,8 +16,14 @@ type Handler interface {
 	Store() func(echo.Context) error
 	Update() func(echo.Context) error
 	Delete() func(echo.Context) error
+	Restore() func(echo.Context) error
 	GetByUuid() func(echo.Context) error
 	GetAll() func(echo.Context) error
+
+	GrantPermissions() func(echo.Context) error
+	RevokePermission() func(echo.Context) error
+	AssignUsers() func(echo.Context) error
+	UnassignUsers() func(echo.Context) error
 }
 
 var (
@@ -69,6 +76,15 @@ func (h *handler) Update() func(echo.Context) error {
 
 		input.Uuid = strings.Trim(c.Param("uuid"), "/")
 
+		version, ok, err := utils.ParseIfMatch(c.Request().Header.Get("If-Match"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			input.Version = version
+			input.IfMatch = true
+		}
+
 		if err := c.Validate(input); err != nil {
 			return err
 		}
@@ -78,6 +94,8 @@ func (h *handler) Update() func(echo.Context) error {
 			return err
 		}
 
+		c.Response().Header().Set("ETag", utils.ETag(res.Version))
+
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
 	}
 }
@@ -101,10 +119,30 @@ func (h *handler) Delete() func(echo.Context) error {
 	}
 }
 
+func (h *handler) Restore() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.RestoreRoleRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RestoreRole(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
 func (h *handler) GetByUuid() func(echo.Context) error {
 	return func(c echo.Context) error {
 		input := dto.GetRoleRequest{
-			Uuid: strings.Trim(c.Param("uuid"), "/"),
+			Uuid:    strings.Trim(c.Param("uuid"), "/"),
+			Include: c.QueryParam("include"),
 		}
 
 		if err := c.Validate(input); err != nil {
@@ -116,6 +154,8 @@ func (h *handler) GetByUuid() func(echo.Context) error {
 			return err
 		}
 
+		c.Response().Header().Set("ETag", utils.ETag(res.Version))
+
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
 	}
 }
@@ -128,6 +168,12 @@ func (h *handler) GetAll() func(echo.Context) error {
 			return err
 		}
 
+		filters, err := queryspec.ParseFilters(c.QueryParams())
+		if err != nil {
+			return err
+		}
+		input.Filters = filters
+
 		if err := c.Validate(input); err != nil {
 			return err
 		}
@@ -140,3 +186,92 @@ func (h *handler) GetAll() func(echo.Context) error {
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res.Data, res.Pagination))
 	}
 }
+
+func (h *handler) GrantPermissions() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GrantRolePermissionsRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.GrantPermissions(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) RevokePermission() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.RevokeRolePermissionRequest{
+			Uuid:       strings.Trim(c.Param("uuid"), "/"),
+			Permission: strings.Trim(c.Param("name"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RevokePermission(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) AssignUsers() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.AssignRoleUsersRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.AssignUsers(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) UnassignUsers() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.AssignRoleUsersRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.UnassignUsers(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
@@ -0,0 +1,56 @@
+package role
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/rbac"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+func init() {
+	rbac.Declare(constants.PRIVILEGE_ESCALATION_PERMISSION)
+}
+
+// EnsureGrantable refuses granting permissions the authenticated user does
+// not hold, unless they hold the escalation permission. Calls without an
+// authenticated user (seeders, commands) are not restricted.
+func EnsureGrantable(ctx context.Context, permissions []string) error {
+	return ensureHeld(ctx, permissions, "You cannot grant permissions you do not have")
+}
+
+// EnsureAssignable refuses assigning a role that grants more than the
+// authenticated user holds
+func EnsureAssignable(ctx context.Context, e *entity.Role) error {
+	return EnsureGrantable(ctx, e.PermissionNames())
+}
+
+// EnsureManageable refuses changing the credentials of a user granted more
+// than the authenticated user holds, who could then act as that user
+func EnsureManageable(ctx context.Context, e *entity.User) error {
+	return ensureHeld(ctx, e.PermissionNames(), "You cannot change the credentials of a user with permissions you do not have")
+}
+
+// ensureHeld refuses with the message when the authenticated user misses one
+// of the permissions, unless they hold the escalation permission
+func ensureHeld(ctx context.Context, permissions []string, message string) error {
+	actor := utils.GetUserFromContext(ctx)
+	if actor == nil {
+		return nil
+	}
+
+	if rbac.UserHas(actor)(constants.PRIVILEGE_ESCALATION_PERMISSION) {
+		return nil
+	}
+
+	missing := rbac.Missing(actor, permissions)
+	if len(missing) > 0 {
+		return errors.NewForbiddenErrorWithErrors(message, map[string]any{
+			"missing": missing,
+		})
+	}
+
+	return nil
+}
@@ -3,18 +3,31 @@ package role
 import (
 	"context"
 
+	"time"
+
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 )
 
 type RoleRepository interface {
 	Create(ctx context.Context, e *entity.Role) (*entity.Role, error)
 	Update(ctx context.Context, e *entity.Role) (*entity.Role, error)
 	Destroy(ctx context.Context, e *entity.Role) error
+	Restore(ctx context.Context, e *entity.Role) (*entity.Role, error)
+	Purge(ctx context.Context, before time.Time) ([]*entity.Role, error)
+	GrantPermissions(ctx context.Context, e *entity.Role, permissions []*entity.Permission) (*entity.Role, error)
+	RevokePermission(ctx context.Context, e *entity.Role, permission *entity.Permission) (*entity.Role, error)
 	FindById(ctx context.Context, id int) (*entity.Role, error)
 	FindByUuid(ctx context.Context, uuid string) (*entity.Role, error)
+	FindTrashedByUuid(ctx context.Context, uuid string) (*entity.Role, error)
 	FindByName(ctx context.Context, name string) (*entity.Role, error)
-	FindAll(ctx context.Context, offset int, limit int, sorts map[string]string, search string) ([]*entity.Role, error)
+	FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Role, error)
+	FindAllByNames(ctx context.Context, names []string) ([]*entity.Role, error)
+	FindAllByIds(ctx context.Context, ids []int) ([]*entity.Role, error)
+	FindAncestorIds(ctx context.Context, id int) ([]int, error)
+	FindPermissionPaths(ctx context.Context, id int, permission string) ([][]string, error)
+	LoadRelations(ctx context.Context, roles []*entity.Role) error
 
 	CountByName(ctx context.Context, name string) (int, error)
-	CountAll(ctx context.Context, search string) (int, error)
+	CountAll(ctx context.Context, spec *queryspec.Spec) (int, error)
 }
@@ -2,10 +2,18 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
+	"github.com/Adhiana46/echo-boilerplate/config"
+	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/entity"
 	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	cachePkg "github.com/Adhiana46/echo-boilerplate/pkg/cache"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 )
 
 var (
@@ -15,12 +23,15 @@ var (
 
 type roleRepository struct {
 	persistent role.RolePersistent
+	cache      *cachePkg.Aside
 }
 
-func NewRoleRepository(persistent role.RolePersistent) role.RoleRepository {
+func NewRoleRepository(persistent role.RolePersistent, cache cachePkg.Cache, cfg *config.CacheConfig) role.RoleRepository {
 	roleRepoInstanceOnce.Do(func() {
 		roleRepoInstance = &roleRepository{
 			persistent: persistent,
+			// a cached role holds its permissions, direct and inherited
+			cache: cachePkg.NewAside(cache, constants.CACHE_NAMESPACE_ROLES, cfg.RoleTTL, constants.CACHE_NAMESPACE_PERMISSIONS),
 		}
 	})
 
@@ -28,37 +39,156 @@ func NewRoleRepository(persistent role.RolePersistent) role.RoleRepository {
 }
 
 func (r *roleRepository) Create(ctx context.Context, e *entity.Role) (*entity.Role, error) {
-	return r.persistent.Create(ctx, e)
+	roleEntity, err := r.persistent.Create(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return roleEntity, nil
 }
 
 func (r *roleRepository) Update(ctx context.Context, e *entity.Role) (*entity.Role, error) {
-	return r.persistent.Update(ctx, e)
+	roleEntity, err := r.persistent.Update(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return roleEntity, nil
 }
 
 func (r *roleRepository) Destroy(ctx context.Context, e *entity.Role) error {
-	return r.persistent.Destroy(ctx, e)
+	err := r.persistent.Destroy(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx)
+
+	return nil
+}
+
+func (r *roleRepository) Restore(ctx context.Context, e *entity.Role) (*entity.Role, error) {
+	roleEntity, err := r.persistent.Restore(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return roleEntity, nil
+}
+
+func (r *roleRepository) Purge(ctx context.Context, before time.Time) ([]*entity.Role, error) {
+	purged, err := r.persistent.Purge(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(purged) > 0 {
+		r.invalidate(ctx)
+	}
+
+	return purged, nil
+}
+
+func (r *roleRepository) GrantPermissions(ctx context.Context, e *entity.Role, permissions []*entity.Permission) (*entity.Role, error) {
+	roleEntity, err := r.persistent.GrantPermissions(ctx, e, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return roleEntity, nil
+}
+
+func (r *roleRepository) RevokePermission(ctx context.Context, e *entity.Role, permission *entity.Permission) (*entity.Role, error) {
+	roleEntity, err := r.persistent.RevokePermission(ctx, e, permission)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return roleEntity, nil
 }
 
 func (r *roleRepository) FindById(ctx context.Context, id int) (*entity.Role, error) {
-	return r.persistent.FindById(ctx, id)
+	return r.cached(ctx, fmt.Sprintf("id:%d", id), func(ctx context.Context) (*entity.Role, error) {
+		return r.persistent.FindById(ctx, id)
+	})
 }
 
 func (r *roleRepository) FindByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
-	return r.persistent.FindByUuid(ctx, uuid)
+	return r.cached(ctx, "uuid:"+uuid, func(ctx context.Context) (*entity.Role, error) {
+		return r.persistent.FindByUuid(ctx, uuid)
+	})
+}
+
+func (r *roleRepository) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.Role, error) {
+	return r.persistent.FindTrashedByUuid(ctx, uuid)
 }
 
 func (r *roleRepository) FindByName(ctx context.Context, name string) (*entity.Role, error) {
-	return r.persistent.FindByName(ctx, name)
+	return r.cached(ctx, "name:"+name, func(ctx context.Context) (*entity.Role, error) {
+		return r.persistent.FindByName(ctx, name)
+	})
+}
+
+func (r *roleRepository) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Role, error) {
+	return r.persistent.FindAll(ctx, spec)
+}
+
+func (r *roleRepository) FindAllByNames(ctx context.Context, names []string) ([]*entity.Role, error) {
+	return r.persistent.FindAllByNames(ctx, names)
+}
+
+func (r *roleRepository) FindAllByIds(ctx context.Context, ids []int) ([]*entity.Role, error) {
+	return r.persistent.FindAllByIds(ctx, ids)
 }
 
-func (r *roleRepository) FindAll(ctx context.Context, offset int, limit int, sorts map[string]string, search string) ([]*entity.Role, error) {
-	return r.persistent.FindAll(ctx, offset, limit, sorts, search)
+func (r *roleRepository) FindAncestorIds(ctx context.Context, id int) ([]int, error) {
+	return r.persistent.FindAncestorIds(ctx, id)
+}
+
+func (r *roleRepository) FindPermissionPaths(ctx context.Context, id int, permission string) ([][]string, error) {
+	return r.persistent.FindPermissionPaths(ctx, id, permission)
+}
+
+func (r *roleRepository) LoadRelations(ctx context.Context, roles []*entity.Role) error {
+	return r.persistent.LoadRelations(ctx, roles)
 }
 
 func (r *roleRepository) CountByName(ctx context.Context, name string) (int, error) {
 	return r.persistent.CountByName(ctx, name)
 }
 
-func (r *roleRepository) CountAll(ctx context.Context, search string) (int, error) {
-	return r.persistent.CountAll(ctx, search)
+func (r *roleRepository) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	return r.persistent.CountAll(ctx, spec)
+}
+
+// cached reads the role through the cache, under a key of the active tenant
+// since shared roles are visible from every tenant but tenant roles are not.
+// Reads inside a transaction skip the cache and misses are loaded from the
+// primary.
+func (r *roleRepository) cached(ctx context.Context, key string, load func(ctx context.Context) (*entity.Role, error)) (*entity.Role, error) {
+	if txmanager.InTx(ctx) {
+		return load(ctx)
+	}
+
+	key = fmt.Sprintf("tenant:%d:%s", utils.GetTenantIdFromContext(ctx), key)
+
+	return cachePkg.Fetch(r.cache, key, func() (*entity.Role, error) {
+		return load(txmanager.OnPrimary(ctx))
+	})
+}
+
+// invalidate drops the cached roles once the write is committed, the parents
+// of a role are cached with it so every role goes
+func (r *roleRepository) invalidate(ctx context.Context) {
+	txmanager.AfterCommit(ctx, r.cache.Invalidate)
 }
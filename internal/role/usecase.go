@@ -2,6 +2,7 @@ package role
 
 import (
 	"context"
+	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/dto"
 )
@@ -10,6 +11,16 @@ type RoleUsecase interface {
 	CreateRole(ctx context.Context, input *dto.CreateRoleRequest) (*dto.RoleResponse, error)
 	UpdateRole(ctx context.Context, input *dto.UpdateRoleRequest) (*dto.RoleResponse, error)
 	DeleteRole(ctx context.Context, input *dto.DeleteRoleRequest) (*dto.RoleResponse, error)
+	RestoreRole(ctx context.Context, input *dto.RestoreRoleRequest) (*dto.RoleResponse, error)
 	Get(ctx context.Context, input *dto.GetRoleRequest) (*dto.RoleResponse, error)
 	GetList(ctx context.Context, input *dto.GetListRoleRequest) (*dto.RoleCollectionResponse, error)
+
+	GrantPermissions(ctx context.Context, input *dto.GrantRolePermissionsRequest) (*dto.RoleResponse, error)
+	RevokePermission(ctx context.Context, input *dto.RevokeRolePermissionRequest) (*dto.RoleResponse, error)
+	AssignUsers(ctx context.Context, input *dto.AssignRoleUsersRequest) (*dto.RoleAssignmentResponse, error)
+	UnassignUsers(ctx context.Context, input *dto.AssignRoleUsersRequest) (*dto.RoleAssignmentResponse, error)
+
+	// PurgeTrashed deletes for good the roles trashed before the given time,
+	// in every tenant, and logs each of them
+	PurgeTrashed(ctx context.Context, before time.Time) (int, error)
 }
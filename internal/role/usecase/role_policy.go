@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/policy"
+)
+
+// rolePolicy is evaluated against the loaded role, so users can read the role
+// they hold without holding roles.read. Tenant ownership and system roles are
+// still checked by the usecase.
+var rolePolicy = policy.New[*entity.Role]("role").
+	Allow("read", policy.HasPermission[*entity.Role]("roles.read"), isOwnRole).
+	Allow("update", policy.HasPermission[*entity.Role]("roles.update")).
+	Allow("delete", policy.HasPermission[*entity.Role]("roles.delete")).
+	Allow("restore", policy.HasPermission[*entity.Role]("roles.restore")).
+	Allow("grant", policy.HasPermission[*entity.Role]("roles.permissions.grant")).
+	Allow("revoke", policy.HasPermission[*entity.Role]("roles.permissions.revoke")).
+	Allow("assign", policy.HasPermission[*entity.Role]("roles.assign"))
+
+var isOwnRole = policy.Attribute(func(actor *dto.UserResponseWithID, e *entity.Role) bool {
+	return actor.Role != nil && actor.Role.Uuid == e.Uuid
+})
@@ -4,16 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"math"
 	"sync"
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/dto"
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
 	"github.com/Adhiana46/echo-boilerplate/internal/permission"
 	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
 	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/google/uuid"
 )
@@ -23,16 +26,25 @@ var (
 	roleUcInstanceOnce sync.Once
 )
 
+// roleIncludes are the relations role responses can embed
+var roleIncludes = []string{"permissions", "parents", "created_by", "updated_by"}
+
 type roleUsecase struct {
-	roleRepo role.RoleRepository
-	permRepo permission.PermissionRepository
+	roleRepo  role.RoleRepository
+	permRepo  permission.PermissionRepository
+	userRepo  user.UserRepository
+	auditRepo audit.AuditLogRepository
+	txManager txmanager.TxManager
 }
 
-func NewRoleUsecase(roleRepo role.RoleRepository, permRepo permission.PermissionRepository) role.RoleUsecase {
+func NewRoleUsecase(roleRepo role.RoleRepository, permRepo permission.PermissionRepository, userRepo user.UserRepository, auditRepo audit.AuditLogRepository, txManager txmanager.TxManager) role.RoleUsecase {
 	roleUcInstanceOnce.Do(func() {
 		roleUcInstance = &roleUsecase{
-			roleRepo: roleRepo,
-			permRepo: permRepo,
+			roleRepo:  roleRepo,
+			permRepo:  permRepo,
+			userRepo:  userRepo,
+			auditRepo: auditRepo,
+			txManager: txManager,
 		}
 	})
 
@@ -40,164 +52,792 @@ func NewRoleUsecase(roleRepo role.RoleRepository, permRepo permission.Permission
 }
 
 func (uc *roleUsecase) CreateRole(ctx context.Context, input *dto.CreateRoleRequest) (*dto.RoleResponse, error) {
-	// Validation
-	numrows, err := uc.roleRepo.CountByName(ctx, input.Name)
+	var res *dto.RoleResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Validation
+		numrows, err := uc.roleRepo.CountByName(ctx, input.Name)
+		if err != nil {
+			return err
+		}
+
+		if numrows > 0 {
+			return errors.NewBadRequestError(fmt.Sprintf("Role with name '%s' already exists", input.Name))
+		}
+
+		permissions, err := uc.permRepo.FindAllByNames(ctx, input.Permissions)
+		if err != nil {
+			return err
+		}
+
+		parents, err := uc.findParents(ctx, input.Parents)
+		if err != nil {
+			return err
+		}
+
+		err = uc.ensureGrantable(ctx, &entity.Role{}, permissions, parents)
+		if err != nil {
+			return err
+		}
+
+		createdBy := sql.NullInt64{}
+		updatedBy := sql.NullInt64{}
+		user := utils.GetUserFromContext(ctx)
+		if user != nil {
+			createdBy = sql.NullInt64{
+				Int64: int64(user.ID),
+				Valid: true,
+			}
+
+			updatedBy = sql.NullInt64{
+				Int64: int64(user.ID),
+				Valid: true,
+			}
+		}
+
+		row, err := uc.roleRepo.Create(ctx, &entity.Role{
+			Uuid: uuid.NewString(),
+			Name: input.Name,
+			CreatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+			CreatedBy: createdBy,
+			UpdatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+			UpdatedBy:   updatedBy,
+			Permissions: permissions,
+			Parents:     parents,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewRoleResponse(row)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_CREATE, row.Uuid, nil, row)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if numrows > 0 {
-		return nil, errors.NewBadRequestError(fmt.Sprintf("Role with name '%s' already exists", input.Name))
-	}
+	return res, nil
+}
+
+func (uc *roleUsecase) UpdateRole(ctx context.Context, input *dto.UpdateRoleRequest) (*dto.RoleResponse, error) {
+	var res *dto.RoleResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = rolePolicy.Authorize(ctx, "update", e)
+		if err != nil {
+			return err
+		}
+
+		err = uc.checkOwned(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		err = utils.CheckVersion(e.Version, input.Version, input.IfMatch)
+		if err != nil {
+			return err
+		}
+
+		// validation logic
+		if e.Name != input.Name {
+			numrows, err := uc.roleRepo.CountByName(ctx, input.Name)
+			if err != nil {
+				return err
+			}
+
+			if numrows > 0 {
+				return errors.NewBadRequestError(fmt.Sprintf("Role with name '%s' already exists", input.Name))
+			}
+		}
 
-	permissions, err := uc.permRepo.FindAllByNames(ctx, input.Permissions)
+		permissions, err := uc.permRepo.FindAllByNames(ctx, input.Permissions)
+		if err != nil {
+			return err
+		}
+
+		parents, err := uc.findParents(ctx, input.Parents)
+		if err != nil {
+			return err
+		}
+
+		err = uc.checkCycle(ctx, e, parents)
+		if err != nil {
+			return err
+		}
+
+		err = uc.ensureGrantable(ctx, e, permissions, parents)
+		if err != nil {
+			return err
+		}
+
+		if e.IsSystem {
+			err = uc.checkSystemUpdate(e, input.Name, permissions, parents)
+			if err != nil {
+				return err
+			}
+		}
+
+		if e.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			keeps, err := uc.grantsRoleManagement(ctx, permissions, parents)
+			if err != nil {
+				return err
+			}
+
+			if !keeps {
+				err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{RoleIds: []int{e.Id}})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		updatedBy := sql.NullInt64{}
+		user := utils.GetUserFromContext(ctx)
+		if user != nil {
+			updatedBy = sql.NullInt64{
+				Int64: int64(user.ID),
+				Valid: true,
+			}
+		}
+
+		before := *e
+
+		e.Name = input.Name
+		e.Permissions = permissions
+		e.Parents = parents
+		e.UpdatedAt = sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		}
+		e.UpdatedBy = updatedBy
+
+		// Update
+		updatedE, err := uc.roleRepo.Update(ctx, e)
+		if err == errors.ErrVersionConflict {
+			return errors.NewVersionConflictError(e.Version, input.IfMatch)
+		}
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewRoleResponse(updatedE)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_UPDATE, e.Uuid, &before, updatedE)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	createdBy := sql.NullInt64{}
-	updatedBy := sql.NullInt64{}
-	user := utils.GetUserFromContext(ctx)
-	if user != nil {
-		createdBy = sql.NullInt64{
-			Int64: int64(user.ID),
-			Valid: true,
+	return res, nil
+}
+
+func (uc *roleUsecase) DeleteRole(ctx context.Context, input *dto.DeleteRoleRequest) (*dto.RoleResponse, error) {
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
 		}
 
-		updatedBy = sql.NullInt64{
-			Int64: int64(user.ID),
-			Valid: true,
+		err = rolePolicy.Authorize(ctx, "delete", e)
+		if err != nil {
+			return err
+		}
+
+		if e.IsSystem {
+			return errors.NewForbiddenError(fmt.Sprintf("System role '%s' cannot be deleted", e.Name))
+		}
+
+		err = uc.checkOwned(ctx, e)
+		if err != nil {
+			return err
 		}
-	}
 
-	row, err := uc.roleRepo.Create(ctx, &entity.Role{
-		Uuid: uuid.NewString(),
-		Name: input.Name,
-		CreatedAt: sql.NullTime{
+		if e.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{RoleIds: []int{e.Id}})
+			if err != nil {
+				return err
+			}
+		}
+
+		before := *e
+
+		e.DeletedAt = sql.NullTime{
 			Time:  time.Now(),
 			Valid: true,
-		},
-		CreatedBy: createdBy,
-		UpdatedAt: sql.NullTime{
+		}
+		e.DeletedBy = uc.actorId(ctx)
+
+		err = uc.roleRepo.Destroy(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return uc.record(ctx, constants.AUDIT_ACTION_DELETE, e.Uuid, &before, nil)
+	})
+
+	return nil, err
+}
+
+func (uc *roleUsecase) RestoreRole(ctx context.Context, input *dto.RestoreRoleRequest) (*dto.RoleResponse, error) {
+	var res *dto.RoleResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.roleRepo.FindTrashedByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = rolePolicy.Authorize(ctx, "restore", e)
+		if err != nil {
+			return err
+		}
+
+		err = uc.checkOwned(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		// the name may have been taken while the role was in the trash
+		numrows, err := uc.roleRepo.CountByName(ctx, e.Name)
+		if err != nil {
+			return err
+		}
+
+		if numrows > 0 {
+			return errors.NewBadRequestError(fmt.Sprintf("Role with name '%s' already exists", e.Name))
+		}
+
+		before := *e
+
+		e.UpdatedAt = sql.NullTime{
 			Time:  time.Now(),
 			Valid: true,
-		},
-		UpdatedBy:   updatedBy,
-		Permissions: permissions,
-	})
+		}
+		e.UpdatedBy = uc.actorId(ctx)
 
+		restoredE, err := uc.roleRepo.Restore(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewRoleResponse(restoredE)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_RESTORE, e.Uuid, &before, restoredE)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewRoleResponse(row), nil
+	return res, nil
 }
 
-func (uc *roleUsecase) UpdateRole(ctx context.Context, input *dto.UpdateRoleRequest) (*dto.RoleResponse, error) {
+// PurgeTrashed logs every purged role along with the purge, in the tenant of
+// the role
+func (uc *roleUsecase) PurgeTrashed(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := uc.roleRepo.Purge(ctx, before)
+		if err != nil {
+			return err
+		}
+		purged = len(rows)
+
+		for _, row := range rows {
+			tenantCtx := utils.WithTenant(ctx, &dto.TenantResponseWithID{ID: row.TenantId})
+
+			err = uc.record(tenantCtx, constants.AUDIT_ACTION_PURGE, row.Uuid, row, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return purged, err
+}
+
+func (uc *roleUsecase) Get(ctx context.Context, input *dto.GetRoleRequest) (*dto.RoleResponse, error) {
+	includes, err := queryspec.ParseIncludes(input.Include, roleIncludes...)
+	if err != nil {
+		return nil, err
+	}
+
 	e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
 	if err != nil {
 		return nil, err
 	}
 
-	// validation logic
-	if e.Name != input.Name {
-		numrows, err := uc.roleRepo.CountByName(ctx, input.Name)
+	err = rolePolicy.Authorize(ctx, "read", e)
+	if err != nil {
+		return nil, err
+	}
+
+	// a single role always comes with its permissions and parents
+	err = user.ExpandActors(ctx, uc.userRepo, includes, []*entity.Role{e})
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewRoleResponse(e), nil
+}
+
+func (uc *roleUsecase) GetList(ctx context.Context, input *dto.GetListRoleRequest) (*dto.RoleCollectionResponse, error) {
+	includes, err := queryspec.ParseIncludes(input.Include, roleIncludes...)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := queryspec.New(input.Page, input.Limit, input.SortBy, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+	spec.Filters = input.Filters
+	spec.SkipCount = input.SkipCount
+	spec.WithTrashed = input.WithTrashed
+	spec.OnlyTrashed = input.OnlyTrashed
+
+	if input.Paginate == queryspec.PaginateCursor || input.Cursor != "" {
+		err = spec.UseCursor(input.Cursor)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		if numrows > 0 {
-			return nil, errors.NewBadRequestError(fmt.Sprintf("Role with name '%s' already exists", input.Name))
+	rows, err := uc.roleRepo.FindAll(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if includes.Has("permissions") || includes.Has("parents") {
+		err = uc.roleRepo.LoadRelations(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			if !includes.Has("permissions") {
+				row.Permissions = nil
+				row.InheritedPermissions = nil
+			}
+			if !includes.Has("parents") {
+				row.Parents = nil
+			}
 		}
 	}
 
-	permissions, err := uc.permRepo.FindAllByNames(ctx, input.Permissions)
+	err = user.ExpandActors(ctx, uc.userRepo, includes, rows)
 	if err != nil {
 		return nil, err
 	}
 
-	updatedBy := sql.NullInt64{}
-	user := utils.GetUserFromContext(ctx)
-	if user != nil {
-		updatedBy = sql.NullInt64{
-			Int64: int64(user.ID),
+	var total *int
+	if !spec.SkipCount {
+		numrows, err := uc.roleRepo.CountAll(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		total = &numrows
+	}
+
+	return dto.NewRoleCollectionResponse(rows, dto.NewPaginationResponse(spec, len(rows), total)), nil
+}
+
+func (uc *roleUsecase) GrantPermissions(ctx context.Context, input *dto.GrantRolePermissionsRequest) (*dto.RoleResponse, error) {
+	var res *dto.RoleResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = rolePolicy.Authorize(ctx, "grant", e)
+		if err != nil {
+			return err
+		}
+
+		err = uc.checkOwned(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		permissions, err := uc.permRepo.FindAllByNames(ctx, input.Permissions)
+		if err != nil {
+			return err
+		}
+
+		found := map[string]bool{}
+		for _, perm := range permissions {
+			found[perm.Name] = true
+		}
+
+		for _, name := range input.Permissions {
+			if !found[name] {
+				return errors.NewBadRequestError(fmt.Sprintf("Permission '%s' is not exists", name))
+			}
+		}
+
+		err = role.EnsureGrantable(ctx, input.Permissions)
+		if err != nil {
+			return err
+		}
+
+		before := *e
+
+		e.UpdatedAt = sql.NullTime{
+			Time:  time.Now(),
 			Valid: true,
 		}
+		e.UpdatedBy = uc.actorId(ctx)
+
+		updatedE, err := uc.roleRepo.GrantPermissions(ctx, e, permissions)
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewRoleResponse(updatedE)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_PERMISSIONS_GRANT, e.Uuid, &before, updatedE)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	e.Name = input.Name
-	e.Permissions = permissions
-	e.UpdatedAt = sql.NullTime{
-		Time:  time.Now(),
-		Valid: true,
+	return res, nil
+}
+
+func (uc *roleUsecase) RevokePermission(ctx context.Context, input *dto.RevokeRolePermissionRequest) (*dto.RoleResponse, error) {
+	var res *dto.RoleResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = rolePolicy.Authorize(ctx, "revoke", e)
+		if err != nil {
+			return err
+		}
+
+		if e.IsSystem {
+			return errors.NewForbiddenError(fmt.Sprintf("Permissions of system role '%s' cannot be revoked", e.Name))
+		}
+
+		err = uc.checkOwned(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		permission, err := uc.permRepo.FindByName(ctx, input.Permission)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewBadRequestError(fmt.Sprintf("Permission '%s' is not exists", input.Permission))
+			}
+			return err
+		}
+
+		if permission.Name == constants.ROLE_MANAGEMENT_PERMISSION && !hasPermission(e.InheritedPermissions, permission.Name) {
+			err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{RoleIds: []int{e.Id}})
+			if err != nil {
+				return err
+			}
+		}
+
+		before := *e
+
+		e.UpdatedAt = sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		}
+		e.UpdatedBy = uc.actorId(ctx)
+
+		updatedE, err := uc.roleRepo.RevokePermission(ctx, e, permission)
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewRoleResponse(updatedE)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_PERMISSION_REVOKE, e.Uuid, &before, updatedE)
+	})
+	if err != nil {
+		return nil, err
 	}
-	e.UpdatedBy = updatedBy
 
-	// Update
-	updatedE, err := uc.roleRepo.Update(ctx, e)
+	return res, nil
+}
+
+func (uc *roleUsecase) AssignUsers(ctx context.Context, input *dto.AssignRoleUsersRequest) (*dto.RoleAssignmentResponse, error) {
+	var res *dto.RoleAssignmentResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = rolePolicy.Authorize(ctx, "assign", e)
+		if err != nil {
+			return err
+		}
+
+		err = role.EnsureAssignable(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		// assigned users lose their previous role
+		if !e.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{RoleUserUuids: input.Users})
+			if err != nil {
+				return err
+			}
+		}
+
+		affected, err := uc.userRepo.AssignRole(ctx, e.Id, input.Users, uc.actorId(ctx))
+		if err != nil {
+			return err
+		}
+
+		res = &dto.RoleAssignmentResponse{
+			Role:     e.Name,
+			Affected: affected,
+		}
+
+		if affected == 0 {
+			return nil
+		}
+
+		return uc.record(ctx, constants.AUDIT_ACTION_USERS_ASSIGN, e.Uuid, nil, map[string]any{"users": input.Users})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewRoleResponse(updatedE), nil
+	return res, nil
 }
 
-func (uc *roleUsecase) DeleteRole(ctx context.Context, input *dto.DeleteRoleRequest) (*dto.RoleResponse, error) {
-	e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+func (uc *roleUsecase) UnassignUsers(ctx context.Context, input *dto.AssignRoleUsersRequest) (*dto.RoleAssignmentResponse, error) {
+	var res *dto.RoleAssignmentResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = rolePolicy.Authorize(ctx, "assign", e)
+		if err != nil {
+			return err
+		}
+
+		if e.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{RoleUserUuids: input.Users})
+			if err != nil {
+				return err
+			}
+		}
+
+		affected, err := uc.userRepo.UnassignRole(ctx, e.Id, input.Users, uc.actorId(ctx))
+		if err != nil {
+			return err
+		}
+
+		res = &dto.RoleAssignmentResponse{
+			Role:     e.Name,
+			Affected: affected,
+		}
+
+		if affected == 0 {
+			return nil
+		}
+
+		return uc.record(ctx, constants.AUDIT_ACTION_USERS_UNASSIGN, e.Uuid, nil, map[string]any{"users": input.Users})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	err = uc.roleRepo.Destroy(ctx, e)
+	return res, nil
+}
 
-	return nil, err
+// record logs a change of the role, before is nil for a created one and after
+// for a deleted one
+func (uc *roleUsecase) record(ctx context.Context, action string, resourceUuid string, before any, after any) error {
+	return audit.Record(ctx, uc.auditRepo, audit.Change{
+		Action:       action,
+		ResourceType: constants.AUDIT_RESOURCE_ROLE,
+		ResourceUuid: resourceUuid,
+		Before:       before,
+		After:        after,
+	})
 }
 
-func (uc *roleUsecase) Get(ctx context.Context, input *dto.GetRoleRequest) (*dto.RoleResponse, error) {
-	e, err := uc.roleRepo.FindByUuid(ctx, input.Uuid)
+// actorId returns the id of the authenticated user for audit columns
+func (uc *roleUsecase) actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
+
+// findParents resolves parent role names, every name must exist
+func (uc *roleUsecase) findParents(ctx context.Context, names []string) ([]*entity.Role, error) {
+	if len(names) == 0 {
+		return []*entity.Role{}, nil
+	}
+
+	parents, err := uc.roleRepo.FindAllByNames(ctx, names)
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewRoleResponse(e), nil
+	found := map[string]bool{}
+	for _, parent := range parents {
+		found[parent.Name] = true
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", name))
+		}
+	}
+
+	return parents, nil
 }
 
-func (uc *roleUsecase) GetList(ctx context.Context, input *dto.GetListRoleRequest) (*dto.RoleCollectionResponse, error) {
-	var err error
-	offset := 0                                 // default
-	limit := constants.DEFAULT_PAGINATION_LIMIT // default
-	sorts := map[string]string{}
-	filter := input.Filter
+// checkCycle rejects parents that are the role itself or already extend it
+func (uc *roleUsecase) checkCycle(ctx context.Context, e *entity.Role, parents []*entity.Role) error {
+	for _, parent := range parents {
+		if parent.Id == e.Id {
+			return errors.NewBadRequestError(fmt.Sprintf("Role '%s' cannot extend itself", e.Name))
+		}
 
-	if input.Limit > 0 {
-		limit = input.Limit
+		ancestorIds, err := uc.roleRepo.FindAncestorIds(ctx, parent.Id)
+		if err != nil {
+			return err
+		}
+
+		for _, ancestorId := range ancestorIds {
+			if ancestorId == e.Id {
+				return errors.NewBadRequestError(fmt.Sprintf("Role '%s' cannot extend '%s', it would create a cycle", e.Name, parent.Name))
+			}
+		}
 	}
 
-	if input.Page > 0 {
-		offset = (input.Page - 1) * limit
+	return nil
+}
+
+// ensureGrantable checks the permissions and parents being added to the role,
+// the ones it already has are left alone so unrelated edits keep working
+func (uc *roleUsecase) ensureGrantable(ctx context.Context, e *entity.Role, permissions []*entity.Permission, parents []*entity.Role) error {
+	names := []string{}
+	for _, perm := range permissions {
+		if !hasPermission(e.Permissions, perm.Name) {
+			names = append(names, perm.Name)
+		}
 	}
 
-	if input.SortBy != "" {
-		sorts, err = utils.QuerySortToMap(input.SortBy)
+	current := map[int]bool{}
+	for _, parent := range e.Parents {
+		current[parent.Id] = true
+	}
+
+	for _, parent := range parents {
+		if current[parent.Id] {
+			continue
+		}
+
+		// resolve the parent's inherited permissions too
+		resolved, err := uc.roleRepo.FindByUuid(ctx, parent.Uuid)
 		if err != nil {
-			return nil, err
+			return err
 		}
+
+		names = append(names, resolved.PermissionNames()...)
 	}
 
-	rows, err := uc.roleRepo.FindAll(ctx, offset, limit, sorts, filter)
-	if err != nil {
-		return nil, err
+	return role.EnsureGrantable(ctx, names)
+}
+
+// checkOwned refuses changing a role shared by every tenant from inside a
+// tenant, it would change the role for the other tenants too
+func (uc *roleUsecase) checkOwned(ctx context.Context, e *entity.Role) error {
+	if e.TenantId != utils.GetTenantIdFromContext(ctx) {
+		return errors.NewForbiddenError(fmt.Sprintf("Role '%s' is shared by every tenant and cannot be changed", e.Name))
 	}
 
-	numrows, err := uc.roleRepo.CountAll(ctx, filter)
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// checkSystemUpdate allows system roles to gain permissions and parents but
+// not to be renamed or to lose any of them
+func (uc *roleUsecase) checkSystemUpdate(e *entity.Role, name string, permissions []*entity.Permission, parents []*entity.Role) error {
+	if e.Name != name {
+		return errors.NewForbiddenError(fmt.Sprintf("System role '%s' cannot be renamed", e.Name))
+	}
+
+	for _, perm := range e.Permissions {
+		if !hasPermission(permissions, perm.Name) {
+			return errors.NewForbiddenError(fmt.Sprintf("Permission '%s' cannot be removed from system role '%s'", perm.Name, e.Name))
+		}
+	}
+
+	kept := map[int]bool{}
+	for _, parent := range parents {
+		kept[parent.Id] = true
+	}
+
+	for _, parent := range e.Parents {
+		if !kept[parent.Id] {
+			return errors.NewForbiddenError(fmt.Sprintf("Parent '%s' cannot be removed from system role '%s'", parent.Name, e.Name))
+		}
+	}
+
+	return nil
+}
+
+// grantsRoleManagement reports whether a role with the given direct
+// permissions and parents would grant the role management permission
+func (uc *roleUsecase) grantsRoleManagement(ctx context.Context, permissions []*entity.Permission, parents []*entity.Role) (bool, error) {
+	if hasPermission(permissions, constants.ROLE_MANAGEMENT_PERMISSION) {
+		return true, nil
+	}
+
+	for _, parent := range parents {
+		// resolve the parent's inherited permissions too
+		resolved, err := uc.roleRepo.FindByUuid(ctx, parent.Uuid)
+		if err != nil {
+			return false, err
+		}
+
+		if resolved.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func hasPermission(permissions []*entity.Permission, name string) bool {
+	for _, perm := range permissions {
+		if perm.Name == name {
+			return true
+		}
 	}
 
-	return dto.NewRoleCollectionResponse(rows, dto.PaginationResponse{
-		Size:        len(rows),
-		Total:       numrows,
-		TotalPages:  int(math.Ceil(float64(numrows) / float64(limit))),
-		CurrentPage: input.Page,
-	}), nil
+	return false
 }
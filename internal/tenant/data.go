@@ -0,0 +1,24 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+type TenantPersistent interface {
+	Create(ctx context.Context, e *entity.Tenant) (*entity.Tenant, error)
+	Update(ctx context.Context, e *entity.Tenant) (*entity.Tenant, error)
+	Destroy(ctx context.Context, e *entity.Tenant) error
+	FindById(ctx context.Context, id int) (*entity.Tenant, error)
+	FindByUuid(ctx context.Context, uuid string) (*entity.Tenant, error)
+	FindBySlug(ctx context.Context, slug string) (*entity.Tenant, error)
+	FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Tenant, error)
+	FindAllByUserId(ctx context.Context, userId int) ([]*entity.Tenant, error)
+	AddUsers(ctx context.Context, tenantId int, roleId int, uuids []string) (int, error)
+	RemoveUsers(ctx context.Context, tenantId int, uuids []string) (int, error)
+
+	CountBySlug(ctx context.Context, slug string) (int, error)
+	CountAll(ctx context.Context, spec *queryspec.Spec) (int, error)
+}
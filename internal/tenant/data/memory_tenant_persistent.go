@@ -0,0 +1,238 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+var (
+	memoryPersistInstance     *memoryTenantPersistent
+	memoryPersistInstanceOnce sync.Once
+)
+
+// memoryTenantPersistent is the registry of tenants kept in a memdb.DB
+type memoryTenantPersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryTenantPersistent(db *memdb.DB) tenant.TenantPersistent {
+	memoryPersistInstanceOnce.Do(func() {
+		memoryPersistInstance = &memoryTenantPersistent{
+			db: db,
+		}
+	})
+
+	return memoryPersistInstance
+}
+
+func (r *memoryTenantPersistent) Create(ctx context.Context, e *entity.Tenant) (*entity.Tenant, error) {
+	insertedId := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		insertedId, err = t.InsertTenant(e)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, insertedId)
+}
+
+func (r *memoryTenantPersistent) Update(ctx context.Context, e *entity.Tenant) (*entity.Tenant, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Tenants, e.Id)
+		if row == nil {
+			return nil
+		}
+
+		row.Slug = e.Slug
+		row.Name = e.Name
+		row.UpdatedAt = e.UpdatedAt
+		row.UpdatedBy = e.UpdatedBy
+
+		return t.SaveTenant(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+func (r *memoryTenantPersistent) Destroy(ctx context.Context, e *entity.Tenant) error {
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.Tenants[e.Id]; !ok {
+			return sql.ErrNoRows
+		}
+
+		return t.DeleteTenant(e.Id)
+	})
+}
+
+func (r *memoryTenantPersistent) FindById(ctx context.Context, id int) (*entity.Tenant, error) {
+	return r.find(ctx, func(row *entity.Tenant) bool {
+		return row.Id == id
+	})
+}
+
+func (r *memoryTenantPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Tenant, error) {
+	return r.find(ctx, func(row *entity.Tenant) bool {
+		return row.Uuid == uuid
+	})
+}
+
+func (r *memoryTenantPersistent) FindBySlug(ctx context.Context, slug string) (*entity.Tenant, error) {
+	return r.find(ctx, func(row *entity.Tenant) bool {
+		return row.Slug == slug
+	})
+}
+
+func (r *memoryTenantPersistent) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Tenant, error) {
+	rows := []*entity.Tenant{}
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		rows, err = queryspec.SelectRows(tenantResource, memdb.Rows(t.Tenants), spec, queryspec.ColumnOf[*entity.Tenant])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// FindAllByUserId returns the tenants the user belongs to, oldest first
+func (r *memoryTenantPersistent) FindAllByUserId(ctx context.Context, userId int) ([]*entity.Tenant, error) {
+	rows := []*entity.Tenant{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Tenants) {
+			m, ok := t.Members[memdb.MemberKey{TenantId: row.Id, UserId: userId}]
+			if ok && !m.DeletedAt.Valid {
+				rows = append(rows, row)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// AddUsers adds the users in uuids to the tenant with the given role, members
+// already in the tenant get the role and leave its trash. Trashed accounts are
+// skipped. It returns the number of affected users.
+func (r *memoryTenantPersistent) AddUsers(ctx context.Context, tenantId int, roleId int, uuids []string) (int, error) {
+	wanted := map[string]bool{}
+	for _, uuid := range uuids {
+		wanted[uuid] = true
+	}
+
+	affected := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Users) {
+			if !wanted[row.Uuid] || row.DeletedAt.Valid {
+				continue
+			}
+
+			err := t.PutMember(memdb.Member{TenantId: tenantId, UserId: row.Id, RoleId: roleId})
+			if err != nil {
+				return err
+			}
+			affected++
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+// RemoveUsers removes the users in uuids from the tenant, its groups and its
+// role grants, their account and their membership in other tenants are kept
+func (r *memoryTenantPersistent) RemoveUsers(ctx context.Context, tenantId int, uuids []string) (int, error) {
+	wanted := map[string]bool{}
+	for _, uuid := range uuids {
+		wanted[uuid] = true
+	}
+
+	affected := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Users {
+			key := memdb.MemberKey{TenantId: tenantId, UserId: row.Id}
+			if _, ok := t.Members[key]; !wanted[row.Uuid] || !ok {
+				continue
+			}
+
+			delete(t.Members, key)
+			affected++
+
+			for gu := range t.GroupUsers {
+				if g, ok := t.Groups[gu.GroupId]; ok && g.TenantId == tenantId && gu.UserId == row.Id {
+					delete(t.GroupUsers, gu)
+				}
+			}
+
+			for id, grant := range t.RoleGrants {
+				if grant.TenantId == tenantId && grant.UserId == row.Id {
+					delete(t.RoleGrants, id)
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+func (r *memoryTenantPersistent) CountBySlug(ctx context.Context, slug string) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Tenants {
+			if row.Slug == slug {
+				numrows++
+			}
+		}
+
+		return nil
+	})
+
+	return numrows, err
+}
+
+func (r *memoryTenantPersistent) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		numrows, err = queryspec.CountRows(tenantResource, memdb.Rows(t.Tenants), spec, queryspec.ColumnOf[*entity.Tenant])
+		return err
+	})
+
+	return numrows, err
+}
+
+func (r *memoryTenantPersistent) find(ctx context.Context, match func(row *entity.Tenant) bool) (*entity.Tenant, error) {
+	var found *entity.Tenant
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Tenants) {
+			if match(row) {
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
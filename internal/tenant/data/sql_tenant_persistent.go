@@ -0,0 +1,204 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	sqlPersistInstance     *sqlTenantPersistent
+	sqlPersistInstanceOnce sync.Once
+)
+
+// tenantResource whitelists the fields of the tenant list
+var tenantResource = &queryspec.Resource{
+	Sortable: map[string]string{
+		"slug":       "slug",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Filterable: map[string]queryspec.Field{},
+	Searchable: []string{"slug", "name"},
+	Key:        "id",
+}
+
+// tenantTable maps the tenants for the CRUD queries
+var tenantTable = crud.Table{
+	Name:       "tenants",
+	Columns:    []string{"id", "uuid", "slug", "name", "created_at", "created_by", "updated_at", "updated_by"},
+	Insertable: []string{"uuid", "slug", "name"},
+	Updatable:  []string{"slug", "name"},
+	Audited:    true,
+	Resource:   tenantResource,
+}
+
+// sqlTenantPersistent is the registry of tenants, it is the only persistent
+// that is not filtered by the active tenant
+type sqlTenantPersistent struct {
+	*crud.Repository[entity.Tenant]
+}
+
+func NewSqlTenantPersistent(db *txmanager.DB) tenant.TenantPersistent {
+	sqlPersistInstanceOnce.Do(func() {
+		sqlPersistInstance = &sqlTenantPersistent{
+			Repository: crud.New[entity.Tenant](db, tenantTable),
+		}
+	})
+
+	return sqlPersistInstance
+}
+
+func (r *sqlTenantPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.Tenant, error) {
+	return r.FindBy(ctx, "uuid", uuid)
+}
+
+func (r *sqlTenantPersistent) FindBySlug(ctx context.Context, slug string) (*entity.Tenant, error) {
+	return r.FindBy(ctx, "slug", slug)
+}
+
+func (r *sqlTenantPersistent) CountBySlug(ctx context.Context, slug string) (int, error) {
+	return r.CountBy(ctx, "slug", slug)
+}
+
+// FindAllByUserId returns the tenants the user belongs to, oldest first
+func (r *sqlTenantPersistent) FindAllByUserId(ctx context.Context, userId int) ([]*entity.Tenant, error) {
+	sql := `
+		SELECT t.id, t.uuid, t.slug, t.name, t.created_at, t.created_by, t.updated_at, t.updated_by
+		FROM tenants t
+		INNER JOIN tenant_users tu ON tu.tenant_id = t.id
+		WHERE tu.user_id = ? AND tu.deleted_at IS NULL
+		ORDER BY t.id
+	`
+
+	rows := []*entity.Tenant{}
+	err := r.DB().SelectContext(ctx, &rows, sql, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// AddUsers adds the users in uuids to the tenant with the given role, members
+// already in the tenant get the role and leave its trash. Trashed accounts are
+// skipped. It returns the number of affected users.
+func (r *sqlTenantPersistent) AddUsers(ctx context.Context, tenantId int, roleId int, uuids []string) (int, error) {
+	if len(uuids) == 0 {
+		return 0, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT u.id, tu.user_id IS NOT NULL AS member
+		FROM users u
+		LEFT JOIN tenant_users tu ON tu.user_id = u.id AND tu.tenant_id = ?
+		WHERE u.uuid IN (?) AND u.deleted_at IS NULL
+	`, tenantId, uuids)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.DB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	users := []struct {
+		Id     int  `db:"id"`
+		Member bool `db:"member"`
+	}{}
+	err = tx.SelectContext(ctx, &users, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// the dialects disagree on upserts, the members are updated and the others
+	// inserted
+	for _, u := range users {
+		query := `INSERT INTO tenant_users (tenant_id, user_id, role_id) VALUES (?, ?, NULLIF(?, 0))`
+		if u.Member {
+			query = `
+				UPDATE tenant_users
+					SET role_id = NULLIF(?, 0),
+						deleted_at = NULL,
+						deleted_by = NULL
+				WHERE tenant_id = ? AND user_id = ?
+			`
+			_, err = tx.ExecContext(ctx, query, roleId, tenantId, u.Id)
+		} else {
+			_, err = tx.ExecContext(ctx, query, tenantId, u.Id, roleId)
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return len(users), tx.Commit()
+}
+
+// RemoveUsers removes the users in uuids from the tenant, its groups and its
+// role grants, their account and their membership in other tenants are kept
+func (r *sqlTenantPersistent) RemoveUsers(ctx context.Context, tenantId int, uuids []string) (int, error) {
+	if len(uuids) == 0 {
+		return 0, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT tu.user_id
+		FROM tenant_users tu
+		INNER JOIN users u ON u.id = tu.user_id
+		WHERE tu.tenant_id = ? AND u.uuid IN (?)
+	`, tenantId, uuids)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.DB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []int{}
+	err = tx.SelectContext(ctx, &ids, query, args...)
+	if err != nil || len(ids) == 0 {
+		tx.Rollback()
+		return 0, err
+	}
+
+	deletes := []squirrel.DeleteBuilder{
+		squirrel.Delete("group_users").
+			Where(squirrel.Eq{"user_id": ids}).
+			Where(squirrel.Expr(`group_id IN (SELECT id FROM "groups" WHERE tenant_id = ?)`, tenantId)),
+		squirrel.Delete("role_grants").
+			Where(squirrel.Eq{"tenant_id": tenantId, "user_id": ids}),
+		squirrel.Delete("tenant_users").
+			Where(squirrel.Eq{"tenant_id": tenantId, "user_id": ids}),
+	}
+
+	for _, d := range deletes {
+		query, args, err := d.ToSql()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return len(ids), tx.Commit()
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	handlerInstance     *handler
+	handlerInstanceOnce sync.Once
+)
+
+type Handler interface {
+	Store() func(echo.Context) error
+	Update() func(echo.Context) error
+	Delete() func(echo.Context) error
+	GetByUuid() func(echo.Context) error
+	GetAll() func(echo.Context) error
+	AddUsers() func(echo.Context) error
+	RemoveUsers() func(echo.Context) error
+}
+
+type handler struct {
+	uc tenant.TenantUsecase
+}
+
+func NewTenantHttpHandler(uc tenant.TenantUsecase) Handler {
+	handlerInstanceOnce.Do(func() {
+		handlerInstance = &handler{
+			uc: uc,
+		}
+	})
+
+	return handlerInstance
+}
+
+func (h *handler) Store() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.CreateTenantRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.CreateTenant(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, utils.JsonSuccess(http.StatusCreated, "", res, nil))
+	}
+}
+
+func (h *handler) Update() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.UpdateTenantRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.UpdateTenant(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) Delete() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.DeleteTenantRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.DeleteTenant(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) GetByUuid() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GetTenantRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.Get(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) GetAll() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.GetListTenantRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.GetList(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res.Data, res.Pagination))
+	}
+}
+
+func (h *handler) AddUsers() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.AddTenantUsersRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.AddUsers(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) RemoveUsers() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.RemoveTenantUsersRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		input.Uuid = strings.Trim(c.Param("uuid"), "/")
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RemoveUsers(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
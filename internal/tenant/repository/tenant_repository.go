@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
+	cachePkg "github.com/Adhiana46/echo-boilerplate/pkg/cache"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+)
+
+var (
+	tenantRepoInstance     *tenantRepository
+	tenantRepoInstanceOnce sync.Once
+)
+
+// tenantRepository caches nothing, but the memberships it writes are part of
+// the cached users
+type tenantRepository struct {
+	persistent tenant.TenantPersistent
+	cache      cachePkg.Cache
+}
+
+func NewTenantRepository(persistent tenant.TenantPersistent, cache cachePkg.Cache) tenant.TenantRepository {
+	tenantRepoInstanceOnce.Do(func() {
+		tenantRepoInstance = &tenantRepository{
+			persistent: persistent,
+			cache:      cache,
+		}
+	})
+
+	return tenantRepoInstance
+}
+
+func (r *tenantRepository) Create(ctx context.Context, e *entity.Tenant) (*entity.Tenant, error) {
+	return r.persistent.Create(ctx, e)
+}
+
+func (r *tenantRepository) Update(ctx context.Context, e *entity.Tenant) (*entity.Tenant, error) {
+	return r.persistent.Update(ctx, e)
+}
+
+func (r *tenantRepository) Destroy(ctx context.Context, e *entity.Tenant) error {
+	err := r.persistent.Destroy(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	r.invalidateUsers(ctx)
+
+	return nil
+}
+
+func (r *tenantRepository) FindById(ctx context.Context, id int) (*entity.Tenant, error) {
+	return r.persistent.FindById(ctx, id)
+}
+
+func (r *tenantRepository) FindByUuid(ctx context.Context, uuid string) (*entity.Tenant, error) {
+	return r.persistent.FindByUuid(ctx, uuid)
+}
+
+func (r *tenantRepository) FindBySlug(ctx context.Context, slug string) (*entity.Tenant, error) {
+	return r.persistent.FindBySlug(ctx, slug)
+}
+
+func (r *tenantRepository) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.Tenant, error) {
+	return r.persistent.FindAll(ctx, spec)
+}
+
+func (r *tenantRepository) FindAllByUserId(ctx context.Context, userId int) ([]*entity.Tenant, error) {
+	return r.persistent.FindAllByUserId(ctx, userId)
+}
+
+func (r *tenantRepository) AddUsers(ctx context.Context, tenantId int, roleId int, uuids []string) (int, error) {
+	added, err := r.persistent.AddUsers(ctx, tenantId, roleId, uuids)
+	if err != nil {
+		return 0, err
+	}
+
+	r.invalidateUsers(ctx)
+
+	return added, nil
+}
+
+func (r *tenantRepository) RemoveUsers(ctx context.Context, tenantId int, uuids []string) (int, error) {
+	removed, err := r.persistent.RemoveUsers(ctx, tenantId, uuids)
+	if err != nil {
+		return 0, err
+	}
+
+	r.invalidateUsers(ctx)
+
+	return removed, nil
+}
+
+func (r *tenantRepository) CountBySlug(ctx context.Context, slug string) (int, error) {
+	return r.persistent.CountBySlug(ctx, slug)
+}
+
+func (r *tenantRepository) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	return r.persistent.CountAll(ctx, spec)
+}
+
+// invalidateUsers drops the cached users once the membership change is
+// committed
+func (r *tenantRepository) invalidateUsers(ctx context.Context) {
+	txmanager.AfterCommit(ctx, func() {
+		cachePkg.Invalidate(r.cache, constants.CACHE_NAMESPACE_USERS)
+	})
+}
@@ -0,0 +1,18 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+)
+
+type TenantUsecase interface {
+	CreateTenant(ctx context.Context, input *dto.CreateTenantRequest) (*dto.TenantResponse, error)
+	UpdateTenant(ctx context.Context, input *dto.UpdateTenantRequest) (*dto.TenantResponse, error)
+	DeleteTenant(ctx context.Context, input *dto.DeleteTenantRequest) (*dto.TenantResponse, error)
+	Get(ctx context.Context, input *dto.GetTenantRequest) (*dto.TenantResponse, error)
+	GetList(ctx context.Context, input *dto.GetListTenantRequest) (*dto.TenantCollectionResponse, error)
+
+	AddUsers(ctx context.Context, input *dto.AddTenantUsersRequest) (*dto.TenantMembershipResponse, error)
+	RemoveUsers(ctx context.Context, input *dto.RemoveTenantUsersRequest) (*dto.TenantMembershipResponse, error)
+}
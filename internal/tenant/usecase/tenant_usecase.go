@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	tenantUcInstance     *tenantUsecase
+	tenantUcInstanceOnce sync.Once
+)
+
+type tenantUsecase struct {
+	tenantRepo tenant.TenantRepository
+	roleRepo   role.RoleRepository
+	userRepo   user.UserRepository
+}
+
+func NewTenantUsecase(tenantRepo tenant.TenantRepository, roleRepo role.RoleRepository, userRepo user.UserRepository) tenant.TenantUsecase {
+	tenantUcInstanceOnce.Do(func() {
+		tenantUcInstance = &tenantUsecase{
+			tenantRepo: tenantRepo,
+			roleRepo:   roleRepo,
+			userRepo:   userRepo,
+		}
+	})
+
+	return tenantUcInstance
+}
+
+func (uc *tenantUsecase) CreateTenant(ctx context.Context, input *dto.CreateTenantRequest) (*dto.TenantResponse, error) {
+	// validation logic
+	numrows, err := uc.tenantRepo.CountBySlug(ctx, input.Slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if numrows > 0 {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("Tenant with slug '%s' already exists", input.Slug))
+	}
+
+	e, err := uc.tenantRepo.Create(ctx, &entity.Tenant{
+		Uuid: uuid.NewString(),
+		Slug: input.Slug,
+		Name: input.Name,
+		CreatedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		CreatedBy: uc.actorId(ctx),
+		UpdatedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		UpdatedBy: uc.actorId(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewTenantResponse(e), nil
+}
+
+func (uc *tenantUsecase) UpdateTenant(ctx context.Context, input *dto.UpdateTenantRequest) (*dto.TenantResponse, error) {
+	e, err := uc.tenantRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	// validation logic
+	if e.Slug != input.Slug {
+		numrows, err := uc.tenantRepo.CountBySlug(ctx, input.Slug)
+		if err != nil {
+			return nil, err
+		}
+
+		if numrows > 0 {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Tenant with slug '%s' already exists", input.Slug))
+		}
+	}
+
+	e.Slug = input.Slug
+	e.Name = input.Name
+	e.UpdatedAt = sql.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	e.UpdatedBy = uc.actorId(ctx)
+
+	updatedE, err := uc.tenantRepo.Update(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewTenantResponse(updatedE), nil
+}
+
+func (uc *tenantUsecase) DeleteTenant(ctx context.Context, input *dto.DeleteTenantRequest) (*dto.TenantResponse, error) {
+	e, err := uc.tenantRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Id == utils.GetTenantIdFromContext(ctx) {
+		return nil, errors.NewBadRequestError("The active tenant cannot be deleted")
+	}
+
+	err = uc.tenantRepo.Destroy(ctx, e)
+
+	return nil, err
+}
+
+func (uc *tenantUsecase) Get(ctx context.Context, input *dto.GetTenantRequest) (*dto.TenantResponse, error) {
+	e, err := uc.tenantRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewTenantResponse(e), nil
+}
+
+func (uc *tenantUsecase) GetList(ctx context.Context, input *dto.GetListTenantRequest) (*dto.TenantCollectionResponse, error) {
+	spec, err := queryspec.New(input.Page, input.Limit, input.SortBy, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := uc.tenantRepo.FindAll(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	numrows, err := uc.tenantRepo.CountAll(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewTenantCollectionResponse(rows, dto.NewPaginationResponse(spec, len(rows), &numrows)), nil
+}
+
+// AddUsers adds users to the tenant with a role of that tenant (or a shared
+// one), members of the tenant get the role instead
+func (uc *tenantUsecase) AddUsers(ctx context.Context, input *dto.AddTenantUsersRequest) (*dto.TenantMembershipResponse, error) {
+	e, err := uc.tenantRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	// roles and guards are resolved inside the target tenant
+	tenantCtx := utils.WithTenant(ctx, dto.NewTenantResponseWithID(e))
+
+	r, err := uc.roleRepo.FindByName(tenantCtx, input.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", input.Role))
+		}
+		return nil, err
+	}
+
+	err = role.EnsureAssignable(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+		err = user.EnsureRoleManagerRemains(tenantCtx, uc.userRepo, user.HolderExclusions{RoleUserUuids: input.Users})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	affected, err := uc.tenantRepo.AddUsers(ctx, e.Id, r.Id, input.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TenantMembershipResponse{
+		Tenant:   e.Slug,
+		Affected: affected,
+	}, nil
+}
+
+func (uc *tenantUsecase) RemoveUsers(ctx context.Context, input *dto.RemoveTenantUsersRequest) (*dto.TenantMembershipResponse, error) {
+	e, err := uc.tenantRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = user.EnsureRoleManagerRemains(utils.WithTenant(ctx, dto.NewTenantResponseWithID(e)), uc.userRepo, user.HolderExclusions{UserUuids: input.Users})
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := uc.tenantRepo.RemoveUsers(ctx, e.Id, input.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TenantMembershipResponse{
+		Tenant:   e.Slug,
+		Affected: affected,
+	}, nil
+}
+
+// actorId returns the id of the authenticated user for audit columns
+func (uc *tenantUsecase) actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+)
+
+// ExpandActors loads the users behind created_by and updated_by when the
+// request includes them, with a single query for all the rows. An included
+// actor that no longer exists is set with its id alone.
+func ExpandActors[T entity.Audited](ctx context.Context, repo UserRepository, includes queryspec.Includes, rows []T) error {
+	creators, updaters := includes.Has("created_by"), includes.Has("updated_by")
+	if !creators && !updaters {
+		return nil
+	}
+
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, row := range rows {
+		createdBy, updatedBy := row.ActorIds()
+		for _, id := range []int{createdBy, updatedBy} {
+			if id != 0 && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	actors, err := repo.FindActorsByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byId := map[int]*entity.Actor{}
+	for _, actor := range actors {
+		byId[actor.Id] = actor
+	}
+
+	actor := func(id int) *entity.Actor {
+		if e, ok := byId[id]; ok {
+			return e
+		}
+
+		return &entity.Actor{Id: id}
+	}
+
+	for _, row := range rows {
+		createdBy, updatedBy := row.ActorIds()
+
+		var creator, updater *entity.Actor
+		if creators {
+			creator = actor(createdBy)
+		}
+		if updaters {
+			updater = actor(updatedBy)
+		}
+
+		row.SetActors(creator, updater)
+	}
+
+	return nil
+}
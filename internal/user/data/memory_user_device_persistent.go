@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+)
+
+var (
+	memoryUserDevicePersistInstance     *memoryUserDevicePersistent
+	memoryUserDevicePersistInstanceOnce sync.Once
+)
+
+type memoryUserDevicePersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryUserDevicePersistent(db *memdb.DB) user.UserDevicePersistent {
+	memoryUserDevicePersistInstanceOnce.Do(func() {
+		memoryUserDevicePersistInstance = &memoryUserDevicePersistent{
+			db: db,
+		}
+	})
+
+	return memoryUserDevicePersistInstance
+}
+
+func (r *memoryUserDevicePersistent) Create(ctx context.Context, e *entity.UserDevice) (*entity.UserDevice, error) {
+	insertId := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		insertId, err = t.InsertUserDevice(e)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, insertId)
+}
+
+func (r *memoryUserDevicePersistent) Update(ctx context.Context, e *entity.UserDevice) (*entity.UserDevice, error) {
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.UserDevices, e.Id)
+		if row == nil {
+			return nil
+		}
+
+		row.UserId = e.UserId
+		row.Token = e.Token
+		row.IP = e.IP
+		row.Location = e.Location
+		row.Platform = e.Platform
+		row.UserAgent = e.UserAgent
+		row.AppVersion = e.AppVersion
+		row.Vendor = e.Vendor
+		row.UpdatedAt = e.UpdatedAt
+		row.UpdatedBy = e.UpdatedBy
+
+		return t.SaveUserDevice(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+func (r *memoryUserDevicePersistent) Destroy(ctx context.Context, e *entity.UserDevice) error {
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.UserDevices[e.Id]; !ok {
+			return sql.ErrNoRows
+		}
+
+		delete(t.UserDevices, e.Id)
+
+		return nil
+	})
+}
+
+func (r *memoryUserDevicePersistent) FindById(ctx context.Context, id int) (*entity.UserDevice, error) {
+	return r.find(ctx, func(row *entity.UserDevice) bool {
+		return row.Id == id
+	})
+}
+
+func (r *memoryUserDevicePersistent) FindByUuid(ctx context.Context, uuid string) (*entity.UserDevice, error) {
+	return r.find(ctx, func(row *entity.UserDevice) bool {
+		return row.Uuid == uuid
+	})
+}
+
+func (r *memoryUserDevicePersistent) FindByToken(ctx context.Context, userId int, token string) (*entity.UserDevice, error) {
+	return r.find(ctx, func(row *entity.UserDevice) bool {
+		return row.UserId == userId && row.Token == token
+	})
+}
+
+func (r *memoryUserDevicePersistent) find(ctx context.Context, match func(row *entity.UserDevice) bool) (*entity.UserDevice, error) {
+	var found *entity.UserDevice
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.UserDevices) {
+			if match(row) {
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,804 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+var (
+	memoryUserPersistInstance     *memoryUserPersistent
+	memoryUserPersistInstanceOnce sync.Once
+)
+
+// memoryUserPersistent keeps the users in a memdb.DB. Like
+// pgUserPersistent it sees the members of the active tenant, with their role
+// and their trash in that tenant.
+type memoryUserPersistent struct {
+	db *memdb.DB
+}
+
+func NewMemoryUserPersistent(db *memdb.DB) user.UserPersistent {
+	memoryUserPersistInstanceOnce.Do(func() {
+		memoryUserPersistInstance = &memoryUserPersistent{
+			db: db,
+		}
+	})
+
+	return memoryUserPersistInstance
+}
+
+// Create inserts the user as a member of the active tenant, RoleId is the role
+// in that tenant
+func (r *memoryUserPersistent) Create(ctx context.Context, e *entity.User) (*entity.User, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	if tenantId == 0 {
+		return nil, errors.NewForbiddenError("No active tenant")
+	}
+
+	row := *e
+	row.DeletedAt = sql.NullTime{}
+	row.DeletedBy = sql.NullInt64{}
+
+	insertId := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) (err error) {
+		insertId, err = t.InsertUser(&row)
+		if err != nil {
+			return err
+		}
+
+		return t.PutMember(memdb.Member{TenantId: tenantId, UserId: insertId, RoleId: e.RoleId})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, insertId)
+}
+
+// Update saves the user and its role in the active tenant, users that are not
+// members of the active tenant are left untouched. The user is only saved at
+// the version it was read with, errors.ErrVersionConflict is returned
+// otherwise.
+func (r *memoryUserPersistent) Update(ctx context.Context, e *entity.User) (*entity.User, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: e.Id}]
+		if !ok || m.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+
+		updated := *m
+		updated.RoleId = e.RoleId
+		err := t.PutMember(updated)
+		if err != nil {
+			return err
+		}
+
+		row := memdb.Row(t.Users, e.Id)
+		if row == nil {
+			return sql.ErrNoRows
+		}
+		if row.Version != e.Version {
+			return errors.ErrVersionConflict
+		}
+
+		row.Username = e.Username
+		row.Email = e.Email
+		row.Name = e.Name
+		row.Status = e.Status
+		row.UpdatedAt = e.UpdatedAt
+		row.UpdatedBy = e.UpdatedBy
+		row.Version++
+
+		return t.SaveUser(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// Destroy moves the user to the trash of the active tenant, the account is
+// trashed too once it is not a live member of any tenant
+func (r *memoryUserPersistent) Destroy(ctx context.Context, e *entity.User) error {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: e.Id}]
+		if !ok || m.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+
+		m.DeletedAt = e.DeletedAt
+		m.DeletedBy = e.DeletedBy
+
+		for _, other := range t.Members {
+			if other.UserId == e.Id && !other.DeletedAt.Valid {
+				return nil
+			}
+		}
+
+		row := memdb.Row(t.Users, e.Id)
+		if row == nil {
+			return nil
+		}
+
+		row.DeletedAt = e.DeletedAt
+		row.DeletedBy = e.DeletedBy
+
+		return t.SaveUser(row)
+	})
+}
+
+// Restore brings the user back from the trash of the active tenant, with its
+// account when it was trashed too. It fails when another account took the
+// username or the email in the meantime.
+func (r *memoryUserPersistent) Restore(ctx context.Context, e *entity.User) (*entity.User, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		for _, other := range t.Users {
+			if other.Id != e.Id && !other.DeletedAt.Valid && (other.Username == e.Username || other.Email == e.Email) {
+				return errors.NewBadRequestError(fmt.Sprintf("Username '%s' or email '%s' is already used by another user", e.Username, e.Email))
+			}
+		}
+
+		m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: e.Id}]
+		if !ok || !m.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+
+		m.DeletedAt = sql.NullTime{}
+		m.DeletedBy = sql.NullInt64{}
+
+		row := memdb.Row(t.Users, e.Id)
+		row.DeletedAt = sql.NullTime{}
+		row.DeletedBy = sql.NullInt64{}
+		row.UpdatedAt = e.UpdatedAt
+		row.UpdatedBy = e.UpdatedBy
+
+		return t.SaveUser(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(ctx, e.Id)
+}
+
+// SaveLastLogin records a sign-in of the account, the version is left alone
+func (r *memoryUserPersistent) SaveLastLogin(ctx context.Context, id int, at time.Time) error {
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		row := memdb.Row(t.Users, id)
+		if row == nil {
+			return sql.ErrNoRows
+		}
+
+		row.LastLoginAt = sql.NullTime{Time: at, Valid: true}
+
+		return t.SaveUser(row)
+	})
+}
+
+// SavePassword sets the password hash of the account, Update leaves it alone
+// as the members it reads have none. Like Update, it only changes the
+// accounts of live members of the active tenant.
+func (r *memoryUserPersistent) SavePassword(ctx context.Context, id int, password string) error {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	return r.db.Update(ctx, func(t *memdb.Tables) error {
+		m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: id}]
+		row := memdb.Row(t.Users, id)
+		if !ok || m.DeletedAt.Valid || row == nil {
+			return sql.ErrNoRows
+		}
+
+		row.Password = password
+
+		return t.SaveUser(row)
+	})
+}
+
+// Purge deletes for good the members trashed before the given time with their
+// groups and role grants in that tenant, then the trashed accounts left
+// without any membership along with their devices. It is not filtered by
+// tenant and returns the purged members as they were in their tenant.
+func (r *memoryUserPersistent) Purge(ctx context.Context, before time.Time) ([]*entity.PurgedMember, error) {
+	purged := []*entity.PurgedMember{}
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		for key, m := range t.Members {
+			if !m.DeletedAt.Valid || !m.DeletedAt.Time.Before(before) {
+				continue
+			}
+
+			row := memdb.Row(t.Users, key.UserId)
+			if row == nil {
+				continue
+			}
+			row.Password = ""
+			row.RoleId = m.RoleId
+			row.DeletedAt = m.DeletedAt
+			row.DeletedBy = m.DeletedBy
+			purged = append(purged, &entity.PurgedMember{TenantId: key.TenantId, User: *row})
+
+			for gu := range t.GroupUsers {
+				if g, ok := t.Groups[gu.GroupId]; ok && g.TenantId == key.TenantId && gu.UserId == key.UserId {
+					delete(t.GroupUsers, gu)
+				}
+			}
+
+			for id, grant := range t.RoleGrants {
+				if grant.TenantId == key.TenantId && grant.UserId == key.UserId {
+					delete(t.RoleGrants, id)
+				}
+			}
+
+			delete(t.Members, key)
+		}
+
+		for id, row := range t.Users {
+			if !row.DeletedAt.Valid || !row.DeletedAt.Time.Before(before) || hasMembership(t, id) {
+				continue
+			}
+
+			t.DeleteUser(id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(purged, func(i, j int) bool {
+		if purged[i].TenantId != purged[j].TenantId {
+			return purged[i].TenantId < purged[j].TenantId
+		}
+		return purged[i].Id < purged[j].Id
+	})
+
+	return purged, nil
+}
+
+func (r *memoryUserPersistent) FindById(ctx context.Context, id int) (*entity.User, error) {
+	return r.findMember(ctx, false, func(row *entity.User) bool {
+		return row.Id == id
+	})
+}
+
+func (r *memoryUserPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.User, error) {
+	return r.findMember(ctx, false, func(row *entity.User) bool {
+		return row.Uuid == uuid
+	})
+}
+
+// FindTrashedByUuid finds a user in the trash of the active tenant
+func (r *memoryUserPersistent) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.User, error) {
+	return r.findMember(ctx, true, func(row *entity.User) bool {
+		return row.Uuid == uuid
+	})
+}
+
+// FindByUsernameOrEmail looks the account up across tenants for signing in,
+// the returned user has no role until it is loaded again inside a tenant
+func (r *memoryUserPersistent) FindByUsernameOrEmail(ctx context.Context, username string) (*entity.User, error) {
+	var found *entity.User
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Users) {
+			if !row.DeletedAt.Valid && (row.Username == username || row.Email == username) {
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (r *memoryUserPersistent) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.User, error) {
+	rows := []*entity.User{}
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		rows, err = queryspec.SelectRows(userResource, tenantMembers(ctx, t), spec, userColumn(t))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return queryspec.Page(userResource, spec, rows)
+}
+
+// Search finds the live members of the tenant whose username, name or email
+// has a word starting with every term, or contains the search. The rank
+// stands in for ts_rank and similarity: the weight of the field each term
+// matches best (username 1, name 0.4, email 0.2) plus the best trigram
+// similarity of the fields.
+func (r *memoryUserPersistent) Search(ctx context.Context, spec *queryspec.Spec) ([]*entity.UserSearchResult, error) {
+	rows := []*entity.UserSearchResult{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range tenantMembers(ctx, t) {
+			if row.DeletedAt.Valid || !searchMatches(row, spec.Search) {
+				continue
+			}
+
+			rows = append(rows, &entity.UserSearchResult{User: *row, Rank: searchRank(row, spec.Search)})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Rank != rows[j].Rank {
+			return rows[i].Rank > rows[j].Rank
+		}
+		return rows[i].Id < rows[j].Id
+	})
+
+	offset := spec.Offset()
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+
+	if spec.Limit < len(rows) {
+		rows = rows[:spec.Limit]
+	}
+
+	return rows, nil
+}
+
+// FindActorsByIds returns the users behind audit columns among the members of
+// the active tenant, whether they were removed from it or not. The actors of
+// other tenants are left out, like by the other reads.
+func (r *memoryUserPersistent) FindActorsByIds(ctx context.Context, ids []int) ([]*entity.Actor, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	wanted := map[int]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	rows := []*entity.Actor{}
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range memdb.Rows(t.Users) {
+			_, member := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: row.Id}]
+			if wanted[row.Id] && member {
+				rows = append(rows, &entity.Actor{Id: row.Id, Uuid: row.Uuid, Name: row.Name})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// AssignRole sets the role in the active tenant of every member in uuids, it
+// returns the number of updated users
+func (r *memoryUserPersistent) AssignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error) {
+	return r.setRole(ctx, uuids, updatedBy, func(m *memdb.Member) (int, bool) {
+		return roleId, true
+	})
+}
+
+// UnassignRole clears the role in the active tenant of the members in uuids
+// that currently hold it, it returns the number of updated users
+func (r *memoryUserPersistent) UnassignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error) {
+	return r.setRole(ctx, uuids, updatedBy, func(m *memdb.Member) (int, bool) {
+		return 0, m.RoleId == roleId
+	})
+}
+
+// setRole gives the live members in uuids the role returned by role, when it
+// tells so, and touches their account
+func (r *memoryUserPersistent) setRole(ctx context.Context, uuids []string, updatedBy sql.NullInt64, role func(m *memdb.Member) (int, bool)) (int, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	wanted := map[string]bool{}
+	for _, uuid := range uuids {
+		wanted[uuid] = true
+	}
+
+	affected := 0
+	err := r.db.Update(ctx, func(t *memdb.Tables) error {
+		now := sql.NullTime{Time: time.Now(), Valid: true}
+
+		for _, row := range memdb.Rows(t.Users) {
+			m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: row.Id}]
+			if !wanted[row.Uuid] || !ok || m.DeletedAt.Valid {
+				continue
+			}
+
+			roleId, apply := role(m)
+			if !apply {
+				continue
+			}
+
+			updated := *m
+			updated.RoleId = roleId
+			err := t.PutMember(updated)
+			if err != nil {
+				return err
+			}
+
+			row.UpdatedAt = now
+			row.UpdatedBy = updatedBy
+			err = t.SaveUser(row)
+			if err != nil {
+				return err
+			}
+
+			affected++
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+// CountByPermission counts the members of the active tenant granted a
+// permission through their role, their groups or the ancestors of those
+// roles. The exclusions simulate a change before doing it.
+func (r *memoryUserPersistent) CountByPermission(ctx context.Context, permission string, exclusions user.HolderExclusions) (int, error) {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	excludedRoles := intSet(exclusions.RoleIds)
+	excludedGroups := intSet(exclusions.GroupIds)
+	leavingGroup := stringSet(exclusions.GroupMemberUuids)
+	losingRole := stringSet(exclusions.RoleUserUuids)
+	leaving := stringSet(exclusions.UserUuids)
+
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		// grants tells whether a live role that is not excluded holds the
+		// permission, itself or through its live ancestors
+		grants := func(roleId int) bool {
+			seen := map[int]bool{}
+			queue := []int{roleId}
+			for len(queue) > 0 {
+				id := queue[0]
+				queue = queue[1:]
+
+				role, ok := t.Roles[id]
+				if !ok || role.DeletedAt.Valid || excludedRoles[id] || seen[id] {
+					continue
+				}
+				seen[id] = true
+
+				for key := range t.RolePermissions {
+					perm, ok := t.Permissions[key.PermissionId]
+					if key.RoleId == id && ok && perm.Name == permission && !perm.DeletedAt.Valid {
+						return true
+					}
+				}
+
+				for key := range t.RoleParents {
+					if key.RoleId == id {
+						queue = append(queue, key.ParentId)
+					}
+				}
+			}
+
+			return false
+		}
+
+		for key, m := range t.Members {
+			account := t.Users[key.UserId]
+			if key.TenantId != tenantId || m.DeletedAt.Valid || leaving[account.Uuid] {
+				continue
+			}
+
+			roleIds := []int{}
+			if !losingRole[account.Uuid] {
+				roleIds = append(roleIds, m.RoleId)
+			}
+
+			for gu := range t.GroupUsers {
+				g, ok := t.Groups[gu.GroupId]
+				if gu.UserId != key.UserId || !ok || g.TenantId != tenantId || excludedGroups[g.Id] {
+					continue
+				}
+				if g.Id == exclusions.GroupId && leavingGroup[account.Uuid] {
+					continue
+				}
+
+				for gr := range t.GroupRoles {
+					if gr.GroupId == g.Id {
+						roleIds = append(roleIds, gr.RoleId)
+					}
+				}
+			}
+
+			for _, roleId := range roleIds {
+				if grants(roleId) {
+					numrows++
+					break
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return numrows, err
+}
+
+// CountByUsername is not filtered by tenant, usernames and emails identify
+// the account across every tenant it belongs to
+func (r *memoryUserPersistent) CountByUsername(ctx context.Context, username string) (int, error) {
+	return r.countAccounts(ctx, func(row *entity.User) bool {
+		return row.Username == username
+	})
+}
+
+func (r *memoryUserPersistent) CountByEmail(ctx context.Context, email string) (int, error) {
+	return r.countAccounts(ctx, func(row *entity.User) bool {
+		return row.Email == email
+	})
+}
+
+func (r *memoryUserPersistent) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) (err error) {
+		numrows, err = queryspec.CountRows(userResource, tenantMembers(ctx, t), spec, userColumn(t))
+		return err
+	})
+
+	return numrows, err
+}
+
+func (r *memoryUserPersistent) CountSearch(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range tenantMembers(ctx, t) {
+			if !row.DeletedAt.Valid && searchMatches(row, spec.Search) {
+				numrows++
+			}
+		}
+
+		return nil
+	})
+
+	return numrows, err
+}
+
+// findMember returns the first live member of the active tenant, or trashed
+// one, that matches
+func (r *memoryUserPersistent) findMember(ctx context.Context, trashed bool, match func(row *entity.User) bool) (*entity.User, error) {
+	var found *entity.User
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range tenantMembers(ctx, t) {
+			if row.DeletedAt.Valid == trashed && match(row) {
+				found = row
+				return nil
+			}
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (r *memoryUserPersistent) countAccounts(ctx context.Context, match func(row *entity.User) bool) (int, error) {
+	numrows := 0
+	err := r.db.View(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Users {
+			if !row.DeletedAt.Valid && match(row) {
+				numrows++
+			}
+		}
+
+		return nil
+	})
+
+	return numrows, err
+}
+
+// tenantMembers returns the members of the active tenant ordered by id, trashed
+// ones included, with their role and their trash in that tenant. Their
+// password hash is left out like the SQL persistent does.
+func tenantMembers(ctx context.Context, t *memdb.Tables) []*entity.User {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	members := []*entity.User{}
+	for _, row := range memdb.Rows(t.Users) {
+		m, ok := t.Members[memdb.MemberKey{TenantId: tenantId, UserId: row.Id}]
+		if !ok {
+			continue
+		}
+
+		row.Password = ""
+		row.RoleId = m.RoleId
+		row.DeletedAt = m.DeletedAt
+		row.DeletedBy = m.DeletedBy
+		members = append(members, row)
+	}
+
+	return members
+}
+
+// userColumn reads the columns of the user list, the role filter is a
+// subquery on Postgres
+func userColumn(t *memdb.Tables) queryspec.ColumnFunc[*entity.User] {
+	roleColumn := userResource.Filterable["role"].Column
+
+	return func(row *entity.User, column string) any {
+		if column != roleColumn {
+			return queryspec.ColumnOf(row, column)
+		}
+
+		role, ok := t.Roles[row.RoleId]
+		if !ok {
+			return nil
+		}
+
+		return role.Name
+	}
+}
+
+func hasMembership(t *memdb.Tables, userId int) bool {
+	for key := range t.Members {
+		if key.UserId == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchField is a field of the search_vector with its weight
+type searchField struct {
+	text   string
+	weight float64
+}
+
+func searchFields(row *entity.User) []searchField {
+	return []searchField{
+		{text: strings.ToLower(row.Username), weight: 1},
+		{text: strings.ToLower(row.Name), weight: 0.4},
+		{text: strings.ToLower(row.Email), weight: 0.2},
+	}
+}
+
+// searchTerms splits the search into its lowercase words of letters and digits
+func searchTerms(search string) []string {
+	return strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// termWeight returns the weight of the best field having a word starting with
+// the term, 0 when none has
+func termWeight(row *entity.User, term string) float64 {
+	best := 0.0
+	for _, field := range searchFields(row) {
+		for _, word := range searchTerms(field.text) {
+			if strings.HasPrefix(word, term) && field.weight > best {
+				best = field.weight
+			}
+		}
+	}
+
+	return best
+}
+
+func searchMatches(row *entity.User, search string) bool {
+	contains := strings.ToLower(search)
+	for _, field := range searchFields(row) {
+		if strings.Contains(field.text, contains) {
+			return true
+		}
+	}
+
+	terms := searchTerms(search)
+	if len(terms) == 0 {
+		return false
+	}
+
+	for _, term := range terms {
+		if termWeight(row, term) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func searchRank(row *entity.User, search string) float64 {
+	rank := 0.0
+
+	terms := searchTerms(search)
+	for _, term := range terms {
+		rank += termWeight(row, term) / float64(len(terms))
+	}
+
+	best := 0.0
+	for _, field := range searchFields(row) {
+		if s := similarity(field.text, strings.ToLower(search)); s > best {
+			best = s
+		}
+	}
+
+	return rank + best
+}
+
+// similarity is the trigram similarity of pg_trgm: the trigrams shared by both
+// texts over the trigrams of either
+func similarity(a string, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range ta {
+		if tb[trigram] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams returns the trigrams of the words of the text, padded with two
+// spaces before and one after
+func trigrams(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range searchTerms(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+
+	return set
+}
+
+func intSet(values []int) map[int]bool {
+	set := map[int]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
+
+func stringSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
@@ -0,0 +1,48 @@
+package data
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Masterminds/squirrel"
+)
+
+var (
+	sqlUserDevicePersistInstance     *sqlUserDevicePersistent
+	sqlUserDevicePersistInstanceOnce sync.Once
+)
+
+// userDeviceTable maps the devices for the CRUD queries
+var userDeviceTable = crud.Table{
+	Name:       "user_devices",
+	Columns:    []string{"id", "uuid", "user_id", "token", "ip", "location", "platform", "user_agent", "app_version", "vendor", "created_at", "created_by", "updated_at", "updated_by"},
+	Insertable: []string{"uuid", "user_id", "token", "ip", "location", "platform", "user_agent", "app_version", "vendor"},
+	Updatable:  []string{"user_id", "token", "ip", "location", "platform", "user_agent", "app_version", "vendor"},
+	Audited:    true,
+}
+
+type sqlUserDevicePersistent struct {
+	*crud.Repository[entity.UserDevice]
+}
+
+func NewSqlUserDevicePersistent(db *txmanager.DB) user.UserDevicePersistent {
+	sqlUserDevicePersistInstanceOnce.Do(func() {
+		sqlUserDevicePersistInstance = &sqlUserDevicePersistent{
+			Repository: crud.New[entity.UserDevice](db, userDeviceTable),
+		}
+	})
+
+	return sqlUserDevicePersistInstance
+}
+
+func (r *sqlUserDevicePersistent) FindByUuid(ctx context.Context, uuid string) (*entity.UserDevice, error) {
+	return r.FindBy(ctx, "uuid", uuid)
+}
+
+func (r *sqlUserDevicePersistent) FindByToken(ctx context.Context, userId int, token string) (*entity.UserDevice, error) {
+	return r.Get(ctx, r.Query(ctx).Where(squirrel.Eq{"user_id": userId, "token": token}))
+}
@@ -0,0 +1,799 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/crud"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	sqlUserPersistInstance     *sqlUserPersistent
+	sqlUserPersistInstanceOnce sync.Once
+)
+
+// userResource whitelists the fields of the user list
+var userResource = &queryspec.Resource{
+	Sortable: map[string]string{
+		"username":      "username",
+		"email":         "email",
+		"name":          "name",
+		"status":        "status",
+		"last_login_at": "last_login_at",
+		"created_at":    "created_at",
+		"updated_at":    "updated_at",
+	},
+	Filterable: map[string]queryspec.Field{
+		"username":      {Column: "username", Type: queryspec.TypeString, Ops: queryspec.TextOps},
+		"email":         {Column: "email", Type: queryspec.TypeString, Ops: queryspec.TextOps},
+		"name":          {Column: "name", Type: queryspec.TypeString, Ops: queryspec.TextOps},
+		"status":        {Column: "status", Type: queryspec.TypeInt, Ops: queryspec.EnumOps},
+		"role":          {Column: "(SELECT name FROM roles WHERE roles.id = users.role_id)", Type: queryspec.TypeString, Ops: queryspec.Nullable(queryspec.TextOps)},
+		"last_login_at": {Column: "last_login_at", Type: queryspec.TypeTime, Ops: queryspec.Nullable(queryspec.RangeOps)},
+		"created_at":    {Column: "created_at", Type: queryspec.TypeTime, Ops: queryspec.RangeOps},
+		"updated_at":    {Column: "updated_at", Type: queryspec.TypeTime, Ops: queryspec.RangeOps},
+	},
+	Searchable: []string{"username", "email", "name"},
+	SoftDelete: "deleted_at",
+	Nullable:   []string{"last_login_at"},
+	Key:        "id",
+}
+
+// userTable maps the members of the active tenant for the CRUD queries, they
+// are read from tenantUsersQuery and their account is written to users. Only
+// the accounts of live members can be changed.
+var userTable = crud.Table{
+	Name:       "users",
+	Columns:    []string{"id", "uuid", "username", "email", "name", "COALESCE(role_id, 0) AS role_id", "status", "last_login_at", "created_at", "created_by", "updated_at", "updated_by", "version", "deleted_at", "deleted_by"},
+	Insertable: []string{"uuid", "username", "email", "password", "name", "status", "last_login_at"},
+	Updatable:  []string{"username", "email", "name", "status"},
+	Version:    "version",
+	Audited:    true,
+	Resource:   userResource,
+	From:       tenantUsersQuery,
+	Writable: func(ctx context.Context) squirrel.Sqlizer {
+		return squirrel.Expr(
+			"EXISTS (SELECT 1 FROM tenant_users WHERE tenant_id = ? AND user_id = users.id AND deleted_at IS NULL)",
+			utils.GetTenantIdFromContext(ctx),
+		)
+	},
+}
+
+// sqlUserPersistent sees the members of the active tenant. Postgres searches
+// them by full text, the other dialects have none and Search narrows the
+// members down with LIKE and ranks them in Go like the in-memory persistent
+// does.
+type sqlUserPersistent struct {
+	*crud.Repository[entity.User]
+}
+
+// tenantUserColumns are the columns of a member, role_id and deleted_at are
+// those of the membership. The password hash is left out, it is only read by
+// FindByUsernameOrEmail to check credentials.
+const tenantUserColumns = `u.id, u.uuid, u.username, u.email, u.name, u.status, u.last_login_at,
+	u.created_at, u.created_by, u.updated_at, u.updated_by, u.version,
+	tu.role_id, tu.deleted_at, tu.deleted_by`
+
+// userColumns are the columns of a live member read from fullTextUsers
+const userColumns = `id, uuid, username, email, name, COALESCE(role_id, 0) AS role_id, status, last_login_at, created_at, created_by, updated_at, updated_by, version`
+
+func NewSqlUserPersistent(db *txmanager.DB) user.UserPersistent {
+	sqlUserPersistInstanceOnce.Do(func() {
+		sqlUserPersistInstance = &sqlUserPersistent{
+			Repository: crud.New[entity.User](db, userTable),
+		}
+	})
+
+	return sqlUserPersistInstance
+}
+
+// Create inserts the user as a member of the active tenant, RoleId is the role
+// in that tenant
+func (r *sqlUserPersistent) Create(ctx context.Context, e *entity.User) (*entity.User, error) {
+	sqlInsertMember := `
+		INSERT INTO tenant_users
+		(tenant_id, user_id, role_id)
+		VALUES
+		(?, ?, NULLIF(?, 0))
+	`
+
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	if tenantId == 0 {
+		return nil, errors.NewForbiddenError("No active tenant")
+	}
+
+	userId := 0
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		var err error
+		userId, err = r.Insert(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		_, err = r.DB().ExecContext(ctx, sqlInsertMember, tenantId, userId, e.RoleId)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), userId)
+}
+
+// Update saves the user and its role in the active tenant, users that are not
+// members of the active tenant are left untouched. The user is only saved at
+// the version it was read with, errors.ErrVersionConflict is returned
+// otherwise.
+func (r *sqlUserPersistent) Update(ctx context.Context, e *entity.User) (*entity.User, error) {
+	sqlMember := `
+		SELECT COUNT(*) AS numrows
+		FROM tenant_users
+		WHERE tenant_id = ? AND user_id = ? AND deleted_at IS NULL
+	`
+
+	sqlUpdateMember := `
+		UPDATE tenant_users
+			SET role_id = NULLIF(?, 0)
+		WHERE tenant_id = ? AND user_id = ? AND deleted_at IS NULL
+	`
+
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		// the membership is checked first, a user outside of the tenant is not
+		// found rather than in conflict
+		members := 0
+		err := r.DB().QueryRowContext(ctx, sqlMember, tenantId, e.Id).Scan(&members)
+		if err != nil {
+			return err
+		}
+		if members == 0 {
+			return sql.ErrNoRows
+		}
+
+		_, err = r.DB().ExecContext(ctx, sqlUpdateMember, e.RoleId, tenantId, e.Id)
+		if err != nil {
+			return err
+		}
+
+		return r.Save(ctx, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+// Destroy moves the user to the trash of the active tenant, the account is
+// trashed too once it is not a live member of any tenant. Groups, role grants
+// and devices are kept for a restore, Purge deletes them for good.
+func (r *sqlUserPersistent) Destroy(ctx context.Context, e *entity.User) error {
+	sqlTrashMember := `
+		UPDATE tenant_users
+			SET deleted_at = ?,
+				deleted_by = ?
+		WHERE tenant_id = ? AND user_id = ? AND deleted_at IS NULL
+	`
+	sqlTrashUser := `
+		UPDATE users
+			SET deleted_at = ?,
+				deleted_by = ?
+		WHERE id = ? AND NOT EXISTS (SELECT 1 FROM tenant_users WHERE user_id = ? AND deleted_at IS NULL)
+	`
+
+	return r.DB().Atomic(ctx, func(ctx context.Context) error {
+		res, err := r.DB().ExecContext(ctx, sqlTrashMember, e.DeletedAt, e.DeletedBy, utils.GetTenantIdFromContext(ctx), e.Id)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		_, err = r.DB().ExecContext(ctx, sqlTrashUser, e.DeletedAt, e.DeletedBy, e.Id, e.Id)
+
+		return err
+	})
+}
+
+// Restore brings the user back from the trash of the active tenant, with its
+// account when it was trashed too. It fails when another account took the
+// username or the email in the meantime.
+func (r *sqlUserPersistent) Restore(ctx context.Context, e *entity.User) (*entity.User, error) {
+	sqlTaken := `
+		SELECT COUNT(id) AS numrows
+		FROM users
+		WHERE id <> ? AND deleted_at IS NULL AND (username = ? OR email = ?)
+	`
+	sqlRestoreMember := `
+		UPDATE tenant_users
+			SET deleted_at = NULL,
+				deleted_by = NULL
+		WHERE tenant_id = ? AND user_id = ? AND deleted_at IS NOT NULL
+	`
+	sqlRestoreUser := `
+		UPDATE users
+			SET deleted_at = NULL,
+				deleted_by = NULL,
+				updated_at = ?,
+				updated_by = ?
+		WHERE id = ?
+	`
+
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		taken := 0
+		err := r.DB().QueryRowContext(ctx, sqlTaken, e.Id, e.Username, e.Email).Scan(&taken)
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errors.NewBadRequestError(fmt.Sprintf("Username '%s' or email '%s' is already used by another user", e.Username, e.Email))
+		}
+
+		res, err := r.DB().ExecContext(ctx, sqlRestoreMember, utils.GetTenantIdFromContext(ctx), e.Id)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		_, err = r.DB().ExecContext(ctx, sqlRestoreUser, e.UpdatedAt, e.UpdatedBy, e.Id)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), e.Id)
+}
+
+// SaveLastLogin records a sign-in of the account. It leaves the version
+// alone, signing in does not conflict with an edit of the user.
+func (r *sqlUserPersistent) SaveLastLogin(ctx context.Context, id int, at time.Time) error {
+	_, err := r.DB().ExecContext(ctx, `UPDATE users SET last_login_at = ? WHERE id = ?`, at, id)
+
+	return err
+}
+
+// SavePassword sets the password hash of the account, Update leaves it alone
+// as the members it reads have none. Like Update, it only changes the
+// accounts of live members of the active tenant.
+func (r *sqlUserPersistent) SavePassword(ctx context.Context, id int, password string) error {
+	sqlSavePassword := `
+		UPDATE users
+			SET password = ?
+		WHERE id = ? AND EXISTS (SELECT 1 FROM tenant_users WHERE tenant_id = ? AND user_id = users.id AND deleted_at IS NULL)
+	`
+
+	res, err := r.DB().ExecContext(ctx, sqlSavePassword, password, id, utils.GetTenantIdFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Purge deletes for good the members trashed before the given time with their
+// groups and role grants in that tenant, then the trashed accounts left
+// without any membership along with their devices. It is not filtered by
+// tenant and returns the purged members as they were in their tenant.
+func (r *sqlUserPersistent) Purge(ctx context.Context, before time.Time) ([]*entity.PurgedMember, error) {
+	sqlPurged := `
+		SELECT tenant_id, ` + userColumns + `, deleted_at, deleted_by
+		FROM (
+			SELECT tu.tenant_id, ` + tenantUserColumns + `
+			FROM users u
+			INNER JOIN tenant_users tu ON tu.user_id = u.id
+			WHERE tu.deleted_at < ?
+		) AS users
+		ORDER BY tenant_id, id
+	`
+	sqlPurgeGroups := `
+		DELETE FROM group_users
+		WHERE EXISTS (
+			SELECT 1
+			FROM "groups" g
+			INNER JOIN tenant_users tu ON tu.tenant_id = g.tenant_id
+			WHERE g.id = group_users.group_id AND tu.user_id = group_users.user_id AND tu.deleted_at < ?
+		)
+	`
+	sqlPurgeGrants := `
+		DELETE FROM role_grants
+		WHERE EXISTS (
+			SELECT 1
+			FROM tenant_users tu
+			WHERE tu.tenant_id = role_grants.tenant_id AND tu.user_id = role_grants.user_id AND tu.deleted_at < ?
+		)
+	`
+	sqlPurgeMembers := `DELETE FROM tenant_users WHERE deleted_at < ?`
+	sqlPurgeUsers := `
+		DELETE FROM users
+		WHERE deleted_at < ? AND NOT EXISTS (SELECT 1 FROM tenant_users WHERE user_id = users.id)
+	`
+
+	purged := []*entity.PurgedMember{}
+	err := r.DB().Atomic(ctx, func(ctx context.Context) error {
+		err := r.DB().SelectContext(ctx, &purged, sqlPurged, before)
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+
+		for _, query := range []string{sqlPurgeGroups, sqlPurgeGrants, sqlPurgeMembers, sqlPurgeUsers} {
+			_, err = r.DB().ExecContext(ctx, query, before)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+// FindTrashedByUuid finds a user in the trash of the active tenant
+func (r *sqlUserPersistent) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.User, error) {
+	return r.FindTrashedBy(ctx, "uuid", uuid)
+}
+
+func (r *sqlUserPersistent) FindByUuid(ctx context.Context, uuid string) (*entity.User, error) {
+	return r.FindBy(ctx, "uuid", uuid)
+}
+
+// FindByUsernameOrEmail looks the account up across tenants for signing in,
+// the returned user has no role until it is loaded again inside a tenant
+func (r *sqlUserPersistent) FindByUsernameOrEmail(ctx context.Context, username string) (*entity.User, error) {
+	sql := `
+		SELECT id, uuid, username, email, password, name, 0 AS role_id, status, last_login_at, created_at, created_by, updated_at, updated_by, version
+		FROM users
+		WHERE (username = ? OR email = ?) AND deleted_at IS NULL
+	`
+
+	row := &entity.User{}
+	err := r.DB().GetContext(ctx, row, sql, username, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
+
+// tenantUsersQuery exposes the members of the active tenant with their role in
+// that tenant, so queries built on it cannot see other tenants. Trashed members
+// are included since userResource scopes the trash.
+func tenantUsersQuery(ctx context.Context) squirrel.SelectBuilder {
+	return squirrel.Select(tenantUserColumns).
+		From("users u").
+		Join("tenant_users tu ON tu.user_id = u.id").
+		Where(squirrel.Eq{"tu.tenant_id": utils.GetTenantIdFromContext(ctx)})
+}
+
+// Search finds the members of the tenant by the words of their username, name
+// or email starting with the terms of the search, or by a partial username,
+// email or name, best matches first
+func (r *sqlUserPersistent) Search(ctx context.Context, spec *queryspec.Spec) ([]*entity.UserSearchResult, error) {
+	if r.DB().Dialect() == txmanager.Postgres {
+		return r.searchFullText(ctx, spec)
+	}
+
+	rows, err := r.search(ctx, spec.Search)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := spec.Offset()
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+
+	if spec.Limit < len(rows) {
+		rows = rows[:spec.Limit]
+	}
+
+	return rows, nil
+}
+
+func (r *sqlUserPersistent) CountSearch(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	if r.DB().Dialect() == txmanager.Postgres {
+		return r.countFullText(ctx, spec)
+	}
+
+	rows, err := r.search(ctx, spec.Search)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(rows), nil
+}
+
+// search returns every member matching the search, ranked. The LIKE
+// conditions keep the members having every term somewhere, or the whole
+// search, searchMatches then checks the terms start a word.
+func (r *sqlUserPersistent) search(ctx context.Context, search string) ([]*entity.UserSearchResult, error) {
+	contains := func(term string) squirrel.Or {
+		like := queryspec.Contains(term)
+
+		return squirrel.Or{
+			squirrel.Expr("LOWER(username) LIKE ? ESCAPE '!'", like),
+			squirrel.Expr("LOWER(email) LIKE ? ESCAPE '!'", like),
+			squirrel.Expr("LOWER(name) LIKE ? ESCAPE '!'", like),
+		}
+	}
+
+	match := squirrel.Or{contains(search)}
+	if terms := searchTerms(search); len(terms) > 0 {
+		every := squirrel.And{}
+		for _, term := range terms {
+			every = append(every, contains(term))
+		}
+		match = append(match, every)
+	}
+
+	users, err := r.Select(ctx, r.Query(ctx).Where(match))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*entity.UserSearchResult{}
+	for _, row := range users {
+		if searchMatches(row, search) {
+			rows = append(rows, &entity.UserSearchResult{User: *row, Rank: searchRank(row, search)})
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Rank != rows[j].Rank {
+			return rows[i].Rank > rows[j].Rank
+		}
+		return rows[i].Id < rows[j].Id
+	})
+
+	return rows, nil
+}
+
+// fullTextUsers are the live members of the tenant in the first placeholder
+// with the search_vector Postgres keeps up to date for the full-text search
+const fullTextUsers = `(
+	SELECT ` + tenantUserColumns + `, u.search_vector
+	FROM users u
+	INNER JOIN tenant_users tu ON tu.user_id = u.id
+	WHERE tu.tenant_id = ? AND tu.deleted_at IS NULL
+) AS users`
+
+// fullTextMatch matches the users of the search, its placeholders are the
+// prefix tsquery of the search then three times its LIKE pattern for partial
+// usernames, emails and names
+const fullTextMatch = `(
+	search_vector @@ to_tsquery('simple', ?)
+	OR LOWER(username) LIKE ? ESCAPE '!'
+	OR LOWER(email) LIKE ? ESCAPE '!'
+	OR LOWER(name) LIKE ? ESCAPE '!'
+)`
+
+// searchFullText is Search on Postgres, ranked by full text and similarity
+func (r *sqlUserPersistent) searchFullText(ctx context.Context, spec *queryspec.Spec) ([]*entity.UserSearchResult, error) {
+	sql := `
+		SELECT ` + userColumns + `,
+			ts_rank(search_vector, to_tsquery('simple', ?))
+				+ GREATEST(similarity(LOWER(username), ?), similarity(LOWER(email), ?), similarity(LOWER(name), ?)) AS rank
+		FROM ` + fullTextUsers + `
+		WHERE ` + fullTextMatch + `
+		ORDER BY rank DESC, id ASC
+		LIMIT ? OFFSET ?
+	`
+
+	tsquery := prefixTsquery(spec.Search)
+	like := queryspec.Contains(spec.Search)
+	lower := strings.ToLower(spec.Search)
+
+	rows := []*entity.UserSearchResult{}
+	err := r.DB().SelectContext(
+		ctx,
+		&rows,
+		sql,
+		tsquery,
+		lower,
+		lower,
+		lower,
+		utils.GetTenantIdFromContext(ctx),
+		tsquery,
+		like,
+		like,
+		like,
+		spec.Limit,
+		spec.Offset(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// countFullText is CountSearch on Postgres
+func (r *sqlUserPersistent) countFullText(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	sql := `
+		SELECT COUNT(id) AS numrows
+		FROM ` + fullTextUsers + `
+		WHERE ` + fullTextMatch + `
+	`
+
+	like := queryspec.Contains(spec.Search)
+
+	numrows := 0
+	err := r.DB().QueryRowContext(
+		ctx,
+		sql,
+		utils.GetTenantIdFromContext(ctx),
+		prefixTsquery(spec.Search),
+		like,
+		like,
+		like,
+	).Scan(&numrows)
+	if err != nil {
+		return 0, err
+	}
+
+	return numrows, nil
+}
+
+// prefixTsquery turns a search into a tsquery matching the words starting
+// with each of its terms, see searchTerms, so that the search cannot inject
+// tsquery operators
+func prefixTsquery(search string) string {
+	terms := []string{}
+	for _, term := range searchTerms(search) {
+		terms = append(terms, term+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// FindActorsByIds returns the users behind audit columns among the members of
+// the active tenant, whether they were removed from it or not. The actors of
+// other tenants are left out, like by the other reads.
+func (r *sqlUserPersistent) FindActorsByIds(ctx context.Context, ids []int) ([]*entity.Actor, error) {
+	if len(ids) == 0 {
+		return []*entity.Actor{}, nil
+	}
+
+	sqlActors := `
+		SELECT u.id, u.uuid, u.name
+		FROM users u
+		INNER JOIN tenant_users tu ON tu.user_id = u.id
+		WHERE tu.tenant_id = ? AND u.id IN (?)
+	`
+
+	query, args, err := sqlx.In(sqlActors, utils.GetTenantIdFromContext(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*entity.Actor{}
+	err = r.DB().SelectContext(ctx, &rows, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// AssignRole sets the role in the active tenant of every member in uuids, it
+// returns the number of updated users
+func (r *sqlUserPersistent) AssignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error) {
+	return r.setRole(ctx, roleId, 0, uuids, updatedBy)
+}
+
+// UnassignRole clears the role in the active tenant of the members in uuids
+// that currently hold it, it returns the number of updated users
+func (r *sqlUserPersistent) UnassignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error) {
+	return r.setRole(ctx, 0, roleId, uuids, updatedBy)
+}
+
+// setRole gives the role to the live members in uuids, only to those holding
+// the held role when it is set. The members are read first as neither SQLite
+// nor MySQL can chain the updates in one statement.
+func (r *sqlUserPersistent) setRole(ctx context.Context, roleId int, held int, uuids []string, updatedBy sql.NullInt64) (int, error) {
+	if len(uuids) == 0 {
+		return 0, nil
+	}
+
+	tenantId := utils.GetTenantIdFromContext(ctx)
+
+	q := squirrel.Select("tu.user_id").
+		From("tenant_users tu").
+		Join("users u ON u.id = tu.user_id").
+		Where("tu.tenant_id = ? AND tu.deleted_at IS NULL", tenantId).
+		Where(squirrel.Eq{"u.uuid": uuids})
+	if held != 0 {
+		q = q.Where(squirrel.Eq{"tu.role_id": held})
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.DB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []int{}
+	err = tx.SelectContext(ctx, &ids, query, args...)
+	if err != nil || len(ids) == 0 {
+		tx.Rollback()
+		return 0, err
+	}
+
+	updates := []squirrel.UpdateBuilder{
+		squirrel.Update("tenant_users").
+			Set("role_id", sql.NullInt64{Int64: int64(roleId), Valid: roleId != 0}).
+			Where(squirrel.Eq{"tenant_id": tenantId, "user_id": ids}),
+		squirrel.Update("users").
+			Set("updated_at", time.Now()).
+			Set("updated_by", updatedBy).
+			Where(squirrel.Eq{"id": ids}),
+	}
+
+	for _, update := range updates {
+		query, args, err := update.ToSql()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return len(ids), tx.Commit()
+}
+
+// CountByPermission counts the members of the active tenant granted a
+// permission through their role, their groups or the ancestors of those
+// roles. The exclusions simulate a change before doing it.
+func (r *sqlUserPersistent) CountByPermission(ctx context.Context, permission string, exclusions user.HolderExclusions) (int, error) {
+	// IN () is invalid, use values that never match
+	nonEmptyUuids := func(uuids []string) []string {
+		if len(uuids) == 0 {
+			return []string{""}
+		}
+		return uuids
+	}
+	nonEmptyIds := func(ids []int) []int {
+		if len(ids) == 0 {
+			return []int{0}
+		}
+		return ids
+	}
+
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	excludeRoleIds := nonEmptyIds(exclusions.RoleIds)
+
+	query, args, err := sqlx.In(`
+		WITH RECURSIVE role_tree (root_id, id) AS (
+			SELECT id, id FROM roles WHERE id NOT IN (?) AND deleted_at IS NULL
+			UNION
+			SELECT rt.root_id, rp.parent_id
+			FROM role_tree rt
+			INNER JOIN role_parents rp ON rp.role_id = rt.id
+			INNER JOIN roles r ON r.id = rp.parent_id AND r.deleted_at IS NULL
+			WHERE rp.parent_id NOT IN (?)
+		),
+		user_roles (user_id, role_id) AS (
+			SELECT tu.user_id, tu.role_id
+			FROM tenant_users tu
+			INNER JOIN users u ON u.id = tu.user_id
+			WHERE tu.tenant_id = ? AND u.uuid NOT IN (?)
+			UNION
+			SELECT gu.user_id, gr.role_id
+			FROM group_users gu
+			INNER JOIN "groups" g ON g.id = gu.group_id
+			INNER JOIN group_roles gr ON gr.group_id = g.id
+			INNER JOIN users u ON u.id = gu.user_id
+			WHERE g.tenant_id = ? AND g.id NOT IN (?) AND NOT (g.id = ? AND u.uuid IN (?))
+		)
+		SELECT COUNT(DISTINCT u.id) AS numrows
+		FROM users u
+		INNER JOIN tenant_users tu ON tu.user_id = u.id AND tu.tenant_id = ? AND tu.deleted_at IS NULL
+		INNER JOIN user_roles ur ON ur.user_id = u.id
+		INNER JOIN role_tree rt ON rt.root_id = ur.role_id
+		INNER JOIN role_permissions rp ON rp.role_id = rt.id
+		INNER JOIN permissions p ON p.id = rp.permission_id
+		WHERE p.name = ? AND p.deleted_at IS NULL AND u.uuid NOT IN (?)
+	`,
+		excludeRoleIds,
+		excludeRoleIds,
+		tenantId,
+		nonEmptyUuids(exclusions.RoleUserUuids),
+		tenantId,
+		nonEmptyIds(exclusions.GroupIds),
+		exclusions.GroupId,
+		nonEmptyUuids(exclusions.GroupMemberUuids),
+		tenantId,
+		permission,
+		nonEmptyUuids(exclusions.UserUuids),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	numrows := 0
+	err = r.DB().QueryRowContext(ctx, query, args...).Scan(&numrows)
+	if err != nil {
+		return 0, err
+	}
+
+	return numrows, nil
+}
+
+// CountByUsername is not filtered by tenant, usernames and emails identify
+// the account across every tenant it belongs to
+func (r *sqlUserPersistent) CountByUsername(ctx context.Context, username string) (int, error) {
+	return r.countAccounts(ctx, "username", username)
+}
+
+func (r *sqlUserPersistent) CountByEmail(ctx context.Context, email string) (int, error) {
+	return r.countAccounts(ctx, "email", email)
+}
+
+func (r *sqlUserPersistent) countAccounts(ctx context.Context, column string, value string) (int, error) {
+	query, args, err := squirrel.Select("COUNT(id) AS numrows").
+		From("users").
+		Where(squirrel.Eq{column: value, "deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	numrows := 0
+	err = r.DB().QueryRowContext(ctx, query, args...).Scan(&numrows)
+	if err != nil {
+		return 0, err
+	}
+
+	return numrows, nil
+}
@@ -5,8 +5,10 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/dto"
 	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
@@ -20,12 +22,17 @@ type Handler interface {
 	Store() func(echo.Context) error
 	Update() func(echo.Context) error
 	Delete() func(echo.Context) error
+	Restore() func(echo.Context) error
 	GetByUuid() func(echo.Context) error
 	GetAll() func(echo.Context) error
+	Search() func(echo.Context) error
+	ExplainPermission() func(echo.Context) error
 
 	SignIn() func(echo.Context) error
 	SignOut() func(echo.Context) error
 	RefreshToken() func(echo.Context) error
+	SwitchTenant() func(echo.Context) error
+	CheckPermissions() func(echo.Context) error
 }
 
 type handler struct {
@@ -73,6 +80,15 @@ func (h *handler) Update() func(echo.Context) error {
 
 		input.Uuid = strings.Trim(c.Param("uuid"), "/")
 
+		version, ok, err := utils.ParseIfMatch(c.Request().Header.Get("If-Match"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			input.Version = version
+			input.IfMatch = true
+		}
+
 		if err := c.Validate(input); err != nil {
 			return err
 		}
@@ -82,6 +98,8 @@ func (h *handler) Update() func(echo.Context) error {
 			return err
 		}
 
+		c.Response().Header().Set("ETag", utils.ETag(res.Version))
+
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
 	}
 }
@@ -105,10 +123,30 @@ func (h *handler) Delete() func(echo.Context) error {
 	}
 }
 
+func (h *handler) Restore() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.RestoreUserRequest{
+			Uuid: strings.Trim(c.Param("uuid"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.RestoreUser(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
 func (h *handler) GetByUuid() func(echo.Context) error {
 	return func(c echo.Context) error {
 		input := dto.GetUserRequest{
-			Uuid: strings.Trim(c.Param("uuid"), "/"),
+			Uuid:    strings.Trim(c.Param("uuid"), "/"),
+			Include: c.QueryParam("include"),
 		}
 
 		if err := c.Validate(input); err != nil {
@@ -120,6 +158,8 @@ func (h *handler) GetByUuid() func(echo.Context) error {
 			return err
 		}
 
+		c.Response().Header().Set("ETag", utils.ETag(res.Version))
+
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
 	}
 }
@@ -132,6 +172,12 @@ func (h *handler) GetAll() func(echo.Context) error {
 			return err
 		}
 
+		filters, err := queryspec.ParseFilters(c.QueryParams())
+		if err != nil {
+			return err
+		}
+		input.Filters = filters
+
 		if err := c.Validate(input); err != nil {
 			return err
 		}
@@ -145,6 +191,27 @@ func (h *handler) GetAll() func(echo.Context) error {
 	}
 }
 
+func (h *handler) Search() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.SearchUserRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.Search(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res.Data, res.Pagination))
+	}
+}
+
 func (h *handler) SignIn() func(echo.Context) error {
 	return func(c echo.Context) error {
 		input := dto.SignInRequest{}
@@ -153,6 +220,10 @@ func (h *handler) SignIn() func(echo.Context) error {
 			return err
 		}
 
+		if input.Tenant == "" {
+			input.Tenant = c.Request().Header.Get(constants.TENANT_HEADER)
+		}
+
 		if err := c.Validate(input); err != nil {
 			return err
 		}
@@ -207,3 +278,65 @@ func (h *handler) RefreshToken() func(echo.Context) error {
 		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
 	}
 }
+
+func (h *handler) SwitchTenant() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.SwitchTenantRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.SwitchTenant(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) ExplainPermission() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.ExplainPermissionRequest{
+			Uuid:       strings.Trim(c.Param("uuid"), "/"),
+			Permission: strings.Trim(c.Param("name"), "/"),
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.ExplainPermission(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
+
+func (h *handler) CheckPermissions() func(echo.Context) error {
+	return func(c echo.Context) error {
+		input := dto.CheckPermissionsRequest{}
+
+		if err := c.Bind(&input); err != nil {
+			return err
+		}
+
+		if err := c.Validate(input); err != nil {
+			return err
+		}
+
+		res, err := h.uc.CheckPermissions(c.Request().Context(), &input)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", res, nil))
+	}
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+)
+
+// HolderExclusions describes a change to simulate when counting the holders
+// of a permission
+type HolderExclusions struct {
+	UserUuids     []string // users leaving the tenant
+	RoleUserUuids []string // users losing their own role
+	RoleIds       []int    // roles deleted or losing the permission
+	GroupIds      []int    // groups deleted or losing the permission
+
+	// members leaving a group
+	GroupId          int
+	GroupMemberUuids []string
+}
+
+// EnsureRoleManagerRemains refuses a change that would leave nobody holding
+// the role management permission
+func EnsureRoleManagerRemains(ctx context.Context, repo UserRepository, exclusions HolderExclusions) error {
+	numrows, err := repo.CountByPermission(ctx, constants.ROLE_MANAGEMENT_PERMISSION, exclusions)
+	if err != nil {
+		return err
+	}
+	if numrows > 0 {
+		return nil
+	}
+
+	// nobody holds it yet (fresh install), there is nothing to lock out
+	numrows, err = repo.CountByPermission(ctx, constants.ROLE_MANAGEMENT_PERMISSION, HolderExclusions{})
+	if err != nil {
+		return err
+	}
+	if numrows == 0 {
+		return nil
+	}
+
+	return errors.NewForbiddenError(fmt.Sprintf("At least one user must keep the '%s' permission", constants.ROLE_MANAGEMENT_PERMISSION))
+}
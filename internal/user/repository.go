@@ -2,22 +2,38 @@ package user
 
 import (
 	"context"
+	"database/sql"
+
+	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
 )
 
 type UserRepository interface {
 	Create(ctx context.Context, e *entity.User) (*entity.User, error)
 	Update(ctx context.Context, e *entity.User) (*entity.User, error)
 	Destroy(ctx context.Context, e *entity.User) error
+	Restore(ctx context.Context, e *entity.User) (*entity.User, error)
+	Purge(ctx context.Context, before time.Time) ([]*entity.PurgedMember, error)
+	SaveLastLogin(ctx context.Context, id int, at time.Time) error
+	SavePassword(ctx context.Context, id int, password string) error
 	FindById(ctx context.Context, id int) (*entity.User, error)
 	FindByUuid(ctx context.Context, uuid string) (*entity.User, error)
+	FindTrashedByUuid(ctx context.Context, uuid string) (*entity.User, error)
 	FindByUsernameOrEmail(ctx context.Context, username string) (*entity.User, error)
-	FindAll(ctx context.Context, offset int, limit int, sorts map[string]string, search string) ([]*entity.User, error)
+	FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.User, error)
+	Search(ctx context.Context, spec *queryspec.Spec) ([]*entity.UserSearchResult, error)
+	FindActorsByIds(ctx context.Context, ids []int) ([]*entity.Actor, error)
+	AssignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error)
+	UnassignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error)
+	LoadRoles(ctx context.Context, users []*entity.User, withPermissions bool) error
 
 	CountByUsername(ctx context.Context, username string) (int, error)
 	CountByEmail(ctx context.Context, email string) (int, error)
-	CountAll(ctx context.Context, search string) (int, error)
+	CountAll(ctx context.Context, spec *queryspec.Spec) (int, error)
+	CountSearch(ctx context.Context, spec *queryspec.Spec) (int, error)
+	CountByPermission(ctx context.Context, permission string, exclusions HolderExclusions) (int, error)
 }
 
 type UserDeviceRepository interface {
@@ -2,11 +2,22 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"sync"
+	"time"
 
+	"github.com/Adhiana46/echo-boilerplate/config"
+	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	"github.com/Adhiana46/echo-boilerplate/internal/group"
 	"github.com/Adhiana46/echo-boilerplate/internal/role"
 	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	cachePkg "github.com/Adhiana46/echo-boilerplate/pkg/cache"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 )
 
 var (
@@ -14,16 +25,32 @@ var (
 	userRepoInstanceOnce sync.Once
 )
 
+// userRepository caches the user rows only, the roles of a user are read
+// through the role repository and its groups and grants are always loaded
 type userRepository struct {
-	userPersistent user.UserPersistent
-	rolePersistent role.RolePersistent
+	userPersistent  user.UserPersistent
+	roleRepository  role.RoleRepository
+	groupPersistent group.GroupPersistent
+	grantPersistent grant.GrantPersistent
+	cache           *cachePkg.Aside
 }
 
-func NewUserRepository(userPersistent user.UserPersistent, rolePersistent role.RolePersistent) user.UserRepository {
+func NewUserRepository(
+	userPersistent user.UserPersistent,
+	roleRepository role.RoleRepository,
+	groupPersistent group.GroupPersistent,
+	grantPersistent grant.GrantPersistent,
+	cache cachePkg.Cache,
+	cfg *config.CacheConfig,
+) user.UserRepository {
 	userRepoInstanceOnce.Do(func() {
 		userRepoInstance = &userRepository{
-			userPersistent: userPersistent,
-			rolePersistent: rolePersistent,
+			userPersistent:  userPersistent,
+			roleRepository:  roleRepository,
+			groupPersistent: groupPersistent,
+			grantPersistent: grantPersistent,
+			// purging a role unassigns its members
+			cache: cachePkg.NewAside(cache, constants.CACHE_NAMESPACE_USERS, cfg.UserTTL, constants.CACHE_NAMESPACE_ROLES),
 		}
 	})
 
@@ -31,67 +58,171 @@ func NewUserRepository(userPersistent user.UserPersistent, rolePersistent role.R
 }
 
 func (r *userRepository) Create(ctx context.Context, e *entity.User) (*entity.User, error) {
-	return r.userPersistent.Create(ctx, e)
+	userEntity, err := r.userPersistent.Create(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return userEntity, nil
 }
 
 func (r *userRepository) Update(ctx context.Context, e *entity.User) (*entity.User, error) {
-	return r.userPersistent.Update(ctx, e)
+	userEntity, err := r.userPersistent.Update(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	return userEntity, nil
 }
 
 func (r *userRepository) Destroy(ctx context.Context, e *entity.User) error {
-	return r.userPersistent.Destroy(ctx, e)
+	err := r.userPersistent.Destroy(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx)
+
+	return nil
 }
 
-func (r *userRepository) FindById(ctx context.Context, id int) (*entity.User, error) {
-	userEntity, err := r.userPersistent.FindById(ctx, id)
+func (r *userRepository) Restore(ctx context.Context, e *entity.User) (*entity.User, error) {
+	userEntity, err := r.userPersistent.Restore(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx)
+
+	err = r.loadRole(ctx, userEntity)
+	if err != nil {
+		return nil, err
+	}
+
+	return userEntity, nil
+}
+
+func (r *userRepository) SaveLastLogin(ctx context.Context, id int, at time.Time) error {
+	err := r.userPersistent.SaveLastLogin(ctx, id, at)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx)
+
+	return nil
+}
+
+func (r *userRepository) SavePassword(ctx context.Context, id int, password string) error {
+	err := r.userPersistent.SavePassword(ctx, id, password)
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(ctx)
+
+	return nil
+}
+
+func (r *userRepository) Purge(ctx context.Context, before time.Time) ([]*entity.PurgedMember, error) {
+	purged, err := r.userPersistent.Purge(ctx, before)
 	if err != nil {
 		return nil, err
 	}
 
-	roleEntity, err := r.rolePersistent.FindById(ctx, userEntity.RoleId)
+	if len(purged) > 0 {
+		r.invalidate(ctx)
+	}
+
+	return purged, nil
+}
+
+func (r *userRepository) FindById(ctx context.Context, id int) (*entity.User, error) {
+	userEntity, err := r.cached(ctx, fmt.Sprintf("id:%d", id), func(ctx context.Context) (*entity.User, error) {
+		return r.userPersistent.FindById(ctx, id)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	userEntity.Role = roleEntity
+	err = r.loadRole(ctx, userEntity)
+	if err != nil {
+		return nil, err
+	}
 
 	return userEntity, nil
 }
 
 func (r *userRepository) FindByUuid(ctx context.Context, uuid string) (*entity.User, error) {
-	userEntity, err := r.userPersistent.FindByUuid(ctx, uuid)
+	userEntity, err := r.cached(ctx, "uuid:"+uuid, func(ctx context.Context) (*entity.User, error) {
+		return r.userPersistent.FindByUuid(ctx, uuid)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	roleEntity, err := r.rolePersistent.FindById(ctx, userEntity.RoleId)
+	err = r.loadRole(ctx, userEntity)
 	if err != nil {
 		return nil, err
 	}
 
-	userEntity.Role = roleEntity
-
 	return userEntity, nil
 }
 
+func (r *userRepository) FindTrashedByUuid(ctx context.Context, uuid string) (*entity.User, error) {
+	return r.userPersistent.FindTrashedByUuid(ctx, uuid)
+}
+
 func (r *userRepository) FindByUsernameOrEmail(ctx context.Context, username string) (*entity.User, error) {
 	userEntity, err := r.userPersistent.FindByUsernameOrEmail(ctx, username)
 	if err != nil {
 		return nil, err
 	}
 
-	roleEntity, err := r.rolePersistent.FindById(ctx, userEntity.RoleId)
+	err = r.loadRole(ctx, userEntity)
 	if err != nil {
 		return nil, err
 	}
 
-	userEntity.Role = roleEntity
-
 	return userEntity, nil
 }
 
-func (r *userRepository) FindAll(ctx context.Context, offset int, limit int, sorts map[string]string, search string) ([]*entity.User, error) {
-	return r.userPersistent.FindAll(ctx, offset, limit, sorts, search)
+func (r *userRepository) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*entity.User, error) {
+	return r.userPersistent.FindAll(ctx, spec)
+}
+
+func (r *userRepository) Search(ctx context.Context, spec *queryspec.Spec) ([]*entity.UserSearchResult, error) {
+	return r.userPersistent.Search(ctx, spec)
+}
+
+func (r *userRepository) FindActorsByIds(ctx context.Context, ids []int) ([]*entity.Actor, error) {
+	return r.userPersistent.FindActorsByIds(ctx, ids)
+}
+
+func (r *userRepository) AssignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error) {
+	assigned, err := r.userPersistent.AssignRole(ctx, roleId, uuids, updatedBy)
+	if err != nil {
+		return 0, err
+	}
+
+	r.invalidate(ctx)
+
+	return assigned, nil
+}
+
+func (r *userRepository) UnassignRole(ctx context.Context, roleId int, uuids []string, updatedBy sql.NullInt64) (int, error) {
+	unassigned, err := r.userPersistent.UnassignRole(ctx, roleId, uuids, updatedBy)
+	if err != nil {
+		return 0, err
+	}
+
+	r.invalidate(ctx)
+
+	return unassigned, nil
 }
 
 func (r *userRepository) CountByUsername(ctx context.Context, username string) (int, error) {
@@ -102,6 +233,144 @@ func (r *userRepository) CountByEmail(ctx context.Context, email string) (int, e
 	return r.userPersistent.CountByEmail(ctx, email)
 }
 
-func (r *userRepository) CountAll(ctx context.Context, search string) (int, error) {
-	return r.userPersistent.CountAll(ctx, search)
+func (r *userRepository) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	return r.userPersistent.CountAll(ctx, spec)
+}
+
+func (r *userRepository) CountSearch(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	return r.userPersistent.CountSearch(ctx, spec)
+}
+
+func (r *userRepository) CountByPermission(ctx context.Context, permission string, exclusions user.HolderExclusions) (int, error) {
+	return r.userPersistent.CountByPermission(ctx, permission, exclusions)
+}
+
+// LoadRoles fills the role of every user with one query, and with three more
+// for the permissions of the roles when asked
+func (r *userRepository) LoadRoles(ctx context.Context, users []*entity.User, withPermissions bool) error {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, userEntity := range users {
+		if userEntity.RoleId != 0 && !seen[userEntity.RoleId] {
+			seen[userEntity.RoleId] = true
+			ids = append(ids, userEntity.RoleId)
+		}
+	}
+
+	roles, err := r.roleRepository.FindAllByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	if withPermissions {
+		err = r.roleRepository.LoadRelations(ctx, roles)
+		if err != nil {
+			return err
+		}
+	}
+
+	byId := map[int]*entity.Role{}
+	for _, roleEntity := range roles {
+		byId[roleEntity.Id] = roleEntity
+	}
+
+	// trashed roles are not found, their members are listed without role
+	for _, userEntity := range users {
+		userEntity.Role = byId[userEntity.RoleId]
+	}
+
+	return nil
+}
+
+// loadRole fills the role of the user, its groups and its role grants in the
+// active tenant, users without role keep a nil Role
+func (r *userRepository) loadRole(ctx context.Context, userEntity *entity.User) error {
+	if userEntity.RoleId != 0 {
+		// a trashed role is left out, the member keeps it for a restore
+		roleEntity, err := r.roleRepository.FindById(ctx, userEntity.RoleId)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		userEntity.Role = roleEntity
+	}
+
+	err := r.loadGroups(ctx, userEntity)
+	if err != nil {
+		return err
+	}
+
+	return r.loadGrants(ctx, userEntity)
+}
+
+// loadGroups fills the groups of the user with the permissions of their
+// roles, a role shared by several groups is loaded once
+func (r *userRepository) loadGroups(ctx context.Context, userEntity *entity.User) error {
+	groups, err := r.groupPersistent.FindAllByUserId(ctx, userEntity.Id)
+	if err != nil {
+		return err
+	}
+
+	loaded := map[int]*entity.Role{}
+	for _, groupEntity := range groups {
+		for i, groupRole := range groupEntity.Roles {
+			roleEntity, ok := loaded[groupRole.Id]
+			if !ok {
+				roleEntity, err = r.roleRepository.FindById(ctx, groupRole.Id)
+				if err != nil {
+					return err
+				}
+
+				loaded[groupRole.Id] = roleEntity
+			}
+
+			groupEntity.Roles[i] = roleEntity
+		}
+	}
+
+	userEntity.Groups = groups
+
+	return nil
+}
+
+// loadGrants fills the role grants of the user, expired ones included until
+// they are swept
+func (r *userRepository) loadGrants(ctx context.Context, userEntity *entity.User) error {
+	grants, err := r.grantPersistent.FindAllByUserId(ctx, userEntity.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, grantEntity := range grants {
+		roleEntity, err := r.roleRepository.FindById(ctx, grantEntity.RoleId)
+		if err != nil {
+			return err
+		}
+
+		grantEntity.Role = roleEntity
+	}
+
+	userEntity.Grants = grants
+
+	return nil
+}
+
+// cached reads the user row through the cache, under a key of the active
+// tenant since the role and the membership of the user depend on it. Reads
+// inside a transaction skip the cache and misses are loaded from the primary.
+func (r *userRepository) cached(ctx context.Context, key string, load func(ctx context.Context) (*entity.User, error)) (*entity.User, error) {
+	if txmanager.InTx(ctx) {
+		return load(ctx)
+	}
+
+	key = fmt.Sprintf("tenant:%d:%s", utils.GetTenantIdFromContext(ctx), key)
+
+	return cachePkg.Fetch(r.cache, key, func() (*entity.User, error) {
+		return load(txmanager.OnPrimary(ctx))
+	})
+}
+
+// invalidate drops the cached users once the write is committed
+func (r *userRepository) invalidate(ctx context.Context) {
+	txmanager.AfterCommit(ctx, r.cache.Invalidate)
 }
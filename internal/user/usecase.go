@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/dto"
 )
@@ -10,11 +11,20 @@ type UserUsecase interface {
 	CreateUser(ctx context.Context, input *dto.CreateUserRequest) (*dto.UserResponse, error)
 	UpdateUser(ctx context.Context, input *dto.UpdateUserRequest) (*dto.UserResponse, error)
 	DeleteUser(ctx context.Context, input *dto.DeleteUserRequest) (*dto.UserResponse, error)
+	RestoreUser(ctx context.Context, input *dto.RestoreUserRequest) (*dto.UserResponse, error)
 	Get(ctx context.Context, input *dto.GetUserRequest) (*dto.UserResponse, error)
 	GetList(ctx context.Context, input *dto.GetListUserRequest) (*dto.UserCollectionResponse, error)
+	Search(ctx context.Context, input *dto.SearchUserRequest) (*dto.UserSearchCollectionResponse, error)
+	ExplainPermission(ctx context.Context, input *dto.ExplainPermissionRequest) (*dto.ExplainPermissionResponse, error)
 
 	// Auth
 	SignIn(ctx context.Context, input *dto.SignInRequest) (*dto.SignInResponse, error)
 	SignOut(ctx context.Context, input *dto.SignOutRequest) (*dto.SignOutResponse, error)
 	RefreshToken(ctx context.Context, input *dto.RefreshTokenRequest) (*dto.RefreshTokenResponse, error)
+	SwitchTenant(ctx context.Context, input *dto.SwitchTenantRequest) (*dto.SwitchTenantResponse, error)
+	CheckPermissions(ctx context.Context, input *dto.CheckPermissionsRequest) (*dto.CheckPermissionsResponse, error)
+
+	// PurgeTrashed deletes for good the members every tenant trashed before
+	// the given time and logs each of them, it returns their number
+	PurgeTrashed(ctx context.Context, before time.Time) (int, error)
 }
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"database/sql"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/pkg/policy"
+)
+
+// userPolicy is evaluated against the loaded user, so users can read and
+// update their own profile without holding users.read / users.update, and
+// read the users they created. Changing role or status always needs the
+// permission, users changing their own password confirm the current one.
+var userPolicy = policy.New[*entity.User]("user").
+	Allow("read", policy.HasPermission[*entity.User]("users.read"), isSelf, isCreator).
+	Allow("update", policy.HasPermission[*entity.User]("users.update"), isSelf).
+	Allow("manage", policy.HasPermission[*entity.User]("users.update")).
+	Allow("delete", policy.HasPermission[*entity.User]("users.delete")).
+	Allow("restore", policy.HasPermission[*entity.User]("users.restore"))
+
+var isSelf = policy.Attribute(func(actor *dto.UserResponseWithID, e *entity.User) bool {
+	return actor.ID == e.Id
+})
+
+var isCreator = policy.IsOwner(func(e *entity.User) sql.NullInt64 {
+	return e.CreatedBy
+})
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+func TestUserPolicy(t *testing.T) {
+	member := func(id int, permissions ...string) *dto.UserResponseWithID {
+		return &dto.UserResponseWithID{
+			ID:   id,
+			Role: &dto.RoleResponse{Name: "member", Permissions: permissions},
+		}
+	}
+
+	alice := &entity.User{Id: 1}
+	bob := &entity.User{Id: 2, CreatedBy: sql.NullInt64{Int64: 3, Valid: true}}
+
+	tests := []struct {
+		name   string
+		actor  *dto.UserResponseWithID
+		action string
+		user   *entity.User
+		want   bool
+	}{
+		{"read self", member(1), "read", alice, true},
+		{"update self", member(1), "update", alice, true},
+		{"manage self", member(1), "manage", alice, false},
+		{"delete self", member(1), "delete", alice, false},
+		{"restore self", member(1), "restore", alice, false},
+		{"read other", member(1), "read", bob, false},
+		{"update other", member(1), "update", bob, false},
+		{"read created", member(3, "users.create"), "read", bob, true},
+		{"update created", member(3, "users.create"), "update", bob, false},
+		{"manage created", member(3, "users.create"), "manage", bob, false},
+		{"delete created", member(3, "users.create"), "delete", bob, false},
+		{"read with permission", member(4, "users.read"), "read", bob, true},
+		{"update with permission", member(4, "users.update"), "update", bob, true},
+		{"manage with permission", member(4, "users.update"), "manage", bob, true},
+		{"delete with permission", member(4, "users.delete"), "delete", bob, true},
+		{"restore with permission", member(4, "users.restore"), "restore", bob, true},
+		{"update with read permission", member(4, "users.read"), "update", bob, false},
+		{"without actor", nil, "read", alice, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := userPolicy.Can(context.Background(), tt.actor, tt.action, tt.user)
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"math"
 	"sync"
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/dto"
 	"github.com/Adhiana46/echo-boilerplate/entity"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit"
 	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
 	"github.com/Adhiana46/echo-boilerplate/internal/user"
 	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/rbac"
 	tokenmanager "github.com/Adhiana46/echo-boilerplate/pkg/token-manager"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -25,25 +29,37 @@ var (
 	userUcInstanceOnce sync.Once
 )
 
+// userIncludes are the relations user responses can embed
+var userIncludes = []string{"role", "role.permissions", "created_by", "updated_by"}
+
 type userUsecase struct {
 	userRepo       user.UserRepository
 	roleRepo       role.RoleRepository
+	tenantRepo     tenant.TenantRepository
 	userDeviceRepo user.UserDeviceRepository
+	auditRepo      audit.AuditLogRepository
 	tokenManager   *tokenmanager.TokenManager
+	txManager      txmanager.TxManager
 }
 
 func NewUserUsecase(
 	userRepo user.UserRepository,
 	roleRepo role.RoleRepository,
+	tenantRepo tenant.TenantRepository,
 	userDeviceRepo user.UserDeviceRepository,
+	auditRepo audit.AuditLogRepository,
 	tokenManager *tokenmanager.TokenManager,
+	txManager txmanager.TxManager,
 ) user.UserUsecase {
 	userUcInstanceOnce.Do(func() {
 		userUcInstance = &userUsecase{
 			userRepo:       userRepo,
 			roleRepo:       roleRepo,
+			tenantRepo:     tenantRepo,
 			userDeviceRepo: userDeviceRepo,
+			auditRepo:      auditRepo,
 			tokenManager:   tokenManager,
+			txManager:      txManager,
 		}
 	})
 
@@ -51,213 +67,447 @@ func NewUserUsecase(
 }
 
 func (uc *userUsecase) CreateUser(ctx context.Context, input *dto.CreateUserRequest) (*dto.UserResponse, error) {
-	// Validation
-	numrows, err := uc.userRepo.CountByEmail(ctx, input.Email)
+	// hashed before the transaction, it is slow and does not need to be
+	// redone when the transaction is retried
+	hashedPassword, err := utils.HashPassword(input.Password)
 	if err != nil {
 		return nil, err
 	}
-	if numrows > 0 {
-		return nil, errors.NewBadRequestError(fmt.Sprintf("User with email '%s' already exists", input.Email))
-	}
 
-	numrows, err = uc.userRepo.CountByUsername(ctx, input.Username)
-	if err != nil {
-		return nil, err
-	}
-	if numrows > 0 {
-		return nil, errors.NewBadRequestError(fmt.Sprintf("User with username '%s' already exists", input.Username))
-	}
+	var res *dto.UserResponse
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Validation
+		numrows, err := uc.userRepo.CountByEmail(ctx, input.Email)
+		if err != nil {
+			return err
+		}
+		if numrows > 0 {
+			return errors.NewBadRequestError(fmt.Sprintf("User with email '%s' already exists", input.Email))
+		}
 
-	role, err := uc.roleRepo.FindByName(ctx, input.Role)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", input.Role))
+		numrows, err = uc.userRepo.CountByUsername(ctx, input.Username)
+		if err != nil {
+			return err
+		}
+		if numrows > 0 {
+			return errors.NewBadRequestError(fmt.Sprintf("User with username '%s' already exists", input.Username))
 		}
-		return nil, err
-	}
 
-	hashedPassword, err := utils.HashPassword(input.Password)
-	if err != nil {
-		return nil, err
-	}
+		role, err := uc.roleRepo.FindByName(ctx, input.Role)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", input.Role))
+			}
+			return err
+		}
 
-	createdBy := sql.NullInt64{}
-	updatedBy := sql.NullInt64{}
-	user := utils.GetUserFromContext(ctx)
-	if user != nil {
-		createdBy = sql.NullInt64{
-			Int64: int64(user.ID),
-			Valid: true,
+		err = uc.ensureAssignable(ctx, role)
+		if err != nil {
+			return err
 		}
 
-		updatedBy = sql.NullInt64{
-			Int64: int64(user.ID),
-			Valid: true,
+		createdBy := sql.NullInt64{}
+		updatedBy := sql.NullInt64{}
+		user := utils.GetUserFromContext(ctx)
+		if user != nil {
+			createdBy = sql.NullInt64{
+				Int64: int64(user.ID),
+				Valid: true,
+			}
+
+			updatedBy = sql.NullInt64{
+				Int64: int64(user.ID),
+				Valid: true,
+			}
 		}
-	}
 
-	row, err := uc.userRepo.Create(ctx, &entity.User{
-		Uuid:        uuid.NewString(),
-		Username:    input.Username,
-		Email:       input.Email,
-		Password:    hashedPassword,
-		Name:        input.Name,
-		RoleId:      role.Id,
-		Status:      input.Status,
-		LastLoginAt: sql.NullTime{},
-		CreatedAt: sql.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		CreatedBy: createdBy,
-		UpdatedAt: sql.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		UpdatedBy: updatedBy,
-		Role:      role,
-	})
+		row, err := uc.userRepo.Create(ctx, &entity.User{
+			Uuid:        uuid.NewString(),
+			Username:    input.Username,
+			Email:       input.Email,
+			Password:    hashedPassword,
+			Name:        input.Name,
+			RoleId:      role.Id,
+			Status:      input.Status,
+			LastLoginAt: sql.NullTime{},
+			CreatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+			CreatedBy: createdBy,
+			UpdatedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
+			UpdatedBy: updatedBy,
+			Role:      role,
+		})
 
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewUserResponse(row)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_CREATE, row.Uuid, nil, row)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewUserResponse(row), nil
+	return res, nil
 }
 
 func (uc *userUsecase) UpdateUser(ctx context.Context, input *dto.UpdateUserRequest) (*dto.UserResponse, error) {
-	e, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
-	if err != nil {
-		return nil, err
+	// hashed before the transaction like in CreateUser
+	hashedPassword := ""
+	if input.Password != "" {
+		var err error
+		hashedPassword, err = utils.HashPassword(input.Password)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// validation logic
-	if e.Email != input.Email {
-		numrows, err := uc.userRepo.CountByEmail(ctx, input.Email)
+	var res *dto.UserResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if numrows > 0 {
-			return nil, errors.NewBadRequestError(fmt.Sprintf("User with email '%s' already exists", input.Email))
+
+		err = userPolicy.AuthorizeFound(ctx, "update", e)
+		if err != nil {
+			return err
 		}
-	}
 
-	if e.Username != input.Username {
-		numrows, err := uc.userRepo.CountByUsername(ctx, input.Username)
+		err = utils.CheckVersion(e.Version, input.Version, input.IfMatch)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if numrows > 0 {
-			return nil, errors.NewBadRequestError(fmt.Sprintf("User with username '%s' already exists", input.Username))
+
+		if hashedPassword != "" || input.Username != e.Username || input.Email != e.Email {
+			err = uc.ensureAccountEditable(ctx, e)
+			if err != nil {
+				return err
+			}
+
+			err = uc.ensureManageable(ctx, e)
+			if err != nil {
+				return err
+			}
 		}
-	}
 
-	role, err := uc.roleRepo.FindByName(ctx, input.Role)
-	if err != nil {
-		return nil, err
-	}
-	if role.Id == 0 {
-		return nil, errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", input.Role))
-	}
+		if hashedPassword != "" {
+			err = uc.checkCurrentPassword(ctx, e, input.CurrentPassword)
+			if err != nil {
+				return err
+			}
+		}
 
-	updatedBy := sql.NullInt64{}
-	user := utils.GetUserFromContext(ctx)
-	if user != nil {
-		updatedBy = sql.NullInt64{
-			Int64: int64(user.ID),
+		// validation logic
+		if e.Email != input.Email {
+			numrows, err := uc.userRepo.CountByEmail(ctx, input.Email)
+			if err != nil {
+				return err
+			}
+			if numrows > 0 {
+				return errors.NewBadRequestError(fmt.Sprintf("User with email '%s' already exists", input.Email))
+			}
+		}
+
+		if e.Username != input.Username {
+			numrows, err := uc.userRepo.CountByUsername(ctx, input.Username)
+			if err != nil {
+				return err
+			}
+			if numrows > 0 {
+				return errors.NewBadRequestError(fmt.Sprintf("User with username '%s' already exists", input.Username))
+			}
+		}
+
+		role, err := uc.roleRepo.FindByName(ctx, input.Role)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewBadRequestError(fmt.Sprintf("Role '%s' is not exists", input.Role))
+			}
+			return err
+		}
+
+		if role.Id != e.RoleId || input.Status != e.Status {
+			err = userPolicy.Authorize(ctx, "manage", e)
+			if err != nil {
+				return err
+			}
+		}
+
+		if role.Id != e.RoleId {
+			err = uc.ensureAssignable(ctx, role)
+			if err != nil {
+				return err
+			}
+		}
+
+		if e.Role != nil && e.Role.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) && !role.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{RoleUserUuids: []string{e.Uuid}})
+			if err != nil {
+				return err
+			}
+		}
+
+		updatedBy := sql.NullInt64{}
+		user := utils.GetUserFromContext(ctx)
+		if user != nil {
+			updatedBy = sql.NullInt64{
+				Int64: int64(user.ID),
+				Valid: true,
+			}
+		}
+
+		before := *e
+
+		// Update e
+		e.Name = input.Name
+		e.Username = input.Username
+		e.Email = input.Email
+		e.Name = input.Name
+		e.Status = input.Status
+		e.RoleId = role.Id
+		e.Role = role
+		e.UpdatedAt = sql.NullTime{
+			Time:  time.Now(),
 			Valid: true,
 		}
-	}
+		e.UpdatedBy = updatedBy
 
-	// Update e
-	e.Name = input.Name
-	e.Username = input.Username
-	e.Email = input.Email
-	e.Name = input.Name
-	e.Status = input.Status
-	e.RoleId = role.Id
-	e.Role = role
-	e.UpdatedAt = sql.NullTime{
-		Time:  time.Now(),
-		Valid: true,
-	}
-	e.UpdatedBy = updatedBy
-	if input.Password != "" {
-		hashedPassword, err := utils.HashPassword(input.Password)
+		// Update
+		updatedE, err := uc.userRepo.Update(ctx, e)
+		if err == errors.ErrVersionConflict {
+			return errors.NewVersionConflictError(e.Version, input.IfMatch)
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		e.Password = hashedPassword
-	}
+		// the hash is saved apart, the users read outside of sign-in have none
+		if hashedPassword != "" {
+			err = uc.userRepo.SavePassword(ctx, e.Id, hashedPassword)
+			if err != nil {
+				return err
+			}
+		}
+
+		res = dto.NewUserResponse(updatedE)
+
+		// the password is not part of the user, its change is logged redacted
+		secrets := []string{}
+		if hashedPassword != "" {
+			secrets = append(secrets, "password")
+		}
 
-	// Update
-	updatedE, err := uc.userRepo.Update(ctx, e)
+		return uc.record(ctx, constants.AUDIT_ACTION_UPDATE, e.Uuid, &before, updatedE, secrets...)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewUserResponse(updatedE), nil
+	return res, nil
 }
 
 func (uc *userUsecase) DeleteUser(ctx context.Context, input *dto.DeleteUserRequest) (*dto.UserResponse, error) {
-	e, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = userPolicy.AuthorizeFound(ctx, "delete", e)
+		if err != nil {
+			return err
+		}
+
+		if e.HasPermission(constants.ROLE_MANAGEMENT_PERMISSION) {
+			err = user.EnsureRoleManagerRemains(ctx, uc.userRepo, user.HolderExclusions{UserUuids: []string{e.Uuid}})
+			if err != nil {
+				return err
+			}
+		}
+
+		before := *e
+
+		e.DeletedAt = sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		}
+		e.DeletedBy = uc.actorId(ctx)
+
+		err = uc.userRepo.Destroy(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		return uc.record(ctx, constants.AUDIT_ACTION_DELETE, e.Uuid, &before, nil)
+	})
+
+	return nil, err
+}
+
+func (uc *userUsecase) RestoreUser(ctx context.Context, input *dto.RestoreUserRequest) (*dto.UserResponse, error) {
+	var res *dto.UserResponse
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := uc.userRepo.FindTrashedByUuid(ctx, input.Uuid)
+		if err != nil {
+			return err
+		}
+
+		err = userPolicy.AuthorizeFound(ctx, "restore", e)
+		if err != nil {
+			return err
+		}
+
+		before := *e
+
+		e.UpdatedAt = sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		}
+		e.UpdatedBy = uc.actorId(ctx)
+
+		restoredE, err := uc.userRepo.Restore(ctx, e)
+		if err != nil {
+			return err
+		}
+
+		res = dto.NewUserResponse(restoredE)
+
+		return uc.record(ctx, constants.AUDIT_ACTION_RESTORE, e.Uuid, &before, restoredE)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	err = uc.userRepo.Destroy(ctx, e)
+	return res, nil
+}
 
-	return nil, err
+// PurgeTrashed logs every purged member along with the purge, in the tenant
+// it was purged from
+func (uc *userUsecase) PurgeTrashed(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := uc.userRepo.Purge(ctx, before)
+		if err != nil {
+			return err
+		}
+		purged = len(rows)
+
+		for _, row := range rows {
+			tenantCtx := utils.WithTenant(ctx, &dto.TenantResponseWithID{ID: row.TenantId})
+
+			err = uc.record(tenantCtx, constants.AUDIT_ACTION_PURGE, row.Uuid, &row.User, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return purged, err
 }
 
 func (uc *userUsecase) Get(ctx context.Context, input *dto.GetUserRequest) (*dto.UserResponse, error) {
+	includes, err := queryspec.ParseIncludes(input.Include, userIncludes...)
+	if err != nil {
+		return nil, err
+	}
+
 	e, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
 	if err != nil {
 		return nil, err
 	}
 
+	err = userPolicy.AuthorizeFound(ctx, "read", e)
+	if err != nil {
+		return nil, err
+	}
+
+	// the role of a single user always comes with its permissions
+	err = user.ExpandActors(ctx, uc.userRepo, includes, []*entity.User{e})
+	if err != nil {
+		return nil, err
+	}
+
 	return dto.NewUserResponse(e), nil
 }
 
 func (uc *userUsecase) GetList(ctx context.Context, input *dto.GetListUserRequest) (*dto.UserCollectionResponse, error) {
-	var err error
-	offset := 0                                 // default
-	limit := constants.DEFAULT_PAGINATION_LIMIT // default
-	sorts := map[string]string{}
-	filter := input.Filter
+	includes, err := queryspec.ParseIncludes(input.Include, userIncludes...)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := queryspec.New(input.Page, input.Limit, input.SortBy, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+	spec.Filters = input.Filters
+	spec.SkipCount = input.SkipCount
+	spec.WithTrashed = input.WithTrashed
+	spec.OnlyTrashed = input.OnlyTrashed
 
-	if input.Limit > 0 {
-		limit = input.Limit
+	if input.Paginate == queryspec.PaginateCursor || input.Cursor != "" {
+		err = spec.UseCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if input.Page > 0 {
-		offset = (input.Page - 1) * limit
+	rows, err := uc.userRepo.FindAll(ctx, spec)
+	if err != nil {
+		return nil, err
 	}
 
-	if input.SortBy != "" {
-		sorts, err = utils.QuerySortToMap(input.SortBy)
+	if includes.Has("role") {
+		err = uc.userRepo.LoadRoles(ctx, rows, includes.Has("role.permissions"))
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	rows, err := uc.userRepo.FindAll(ctx, offset, limit, sorts, filter)
+	err = user.ExpandActors(ctx, uc.userRepo, includes, rows)
 	if err != nil {
 		return nil, err
 	}
 
-	numrows, err := uc.userRepo.CountAll(ctx, filter)
+	var total *int
+	if !spec.SkipCount {
+		numrows, err := uc.userRepo.CountAll(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		total = &numrows
+	}
+
+	return dto.NewUserCollectionResponse(rows, dto.NewPaginationResponse(spec, len(rows), total)), nil
+}
+
+func (uc *userUsecase) Search(ctx context.Context, input *dto.SearchUserRequest) (*dto.UserSearchCollectionResponse, error) {
+	spec, err := queryspec.New(input.Page, input.Limit, "", input.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := uc.userRepo.Search(ctx, spec)
 	if err != nil {
 		return nil, err
 	}
 
-	return dto.NewUserCollectionResponse(rows, dto.PaginationResponse{
-		Size:        len(rows),
-		Total:       numrows,
-		TotalPages:  int(math.Ceil(float64(numrows) / float64(limit))),
-		CurrentPage: input.Page,
-	}), nil
+	numrows, err := uc.userRepo.CountSearch(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.NewUserSearchCollectionResponse(rows, spec.Search, dto.NewPaginationResponse(spec, len(rows), &numrows)), nil
 }
 
 func (uc *userUsecase) SignIn(ctx context.Context, input *dto.SignInRequest) (*dto.SignInResponse, error) {
@@ -271,81 +521,114 @@ func (uc *userUsecase) SignIn(ctx context.Context, input *dto.SignInRequest) (*d
 		return nil, err
 	}
 
-	// set user last_login_at
-	user.LastLoginAt = sql.NullTime{
-		Time:  time.Now(),
-		Valid: true,
-	}
-
 	err = utils.ComparePassword(user.Password, input.Password)
 	if err != nil {
 		return nil, invalidCredsErr
 	}
 
-	// save device
+	tenant, err := uc.findMemberTenant(ctx, user.Id, input.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	ctx = utils.WithTenant(ctx, dto.NewTenantResponseWithID(tenant))
+
+	// the user and the device are saved together, the closure may run again
+	// so it starts over from a fresh copy of the user
+	userId := user.Id
+	var signedIn *entity.User
 	var userDevice *entity.UserDevice
-	if input.Device.Token != "" {
-		userDevice, err = uc.userDeviceRepo.FindByToken(ctx, user.Id, input.Device.Token)
-		if err != nil && err != sql.ErrNoRows {
-			return nil, err
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// reload inside the tenant, the role of the user depends on it
+		user, err := uc.userRepo.FindById(ctx, userId)
+		if err != nil {
+			return err
 		}
 
-		if userDevice != nil {
-			userDevice.IP = input.Device.IP
-			userDevice.Location = input.Device.Location
-			userDevice.Platform = input.Device.Platform
-			userDevice.UserAgent = input.Device.UserAgent
-			userDevice.AppVersion = input.Device.AppVersion
-			userDevice.Vendor = input.Device.Vendor
-			userDevice.UpdatedAt = sql.NullTime{
-				Time:  time.Now(),
-				Valid: true,
-			}
-			userDevice.UpdatedBy = sql.NullInt64{
-				Int64: int64(user.Id),
-				Valid: true,
-			}
+		// set user last_login_at
+		user.LastLoginAt = sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		}
 
-			_, err = uc.userDeviceRepo.Update(ctx, userDevice)
-			if err != nil {
-				return nil, err
+		// save device
+		userDevice = nil
+		if input.Device.Token != "" {
+			userDevice, err = uc.userDeviceRepo.FindByToken(ctx, user.Id, input.Device.Token)
+			if err != nil && err != sql.ErrNoRows {
+				return err
 			}
-		} else {
-			userDevice, err = uc.userDeviceRepo.Create(ctx, &entity.UserDevice{
-				Uuid:       uuid.NewString(),
-				UserId:     user.Id,
-				Token:      input.Device.Token,
-				IP:         input.Device.IP,
-				Location:   input.Device.Location,
-				Platform:   input.Device.Platform,
-				UserAgent:  input.Device.UserAgent,
-				AppVersion: input.Device.AppVersion,
-				Vendor:     input.Device.Vendor,
-				CreatedAt: sql.NullTime{
-					Time:  time.Now(),
-					Valid: true,
-				},
-				CreatedBy: sql.NullInt64{
-					Int64: int64(user.Id),
-					Valid: true,
-				},
-				UpdatedAt: sql.NullTime{
+
+			if userDevice != nil {
+				userDevice.IP = input.Device.IP
+				userDevice.Location = input.Device.Location
+				userDevice.Platform = input.Device.Platform
+				userDevice.UserAgent = input.Device.UserAgent
+				userDevice.AppVersion = input.Device.AppVersion
+				userDevice.Vendor = input.Device.Vendor
+				userDevice.UpdatedAt = sql.NullTime{
 					Time:  time.Now(),
 					Valid: true,
-				},
-				UpdatedBy: sql.NullInt64{
+				}
+				userDevice.UpdatedBy = sql.NullInt64{
 					Int64: int64(user.Id),
 					Valid: true,
-				},
-			})
-			if err != nil {
-				return nil, err
+				}
+
+				_, err = uc.userDeviceRepo.Update(ctx, userDevice)
+				if err != nil {
+					return err
+				}
+			} else {
+				userDevice, err = uc.userDeviceRepo.Create(ctx, &entity.UserDevice{
+					Uuid:       uuid.NewString(),
+					UserId:     user.Id,
+					Token:      input.Device.Token,
+					IP:         input.Device.IP,
+					Location:   input.Device.Location,
+					Platform:   input.Device.Platform,
+					UserAgent:  input.Device.UserAgent,
+					AppVersion: input.Device.AppVersion,
+					Vendor:     input.Device.Vendor,
+					CreatedAt: sql.NullTime{
+						Time:  time.Now(),
+						Valid: true,
+					},
+					CreatedBy: sql.NullInt64{
+						Int64: int64(user.Id),
+						Valid: true,
+					},
+					UpdatedAt: sql.NullTime{
+						Time:  time.Now(),
+						Valid: true,
+					},
+					UpdatedBy: sql.NullInt64{
+						Int64: int64(user.Id),
+						Valid: true,
+					},
+				})
+				if err != nil {
+					return err
+				}
 			}
 		}
+
+		// save user last_login_at, signing in leaves the version as it was
+		err = uc.userRepo.SaveLastLogin(ctx, user.Id, user.LastLoginAt.Time)
+		if err != nil {
+			return err
+		}
+
+		signedIn = user
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	user = signedIn
 
 	// Access Token
-	accessToken, err := uc.tokenManager.GenerateToken(dto.NewUserClaims(user, userDevice, jwt.RegisteredClaims{
+	accessToken, err := uc.tokenManager.GenerateToken(dto.NewUserClaims(user, userDevice, tenant, jwt.RegisteredClaims{
 		ExpiresAt: &jwt.NumericDate{
 			Time: time.Now().Add(constants.ACCESS_TOKEN_DURATION),
 		},
@@ -355,7 +638,7 @@ func (uc *userUsecase) SignIn(ctx context.Context, input *dto.SignInRequest) (*d
 	}
 
 	// Refresh Token
-	refreshToken, err := uc.tokenManager.GenerateToken(dto.NewUserClaims(user, userDevice, jwt.RegisteredClaims{
+	refreshToken, err := uc.tokenManager.GenerateToken(dto.NewUserClaims(user, userDevice, tenant, jwt.RegisteredClaims{
 		ExpiresAt: &jwt.NumericDate{
 			Time: time.Now().Add(constants.REFRESH_TOKEN_DURATION),
 		},
@@ -364,16 +647,11 @@ func (uc *userUsecase) SignIn(ctx context.Context, input *dto.SignInRequest) (*d
 		return nil, err
 	}
 
-	// Update user last_login_at
-	_, err = uc.userRepo.Update(ctx, user)
-	if err != nil {
-		return nil, err
-	}
-
 	return &dto.SignInResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		Device:       *dto.NewUserDevice(userDevice),
+		Tenant:       dto.NewTenantResponse(tenant),
 	}, nil
 }
 
@@ -415,6 +693,7 @@ func (uc *userUsecase) RefreshToken(ctx context.Context, input *dto.RefreshToken
 	accessToken, err := uc.tokenManager.GenerateToken(&dto.UserClaims{
 		User:   claims.User,
 		Device: claims.Device,
+		Tenant: claims.Tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: &jwt.NumericDate{
 				Time: time.Now().Add(constants.ACCESS_TOKEN_DURATION),
@@ -429,6 +708,7 @@ func (uc *userUsecase) RefreshToken(ctx context.Context, input *dto.RefreshToken
 	refreshToken, err := uc.tokenManager.GenerateToken(&dto.UserClaims{
 		User:   claims.User,
 		Device: claims.Device,
+		Tenant: claims.Tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: &jwt.NumericDate{
 				Time: time.Now().Add(constants.REFRESH_TOKEN_DURATION),
@@ -447,3 +727,266 @@ func (uc *userUsecase) RefreshToken(ctx context.Context, input *dto.RefreshToken
 		RefreshToken: refreshToken,
 	}, err
 }
+
+func (uc *userUsecase) SwitchTenant(ctx context.Context, input *dto.SwitchTenantRequest) (*dto.SwitchTenantResponse, error) {
+	actor := utils.GetUserFromContext(ctx)
+	if actor == nil {
+		return nil, errors.NewUnauthorizedError("")
+	}
+
+	tenant, err := uc.findMemberTenant(ctx, actor.ID, input.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	ctx = utils.WithTenant(ctx, dto.NewTenantResponseWithID(tenant))
+
+	user, err := uc.userRepo.FindById(ctx, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	device, _ := ctx.Value("device").(*dto.UserDevice)
+
+	// Access Token
+	accessToken, err := uc.tokenManager.GenerateToken(&dto.UserClaims{
+		User:   dto.NewUserResponseWithID(user),
+		Device: device,
+		Tenant: dto.NewTenantResponseWithID(tenant),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: &jwt.NumericDate{
+				Time: time.Now().Add(constants.ACCESS_TOKEN_DURATION),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh Token
+	refreshToken, err := uc.tokenManager.GenerateToken(&dto.UserClaims{
+		User:   dto.NewUserResponseWithID(user),
+		Device: device,
+		Tenant: dto.NewTenantResponseWithID(tenant),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: &jwt.NumericDate{
+				Time: time.Now().Add(constants.REFRESH_TOKEN_DURATION),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.SwitchTenantResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Tenant:       dto.NewTenantResponse(tenant),
+	}, nil
+}
+
+func (uc *userUsecase) CheckPermissions(ctx context.Context, input *dto.CheckPermissionsRequest) (*dto.CheckPermissionsResponse, error) {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, errors.NewUnauthorizedError("")
+	}
+
+	requirements := map[string]rbac.Requirement{}
+	invalids := map[string]any{}
+	for _, expr := range input.Permissions {
+		requirement, err := rbac.Parse(expr)
+		if err != nil {
+			invalids[expr] = []string{err.Error()}
+			continue
+		}
+
+		requirements[expr] = requirement
+	}
+
+	if len(invalids) > 0 {
+		return nil, errors.NewValidationError("Invalid permission expression", invalids)
+	}
+
+	result := dto.CheckPermissionsResponse{}
+	for expr, requirement := range requirements {
+		result[expr] = rbac.Granted(user, requirement)
+	}
+
+	return &result, nil
+}
+
+// ExplainPermission lists every role chain granting the permission to the
+// user, through their own role, one of their groups or an active role grant
+func (uc *userUsecase) ExplainPermission(ctx context.Context, input *dto.ExplainPermissionRequest) (*dto.ExplainPermissionResponse, error) {
+	e, err := uc.userRepo.FindByUuid(ctx, input.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := []*dto.PermissionGrantResponse{}
+	if e.Role != nil {
+		paths, err := uc.roleRepo.FindPermissionPaths(ctx, e.Role.Id, input.Permission)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			grants = append(grants, &dto.PermissionGrantResponse{
+				Source: constants.GRANT_SOURCE_ROLE,
+				Path:   path,
+			})
+		}
+	}
+
+	for _, g := range e.Groups {
+		for _, r := range g.Roles {
+			paths, err := uc.roleRepo.FindPermissionPaths(ctx, r.Id, input.Permission)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, path := range paths {
+				grants = append(grants, &dto.PermissionGrantResponse{
+					Source: constants.GRANT_SOURCE_GROUP,
+					Group:  g.Name,
+					Path:   path,
+				})
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, g := range e.Grants {
+		if !g.IsActive(now) {
+			continue
+		}
+
+		paths, err := uc.roleRepo.FindPermissionPaths(ctx, g.RoleId, input.Permission)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			grants = append(grants, &dto.PermissionGrantResponse{
+				Source: constants.GRANT_SOURCE_TEMPORARY,
+				Path:   path,
+			})
+		}
+	}
+
+	return &dto.ExplainPermissionResponse{
+		User:       e.Username,
+		Permission: input.Permission,
+		Granted:    len(grants) > 0,
+		Grants:     grants,
+	}, nil
+}
+
+// record logs a change of the user, before is nil for a created one and after
+// for a deleted one. secrets names the changed secrets the user does not hold.
+func (uc *userUsecase) record(ctx context.Context, action string, resourceUuid string, before any, after any, secrets ...string) error {
+	return audit.Record(ctx, uc.auditRepo, audit.Change{
+		Action:       action,
+		ResourceType: constants.AUDIT_RESOURCE_USER,
+		ResourceUuid: resourceUuid,
+		Before:       before,
+		After:        after,
+		Secrets:      secrets,
+	})
+}
+
+// actorId returns the id of the authenticated user for audit columns
+func (uc *userUsecase) actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
+
+// ensureAssignable keeps actors from assigning a role more powerful than their own
+func (uc *userUsecase) ensureAssignable(ctx context.Context, e *entity.Role) error {
+	return role.EnsureAssignable(ctx, e)
+}
+
+// ensureAccountEditable refuses changing the username, the email or the
+// password of another user who is also a live member of other tenants, the
+// account is shared by all of them while the actor only administers the
+// active one. Calls outside of a tenant are not restricted.
+func (uc *userUsecase) ensureAccountEditable(ctx context.Context, e *entity.User) error {
+	tenantId := utils.GetTenantIdFromContext(ctx)
+	actor := utils.GetUserFromContext(ctx)
+	if tenantId == 0 || (actor != nil && actor.ID == e.Id) {
+		return nil
+	}
+
+	tenants, err := uc.tenantRepo.FindAllByUserId(ctx, e.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Id != tenantId {
+			return errors.NewForbiddenError("The username, email and password of a user belonging to other tenants cannot be changed")
+		}
+	}
+
+	return nil
+}
+
+// checkCurrentPassword makes users changing their own password confirm the
+// current one, a session alone is not enough to take the account over
+func (uc *userUsecase) checkCurrentPassword(ctx context.Context, e *entity.User, current string) error {
+	actor := utils.GetUserFromContext(ctx)
+	if actor == nil || actor.ID != e.Id {
+		return nil
+	}
+
+	account, err := uc.userRepo.FindByUsernameOrEmail(ctx, e.Username)
+	if err != nil {
+		return err
+	}
+
+	err = utils.ComparePassword(account.Password, current)
+	if err != nil {
+		return errors.NewValidationError("Invalid current password", map[string]any{
+			"current_password": []string{"current_password does not match the password of the user"},
+		})
+	}
+
+	return nil
+}
+
+// ensureManageable keeps actors from taking over the account of a user more
+// powerful than them by changing its credentials
+func (uc *userUsecase) ensureManageable(ctx context.Context, e *entity.User) error {
+	return role.EnsureManageable(ctx, e)
+}
+
+// findMemberTenant returns the tenant matching ref (uuid or slug) among the
+// tenants of the user, or the first one when ref is empty
+func (uc *userUsecase) findMemberTenant(ctx context.Context, userId int, ref string) (*entity.Tenant, error) {
+	tenants, err := uc.tenantRepo.FindAllByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tenants) == 0 {
+		return nil, errors.NewForbiddenError("User does not belong to any tenant")
+	}
+
+	if ref == "" {
+		return tenants[0], nil
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Uuid == ref || tenant.Slug == ref {
+			return tenant, nil
+		}
+	}
+
+	return nil, errors.NewForbiddenError(fmt.Sprintf("User does not belong to tenant '%s'", ref))
+}
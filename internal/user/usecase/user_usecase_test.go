@@ -0,0 +1,342 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/config"
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	auditData "github.com/Adhiana46/echo-boilerplate/internal/audit/data"
+	auditRepository "github.com/Adhiana46/echo-boilerplate/internal/audit/repository"
+	grantData "github.com/Adhiana46/echo-boilerplate/internal/grant/data"
+	groupData "github.com/Adhiana46/echo-boilerplate/internal/group/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	permissionData "github.com/Adhiana46/echo-boilerplate/internal/permission/data"
+	roleData "github.com/Adhiana46/echo-boilerplate/internal/role/data"
+	roleRepository "github.com/Adhiana46/echo-boilerplate/internal/role/repository"
+	"github.com/Adhiana46/echo-boilerplate/internal/tenant"
+	tenantData "github.com/Adhiana46/echo-boilerplate/internal/tenant/data"
+	tenantRepository "github.com/Adhiana46/echo-boilerplate/internal/tenant/repository"
+	userData "github.com/Adhiana46/echo-boilerplate/internal/user/data"
+	userRepository "github.com/Adhiana46/echo-boilerplate/internal/user/repository"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// the repositories and the usecase are singletons, every test shares them
+// over one in-memory database and works in a tenant of its own
+var (
+	testUc          *userUsecase
+	testPermissions permission.PermissionPersistent
+	testTenants     tenant.TenantPersistent
+	testOnce        sync.Once
+)
+
+func newTestUsecase() *userUsecase {
+	testOnce.Do(func() {
+		db := memdb.New()
+		cacheCfg := &config.CacheConfig{}
+
+		testPermissions = permissionData.NewMemoryPermissionPersistent(db)
+		testTenants = tenantData.NewMemoryTenantPersistent(db)
+
+		roleRepo := roleRepository.NewRoleRepository(roleData.NewMemoryRolePersistent(db), nil, cacheCfg)
+		userRepo := userRepository.NewUserRepository(
+			userData.NewMemoryUserPersistent(db),
+			roleRepo,
+			groupData.NewMemoryGroupPersistent(db),
+			grantData.NewMemoryGrantPersistent(db),
+			nil,
+			cacheCfg,
+		)
+
+		testUc = NewUserUsecase(
+			userRepo,
+			roleRepo,
+			tenantRepository.NewTenantRepository(testTenants, nil),
+			userRepository.NewUserDeviceRepository(userData.NewMemoryUserDevicePersistent(db)),
+			auditRepository.NewAuditLogRepository(auditData.NewMemoryAuditLogPersistent(db)),
+			nil,
+			memdb.NewTxManager(db),
+		).(*userUsecase)
+	})
+
+	return testUc
+}
+
+// fixture creates the roles and the users of a test in a new tenant
+type fixture struct {
+	t   *testing.T
+	uc  *userUsecase
+	ctx context.Context
+}
+
+func newFixture(t *testing.T) *fixture {
+	uc := newTestUsecase()
+
+	f := &fixture{t: t, uc: uc}
+	f.ctx = f.tenant()
+
+	return f
+}
+
+// tenant creates a tenant and returns a context inside it, without actor
+func (f *fixture) tenant() context.Context {
+	key := uuid.NewString()
+	e, err := testTenants.Create(context.Background(), &entity.Tenant{
+		Uuid:      key,
+		Slug:      key,
+		Name:      "Tenant " + key,
+		CreatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	return utils.WithTenant(context.Background(), dto.NewTenantResponseWithID(e))
+}
+
+// role creates a role of the tenant granting the permissions, the permissions
+// missing from the catalog are added to it
+func (f *fixture) role(ctx context.Context, permissions ...string) *entity.Role {
+	rows := []*entity.Permission{}
+	for _, name := range permissions {
+		row, err := testPermissions.FindByName(ctx, name)
+		if err == sql.ErrNoRows {
+			row, err = testPermissions.Create(ctx, &entity.Permission{
+				Uuid:      uuid.NewString(),
+				Name:      name,
+				Type:      "action",
+				CreatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+				UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			})
+		}
+		if err != nil {
+			f.t.Fatal(err)
+		}
+
+		rows = append(rows, row)
+	}
+
+	e, err := f.uc.roleRepo.Create(ctx, &entity.Role{
+		Uuid:        uuid.NewString(),
+		Name:        uuid.NewString(),
+		Permissions: rows,
+		CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	return e
+}
+
+// user creates a member of the tenant of ctx whose password is "secret"
+func (f *fixture) user(ctx context.Context, r *entity.Role) *entity.User {
+	hashedPassword, err := utils.HashPassword("secret")
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	username := uuid.NewString()[:30]
+	e, err := f.uc.userRepo.Create(ctx, &entity.User{
+		Uuid:      uuid.NewString(),
+		Username:  username,
+		Email:     username + "@example.com",
+		Password:  hashedPassword,
+		Name:      "User " + username,
+		RoleId:    r.Id,
+		Status:    1,
+		CreatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	return e
+}
+
+// as returns ctx with the user signed in, as the user is in the tenant of ctx
+func (f *fixture) as(ctx context.Context, u *entity.User) context.Context {
+	e, err := f.uc.userRepo.FindById(ctx, u.Id)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	return context.WithValue(ctx, "user", dto.NewUserResponseWithID(e))
+}
+
+// update changes the password of the user, keeping the rest as it is
+func (f *fixture) update(ctx context.Context, u *entity.User, password string, currentPassword string) error {
+	e, err := f.uc.userRepo.FindById(f.ctx, u.Id)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.uc.UpdateUser(ctx, &dto.UpdateUserRequest{
+		Uuid:                 e.Uuid,
+		Username:             e.Username,
+		Email:                e.Email,
+		Name:                 e.Name,
+		Status:               e.Status,
+		Role:                 e.Role.Name,
+		Password:             password,
+		PasswordConfirmation: password,
+		CurrentPassword:      currentPassword,
+		Version:              e.Version,
+	})
+
+	return err
+}
+
+// signsIn reports whether the user has the password
+func (f *fixture) signsIn(u *entity.User, password string) bool {
+	account, err := f.uc.userRepo.FindByUsernameOrEmail(f.ctx, u.Username)
+	if err != nil {
+		return false
+	}
+
+	return utils.ComparePassword(account.Password, password) == nil
+}
+
+// statusOf returns the HTTP status of an error, 0 for none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if err == sql.ErrNoRows {
+		return 404
+	}
+
+	if customErr, ok := err.(errors.CustomError); ok {
+		return customErr.StatusCode()
+	}
+
+	return 500
+}
+
+func TestUpdateOwnPassword(t *testing.T) {
+	f := newFixture(t)
+	member := f.role(f.ctx)
+
+	tests := []struct {
+		name            string
+		currentPassword string
+		wantStatus      int
+	}{
+		{"without the current password", "", 400},
+		{"with a wrong current password", "wrong", 400},
+		{"with the current password", "secret", 0},
+	}
+
+	for _, tt := range tests {
+		alice := f.user(f.ctx, member)
+		ctx := f.as(f.ctx, alice)
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.update(ctx, alice, "changed", tt.currentPassword)
+			if statusOf(err) != tt.wantStatus {
+				t.Fatalf("got %v, want status %d", err, tt.wantStatus)
+			}
+
+			changed := f.signsIn(alice, "changed")
+			if changed != (tt.wantStatus == 0) {
+				t.Errorf("password changed: %v, want %v", changed, tt.wantStatus == 0)
+			}
+		})
+	}
+}
+
+func TestUpdatePasswordOfAnotherUser(t *testing.T) {
+	f := newFixture(t)
+	admin := f.user(f.ctx, f.role(f.ctx, "users.read", "users.update"))
+	bob := f.user(f.ctx, f.role(f.ctx))
+
+	err := f.update(f.as(f.ctx, admin), bob, "changed", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !f.signsIn(bob, "changed") {
+		t.Error("the password was not changed")
+	}
+}
+
+func TestUpdateAccountOfMemberOfAnotherTenant(t *testing.T) {
+	f := newFixture(t)
+	admin := f.user(f.ctx, f.role(f.ctx, "users.read", "users.update"))
+	bob := f.user(f.ctx, f.role(f.ctx))
+
+	// bob also belongs to another tenant, where they administer users
+	otherCtx := f.tenant()
+	otherAdmin := f.role(otherCtx, "users.read", "users.update")
+	_, err := f.uc.tenantRepo.AddUsers(otherCtx, utils.GetTenantIdFromContext(otherCtx), otherAdmin.Id, []string{bob.Uuid})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.update(f.as(f.ctx, admin), bob, "taken over", "")
+	if statusOf(err) != 403 {
+		t.Fatalf("got %v, want status 403", err)
+	}
+
+	if f.signsIn(bob, "taken over") {
+		t.Error("the password was changed from another tenant")
+	}
+
+	// bob can still change their own password
+	err = f.update(f.as(f.ctx, bob), bob, "changed", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !f.signsIn(bob, "changed") {
+		t.Error("the password was not changed")
+	}
+}
+
+func TestUpdatePasswordOfMorePowerfulUser(t *testing.T) {
+	f := newFixture(t)
+	admin := f.user(f.ctx, f.role(f.ctx, "users.read", "users.update"))
+	superAdmin := f.user(f.ctx, f.role(f.ctx, "users.read", "users.update", "roles.update"))
+	escalator := f.user(f.ctx, f.role(f.ctx, "users.read", "users.update", "roles.escalate"))
+
+	tests := []struct {
+		name       string
+		actor      *entity.User
+		target     *entity.User
+		wantStatus int
+	}{
+		{"granted more than the actor", admin, superAdmin, 403},
+		{"granted as much as the actor", superAdmin, admin, 0},
+		{"actor holding the escalation permission", escalator, superAdmin, 0},
+	}
+
+	for _, tt := range tests {
+		ctx := f.as(f.ctx, tt.actor)
+
+		t.Run(tt.name, func(t *testing.T) {
+			password := uuid.NewString()
+
+			err := f.update(ctx, tt.target, password, "")
+			if statusOf(err) != tt.wantStatus {
+				t.Fatalf("got %v, want status %d", err, tt.wantStatus)
+			}
+
+			changed := f.signsIn(tt.target, password)
+			if changed != (tt.wantStatus == 0) {
+				t.Errorf("password changed: %v, want %v", changed, tt.wantStatus == 0)
+			}
+		})
+	}
+}
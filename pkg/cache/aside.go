@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/logger"
+)
+
+// generationPrefix prefixes the keys holding the generation of a namespace
+const generationPrefix = "generation:"
+
+// generationSeq tells apart the generations bumped in the same nanosecond
+var generationSeq atomic.Uint64
+
+// Aside reads entities through the cache. A miss is loaded once however many
+// callers wait on it, then stored as JSON for the ttl.
+//
+// The keys are prefixed by the generation of the namespace and of the ones it
+// depends on, bumping a generation drops every key built on it at once. A role
+// caches its permissions, so the roles depend on the permissions and a renamed
+// permission drops the cached roles as well.
+type Aside struct {
+	cache     Cache
+	namespace string
+	dependsOn []string
+	ttl       time.Duration
+	flight    *flight
+	stats     *Stats
+}
+
+// NewAside returns a reader of the namespace, a nil cache or a ttl under a
+// second leaves every read to the loader
+func NewAside(c Cache, namespace string, ttl time.Duration, dependsOn ...string) *Aside {
+	return &Aside{
+		cache:     c,
+		namespace: namespace,
+		dependsOn: dependsOn,
+		ttl:       ttl,
+		flight:    newFlight(),
+		stats:     statsOf(namespace),
+	}
+}
+
+// Enabled reports whether the reads go through the cache
+func (a *Aside) Enabled() bool {
+	return a.cache != nil && a.ttl >= time.Second
+}
+
+// Invalidate drops every key of the namespace and of the namespaces depending
+// on it
+func (a *Aside) Invalidate() {
+	Invalidate(a.cache, a.namespace)
+}
+
+// Fetch returns the entity cached under the key, or loads and caches it. Every
+// caller decodes its own copy, so the entities returned are never shared. The
+// errors of the loader are returned as they are and nothing is cached for them.
+func Fetch[T any](a *Aside, key string, load func() (*T, error)) (*T, error) {
+	if !a.Enabled() {
+		return load()
+	}
+
+	prefix, err := a.prefix()
+	if err != nil {
+		a.fail(err)
+		return load()
+	}
+	key = prefix + key
+
+	value, err := a.cache.Get(key)
+	switch {
+	case err == nil:
+		if e, err := decode[T](value); err == nil {
+			a.stats.hits.Add(1)
+			return e, nil
+		}
+		// a value stored by an older shape of the entity is loaded again
+	case err != ErrCacheNil:
+		a.fail(err)
+	}
+	a.stats.misses.Add(1)
+
+	value, err, shared := a.flight.do(key, func() (string, error) {
+		e, err := load()
+		if err != nil {
+			return "", err
+		}
+
+		encoded, err := json.Marshal(e)
+		if err != nil {
+			return "", err
+		}
+
+		if err := a.cache.Set(key, string(encoded), int32(a.ttl/time.Second)); err != nil {
+			a.fail(err)
+		}
+
+		return string(encoded), nil
+	})
+	if shared {
+		a.stats.shared.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decode[T](value)
+}
+
+// Invalidate bumps the generations of the namespaces, the keys they prefixed
+// are no longer read and are left to expire
+func Invalidate(c Cache, namespaces ...string) {
+	if c == nil {
+		return
+	}
+
+	for _, namespace := range namespaces {
+		if _, err := bump(c, namespace); err != nil {
+			statsOf(namespace).errors.Add(1)
+			logger.Warnf("[Cache]: invalidating %s failed: %v", namespace, err)
+		}
+	}
+}
+
+// prefix builds the key prefix from the current generations, a namespace
+// without one yet gets its first
+func (a *Aside) prefix() (string, error) {
+	var b strings.Builder
+	b.WriteString(a.namespace)
+
+	for _, namespace := range append([]string{a.namespace}, a.dependsOn...) {
+		generation, err := a.cache.Get(generationPrefix + namespace)
+		if err == ErrCacheNil {
+			generation, err = bump(a.cache, namespace)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(":")
+		b.WriteString(generation)
+	}
+	b.WriteString(":")
+
+	return b.String(), nil
+}
+
+func (a *Aside) fail(err error) {
+	a.stats.errors.Add(1)
+	logger.Warnf("[Cache]: reading %s failed: %v", a.namespace, err)
+}
+
+// bump stores a new generation for the namespace, it does not expire
+func bump(c Cache, namespace string) (string, error) {
+	generation := strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatUint(generationSeq.Add(1), 36)
+
+	err := c.Set(generationPrefix+namespace, generation, 0)
+	if err != nil {
+		return "", err
+	}
+
+	return generation, nil
+}
+
+func decode[T any](value string) (*T, error) {
+	e := new(T)
+
+	err := json.Unmarshal([]byte(value), e)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
@@ -0,0 +1,50 @@
+package cache
+
+import "sync"
+
+// call is a load in flight, the callers waiting on it share its result
+type call struct {
+	wg    sync.WaitGroup
+	value string
+	err   error
+}
+
+// flight runs a single load per key at a time, so a missing key read by many
+// callers at once hits the database once
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func newFlight() *flight {
+	return &flight{
+		calls: map[string]*call{},
+	}
+}
+
+// do runs fn for the key unless a run is in flight, in which case it waits for
+// that run and returns its result with shared set
+func (f *flight) do(key string, fn func() (string, error)) (value string, err error, shared bool) {
+	f.mu.Lock()
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		c.wg.Wait()
+		return c.value, c.err, true
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.value, c.err = fn()
+
+	return c.value, c.err, false
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats counts the reads of a namespace. Shared counts the misses served by a
+// load another caller had in flight, Errors the cache failures that fell back
+// to the database.
+type Stats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+	shared atomic.Int64
+	errors atomic.Int64
+}
+
+// StatsSnapshot is the value of the counters of a namespace at a given time
+type StatsSnapshot struct {
+	Namespace string  `json:"namespace"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Shared    int64   `json:"shared"`
+	Errors    int64   `json:"errors"`
+	HitRatio  float64 `json:"hit_ratio"`
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*Stats{}
+)
+
+// statsOf returns the counters of the namespace, they are shared by every
+// reader of the namespace
+func statsOf(namespace string) *Stats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	s, ok := stats[namespace]
+	if !ok {
+		s = &Stats{}
+		stats[namespace] = s
+	}
+
+	return s
+}
+
+// CollectStats returns the counters of every namespace read so far, ordered by
+// namespace
+func CollectStats() []StatsSnapshot {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	snapshots := []StatsSnapshot{}
+	for namespace, s := range stats {
+		snapshot := StatsSnapshot{
+			Namespace: namespace,
+			Hits:      s.hits.Load(),
+			Misses:    s.misses.Load(),
+			Shared:    s.shared.Load(),
+			Errors:    s.errors.Load(),
+		}
+		if reads := snapshot.Hits + snapshot.Misses; reads > 0 {
+			snapshot.HitRatio = float64(snapshot.Hits) / float64(reads)
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Namespace < snapshots[j].Namespace
+	})
+
+	return snapshots
+}
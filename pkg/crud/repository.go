@@ -0,0 +1,535 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+	"github.com/Masterminds/squirrel"
+)
+
+// Table maps an entity to its table, columns are the db tags of the entity
+type Table struct {
+	Name string
+	// Key is the primary key column, generated by the database
+	Key string
+	// Columns are selected into the entity, an entry may be an expression
+	// with an alias such as "COALESCE(tenant_id, 0) AS tenant_id"
+	Columns []string
+	// Insertable and Updatable are the columns Create and Update write from
+	// the entity, audit columns excluded
+	Insertable []string
+	Updatable  []string
+	// Version is the optimistic locking column, Update only saves a row still
+	// at the version of the entity and bumps it
+	Version string
+	// Audited tables have created_at, created_by, updated_at, updated_by and,
+	// when soft-deleted, deleted_by columns, the ones the entity leaves unset
+	// are filled with the current time and user
+	Audited bool
+	// Resource whitelists the list requests, its SoftDelete column makes
+	// Destroy move rows to the trash
+	Resource *queryspec.Resource
+	// From is what the rows are read from when it is not the table itself,
+	// e.g. a join adding columns of another table. It is selected as Name,
+	// the writes still go to the table.
+	From func(ctx context.Context) squirrel.SelectBuilder
+	// Owner returns the columns Create sets from the context, e.g. the tenant
+	// of the row
+	Owner func(ctx context.Context) map[string]any
+	// Scope restricts every query but inserts, e.g. to the tenant of the
+	// context
+	Scope func(ctx context.Context) squirrel.Sqlizer
+	// Writable restricts the rows the writes change on top of Scope, e.g. to
+	// the rows a tenant owns among the ones it sees
+	Writable func(ctx context.Context) squirrel.Sqlizer
+}
+
+// Repository is the CRUD persistence of an entity, persistents embed it and
+// add or override the queries that are specific to them
+type Repository[T any] struct {
+	db    *txmanager.DB
+	table Table
+	sq    squirrel.StatementBuilderType
+}
+
+func New[T any](db *txmanager.DB, table Table) *Repository[T] {
+	if table.Key == "" {
+		table.Key = "id"
+	}
+
+	if table.Resource == nil {
+		table.Resource = &queryspec.Resource{}
+	}
+
+	return &Repository[T]{
+		db:    db,
+		table: table,
+		sq:    squirrel.StatementBuilder,
+	}
+}
+
+// DB is the connection of the repository, for custom queries
+func (r *Repository[T]) DB() *txmanager.DB {
+	return r.db
+}
+
+func (r *Repository[T]) Create(ctx context.Context, e *T) (*T, error) {
+	id, err := r.Insert(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), id)
+}
+
+// Insert is Create without reading the row back, it returns its key
+func (r *Repository[T]) Insert(ctx context.Context, e *T) (int, error) {
+	values := r.values(e, r.table.Insertable)
+
+	if r.table.Owner != nil {
+		for column, value := range r.table.Owner(ctx) {
+			values[column] = value
+		}
+	}
+
+	if r.table.Audited {
+		now, actor := time.Now(), actorId(ctx)
+
+		values["created_at"] = r.timeOr(e, "created_at", now)
+		values["created_by"] = r.actorOr(e, "created_by", actor)
+		values["updated_at"] = r.timeOr(e, "updated_at", now)
+		values["updated_by"] = r.actorOr(e, "updated_by", actor)
+	}
+
+	query, args, err := r.sq.Insert(r.table.Name).SetMap(values).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	return r.db.Insert(ctx, r.db, r.table.Key, query, args...)
+}
+
+// Update saves the live row of the entity. A missing row fails with
+// sql.ErrNoRows, with a version column a row changed since the entity was
+// read fails with errors.ErrVersionConflict.
+func (r *Repository[T]) Update(ctx context.Context, e *T) (*T, error) {
+	err := r.Save(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), r.key(e))
+}
+
+// Save is Update without reading the row back
+func (r *Repository[T]) Save(ctx context.Context, e *T) error {
+	values := r.values(e, r.table.Updatable)
+
+	if r.table.Audited {
+		values["updated_at"] = r.timeOr(e, "updated_at", time.Now())
+		values["updated_by"] = r.actorOr(e, "updated_by", actorId(ctx))
+	}
+
+	where := r.writeWhere(ctx, r.alive(), squirrel.Eq{r.table.Key: r.key(e)})
+
+	if r.table.Version != "" {
+		values[r.table.Version] = squirrel.Expr(r.table.Version + " + 1")
+		where = append(where, squirrel.Eq{r.table.Version: r.field(e, r.table.Version).Interface()})
+	}
+
+	query, args, err := r.sq.Update(r.table.Name).
+		SetMap(values).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	err = r.exec(ctx, query, args)
+	if err == sql.ErrNoRows && r.table.Version != "" {
+		return r.versionError(ctx, e)
+	}
+
+	return err
+}
+
+// versionError tells a row changed since the entity was read from a row the
+// writes do not see, e.g. one of another tenant
+func (r *Repository[T]) versionError(ctx context.Context, e *T) error {
+	q := r.sq.Select(fmt.Sprintf("COUNT(%s) AS numrows", r.table.Key)).
+		From(r.table.Name).
+		Where(r.writeWhere(ctx, r.alive(), squirrel.Eq{r.table.Key: r.key(e)}))
+
+	numrows, err := r.count(ctx, q)
+	if err != nil {
+		return err
+	}
+	if numrows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return errors.ErrVersionConflict
+}
+
+// Touch records a change made to the rows that belong to the live row of the
+// entity, such as its relations: the audit columns are set and the version
+// is bumped whatever the version of the entity. A missing row fails with
+// sql.ErrNoRows.
+func (r *Repository[T]) Touch(ctx context.Context, e *T) error {
+	values := map[string]any{}
+
+	if r.table.Audited {
+		values["updated_at"] = r.timeOr(e, "updated_at", time.Now())
+		values["updated_by"] = r.actorOr(e, "updated_by", actorId(ctx))
+	}
+
+	if r.table.Version != "" {
+		values[r.table.Version] = squirrel.Expr(r.table.Version + " + 1")
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	query, args, err := r.sq.Update(r.table.Name).
+		SetMap(values).
+		Where(r.writeWhere(ctx, r.alive(), squirrel.Eq{r.table.Key: r.key(e)})).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.exec(ctx, query, args)
+}
+
+// Destroy moves the row to the trash of soft-deleted tables and deletes it
+// otherwise, a missing row fails with sql.ErrNoRows
+func (r *Repository[T]) Destroy(ctx context.Context, e *T) error {
+	where := r.writeWhere(ctx, r.alive(), squirrel.Eq{r.table.Key: r.key(e)})
+
+	column := r.table.Resource.SoftDelete
+	if column == "" {
+		query, args, err := r.sq.Delete(r.table.Name).Where(where).ToSql()
+		if err != nil {
+			return err
+		}
+
+		return r.exec(ctx, query, args)
+	}
+
+	values := map[string]any{
+		column: r.timeOr(e, column, time.Now()),
+	}
+	if r.table.Audited {
+		values["deleted_by"] = r.actorOr(e, "deleted_by", actorId(ctx))
+	}
+
+	query, args, err := r.sq.Update(r.table.Name).SetMap(values).Where(where).ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.exec(ctx, query, args)
+}
+
+// Restore takes the row of the entity out of the trash
+func (r *Repository[T]) Restore(ctx context.Context, e *T) (*T, error) {
+	column := r.table.Resource.SoftDelete
+	if column == "" {
+		return nil, fmt.Errorf("table %s has no trash", r.table.Name)
+	}
+
+	values := map[string]any{
+		column: nil,
+	}
+	if r.table.Audited {
+		values["deleted_by"] = nil
+		values["updated_at"] = r.timeOr(e, "updated_at", time.Now())
+		values["updated_by"] = r.actorOr(e, "updated_by", actorId(ctx))
+	}
+
+	where := r.writeWhere(ctx, squirrel.NotEq{column: nil}, squirrel.Eq{r.table.Key: r.key(e)})
+
+	query, args, err := r.sq.Update(r.table.Name).SetMap(values).Where(where).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.exec(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindById(txmanager.OnPrimary(ctx), r.key(e))
+}
+
+// Purge deletes for good the rows trashed before the given time, it returns
+// their number
+func (r *Repository[T]) Purge(ctx context.Context, before time.Time) (int, error) {
+	column := r.table.Resource.SoftDelete
+	if column == "" {
+		return 0, nil
+	}
+
+	query, args, err := r.sq.Delete(r.table.Name).
+		Where(r.where(ctx, squirrel.Lt{column: before})).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := res.RowsAffected()
+
+	return int(purged), err
+}
+
+func (r *Repository[T]) FindById(ctx context.Context, id int) (*T, error) {
+	return r.FindBy(ctx, r.table.Key, id)
+}
+
+// FindBy returns the live row whose column equals the value
+func (r *Repository[T]) FindBy(ctx context.Context, column string, value any) (*T, error) {
+	return r.Get(ctx, r.Query(ctx).Where(squirrel.Eq{column: value}))
+}
+
+// FindTrashedBy returns the trashed row whose column equals the value
+func (r *Repository[T]) FindTrashedBy(ctx context.Context, column string, value any) (*T, error) {
+	trash := r.table.Resource.SoftDelete
+	if trash == "" {
+		return nil, sql.ErrNoRows
+	}
+
+	q := r.read(ctx, r.table.Columns...).
+		Where(r.where(ctx, squirrel.NotEq{trash: nil}, squirrel.Eq{column: value}))
+
+	return r.Get(ctx, q)
+}
+
+// FindAllIn returns the live rows whose column is one of the values
+func (r *Repository[T]) FindAllIn(ctx context.Context, column string, values any) ([]*T, error) {
+	if reflect.ValueOf(values).Len() == 0 {
+		return []*T{}, nil
+	}
+
+	return r.Select(ctx, r.Query(ctx).Where(squirrel.Eq{column: values}))
+}
+
+// FindAll returns a page of the list request, trashed rows included only when
+// the spec asks for them
+func (r *Repository[T]) FindAll(ctx context.Context, spec *queryspec.Spec) ([]*T, error) {
+	q, err := r.table.Resource.Select(r.scoped(ctx, r.read(ctx, r.table.Columns...)), spec)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Select(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryspec.Page(r.table.Resource, spec, rows)
+}
+
+// CountBy counts the live rows whose column equals the value
+func (r *Repository[T]) CountBy(ctx context.Context, column string, value any) (int, error) {
+	q := r.read(ctx, fmt.Sprintf("COUNT(%s) AS numrows", r.table.Key)).
+		Where(r.where(ctx, r.alive(), squirrel.Eq{column: value}))
+
+	return r.count(ctx, q)
+}
+
+// CountAll counts the rows of the list request
+func (r *Repository[T]) CountAll(ctx context.Context, spec *queryspec.Spec) (int, error) {
+	q := r.scoped(ctx, r.read(ctx, fmt.Sprintf("COUNT(%s) AS numrows", r.table.Key)))
+
+	q, err := r.table.Resource.Count(q, spec)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.count(ctx, q)
+}
+
+// Query selects the columns of the live rows in the scope, custom queries add
+// their conditions and run it with Get or Select
+func (r *Repository[T]) Query(ctx context.Context) squirrel.SelectBuilder {
+	return r.read(ctx, r.table.Columns...).
+		Where(r.where(ctx, r.alive()))
+}
+
+// read selects the columns from the table, or from the rows of From
+func (r *Repository[T]) read(ctx context.Context, columns ...string) squirrel.SelectBuilder {
+	q := r.sq.Select(columns...)
+	if r.table.From != nil {
+		return q.FromSelect(r.table.From(ctx), r.table.Name)
+	}
+
+	return q.From(r.table.Name)
+}
+
+func (r *Repository[T]) Get(ctx context.Context, q squirrel.SelectBuilder) (*T, error) {
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	e := new(T)
+	err = r.db.GetContext(ctx, e, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *Repository[T]) Select(ctx context.Context, q squirrel.SelectBuilder) ([]*T, error) {
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*T{}
+	err = r.db.SelectContext(ctx, &rows, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (r *Repository[T]) count(ctx context.Context, q squirrel.SelectBuilder) (int, error) {
+	query, args, err := q.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	numrows := 0
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&numrows)
+	if err != nil {
+		return 0, err
+	}
+
+	return numrows, nil
+}
+
+// exec runs a statement that must change a row, sql.ErrNoRows when none is
+func (r *Repository[T]) exec(ctx context.Context, query string, args []any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// alive leaves the trashed rows out, it is nil for tables without trash
+func (r *Repository[T]) alive() squirrel.Sqlizer {
+	if r.table.Resource.SoftDelete == "" {
+		return nil
+	}
+
+	return squirrel.Eq{r.table.Resource.SoftDelete: nil}
+}
+
+// where joins the scope and the conditions, nil conditions are skipped
+func (r *Repository[T]) where(ctx context.Context, conds ...squirrel.Sqlizer) squirrel.And {
+	where := squirrel.And{}
+
+	if r.table.Scope != nil {
+		where = append(where, r.table.Scope(ctx))
+	}
+
+	for _, cond := range conds {
+		if cond != nil {
+			where = append(where, cond)
+		}
+	}
+
+	return where
+}
+
+// writeWhere is where for the writes, restricted to the Writable rows
+func (r *Repository[T]) writeWhere(ctx context.Context, conds ...squirrel.Sqlizer) squirrel.And {
+	if r.table.Writable != nil {
+		conds = append(conds, r.table.Writable(ctx))
+	}
+
+	return r.where(ctx, conds...)
+}
+
+func (r *Repository[T]) scoped(ctx context.Context, q squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if r.table.Scope == nil {
+		return q
+	}
+
+	return q.Where(r.table.Scope(ctx))
+}
+
+// field returns the field of the entity mapped to the column
+func (r *Repository[T]) field(e *T, column string) reflect.Value {
+	return r.db.Mapper.FieldByName(reflect.ValueOf(e).Elem(), column)
+}
+
+func (r *Repository[T]) key(e *T) int {
+	return int(r.field(e, r.table.Key).Int())
+}
+
+func (r *Repository[T]) values(e *T, columns []string) map[string]any {
+	values := map[string]any{}
+	for _, column := range columns {
+		values[column] = r.field(e, column).Interface()
+	}
+
+	return values
+}
+
+// timeOr returns the time of the entity when it is set, now otherwise
+func (r *Repository[T]) timeOr(e *T, column string, now time.Time) sql.NullTime {
+	if t, ok := r.field(e, column).Interface().(sql.NullTime); ok && t.Valid {
+		return t
+	}
+
+	return sql.NullTime{Time: now, Valid: true}
+}
+
+// actorOr returns the user of the entity when it is set, the actor otherwise
+func (r *Repository[T]) actorOr(e *T, column string, actor sql.NullInt64) sql.NullInt64 {
+	if id, ok := r.field(e, column).Interface().(sql.NullInt64); ok && id.Valid {
+		return id
+	}
+
+	return actor
+}
+
+// actorId returns the id of the authenticated user, unset outside requests
+func actorId(ctx context.Context) sql.NullInt64 {
+	user := utils.GetUserFromContext(ctx)
+	if user == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{
+		Int64: int64(user.ID),
+		Valid: true,
+	}
+}
@@ -4,6 +4,7 @@ import "net/http"
 
 type ForbiddenError struct {
 	message string
+	errors  map[string]any
 }
 
 func NewForbiddenError(message string) CustomError {
@@ -12,6 +13,13 @@ func NewForbiddenError(message string) CustomError {
 	}
 }
 
+func NewForbiddenErrorWithErrors(message string, errors map[string]any) CustomError {
+	return &ForbiddenError{
+		message: message,
+		errors:  errors,
+	}
+}
+
 func (e *ForbiddenError) Error() string {
 	if e.message != "" {
 		return e.message
@@ -33,5 +41,5 @@ func (e *ForbiddenError) Message() string {
 }
 
 func (e *ForbiddenError) Errors() map[string]any {
-	return nil
+	return e.errors
 }
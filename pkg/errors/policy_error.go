@@ -0,0 +1,47 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// PolicyError is a Forbidden error raised when a policy denies an action on
+// a resource, it carries which action and resource were denied.
+type PolicyError struct {
+	resource string
+	action   string
+}
+
+func NewPolicyError(resource string, action string) CustomError {
+	return &PolicyError{
+		resource: resource,
+		action:   action,
+	}
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message()
+}
+
+func (e *PolicyError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+func (e *PolicyError) Message() string {
+	return fmt.Sprintf("Not allowed to %s %s", e.action, e.resource)
+}
+
+func (e *PolicyError) Errors() map[string]any {
+	return map[string]any{
+		"resource": e.resource,
+		"action":   e.action,
+	}
+}
+
+func (e *PolicyError) Resource() string {
+	return e.resource
+}
+
+func (e *PolicyError) Action() string {
+	return e.action
+}
@@ -0,0 +1,37 @@
+package errors
+
+import "net/http"
+
+type PreconditionRequiredError struct {
+	message string
+}
+
+func NewPreconditionRequiredError(message string) CustomError {
+	return &PreconditionRequiredError{
+		message: message,
+	}
+}
+
+func (e *PreconditionRequiredError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+
+	return http.StatusText(http.StatusPreconditionRequired)
+}
+
+func (e *PreconditionRequiredError) StatusCode() int {
+	return http.StatusPreconditionRequired
+}
+
+func (e *PreconditionRequiredError) Message() string {
+	if e.message != "" {
+		return e.message
+	}
+
+	return http.StatusText(http.StatusPreconditionRequired)
+}
+
+func (e *PreconditionRequiredError) Errors() map[string]any {
+	return nil
+}
@@ -0,0 +1,56 @@
+package errors
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrVersionConflict is returned by the conditional updates of the persistents
+// when the row is no longer at the version the update was based on
+var ErrVersionConflict = stdErrors.New("version conflict")
+
+// VersionConflictError rejects an update based on a stale version, it is a
+// 412 when the version came from If-Match and a 409 when it came from the
+// body. Current is 0 when the row changed between the read and the write.
+type VersionConflictError struct {
+	current      int
+	precondition bool
+}
+
+func NewVersionConflictError(current int, precondition bool) CustomError {
+	return &VersionConflictError{
+		current:      current,
+		precondition: precondition,
+	}
+}
+
+func (e *VersionConflictError) Error() string {
+	return e.Message()
+}
+
+func (e *VersionConflictError) StatusCode() int {
+	if e.precondition {
+		return http.StatusPreconditionFailed
+	}
+
+	return http.StatusConflict
+}
+
+func (e *VersionConflictError) Message() string {
+	if e.current > 0 {
+		return fmt.Sprintf("The resource was changed by someone else, it is now at version %d", e.current)
+	}
+
+	return "The resource was changed by someone else"
+}
+
+func (e *VersionConflictError) Errors() map[string]any {
+	if e.current == 0 {
+		return nil
+	}
+
+	return map[string]any{
+		"version": e.current,
+	}
+}
@@ -0,0 +1,48 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Event is something that happened in the application, Payload depends on
+// the event name
+type Event struct {
+	Name       string
+	Payload    any
+	OccurredAt time.Time
+}
+
+type Listener func(ctx context.Context, e Event)
+
+var (
+	listenersMu sync.RWMutex
+	listeners   = map[string][]Listener{}
+)
+
+// Subscribe registers a listener for the events with the given name
+func Subscribe(name string, listener Listener) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	listeners[name] = append(listeners[name], listener)
+}
+
+// Publish calls the listeners of the event synchronously, in the order they
+// subscribed
+func Publish(ctx context.Context, name string, payload any) {
+	listenersMu.RLock()
+	subscribed := listeners[name]
+	listenersMu.RUnlock()
+
+	e := Event{
+		Name:       name,
+		Payload:    payload,
+		OccurredAt: time.Now(),
+	}
+
+	for _, listener := range subscribed {
+		listener(ctx, e)
+	}
+}
@@ -0,0 +1,646 @@
+package memdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+// InsertUser inserts the account and returns its id, the role of the user is
+// set through its Member
+func (t *Tables) InsertUser(e *entity.User) (int, error) {
+	row := userRow(*e)
+	row.Version = 1
+	row.CreatedAt = orNow(row.CreatedAt)
+	row.UpdatedAt = orNow(row.UpdatedAt)
+
+	err := t.checkUser(row)
+	if err != nil {
+		return 0, err
+	}
+
+	row.Id = t.nextId("users")
+	t.Users[row.Id] = row
+
+	return row.Id, nil
+}
+
+// SaveUser replaces the account with the id of the user
+func (t *Tables) SaveUser(e *entity.User) error {
+	if _, ok := t.Users[e.Id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	row := userRow(*e)
+
+	err := t.checkUser(row)
+	if err != nil {
+		return err
+	}
+
+	t.Users[row.Id] = row
+
+	return nil
+}
+
+func (t *Tables) checkUser(row *entity.User) error {
+	for _, other := range t.Users {
+		if other.Id == row.Id {
+			continue
+		}
+
+		if other.Uuid == row.Uuid {
+			return uniqueViolation("users_uuid_key")
+		}
+
+		if other.DeletedAt.Valid || row.DeletedAt.Valid {
+			continue
+		}
+
+		if other.Username == row.Username {
+			return uniqueViolation("users_username_key")
+		}
+
+		if other.Email == row.Email {
+			return uniqueViolation("users_email_key")
+		}
+	}
+
+	return nil
+}
+
+// DeleteUser deletes the account with its devices, memberships, groups and
+// role grants
+func (t *Tables) DeleteUser(id int) {
+	for deviceId, device := range t.UserDevices {
+		if device.UserId == id {
+			delete(t.UserDevices, deviceId)
+		}
+	}
+
+	for key := range t.Members {
+		if key.UserId == id {
+			delete(t.Members, key)
+		}
+	}
+
+	for key := range t.GroupUsers {
+		if key.UserId == id {
+			delete(t.GroupUsers, key)
+		}
+	}
+
+	for grantId, grant := range t.RoleGrants {
+		if grant.UserId == id {
+			delete(t.RoleGrants, grantId)
+		}
+	}
+
+	delete(t.Users, id)
+}
+
+// PutMember inserts the membership, or replaces the one of the same user in
+// the same tenant
+func (t *Tables) PutMember(m Member) error {
+	if _, ok := t.Tenants[m.TenantId]; !ok {
+		return foreignKeyViolation("fk_tenant_users_tenant_id")
+	}
+
+	if _, ok := t.Users[m.UserId]; !ok {
+		return foreignKeyViolation("fk_tenant_users_user_id")
+	}
+
+	if _, ok := t.Roles[m.RoleId]; m.RoleId != 0 && !ok {
+		return foreignKeyViolation("fk_tenant_users_role_id")
+	}
+
+	key := MemberKey{TenantId: m.TenantId, UserId: m.UserId}
+	if existing, ok := t.Members[key]; ok {
+		m.CreatedAt = existing.CreatedAt
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+
+	t.Members[key] = &m
+
+	return nil
+}
+
+func (t *Tables) InsertUserDevice(e *entity.UserDevice) (int, error) {
+	row := *e
+	row.CreatedAt = orNow(row.CreatedAt)
+	row.UpdatedAt = orNow(row.UpdatedAt)
+
+	err := t.checkUserDevice(&row)
+	if err != nil {
+		return 0, err
+	}
+
+	row.Id = t.nextId("user_devices")
+	t.UserDevices[row.Id] = &row
+
+	return row.Id, nil
+}
+
+func (t *Tables) SaveUserDevice(e *entity.UserDevice) error {
+	if _, ok := t.UserDevices[e.Id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	row := *e
+
+	err := t.checkUserDevice(&row)
+	if err != nil {
+		return err
+	}
+
+	t.UserDevices[row.Id] = &row
+
+	return nil
+}
+
+func (t *Tables) checkUserDevice(row *entity.UserDevice) error {
+	if _, ok := t.Users[row.UserId]; !ok {
+		return foreignKeyViolation("fk_user_devices_user_id")
+	}
+
+	for _, other := range t.UserDevices {
+		if other.Id != row.Id && other.Uuid == row.Uuid {
+			return uniqueViolation("user_devices_uuid_key")
+		}
+	}
+
+	return nil
+}
+
+// InsertRole inserts the role without its permissions and parents, see
+// AddRolePermission and AddRoleParent
+func (t *Tables) InsertRole(e *entity.Role) (int, error) {
+	row := roleRow(*e)
+	row.Version = 1
+	row.CreatedAt = orNow(row.CreatedAt)
+	row.UpdatedAt = orNow(row.UpdatedAt)
+
+	err := t.checkRole(row)
+	if err != nil {
+		return 0, err
+	}
+
+	row.Id = t.nextId("roles")
+	t.Roles[row.Id] = row
+
+	return row.Id, nil
+}
+
+func (t *Tables) SaveRole(e *entity.Role) error {
+	if _, ok := t.Roles[e.Id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	row := roleRow(*e)
+
+	err := t.checkRole(row)
+	if err != nil {
+		return err
+	}
+
+	t.Roles[row.Id] = row
+
+	return nil
+}
+
+func (t *Tables) checkRole(row *entity.Role) error {
+	if _, ok := t.Tenants[row.TenantId]; row.TenantId != 0 && !ok {
+		return foreignKeyViolation("fk_roles_tenant_id")
+	}
+
+	for _, other := range t.Roles {
+		if other.Id == row.Id {
+			continue
+		}
+
+		if other.Uuid == row.Uuid {
+			return uniqueViolation("roles_uuid_key")
+		}
+
+		if !other.DeletedAt.Valid && !row.DeletedAt.Valid && other.TenantId == row.TenantId && other.Name == row.Name {
+			return uniqueViolation("roles_tenant_id_name_key")
+		}
+	}
+
+	return nil
+}
+
+// DeleteRole deletes the role with its permissions, parents, children and
+// grants. It fails while a member holds the role.
+func (t *Tables) DeleteRole(id int) error {
+	for _, m := range t.Members {
+		if m.RoleId == id {
+			return foreignKeyViolation("fk_tenant_users_role_id")
+		}
+	}
+
+	for key := range t.RolePermissions {
+		if key.RoleId == id {
+			delete(t.RolePermissions, key)
+		}
+	}
+
+	for key := range t.RoleParents {
+		if key.RoleId == id || key.ParentId == id {
+			delete(t.RoleParents, key)
+		}
+	}
+
+	for key := range t.GroupRoles {
+		if key.RoleId == id {
+			delete(t.GroupRoles, key)
+		}
+	}
+
+	for grantId, grant := range t.RoleGrants {
+		if grant.RoleId == id {
+			delete(t.RoleGrants, grantId)
+		}
+	}
+
+	delete(t.Roles, id)
+
+	return nil
+}
+
+// AddRolePermission grants the permission to the role, a permission already
+// granted is kept
+func (t *Tables) AddRolePermission(roleId int, permissionId int) error {
+	if _, ok := t.Roles[roleId]; !ok {
+		return foreignKeyViolation("fk_role_permissions_role_id")
+	}
+
+	if _, ok := t.Permissions[permissionId]; !ok {
+		return foreignKeyViolation("fk_role_permissions_permission_id")
+	}
+
+	t.RolePermissions[RolePermission{RoleId: roleId, PermissionId: permissionId}] = true
+
+	return nil
+}
+
+func (t *Tables) AddRoleParent(roleId int, parentId int) error {
+	if _, ok := t.Roles[roleId]; !ok {
+		return foreignKeyViolation("fk_role_parents_role_id")
+	}
+
+	if _, ok := t.Roles[parentId]; !ok {
+		return foreignKeyViolation("fk_role_parents_parent_id")
+	}
+
+	key := RoleParent{RoleId: roleId, ParentId: parentId}
+	if t.RoleParents[key] {
+		return uniqueViolation("role_parents_pkey")
+	}
+
+	t.RoleParents[key] = true
+
+	return nil
+}
+
+func (t *Tables) InsertPermission(e *entity.Permission) (int, error) {
+	row := permissionRow(*e)
+	row.Version = 1
+	row.CreatedAt = orNow(row.CreatedAt)
+	row.UpdatedAt = orNow(row.UpdatedAt)
+
+	err := t.checkPermission(row)
+	if err != nil {
+		return 0, err
+	}
+
+	row.Id = t.nextId("permissions")
+	t.Permissions[row.Id] = row
+
+	return row.Id, nil
+}
+
+func (t *Tables) SavePermission(e *entity.Permission) error {
+	if _, ok := t.Permissions[e.Id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	row := permissionRow(*e)
+
+	err := t.checkPermission(row)
+	if err != nil {
+		return err
+	}
+
+	t.Permissions[row.Id] = row
+
+	return nil
+}
+
+func (t *Tables) checkPermission(row *entity.Permission) error {
+	for _, other := range t.Permissions {
+		if other.Id == row.Id {
+			continue
+		}
+
+		if other.Uuid == row.Uuid {
+			return uniqueViolation("permissions_uuid_key")
+		}
+
+		if !other.DeletedAt.Valid && !row.DeletedAt.Valid && other.Name == row.Name {
+			return uniqueViolation("permissions_name_key")
+		}
+	}
+
+	return nil
+}
+
+// DeletePermission deletes the permission and revokes it from every role
+func (t *Tables) DeletePermission(id int) {
+	for key := range t.RolePermissions {
+		if key.PermissionId == id {
+			delete(t.RolePermissions, key)
+		}
+	}
+
+	delete(t.Permissions, id)
+}
+
+func (t *Tables) InsertTenant(e *entity.Tenant) (int, error) {
+	row := *e
+	row.CreatedAt = orNow(row.CreatedAt)
+	row.UpdatedAt = orNow(row.UpdatedAt)
+
+	err := t.checkTenant(&row)
+	if err != nil {
+		return 0, err
+	}
+
+	row.Id = t.nextId("tenants")
+	t.Tenants[row.Id] = &row
+
+	return row.Id, nil
+}
+
+func (t *Tables) SaveTenant(e *entity.Tenant) error {
+	if _, ok := t.Tenants[e.Id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	row := *e
+
+	err := t.checkTenant(&row)
+	if err != nil {
+		return err
+	}
+
+	t.Tenants[row.Id] = &row
+
+	return nil
+}
+
+func (t *Tables) checkTenant(row *entity.Tenant) error {
+	for _, other := range t.Tenants {
+		if other.Id == row.Id {
+			continue
+		}
+
+		if other.Uuid == row.Uuid {
+			return uniqueViolation("tenants_uuid_key")
+		}
+
+		if other.Slug == row.Slug {
+			return uniqueViolation("tenants_slug_key")
+		}
+	}
+
+	return nil
+}
+
+// DeleteTenant deletes the tenant with its memberships, roles, groups and
+// role grants
+func (t *Tables) DeleteTenant(id int) error {
+	for key := range t.Members {
+		if key.TenantId == id {
+			delete(t.Members, key)
+		}
+	}
+
+	for groupId, group := range t.Groups {
+		if group.TenantId == id {
+			t.DeleteGroup(groupId)
+		}
+	}
+
+	for grantId, grant := range t.RoleGrants {
+		if grant.TenantId == id {
+			delete(t.RoleGrants, grantId)
+		}
+	}
+
+	for roleId, role := range t.Roles {
+		if role.TenantId != id {
+			continue
+		}
+
+		err := t.DeleteRole(roleId)
+		if err != nil {
+			return err
+		}
+	}
+
+	delete(t.Tenants, id)
+
+	return nil
+}
+
+// InsertGroup inserts the group without its roles, see AddGroupRole
+func (t *Tables) InsertGroup(e *entity.Group) (int, error) {
+	row := *e
+	row.Roles = nil
+	row.CreatedAt = orNow(row.CreatedAt)
+	row.UpdatedAt = orNow(row.UpdatedAt)
+
+	err := t.checkGroup(&row)
+	if err != nil {
+		return 0, err
+	}
+
+	row.Id = t.nextId("groups")
+	t.Groups[row.Id] = &row
+
+	return row.Id, nil
+}
+
+func (t *Tables) SaveGroup(e *entity.Group) error {
+	if _, ok := t.Groups[e.Id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	row := *e
+	row.Roles = nil
+
+	err := t.checkGroup(&row)
+	if err != nil {
+		return err
+	}
+
+	t.Groups[row.Id] = &row
+
+	return nil
+}
+
+func (t *Tables) checkGroup(row *entity.Group) error {
+	if _, ok := t.Tenants[row.TenantId]; !ok {
+		return foreignKeyViolation("fk_groups_tenant_id")
+	}
+
+	for _, other := range t.Groups {
+		if other.Id == row.Id {
+			continue
+		}
+
+		if other.Uuid == row.Uuid {
+			return uniqueViolation("groups_uuid_key")
+		}
+
+		if other.TenantId == row.TenantId && other.Name == row.Name {
+			return uniqueViolation("uq_groups_tenant_id_name")
+		}
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes the group with its members and roles
+func (t *Tables) DeleteGroup(id int) {
+	for key := range t.GroupUsers {
+		if key.GroupId == id {
+			delete(t.GroupUsers, key)
+		}
+	}
+
+	for key := range t.GroupRoles {
+		if key.GroupId == id {
+			delete(t.GroupRoles, key)
+		}
+	}
+
+	delete(t.Groups, id)
+}
+
+// AddGroupUser adds the user to the group, it reports false when the user
+// already is in the group
+func (t *Tables) AddGroupUser(groupId int, userId int) (bool, error) {
+	if _, ok := t.Groups[groupId]; !ok {
+		return false, foreignKeyViolation("fk_group_users_group_id")
+	}
+
+	if _, ok := t.Users[userId]; !ok {
+		return false, foreignKeyViolation("fk_group_users_user_id")
+	}
+
+	key := GroupUser{GroupId: groupId, UserId: userId}
+	if t.GroupUsers[key] {
+		return false, nil
+	}
+
+	t.GroupUsers[key] = true
+
+	return true, nil
+}
+
+// AddGroupRole grants the role to the group, a role already granted is kept
+func (t *Tables) AddGroupRole(groupId int, roleId int) error {
+	if _, ok := t.Groups[groupId]; !ok {
+		return foreignKeyViolation("fk_group_roles_group_id")
+	}
+
+	if _, ok := t.Roles[roleId]; !ok {
+		return foreignKeyViolation("fk_group_roles_role_id")
+	}
+
+	t.GroupRoles[GroupRole{GroupId: groupId, RoleId: roleId}] = true
+
+	return nil
+}
+
+func (t *Tables) InsertRoleGrant(e *entity.RoleGrant) (int, error) {
+	row := *e
+	row.Role = nil
+	row.CreatedAt = orNow(row.CreatedAt)
+
+	if _, ok := t.Tenants[row.TenantId]; !ok {
+		return 0, foreignKeyViolation("fk_role_grants_tenant_id")
+	}
+
+	if _, ok := t.Users[row.UserId]; !ok {
+		return 0, foreignKeyViolation("fk_role_grants_user_id")
+	}
+
+	if _, ok := t.Roles[row.RoleId]; !ok {
+		return 0, foreignKeyViolation("fk_role_grants_role_id")
+	}
+
+	for _, other := range t.RoleGrants {
+		if other.Uuid == row.Uuid {
+			return 0, uniqueViolation("role_grants_uuid_key")
+		}
+	}
+
+	row.Id = t.nextId("role_grants")
+	t.RoleGrants[row.Id] = &row
+
+	return row.Id, nil
+}
+
+// InsertAuditLog appends the log, logs have no foreign keys and are never
+// changed afterwards
+func (t *Tables) InsertAuditLog(e *entity.AuditLog) (int, error) {
+	row := *e
+	row.Actor = nil
+	row.CreatedAt = orNow(row.CreatedAt)
+
+	for _, other := range t.AuditLogs {
+		if other.Uuid == row.Uuid {
+			return 0, uniqueViolation("audit_logs_uuid_key")
+		}
+	}
+
+	row.Id = t.nextId("audit_logs")
+	t.AuditLogs[row.Id] = &row
+
+	return row.Id, nil
+}
+
+// userRow is the account of a user, without the relations and the role
+func userRow(e entity.User) *entity.User {
+	e.RoleId = 0
+	e.Role = nil
+	e.Groups = nil
+	e.Grants = nil
+	e.Creator = nil
+	e.Updater = nil
+
+	return &e
+}
+
+func roleRow(e entity.Role) *entity.Role {
+	e.Permissions = nil
+	e.Parents = nil
+	e.InheritedPermissions = nil
+	e.Creator = nil
+	e.Updater = nil
+
+	return &e
+}
+
+func permissionRow(e entity.Permission) *entity.Permission {
+	e.Creator = nil
+	e.Updater = nil
+
+	return &e
+}
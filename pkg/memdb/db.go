@@ -0,0 +1,124 @@
+package memdb
+
+import (
+	"context"
+	"sync"
+
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
+)
+
+// DB keeps the tables of the application in memory, for the tests and the
+// demos that run without Postgres. Statements run one at a time and a
+// transaction holds the whole database until it ends, so every transaction is
+// serializable and none ever fails to serialize.
+type DB struct {
+	mu     sync.Mutex
+	tables *Tables
+}
+
+type txKey struct{}
+
+// tx is a transaction in progress on db, mu orders the statements run by the
+// goroutines sharing its context
+type tx struct {
+	db *DB
+	mu sync.Mutex
+}
+
+func New() *DB {
+	return &DB{
+		tables: newTables(),
+	}
+}
+
+// View runs a statement that only reads the tables
+func (db *DB) View(ctx context.Context, fn func(t *Tables) error) error {
+	unlock := db.lock(ctx)
+	defer unlock()
+
+	return fn(db.tables)
+}
+
+// Update runs a statement that changes the tables, the changes are undone
+// when fn fails so that a statement applies entirely or not at all
+func (db *DB) Update(ctx context.Context, fn func(t *Tables) error) error {
+	unlock := db.lock(ctx)
+	defer unlock()
+
+	snapshot := db.tables.clone()
+
+	err := fn(db.tables)
+	if err != nil {
+		db.tables = snapshot
+	}
+
+	return err
+}
+
+// lock takes the database, or only the transaction of the context when it
+// already holds the database
+func (db *DB) lock(ctx context.Context) func() {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && t.db == db {
+		t.mu.Lock()
+		return t.mu.Unlock
+	}
+
+	db.mu.Lock()
+	return db.mu.Unlock
+}
+
+var (
+	txManagerInstance     *txManager
+	txManagerInstanceOnce sync.Once
+)
+
+type txManager struct {
+	db *DB
+}
+
+// NewTxManager returns the transactions of the database, they carry a
+// txmanager.Detached context so that the repositories defer their cache
+// invalidations to the commit as they do on Postgres
+func NewTxManager(db *DB) txmanager.TxManager {
+	txManagerInstanceOnce.Do(func() {
+		txManagerInstance = &txManager{
+			db: db,
+		}
+	})
+
+	return txManagerInstance
+}
+
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && t.db == m.db {
+		return fn(ctx)
+	}
+
+	m.db.mu.Lock()
+
+	snapshot := m.db.tables.clone()
+	rollback := func() {
+		m.db.tables = snapshot
+		m.db.mu.Unlock()
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	txCtx, commit := txmanager.Detached(context.WithValue(ctx, txKey{}, &tx{db: m.db}))
+
+	err := fn(txCtx)
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	m.db.mu.Unlock()
+	commit()
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package memdb
+
+import "fmt"
+
+// ConstraintError is a statement breaking a unique or a foreign key
+// constraint of the schema, Constraint is named as in database/migrations
+type ConstraintError struct {
+	Constraint string
+	Unique     bool
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Unique {
+		return fmt.Sprintf("duplicate key value violates unique constraint \"%s\"", e.Constraint)
+	}
+
+	return fmt.Sprintf("violates foreign key constraint \"%s\"", e.Constraint)
+}
+
+func uniqueViolation(constraint string) error {
+	return &ConstraintError{Constraint: constraint, Unique: true}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &ConstraintError{Constraint: constraint}
+}
@@ -0,0 +1,179 @@
+package memdb
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/entity"
+)
+
+// Member is a row of tenant_users, a user in a tenant with their role there.
+// RoleId is 0 for NULL.
+type Member struct {
+	TenantId  int
+	UserId    int
+	RoleId    int
+	CreatedAt time.Time
+	DeletedAt sql.NullTime
+	DeletedBy sql.NullInt64
+}
+
+type MemberKey struct {
+	TenantId int
+	UserId   int
+}
+
+type RolePermission struct {
+	RoleId       int
+	PermissionId int
+}
+
+type RoleParent struct {
+	RoleId   int
+	ParentId int
+}
+
+type GroupUser struct {
+	GroupId int
+	UserId  int
+}
+
+type GroupRole struct {
+	GroupId int
+	RoleId  int
+}
+
+// Tables are the rows of the schema of database/migrations keyed by primary
+// key, entities are stored without their relations. Users are the accounts:
+// their DeletedAt is the trash of the account and their RoleId is unused, the
+// role and the trash in a tenant are those of the Member.
+//
+// The maps are read directly, rows are written through the Insert, Save and
+// Delete methods which check the constraints and cascade like the schema does.
+type Tables struct {
+	Users           map[int]*entity.User
+	Members         map[MemberKey]*Member
+	UserDevices     map[int]*entity.UserDevice
+	Roles           map[int]*entity.Role
+	RolePermissions map[RolePermission]bool
+	RoleParents     map[RoleParent]bool
+	Permissions     map[int]*entity.Permission
+	Tenants         map[int]*entity.Tenant
+	Groups          map[int]*entity.Group
+	GroupUsers      map[GroupUser]bool
+	GroupRoles      map[GroupRole]bool
+	RoleGrants      map[int]*entity.RoleGrant
+	AuditLogs       map[int]*entity.AuditLog
+
+	// sequences are the last ids given by table, they never go back
+	sequences map[string]int
+}
+
+func newTables() *Tables {
+	return &Tables{
+		Users:           map[int]*entity.User{},
+		Members:         map[MemberKey]*Member{},
+		UserDevices:     map[int]*entity.UserDevice{},
+		Roles:           map[int]*entity.Role{},
+		RolePermissions: map[RolePermission]bool{},
+		RoleParents:     map[RoleParent]bool{},
+		Permissions:     map[int]*entity.Permission{},
+		Tenants:         map[int]*entity.Tenant{},
+		Groups:          map[int]*entity.Group{},
+		GroupUsers:      map[GroupUser]bool{},
+		GroupRoles:      map[GroupRole]bool{},
+		RoleGrants:      map[int]*entity.RoleGrant{},
+		AuditLogs:       map[int]*entity.AuditLog{},
+		sequences:       map[string]int{},
+	}
+}
+
+// clone copies the tables, rows included, for a transaction to roll back to
+func (t *Tables) clone() *Tables {
+	c := &Tables{
+		Users:           cloneRows(t.Users),
+		Members:         cloneRows(t.Members),
+		UserDevices:     cloneRows(t.UserDevices),
+		Roles:           cloneRows(t.Roles),
+		RolePermissions: cloneSet(t.RolePermissions),
+		RoleParents:     cloneSet(t.RoleParents),
+		Permissions:     cloneRows(t.Permissions),
+		Tenants:         cloneRows(t.Tenants),
+		Groups:          cloneRows(t.Groups),
+		GroupUsers:      cloneSet(t.GroupUsers),
+		GroupRoles:      cloneSet(t.GroupRoles),
+		RoleGrants:      cloneRows(t.RoleGrants),
+		AuditLogs:       cloneRows(t.AuditLogs),
+		sequences:       map[string]int{},
+	}
+
+	for table, id := range t.sequences {
+		c.sequences[table] = id
+	}
+
+	return c
+}
+
+func (t *Tables) nextId(table string) int {
+	t.sequences[table]++
+	return t.sequences[table]
+}
+
+// Rows returns copies of the rows ordered by id, for the caller to change
+// freely
+func Rows[T any](rows map[int]*T) []*T {
+	ids := make([]int, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	copies := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		row := *rows[id]
+		copies = append(copies, &row)
+	}
+
+	return copies
+}
+
+// Row returns a copy of the row with the id, nil when there is none
+func Row[T any](rows map[int]*T, id int) *T {
+	row, ok := rows[id]
+	if !ok {
+		return nil
+	}
+
+	copied := *row
+
+	return &copied
+}
+
+func cloneRows[K comparable, V any](rows map[K]*V) map[K]*V {
+	c := make(map[K]*V, len(rows))
+	for key, row := range rows {
+		copied := *row
+		c[key] = &copied
+	}
+
+	return c
+}
+
+func cloneSet[K comparable](set map[K]bool) map[K]bool {
+	c := make(map[K]bool, len(set))
+	for key := range set {
+		c[key] = true
+	}
+
+	return c
+}
+
+// orNow is the CURRENT_TIMESTAMP default of the audit columns
+func orNow(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		return t
+	}
+
+	return sql.NullTime{Time: time.Now(), Valid: true}
+}
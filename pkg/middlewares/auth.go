@@ -3,8 +3,10 @@ package middlewares
 import (
 	"context"
 
+	"github.com/Adhiana46/echo-boilerplate/constants"
 	"github.com/Adhiana46/echo-boilerplate/dto"
 	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/rbac"
 	tokenmanager "github.com/Adhiana46/echo-boilerplate/pkg/token-manager"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	"github.com/golang-jwt/jwt/v4"
@@ -12,8 +14,11 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// Authenticate validates the token and puts the user, the device and the
+// active tenant of its claims in the request context. A tenant header that
+// does not match the tenant of the token is rejected.
 func Authenticate(tokenManager *tokenmanager.TokenManager) echo.MiddlewareFunc {
-	return echojwt.WithConfig(echojwt.Config{
+	jwtMiddleware := echojwt.WithConfig(echojwt.Config{
 		ErrorHandler: func(c echo.Context, err error) error {
 			if err != nil {
 				return errors.NewUnauthorizedError(err.Error())
@@ -38,15 +43,51 @@ func Authenticate(tokenManager *tokenmanager.TokenManager) echo.MiddlewareFunc {
 			c.Set("device", claims.Device)
 
 			// Set request context
+			c.Set("tenant", claims.Tenant)
+
 			ctx := context.WithValue(c.Request().Context(), "user", claims.User)
 			ctx = context.WithValue(ctx, "device", claims.Device)
+			ctx = utils.WithTenant(ctx, claims.Tenant)
 
 			c.SetRequest(c.Request().WithContext(ctx))
 		},
 	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return jwtMiddleware(func(c echo.Context) error {
+			ref := c.Request().Header.Get(constants.TENANT_HEADER)
+			if ref == "" {
+				return next(c)
+			}
+
+			tenant := utils.GetTenantFromContext(c.Request().Context())
+			if tenant == nil || (tenant.Uuid != ref && tenant.Slug != ref) {
+				return errors.NewForbiddenError("The token was not issued for this tenant")
+			}
+
+			return next(c)
+		})
+	}
 }
 
+// Permissions requires every given permission, each argument may also be an
+// expression such as "users.read || users.admin". Expressions are parsed once
+// when the route is registered, invalid ones panic at startup.
 func Permissions(permissions ...string) echo.MiddlewareFunc {
+	requirements := []rbac.Requirement{}
+	for _, permission := range permissions {
+		requirements = append(requirements, rbac.MustParse(permission))
+	}
+
+	return Requires(rbac.AllOf(requirements...))
+}
+
+// Requires checks a permission requirement against the authenticated user,
+// the 403 payload lists which parts of the requirement failed. Referenced
+// permissions are declared so they can be synced and verified at startup.
+func Requires(requirement rbac.Requirement) echo.MiddlewareFunc {
+	rbac.Declare(requirement.Permissions()...)
+
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			ctx := c.Request().Context()
@@ -56,25 +97,16 @@ func Permissions(permissions ...string) echo.MiddlewareFunc {
 				return errors.NewUnauthorizedError("")
 			}
 
-			// check if user does not have role
-			if user.Role == nil {
+			// check if user does not have role, directly or through a group
+			if user.Role == nil && len(user.Groups) == 0 {
 				return errors.NewForbiddenError("")
 			}
 
-			userPermissionsMap := map[string]string{}
-			for _, userPermission := range user.Role.Permissions {
-				userPermissionsMap[userPermission] = userPermission
-			}
-
-			hasPermission := true
-			for _, permission := range permissions {
-				_, isExists := userPermissionsMap[permission]
-
-				hasPermission = hasPermission && isExists
-			}
-
-			if !hasPermission {
-				return errors.NewForbiddenError("")
+			if !rbac.Granted(user, requirement) {
+				return errors.NewForbiddenErrorWithErrors("", map[string]any{
+					"required": requirement.String(),
+					"unmet":    rbac.Unmet(requirement, rbac.UserHas(user)),
+				})
 			}
 
 			return next(c)
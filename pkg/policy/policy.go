@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/rbac"
+	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
+)
+
+// Rule decides whether the actor may perform an action on the loaded resource
+type Rule[T any] func(ctx context.Context, actor *dto.UserResponseWithID, resource T) bool
+
+// Policy holds the rules of one resource type, keyed by action. An action is
+// allowed when at least one of its rules passes, actions without rules are
+// always denied.
+type Policy[T any] struct {
+	resource string
+	rules    map[string][]Rule[T]
+}
+
+func New[T any](resource string) *Policy[T] {
+	return &Policy[T]{
+		resource: resource,
+		rules:    map[string][]Rule[T]{},
+	}
+}
+
+// Allow registers rules for an action, calling it again appends more rules
+func (p *Policy[T]) Allow(action string, rules ...Rule[T]) *Policy[T] {
+	p.rules[action] = append(p.rules[action], rules...)
+
+	return p
+}
+
+// Can reports whether the actor may perform the action on the resource
+func (p *Policy[T]) Can(ctx context.Context, actor *dto.UserResponseWithID, action string, resource T) bool {
+	if actor == nil {
+		return false
+	}
+
+	for _, rule := range p.rules[action] {
+		if rule(ctx, actor, resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authorize checks the actor from the request context, it returns an
+// UnauthorizedError without actor and a PolicyError when denied.
+func (p *Policy[T]) Authorize(ctx context.Context, action string, resource T) error {
+	actor := utils.GetUserFromContext(ctx)
+	if actor == nil {
+		return errors.NewUnauthorizedError("")
+	}
+
+	if !p.Can(ctx, actor, action, resource) {
+		return errors.NewPolicyError(p.resource, action)
+	}
+
+	return nil
+}
+
+// AuthorizeFound is Authorize for a resource looked up by the caller. A
+// resource the actor may not read is reported as not found (sql.ErrNoRows)
+// rather than forbidden, so denials do not tell which resources exist.
+func (p *Policy[T]) AuthorizeFound(ctx context.Context, action string, resource T) error {
+	actor := utils.GetUserFromContext(ctx)
+	if actor != nil && !p.Can(ctx, actor, "read", resource) {
+		return sql.ErrNoRows
+	}
+
+	return p.Authorize(ctx, action, resource)
+}
+
+// HasPermission passes when the actor's role grants the permission, the
+// permission is declared like the ones referenced by routes
+func HasPermission[T any](name string) Rule[T] {
+	rbac.Declare(name)
+
+	return func(ctx context.Context, actor *dto.UserResponseWithID, resource T) bool {
+		return rbac.UserHas(actor)(name)
+	}
+}
+
+// IsOwner passes when the resource was created by the actor
+func IsOwner[T any](createdBy func(resource T) sql.NullInt64) Rule[T] {
+	return func(ctx context.Context, actor *dto.UserResponseWithID, resource T) bool {
+		owner := createdBy(resource)
+
+		return owner.Valid && int(owner.Int64) == actor.ID
+	}
+}
+
+// Attribute wraps a custom predicate over the actor and the resource
+func Attribute[T any](fn func(actor *dto.UserResponseWithID, resource T) bool) Rule[T] {
+	return func(ctx context.Context, actor *dto.UserResponseWithID, resource T) bool {
+		return fn(actor, resource)
+	}
+}
+
+// All passes when every rule passes
+func All[T any](rules ...Rule[T]) Rule[T] {
+	return func(ctx context.Context, actor *dto.UserResponseWithID, resource T) bool {
+		for _, rule := range rules {
+			if !rule(ctx, actor, resource) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Not inverts a rule
+func Not[T any](rule Rule[T]) Rule[T] {
+	return func(ctx context.Context, actor *dto.UserResponseWithID, resource T) bool {
+		return !rule(ctx, actor, resource)
+	}
+}
@@ -0,0 +1,285 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+)
+
+type document struct {
+	CreatedBy sql.NullInt64
+	Public    bool
+}
+
+var isPublic = Attribute(func(actor *dto.UserResponseWithID, d *document) bool {
+	return d.Public
+})
+
+var isAuthor = IsOwner(func(d *document) sql.NullInt64 {
+	return d.CreatedBy
+})
+
+func actorWith(id int, permissions ...string) *dto.UserResponseWithID {
+	return &dto.UserResponseWithID{
+		ID:   id,
+		Role: &dto.RoleResponse{Name: "member", Permissions: permissions},
+	}
+}
+
+func createdBy(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: true}
+}
+
+func TestCan(t *testing.T) {
+	documentPolicy := New[*document]("document").
+		Allow("read", HasPermission[*document]("documents.read"), isPublic, isAuthor).
+		Allow("update", HasPermission[*document]("documents.update"), isAuthor).
+		Allow("publish", All(HasPermission[*document]("documents.update"), Not(isPublic))).
+		Allow("delete")
+
+	tests := []struct {
+		name     string
+		actor    *dto.UserResponseWithID
+		action   string
+		resource *document
+		want     bool
+	}{
+		{
+			name:     "without actor",
+			actor:    nil,
+			action:   "read",
+			resource: &document{Public: true},
+			want:     false,
+		},
+		{
+			name:     "permission granted",
+			actor:    actorWith(1, "documents.read"),
+			action:   "read",
+			resource: &document{},
+			want:     true,
+		},
+		{
+			name:     "permission of another action",
+			actor:    actorWith(1, "documents.read"),
+			action:   "update",
+			resource: &document{},
+			want:     false,
+		},
+		{
+			name:     "permission inherited by the role",
+			actor:    &dto.UserResponseWithID{ID: 1, Role: &dto.RoleResponse{InheritedPermissions: []string{"documents.update"}}},
+			action:   "update",
+			resource: &document{},
+			want:     true,
+		},
+		{
+			name:     "attribute passes",
+			actor:    actorWith(1),
+			action:   "read",
+			resource: &document{Public: true},
+			want:     true,
+		},
+		{
+			name:     "owner",
+			actor:    actorWith(1),
+			action:   "update",
+			resource: &document{CreatedBy: createdBy(1)},
+			want:     true,
+		},
+		{
+			name:     "not the owner",
+			actor:    actorWith(2),
+			action:   "update",
+			resource: &document{CreatedBy: createdBy(1)},
+			want:     false,
+		},
+		{
+			name:     "owner unknown",
+			actor:    actorWith(0),
+			action:   "update",
+			resource: &document{},
+			want:     false,
+		},
+		{
+			name:     "all rules pass",
+			actor:    actorWith(1, "documents.update"),
+			action:   "publish",
+			resource: &document{},
+			want:     true,
+		},
+		{
+			name:     "one of all rules fails",
+			actor:    actorWith(1, "documents.update"),
+			action:   "publish",
+			resource: &document{Public: true},
+			want:     false,
+		},
+		{
+			name:     "action without rules",
+			actor:    actorWith(1, "documents.read", "documents.update"),
+			action:   "delete",
+			resource: &document{CreatedBy: createdBy(1)},
+			want:     false,
+		},
+		{
+			name:     "unknown action",
+			actor:    actorWith(1, "documents.read"),
+			action:   "archive",
+			resource: &document{Public: true},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := documentPolicy.Can(context.Background(), tt.actor, tt.action, tt.resource)
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowAppends(t *testing.T) {
+	documentPolicy := New[*document]("document").
+		Allow("read", isAuthor).
+		Allow("read", isPublic)
+
+	if !documentPolicy.Can(context.Background(), actorWith(2), "read", &document{Public: true}) {
+		t.Error("rules registered by a second Allow are not evaluated")
+	}
+
+	if !documentPolicy.Can(context.Background(), actorWith(1), "read", &document{CreatedBy: createdBy(1)}) {
+		t.Error("rules registered by the first Allow are replaced")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	documentPolicy := New[*document]("document").
+		Allow("read", isPublic)
+
+	tests := []struct {
+		name       string
+		actor      *dto.UserResponseWithID
+		resource   *document
+		wantStatus int
+	}{
+		{
+			name:       "allowed",
+			actor:      actorWith(1),
+			resource:   &document{Public: true},
+			wantStatus: 0,
+		},
+		{
+			name:       "denied",
+			actor:      actorWith(1),
+			resource:   &document{},
+			wantStatus: 403,
+		},
+		{
+			name:       "without actor",
+			actor:      nil,
+			resource:   &document{Public: true},
+			wantStatus: 401,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.actor != nil {
+				ctx = context.WithValue(ctx, "user", tt.actor)
+			}
+
+			err := documentPolicy.Authorize(ctx, "read", tt.resource)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("got %v, want nil", err)
+				}
+				return
+			}
+
+			customErr, ok := err.(errors.CustomError)
+			if !ok {
+				t.Fatalf("got %v, want a custom error", err)
+			}
+
+			if customErr.StatusCode() != tt.wantStatus {
+				t.Errorf("got status %d, want %d", customErr.StatusCode(), tt.wantStatus)
+			}
+
+			if policyErr, ok := err.(*errors.PolicyError); ok {
+				if policyErr.Resource() != "document" || policyErr.Action() != "read" {
+					t.Errorf("got %s %s, want read document", policyErr.Action(), policyErr.Resource())
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorizeFound(t *testing.T) {
+	documentPolicy := New[*document]("document").
+		Allow("read", isPublic, isAuthor).
+		Allow("update", isAuthor)
+
+	tests := []struct {
+		name       string
+		actor      *dto.UserResponseWithID
+		resource   *document
+		wantStatus int
+	}{
+		{
+			name:       "allowed",
+			actor:      actorWith(1),
+			resource:   &document{CreatedBy: createdBy(1)},
+			wantStatus: 0,
+		},
+		{
+			name:       "readable but denied",
+			actor:      actorWith(2),
+			resource:   &document{CreatedBy: createdBy(1), Public: true},
+			wantStatus: 403,
+		},
+		{
+			name:       "not readable",
+			actor:      actorWith(2),
+			resource:   &document{CreatedBy: createdBy(1)},
+			wantStatus: 404,
+		},
+		{
+			name:       "without actor",
+			actor:      nil,
+			resource:   &document{Public: true},
+			wantStatus: 401,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.actor != nil {
+				ctx = context.WithValue(ctx, "user", tt.actor)
+			}
+
+			err := documentPolicy.AuthorizeFound(ctx, "update", tt.resource)
+
+			status := 0
+			switch e := err.(type) {
+			case nil:
+			case errors.CustomError:
+				status = e.StatusCode()
+			default:
+				if err == sql.ErrNoRows {
+					status = 404
+				}
+			}
+
+			if status != tt.wantStatus {
+				t.Errorf("got %v (status %d), want status %d", err, status, tt.wantStatus)
+			}
+		})
+	}
+}
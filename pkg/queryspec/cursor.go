@@ -0,0 +1,357 @@
+package queryspec
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Masterminds/squirrel"
+)
+
+// PaginateCursor is the paginate query parameter that opts in keyset pages
+const PaginateCursor = "cursor"
+
+var (
+	cursorSecret   []byte
+	cursorSecretMu sync.RWMutex
+)
+
+// SetCursorSecret sets the key cursors are signed with
+func SetCursorSecret(secret string) {
+	cursorSecretMu.Lock()
+	defer cursorSecretMu.Unlock()
+
+	cursorSecret = []byte(secret)
+}
+
+// Cursor points at a row of a keyset page, the next page starts after it and
+// the previous one ends before it
+type Cursor struct {
+	// Order is the ORDER BY the cursor was made for, a cursor cannot be
+	// reused with another sort
+	Order string
+	// Values are the sort values of the row, in the order of the sort
+	Values []any
+	Before bool
+}
+
+// cursorValue keeps the Go type of a sort value through JSON
+type cursorValue struct {
+	T string `json:"t"`
+	V string `json:"v"`
+}
+
+type cursorPayload struct {
+	O string        `json:"o"`
+	V []cursorValue `json:"v"`
+	B bool          `json:"b,omitempty"`
+}
+
+// Encode returns the opaque, signed form of the cursor
+func (c *Cursor) Encode() (string, error) {
+	payload := cursorPayload{O: c.Order, B: c.Before}
+	for _, value := range c.Values {
+		switch v := value.(type) {
+		case string:
+			payload.V = append(payload.V, cursorValue{T: "s", V: v})
+		case int64:
+			payload.V = append(payload.V, cursorValue{T: "i", V: strconv.FormatInt(v, 10)})
+		case bool:
+			payload.V = append(payload.V, cursorValue{T: "b", V: strconv.FormatBool(v)})
+		case time.Time:
+			payload.V = append(payload.V, cursorValue{T: "t", V: v.Format(time.RFC3339Nano)})
+		default:
+			return "", fmt.Errorf("queryspec: cannot encode %T in a cursor", value)
+		}
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(raw)
+
+	return body + "." + base64.RawURLEncoding.EncodeToString(sign(body)), nil
+}
+
+// DecodeCursor verifies and decodes a cursor made by Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	invalid := errors.NewBadRequestError("Invalid cursor")
+
+	body, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, invalid
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(body)) {
+		return nil, invalid
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, invalid
+	}
+
+	payload := cursorPayload{}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, invalid
+	}
+
+	c := &Cursor{Order: payload.O, Before: payload.B}
+	for _, value := range payload.V {
+		var v any
+		switch value.T {
+		case "s":
+			v = value.V
+		case "i":
+			v, err = strconv.ParseInt(value.V, 10, 64)
+		case "b":
+			v, err = strconv.ParseBool(value.V)
+		case "t":
+			v, err = time.Parse(time.RFC3339Nano, value.V)
+		default:
+			err = fmt.Errorf("unknown type %s", value.T)
+		}
+		if err != nil {
+			return nil, invalid
+		}
+
+		c.Values = append(c.Values, v)
+	}
+
+	return c, nil
+}
+
+func sign(body string) []byte {
+	cursorSecretMu.RLock()
+	defer cursorSecretMu.RUnlock()
+
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write([]byte(body))
+
+	return mac.Sum(nil)
+}
+
+// keysetColumn is a column of the ORDER BY of a keyset page
+type keysetColumn struct {
+	column string
+	desc   bool
+}
+
+// keyset returns the ORDER BY columns of a keyset page, nullable fields are
+// refused since a NULL cannot be compared to a cursor value
+func (r *Resource) keyset(spec *Spec) ([]keysetColumn, error) {
+	orderBy, err := r.OrderBy(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	nullable := map[string]bool{}
+	for _, field := range r.Nullable {
+		nullable[r.Sortable[field]] = true
+	}
+
+	columns := []keysetColumn{}
+	for _, clause := range orderBy {
+		column, dir, _ := strings.Cut(clause, " ")
+		if nullable[column] {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Column '%s' cannot be sorted in cursor pages", column))
+		}
+
+		columns = append(columns, keysetColumn{column: column, desc: dir == "DESC"})
+	}
+
+	return columns, nil
+}
+
+func keysetOrder(columns []keysetColumn) string {
+	clauses := []string{}
+	for _, c := range columns {
+		dir := "ASC"
+		if c.desc {
+			dir = "DESC"
+		}
+		clauses = append(clauses, c.column+" "+dir)
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// selectKeyset selects the rows after (or before) the cursor of the spec
+// instead of an offset, plus one row to know whether there are more
+func (r *Resource) selectKeyset(q squirrel.SelectBuilder, spec *Spec) (squirrel.SelectBuilder, error) {
+	columns, err := r.keyset(spec)
+	if err != nil {
+		return q, err
+	}
+
+	before := spec.Cursor != nil && spec.Cursor.Before
+
+	if spec.Cursor != nil {
+		if spec.Cursor.Order != keysetOrder(columns) || len(spec.Cursor.Values) != len(columns) {
+			return q, errors.NewBadRequestError("Cursor does not match the sort of the request")
+		}
+
+		// (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ..., with < on descending
+		// columns, and the other way around to page backwards
+		after := squirrel.Or{}
+		for i, c := range columns {
+			cond := squirrel.And{}
+			for j := 0; j < i; j++ {
+				cond = append(cond, squirrel.Eq{columns[j].column: spec.Cursor.Values[j]})
+			}
+
+			if c.desc != before {
+				cond = append(cond, squirrel.Lt{c.column: spec.Cursor.Values[i]})
+			} else {
+				cond = append(cond, squirrel.Gt{c.column: spec.Cursor.Values[i]})
+			}
+
+			after = append(after, cond)
+		}
+
+		q = q.Where(after)
+	}
+
+	orderBy := []string{}
+	for _, c := range columns {
+		// backwards pages are read in reverse and flipped back by Page
+		desc := c.desc != before
+		if desc {
+			orderBy = append(orderBy, c.column+" DESC")
+		} else {
+			orderBy = append(orderBy, c.column+" ASC")
+		}
+	}
+
+	return q.OrderBy(orderBy...).Limit(uint64(spec.Limit + 1)), nil
+}
+
+// Page trims the rows of a keyset select to the page and sets the Next and
+// Prev cursors of the spec, rows of offset pages are returned as they are
+func Page[T any](r *Resource, spec *Spec, rows []T) ([]T, error) {
+	if !spec.Keyset {
+		return rows, nil
+	}
+
+	columns, err := r.keyset(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	before := spec.Cursor != nil && spec.Cursor.Before
+	more := len(rows) > spec.Limit
+	if more {
+		rows = rows[:spec.Limit]
+	}
+
+	if before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, nil
+	}
+
+	// going forward there is a previous page once past the first one, going
+	// backwards there is always a next page, the one we came from
+	hasNext := more || before
+	hasPrev := spec.Cursor != nil && (!before || more)
+
+	if hasNext {
+		spec.Next, err = rowCursor(columns, rows[len(rows)-1], false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if hasPrev {
+		spec.Prev, err = rowCursor(columns, rows[0], true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rows, nil
+}
+
+// rowCursor encodes the sort values of a row, read from the fields tagged
+// with the columns
+func rowCursor(columns []keysetColumn, row any, before bool) (string, error) {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("queryspec: cannot read the cursor of a %T", row)
+	}
+
+	c := &Cursor{Order: keysetOrder(columns), Before: before}
+	for _, column := range columns {
+		field, ok := fieldByColumn(v, column.column)
+		if !ok {
+			return "", fmt.Errorf("queryspec: %T has no field for column %s", row, column.column)
+		}
+
+		value, err := cursorValueOf(field)
+		if err != nil {
+			return "", err
+		}
+
+		c.Values = append(c.Values, value)
+	}
+
+	return c.Encode()
+}
+
+func fieldByColumn(v reflect.Value, column string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("db"), ",")
+		if tag == column {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func cursorValueOf(field reflect.Value) (any, error) {
+	switch value := field.Interface().(type) {
+	case time.Time:
+		return value, nil
+	case sql.NullTime:
+		if value.Valid {
+			return value.Time, nil
+		}
+	case sql.NullString:
+		if value.Valid {
+			return value.String, nil
+		}
+	case sql.NullInt64:
+		if value.Valid {
+			return value.Int64, nil
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), nil
+	case reflect.Bool:
+		return field.Bool(), nil
+	}
+
+	return nil, fmt.Errorf("queryspec: cannot use a %s as a cursor value", field.Type())
+}
@@ -0,0 +1,232 @@
+package queryspec
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+)
+
+type cursorRow struct {
+	Id        int64     `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+var cursorResource = &Resource{
+	Sortable: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	DefaultSorts: []Sort{{Field: "created_at", Dir: DirDesc}},
+	Key:          "id",
+}
+
+// cursorRows has names shared by several rows, their order comes from the id
+var cursorRows = []cursorRow{
+	{Id: 5, Name: "carol"},
+	{Id: 1, Name: "alice"},
+	{Id: 4, Name: "bob"},
+	{Id: 2, Name: "bob"},
+	{Id: 3, Name: "bob"},
+	{Id: 6, Name: "carol"},
+}
+
+// listPage returns the ids of a keyset page of cursorRows and the spec
+// holding its cursors
+func listPage(sortBy string, token string) ([]int64, *Spec, error) {
+	spec, err := New(0, 2, sortBy, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = spec.UseCursor(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := append([]cursorRow{}, cursorRows...)
+	rows, err = SelectRows(cursorResource, rows, spec, ColumnOf[cursorRow])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = Page(cursorResource, spec, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := []int64{}
+	for _, row := range rows {
+		ids = append(ids, row.Id)
+	}
+
+	return ids, spec, nil
+}
+
+func withCursorSecret(t *testing.T, secret string) {
+	SetCursorSecret(secret)
+	t.Cleanup(func() { SetCursorSecret("") })
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	withCursorSecret(t, "secret")
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	c := &Cursor{
+		Order:  "name ASC, created_at DESC, id ASC",
+		Values: []any{"bob", at, int64(42), true},
+		Before: true,
+	}
+
+	token, err := c.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeCursor(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Order != c.Order || decoded.Before != c.Before || len(decoded.Values) != len(c.Values) {
+		t.Fatalf("got %+v, want %+v", decoded, c)
+	}
+
+	if decoded.Values[0] != "bob" || !decoded.Values[1].(time.Time).Equal(at) || decoded.Values[2] != int64(42) || decoded.Values[3] != true {
+		t.Errorf("got values %v, want %v", decoded.Values, c.Values)
+	}
+
+	_, err = (&Cursor{Values: []any{1.5}}).Encode()
+	if err == nil {
+		t.Error("encoded a float, want an error")
+	}
+}
+
+func TestDecodeCursorRefusesTamperedCursors(t *testing.T) {
+	withCursorSecret(t, "secret")
+
+	token, err := (&Cursor{Order: "id ASC", Values: []any{int64(1)}}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged, err := (&Cursor{Order: "id ASC", Values: []any{int64(2)}}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, signature := token[:len(token)-44], token[len(token)-43:]
+	forgedBody := forged[:len(forged)-44]
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"another body", func() string { return forgedBody + "." + signature }},
+		{"no signature", func() string { return body }},
+		{"a malformed signature", func() string { return body + ".!" }},
+		{"a malformed body", func() string { return "!." + signature }},
+		{"another secret", func() string {
+			SetCursorSecret("another")
+			defer SetCursorSecret("secret")
+
+			token, _ := (&Cursor{Order: "id ASC", Values: []any{int64(1)}}).Encode()
+			return token
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.token())
+			if customErr, ok := err.(errors.CustomError); !ok || customErr.StatusCode() != http.StatusBadRequest {
+				t.Errorf("got %v, want a bad request", err)
+			}
+		})
+	}
+}
+
+func TestCursorOfAnotherSort(t *testing.T) {
+	withCursorSecret(t, "secret")
+
+	_, spec, err := listPage("name.asc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sortBy := range []string{"name.desc", "created_at.asc", ""} {
+		t.Run(fmt.Sprintf("sortBy=%s", sortBy), func(t *testing.T) {
+			_, _, err := listPage(sortBy, spec.Next)
+			if customErr, ok := err.(errors.CustomError); !ok || customErr.StatusCode() != http.StatusBadRequest {
+				t.Errorf("got %v, want a bad request", err)
+			}
+		})
+	}
+}
+
+func TestCursorPages(t *testing.T) {
+	withCursorSecret(t, "secret")
+
+	tests := []struct {
+		sortBy string
+		pages  [][]int64
+	}{
+		// the bobs and the carols are told apart by their id
+		{"name.asc", [][]int64{{1, 2}, {3, 4}, {5, 6}}},
+		{"name.desc", [][]int64{{5, 6}, {2, 3}, {4, 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			// forward from the first page, which has no previous one
+			token := ""
+			specs := []*Spec{}
+			for i, want := range tt.pages {
+				ids, spec, err := listPage(tt.sortBy, token)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if fmt.Sprint(ids) != fmt.Sprint(want) {
+					t.Fatalf("page %d: got %v, want %v", i+1, ids, want)
+				}
+
+				if (spec.Prev != "") != (i > 0) {
+					t.Errorf("page %d: previous cursor %q", i+1, spec.Prev)
+				}
+
+				specs = append(specs, spec)
+				token = spec.Next
+			}
+
+			if token != "" {
+				t.Errorf("last page: next cursor %q, want none", token)
+			}
+
+			// and back from the last page
+			token = specs[len(specs)-1].Prev
+			for i := len(tt.pages) - 2; i >= 0; i-- {
+				ids, spec, err := listPage(tt.sortBy, token)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if fmt.Sprint(ids) != fmt.Sprint(tt.pages[i]) {
+					t.Fatalf("page %d backwards: got %v, want %v", i+1, ids, tt.pages[i])
+				}
+
+				if spec.Next == "" {
+					t.Errorf("page %d backwards: no next cursor", i+1)
+				}
+
+				token = spec.Prev
+			}
+
+			if token != "" {
+				t.Errorf("first page backwards: previous cursor %q, want none", token)
+			}
+		})
+	}
+}
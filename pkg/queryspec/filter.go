@@ -0,0 +1,204 @@
+package queryspec
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Masterminds/squirrel"
+)
+
+// Filter operators, filter[field]=value is an OpEq
+const (
+	OpEq     = "eq"
+	OpNeq    = "neq"
+	OpGt     = "gt"
+	OpGte    = "gte"
+	OpLt     = "lt"
+	OpLte    = "lte"
+	OpIn     = "in"
+	OpLike   = "like"
+	OpIsNull = "null"
+)
+
+// Operator sets of the usual fields
+var (
+	TextOps  = []string{OpEq, OpNeq, OpIn, OpLike}
+	EnumOps  = []string{OpEq, OpNeq, OpIn}
+	RangeOps = []string{OpEq, OpGt, OpGte, OpLt, OpLte}
+)
+
+// Nullable adds OpIsNull to a set of operators
+func Nullable(ops []string) []string {
+	return append(append([]string{}, ops...), OpIsNull)
+}
+
+// Types of filterable fields, values are parsed to them before reaching the
+// SQL so that a malformed value is a 400 rather than a database error
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeTime   = "time"
+)
+
+// filterDateLayouts are the accepted layouts of TypeTime values
+var filterDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// Field declares a filterable field
+type Field struct {
+	// Column is the column or the SQL expression the field is matched on
+	Column string
+	Type   string
+	// Ops are the operators allowed on the field
+	Ops []string
+}
+
+func (f Field) allows(op string) bool {
+	for _, allowed := range f.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseFilters collects the filter[field]=value and filter[field][op]=value
+// query parameters, the plain "filter" parameter is the search and is ignored
+func ParseFilters(values url.Values) ([]Filter, error) {
+	filters := []Filter{}
+	invalids := map[string]any{}
+
+	// url.Values is a map, keys are sorted so the same request always builds
+	// the same SQL
+	keys := []string{}
+	for key := range values {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		vals := values[key]
+
+		chunks := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]"), "][")
+		if !strings.HasSuffix(key, "]") || len(chunks) > 2 || chunks[0] == "" {
+			invalids[key] = "malformed filter, should be filter[field] or filter[field][operator]"
+			continue
+		}
+
+		op := OpEq
+		if len(chunks) == 2 {
+			op = strings.ToLower(chunks[1])
+		}
+
+		for _, val := range vals {
+			filters = append(filters, Filter{
+				Field: chunks[0],
+				Op:    op,
+				Value: val,
+			})
+		}
+	}
+
+	if len(invalids) > 0 {
+		return nil, errors.NewValidationError("Invalid filters", invalids)
+	}
+
+	return filters, nil
+}
+
+// filterKey is the query parameter of a filter, used to report its errors
+func filterKey(f Filter) string {
+	return fmt.Sprintf("filter[%s][%s]", f.Field, f.Op)
+}
+
+// Cond returns the condition of a filter on the field
+func (f Field) Cond(filter Filter) (squirrel.Sqlizer, error) {
+	if !f.allows(filter.Op) {
+		return nil, fmt.Errorf("operator '%s' is not allowed on this field", filter.Op)
+	}
+
+	switch filter.Op {
+	case OpIsNull:
+		isNull, err := strconv.ParseBool(filter.Value)
+		if err != nil {
+			return nil, fmt.Errorf("value should be true or false")
+		}
+		if isNull {
+			return squirrel.Eq{f.Column: nil}, nil
+		}
+		return squirrel.NotEq{f.Column: nil}, nil
+	case OpLike:
+		if f.Type != TypeString {
+			return nil, fmt.Errorf("operator 'like' is only allowed on text fields")
+		}
+		return squirrel.Expr(fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '!'", f.Column), Contains(filter.Value)), nil
+	case OpIn:
+		values := []any{}
+		for _, raw := range strings.Split(filter.Value, ",") {
+			value, err := f.parse(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return squirrel.Eq{f.Column: values}, nil
+	}
+
+	value, err := f.parse(filter.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filter.Op {
+	case OpEq:
+		return squirrel.Eq{f.Column: value}, nil
+	case OpNeq:
+		return squirrel.NotEq{f.Column: value}, nil
+	case OpGt:
+		return squirrel.Gt{f.Column: value}, nil
+	case OpGte:
+		return squirrel.GtOrEq{f.Column: value}, nil
+	case OpLt:
+		return squirrel.Lt{f.Column: value}, nil
+	case OpLte:
+		return squirrel.LtOrEq{f.Column: value}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator '%s'", filter.Op)
+}
+
+// parse converts a filter value to the type of the field
+func (f Field) parse(raw string) (any, error) {
+	switch f.Type {
+	case TypeInt:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("value '%s' should be an integer", raw)
+		}
+		return value, nil
+	case TypeBool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("value '%s' should be true or false", raw)
+		}
+		return value, nil
+	case TypeTime:
+		for _, layout := range filterDateLayouts {
+			value, err := time.Parse(layout, raw)
+			if err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("value '%s' should be a date (YYYY-MM-DD) or an RFC 3339 time", raw)
+	}
+
+	return raw, nil
+}
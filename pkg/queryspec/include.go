@@ -0,0 +1,54 @@
+package queryspec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+)
+
+// Includes are the relations a request embeds in its response, a nested
+// relation such as role.permissions implies its parents
+type Includes map[string]bool
+
+// ParseIncludes parses include=role,role.permissions, only the allowed
+// relations may be asked for
+func ParseIncludes(include string, allowed ...string) (Includes, error) {
+	includes := Includes{}
+	if strings.TrimSpace(include) == "" {
+		return includes, nil
+	}
+
+	whitelist := map[string]bool{}
+	for _, name := range allowed {
+		whitelist[name] = true
+	}
+
+	invalids := map[string]any{}
+	for _, raw := range strings.Split(include, ",") {
+		name := strings.TrimSpace(raw)
+		if name == "" {
+			continue
+		}
+
+		if !whitelist[name] {
+			invalids[name] = fmt.Sprintf("Relation '%s' cannot be included", name)
+			continue
+		}
+
+		chunks := strings.Split(name, ".")
+		for i := range chunks {
+			includes[strings.Join(chunks[:i+1], ".")] = true
+		}
+	}
+
+	if len(invalids) > 0 {
+		return nil, errors.NewValidationError("Invalid include", invalids)
+	}
+
+	return includes, nil
+}
+
+func (i Includes) Has(name string) bool {
+	return i[name]
+}
@@ -0,0 +1,332 @@
+package queryspec
+
+import (
+	"database/sql"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+)
+
+// ColumnFunc reads the value of a column, or of the SQL expression of a
+// Field, from a row of an in-memory list. nil stands for NULL.
+type ColumnFunc[T any] func(row T, column string) any
+
+// ColumnOf reads the field of the row tagged with the column, NULL for a
+// column the row has no field for
+func ColumnOf[T any](row T, column string) any {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	field, ok := fieldByColumn(v, column)
+	if !ok {
+		return nil
+	}
+
+	return field.Interface()
+}
+
+// SelectRows is Select for rows held in memory: it keeps the rows matching
+// the trash, the search and the filters of the spec, orders them as Postgres
+// does (NULLs last going up, first going down) and cuts the page. Rows of
+// keyset pages go through Page like the rows of a select.
+func SelectRows[T any](r *Resource, rows []T, spec *Spec, column ColumnFunc[T]) ([]T, error) {
+	matched, err := filterRows(r, rows, spec, column)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.Keyset {
+		return selectKeysetRows(r, matched, spec, column)
+	}
+
+	orderBy, err := r.OrderBy(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []keysetColumn{}
+	for _, clause := range orderBy {
+		name, dir, _ := strings.Cut(clause, " ")
+		columns = append(columns, keysetColumn{column: name, desc: dir == "DESC"})
+	}
+	sortRows(matched, columns, column)
+
+	offset := spec.Offset()
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+
+	if spec.Limit < len(matched) {
+		matched = matched[:spec.Limit]
+	}
+
+	return matched, nil
+}
+
+// CountRows is Count for rows held in memory
+func CountRows[T any](r *Resource, rows []T, spec *Spec, column ColumnFunc[T]) (int, error) {
+	matched, err := filterRows(r, rows, spec, column)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(matched), nil
+}
+
+// filterRows keeps the rows matching the conditions of Where, which checks
+// the spec so that a bad request fails the same way whatever the store
+func filterRows[T any](r *Resource, rows []T, spec *Spec, column ColumnFunc[T]) ([]T, error) {
+	if _, err := r.Where(spec); err != nil {
+		return nil, err
+	}
+
+	search := strings.ToLower(spec.Search)
+
+	matched := []T{}
+	for _, row := range rows {
+		if r.SoftDelete != "" {
+			trashed := !isNull(column(row, r.SoftDelete))
+			if spec.OnlyTrashed && !trashed || !spec.OnlyTrashed && !spec.WithTrashed && trashed {
+				continue
+			}
+		}
+
+		if search != "" && len(r.Searchable) > 0 {
+			found := false
+			for _, searchable := range r.Searchable {
+				value, _ := normalize(column(row, searchable)).(string)
+				if strings.Contains(strings.ToLower(value), search) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				continue
+			}
+		}
+
+		keep := true
+		for _, filter := range spec.Filters {
+			field := r.Filterable[filter.Field]
+			if !field.match(filter, column(row, field.Column)) {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			matched = append(matched, row)
+		}
+	}
+
+	return matched, nil
+}
+
+// match is Cond for a value held in memory, the filter has been checked by
+// Cond already. Like in SQL a NULL only matches OpIsNull.
+func (f Field) match(filter Filter, value any) bool {
+	if filter.Op == OpIsNull {
+		want, _ := strconv.ParseBool(filter.Value)
+		return isNull(value) == want
+	}
+
+	if isNull(value) {
+		return false
+	}
+	value = normalize(value)
+
+	switch filter.Op {
+	case OpLike:
+		text, _ := value.(string)
+		return strings.Contains(strings.ToLower(text), strings.ToLower(filter.Value))
+	case OpIn:
+		for _, raw := range strings.Split(filter.Value, ",") {
+			parsed, _ := f.parse(strings.TrimSpace(raw))
+			if compareValues(value, normalize(parsed)) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	parsed, _ := f.parse(filter.Value)
+	c := compareValues(value, normalize(parsed))
+
+	switch filter.Op {
+	case OpEq:
+		return c == 0
+	case OpNeq:
+		return c != 0
+	case OpGt:
+		return c > 0
+	case OpGte:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	}
+
+	return false
+}
+
+// selectKeysetRows is selectKeyset for rows held in memory
+func selectKeysetRows[T any](r *Resource, rows []T, spec *Spec, column ColumnFunc[T]) ([]T, error) {
+	columns, err := r.keyset(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	before := spec.Cursor != nil && spec.Cursor.Before
+
+	if spec.Cursor != nil {
+		if spec.Cursor.Order != keysetOrder(columns) || len(spec.Cursor.Values) != len(columns) {
+			return nil, errors.NewBadRequestError("Cursor does not match the sort of the request")
+		}
+
+		after := []T{}
+		for _, row := range rows {
+			for i, c := range columns {
+				cmp := compareValues(normalize(column(row, c.column)), spec.Cursor.Values[i])
+				if cmp == 0 {
+					continue
+				}
+
+				if (cmp < 0) == (c.desc != before) {
+					after = append(after, row)
+				}
+				break
+			}
+		}
+		rows = after
+	}
+
+	// backwards pages are read in reverse and flipped back by Page
+	ordered := []keysetColumn{}
+	for _, c := range columns {
+		ordered = append(ordered, keysetColumn{column: c.column, desc: c.desc != before})
+	}
+	sortRows(rows, ordered, column)
+
+	if spec.Limit+1 < len(rows) {
+		rows = rows[:spec.Limit+1]
+	}
+
+	return rows, nil
+}
+
+func sortRows[T any](rows []T, columns []keysetColumn, column ColumnFunc[T]) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, c := range columns {
+			a, b := column(rows[i], c.column), column(rows[j], c.column)
+
+			var cmp int
+			switch {
+			case isNull(a) && isNull(b):
+				cmp = 0
+			case isNull(a):
+				cmp = 1
+			case isNull(b):
+				cmp = -1
+			default:
+				cmp = compareValues(normalize(a), normalize(b))
+			}
+
+			if c.desc {
+				cmp = -cmp
+			}
+
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+
+		return false
+	})
+}
+
+func isNull(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case sql.NullTime:
+		return !v.Valid
+	case sql.NullInt64:
+		return !v.Valid
+	case sql.NullString:
+		return !v.Valid
+	case sql.NullBool:
+		return !v.Valid
+	}
+
+	return false
+}
+
+// normalize unwraps the valid sql.Null* values and widens the integers, so
+// that the values of a column and of a filter or a cursor compare
+func normalize(value any) any {
+	switch v := value.(type) {
+	case sql.NullTime:
+		return v.Time
+	case sql.NullInt64:
+		return v.Int64
+	case sql.NullString:
+		return v.String
+	case sql.NullBool:
+		return v.Bool
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	}
+
+	return value
+}
+
+// compareValues orders two normalized values of the same type, values of
+// different types are equal
+func compareValues(a any, b any) int {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x != y {
+			if y {
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+		}
+	}
+
+	return 0
+}
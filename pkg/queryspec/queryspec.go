@@ -0,0 +1,319 @@
+package queryspec
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Masterminds/squirrel"
+)
+
+const (
+	DirAsc  = "asc"
+	DirDesc = "desc"
+)
+
+type Sort struct {
+	Field string
+	Dir   string
+}
+
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// Spec is a list request of any resource: a page, an ordered list of sorts, a
+// free-text search and field filters. Fields are API names, resources map
+// them to columns.
+type Spec struct {
+	Page    int
+	Limit   int
+	Sorts   []Sort
+	Search  string
+	Filters []Filter
+
+	// Keyset pages from Cursor instead of an offset, the first page has no
+	// cursor
+	Keyset bool
+	Cursor *Cursor
+	// SkipCount leaves the total of the list unknown
+	SkipCount bool
+
+	// WithTrashed lists soft-deleted rows along with the others, OnlyTrashed
+	// lists them alone
+	WithTrashed bool
+	OnlyTrashed bool
+
+	// Next and Prev are the cursors of the pages around a keyset page, set by
+	// Page
+	Next string
+	Prev string
+}
+
+var (
+	maxLimit   = constants.MAX_PAGINATION_LIMIT
+	maxLimitMu sync.RWMutex
+)
+
+// SetMaxLimit sets the largest page a list request may ask for
+func SetMaxLimit(limit int) {
+	maxLimitMu.Lock()
+	defer maxLimitMu.Unlock()
+
+	maxLimit = limit
+}
+
+// New builds the spec of a list request, sortBy is "field.dir,-field" and
+// keeps its order. A limit above the maximum is refused rather than capped,
+// the client would otherwise take a short page for the last one.
+func New(page int, limit int, sortBy string, search string) (*Spec, error) {
+	if limit <= 0 {
+		limit = constants.DEFAULT_PAGINATION_LIMIT
+	}
+
+	maxLimitMu.RLock()
+	max := maxLimit
+	maxLimitMu.RUnlock()
+
+	if limit > max {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("limit query parameter should not be greater than %d", max))
+	}
+
+	sorts, err := ParseSorts(sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Spec{
+		Page:   page,
+		Limit:  limit,
+		Sorts:  sorts,
+		Search: strings.TrimSpace(search),
+	}, nil
+}
+
+// UseCursor switches the spec to keyset pages, starting from the first page
+// when the token is empty
+func (s *Spec) UseCursor(token string) error {
+	s.Keyset = true
+	s.Page = 0
+
+	if token == "" {
+		return nil
+	}
+
+	c, err := DecodeCursor(token)
+	if err != nil {
+		return err
+	}
+	s.Cursor = c
+
+	return nil
+}
+
+// Offset returns the number of rows before the requested page
+func (s *Spec) Offset() int {
+	if s.Page <= 0 {
+		return 0
+	}
+
+	return (s.Page - 1) * s.Limit
+}
+
+// ParseSorts parses sortBy=name.asc,updated_at.desc, a field without a
+// direction is ascending and -field is short for field.desc
+func ParseSorts(sortBy string) ([]Sort, error) {
+	sorts := []Sort{}
+	if sortBy == "" {
+		return sorts, nil
+	}
+
+	for _, raw := range strings.Split(sortBy, ",") {
+		raw = strings.TrimSpace(raw)
+
+		field, dir := raw, DirAsc
+		if strings.HasPrefix(raw, "-") {
+			field, dir = raw[1:], DirDesc
+		} else if chunks := strings.Split(raw, "."); len(chunks) > 1 {
+			if len(chunks) != 2 {
+				return nil, errors.NewBadRequestError("malformed sortBy query parameter, should be field.orderdirection")
+			}
+
+			field, dir = chunks[0], strings.ToLower(chunks[1])
+		}
+
+		if field == "" || strings.ContainsAny(field, ".-") {
+			return nil, errors.NewBadRequestError("malformed sortBy query parameter, should be field.orderdirection")
+		}
+
+		if dir != DirAsc && dir != DirDesc {
+			return nil, errors.NewBadRequestError("malformed orderdirection in sortBy query parameter, should be asc or desc")
+		}
+
+		sorts = append(sorts, Sort{Field: field, Dir: dir})
+	}
+
+	return sorts, nil
+}
+
+// Resource whitelists what list requests may do on a table, only mapped
+// columns ever reach the SQL and values are always bound as parameters
+type Resource struct {
+	// Sortable maps the sortable fields to their column
+	Sortable map[string]string
+	// Filterable declares the filterable fields with their operators
+	Filterable map[string]Field
+	// Searchable columns are matched case-insensitively by the search term,
+	// a row matches when any of them does
+	Searchable []string
+	// SoftDelete is the deleted_at column of soft-deleted tables, their
+	// trashed rows are left out unless the spec asks for them
+	SoftDelete string
+	// Nullable sortable fields cannot be sorted in keyset pages
+	Nullable []string
+	// DefaultSorts applies when the request has no sort
+	DefaultSorts []Sort
+	// Key is a unique column appended to every ORDER BY so that rows with
+	// equal sort values keep the same order from one page to the next
+	Key string
+}
+
+// Where returns the conditions of the trash, the search and the filters
+func (r *Resource) Where(spec *Spec) (squirrel.And, error) {
+	where := squirrel.And{}
+
+	if r.SoftDelete != "" {
+		if spec.OnlyTrashed {
+			where = append(where, squirrel.NotEq{r.SoftDelete: nil})
+		} else if !spec.WithTrashed {
+			where = append(where, squirrel.Eq{r.SoftDelete: nil})
+		}
+	} else if spec.WithTrashed || spec.OnlyTrashed {
+		return nil, errors.NewBadRequestError("This list has no trash")
+	}
+
+	if spec.Search != "" && len(r.Searchable) > 0 {
+		term := Contains(spec.Search)
+
+		search := squirrel.Or{}
+		for _, column := range r.Searchable {
+			search = append(search, squirrel.Expr(fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '!'", column), term))
+		}
+
+		where = append(where, search)
+	}
+
+	invalids := map[string]any{}
+	for _, filter := range spec.Filters {
+		field, ok := r.Filterable[filter.Field]
+		if !ok {
+			invalids[filterKey(filter)] = fmt.Sprintf("Field '%s' cannot be filtered", filter.Field)
+			continue
+		}
+
+		cond, err := field.Cond(filter)
+		if err != nil {
+			invalids[filterKey(filter)] = err.Error()
+			continue
+		}
+
+		where = append(where, cond)
+	}
+
+	if len(invalids) > 0 {
+		return nil, errors.NewValidationError("Invalid filters", invalids)
+	}
+
+	return where, nil
+}
+
+// OrderBy returns the ORDER BY clauses of the spec, ending with the key
+func (r *Resource) OrderBy(spec *Spec) ([]string, error) {
+	sorts := spec.Sorts
+	if len(sorts) == 0 {
+		sorts = r.DefaultSorts
+	}
+
+	clauses := []string{}
+	seen := map[string]bool{}
+	for _, sort := range sorts {
+		column, ok := r.Sortable[sort.Field]
+		if !ok {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Field '%s' cannot be sorted", sort.Field))
+		}
+
+		if sort.Dir != DirAsc && sort.Dir != DirDesc {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("Order direction for field '%s' should be 'asc' or 'desc'", sort.Field))
+		}
+
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+
+		clauses = append(clauses, fmt.Sprintf("%s %s", column, strings.ToUpper(sort.Dir)))
+	}
+
+	if r.Key != "" && !seen[r.Key] {
+		clauses = append(clauses, r.Key+" ASC")
+	}
+
+	return clauses, nil
+}
+
+// Select applies the conditions, the order and the page of the spec, rows of
+// keyset pages go through Page
+func (r *Resource) Select(q squirrel.SelectBuilder, spec *Spec) (squirrel.SelectBuilder, error) {
+	where, err := r.Where(spec)
+	if err != nil {
+		return q, err
+	}
+
+	if len(where) > 0 {
+		q = q.Where(where)
+	}
+
+	if spec.Keyset {
+		return r.selectKeyset(q, spec)
+	}
+
+	orderBy, err := r.OrderBy(spec)
+	if err != nil {
+		return q, err
+	}
+
+	return q.OrderBy(orderBy...).
+		Offset(uint64(spec.Offset())).
+		Limit(uint64(spec.Limit)), nil
+}
+
+// Count applies the conditions of the spec only
+func (r *Resource) Count(q squirrel.SelectBuilder, spec *Spec) (squirrel.SelectBuilder, error) {
+	where, err := r.Where(spec)
+	if err != nil {
+		return q, err
+	}
+
+	if len(where) > 0 {
+		q = q.Where(where)
+	}
+
+	return q, nil
+}
+
+// Contains returns the pattern of LOWER(column) LIKE ? ESCAPE '!' matching the
+// columns that contain the term
+func Contains(term string) string {
+	return "%" + escapeLike(strings.ToLower(term)) + "%"
+}
+
+// escapeLike makes the wildcards of a search term match literally. The escape
+// character is ! since SQLite has no default one and a backslash would need
+// escaping itself in the string literals of MySQL.
+func escapeLike(term string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(term)
+}
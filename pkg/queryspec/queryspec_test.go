@@ -0,0 +1,307 @@
+package queryspec
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+)
+
+func TestNewLimit(t *testing.T) {
+	SetMaxLimit(50)
+	defer SetMaxLimit(constants.MAX_PAGINATION_LIMIT)
+
+	tests := []struct {
+		name    string
+		limit   int
+		want    int
+		wantErr bool
+	}{
+		{name: "default", limit: 0, want: constants.DEFAULT_PAGINATION_LIMIT},
+		{name: "negative", limit: -1, want: constants.DEFAULT_PAGINATION_LIMIT},
+		{name: "within the maximum", limit: 20, want: 20},
+		{name: "at the maximum", limit: 50, want: 50},
+		{name: "above the maximum", limit: 51, wantErr: true},
+		{name: "far above the maximum", limit: 1 << 30, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := New(1, tt.limit, "", "")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got limit %d, want an error", spec.Limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if spec.Limit != tt.want {
+				t.Errorf("got limit %d, want %d", spec.Limit, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSorts(t *testing.T) {
+	tests := []struct {
+		sortBy  string
+		want    string
+		wantErr bool
+	}{
+		{sortBy: "", want: "[]"},
+		{sortBy: "name.asc", want: "[{name asc}]"},
+		{sortBy: "name.DESC", want: "[{name desc}]"},
+		{sortBy: "name", want: "[{name asc}]"},
+		{sortBy: "-name", want: "[{name desc}]"},
+		{sortBy: "status.asc, -created_at,name", want: "[{status asc} {created_at desc} {name asc}]"},
+		{sortBy: "name.up", wantErr: true},
+		{sortBy: "name.", wantErr: true},
+		{sortBy: "name.asc.desc", wantErr: true},
+		{sortBy: "-name.desc", wantErr: true},
+		{sortBy: "-", wantErr: true},
+		{sortBy: "name,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			sorts, err := ParseSorts(tt.sortBy)
+			if tt.wantErr {
+				if customErr, ok := err.(errors.CustomError); !ok || customErr.StatusCode() != http.StatusBadRequest {
+					t.Fatalf("got %v, want a bad request", sorts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fmt.Sprint(sorts); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	r := &Resource{
+		Sortable: map[string]string{
+			"name":       "name",
+			"role":       "role_name",
+			"created_at": "created_at",
+			"id":         "id",
+		},
+		DefaultSorts: []Sort{{Field: "created_at", Dir: DirDesc}},
+		Key:          "id",
+	}
+
+	tests := []struct {
+		sortBy  string
+		want    string
+		wantErr bool
+	}{
+		{sortBy: "", want: "[created_at DESC id ASC]"},
+		{sortBy: "name.asc", want: "[name ASC id ASC]"},
+		{sortBy: "-role,name", want: "[role_name DESC name ASC id ASC]"},
+		{sortBy: "name,-name", want: "[name ASC id ASC]"},
+		// the key is not appended twice, it keeps the requested direction
+		{sortBy: "-id", want: "[id DESC]"},
+		{sortBy: "password", wantErr: true},
+		{sortBy: "name,role_name", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			sorts, err := ParseSorts(tt.sortBy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			orderBy, err := r.OrderBy(&Spec{Sorts: sorts})
+			if tt.wantErr {
+				if customErr, ok := err.(errors.CustomError); !ok || customErr.StatusCode() != http.StatusBadRequest {
+					t.Fatalf("got %v, want a bad request", orderBy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fmt.Sprint(orderBy); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr string
+	}{
+		{query: "filter[status]=1", want: "[{status eq 1}]"},
+		{query: "filter[created_at][GTE]=2026-01-01", want: "[{created_at gte 2026-01-01}]"},
+		{query: "filter[role]=admin&filter[role]=editor", want: "[{role eq admin} {role eq editor}]"},
+		{query: "filter[status]=1&filter[name][like]=al", want: "[{name like al} {status eq 1}]"},
+		{query: "filter=alice&sortBy=name.asc", want: "[]"},
+		{query: "filter[]=1", wantErr: "filter[]"},
+		{query: "filter[][eq]=1", wantErr: "filter[][eq]"},
+		{query: "filter[status][eq][x]=1", wantErr: "filter[status][eq][x]"},
+		{query: "filter[status=1", wantErr: "filter[status"},
+		{query: "filter[status]x=1", wantErr: "filter[status]x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filters, err := ParseFilters(values)
+			if tt.wantErr != "" {
+				if _, ok := invalidFilters(err)[tt.wantErr]; !ok {
+					t.Fatalf("got %v, want %s to be invalid", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fmt.Sprint(filters); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+var filterResource = &Resource{
+	Filterable: map[string]Field{
+		"name":          {Column: "name", Type: TypeString, Ops: TextOps},
+		"status":        {Column: "status", Type: TypeInt, Ops: EnumOps},
+		"active":        {Column: "active", Type: TypeBool, Ops: EnumOps},
+		"created_at":    {Column: "created_at", Type: TypeTime, Ops: RangeOps},
+		"last_login_at": {Column: "last_login_at", Type: TypeTime, Ops: Nullable(RangeOps)},
+	},
+}
+
+func TestFilterCond(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		filter   Filter
+		wantSql  string
+		wantArgs []any
+	}{
+		{Filter{"name", OpEq, "alice"}, "name = ?", []any{"alice"}},
+		{Filter{"name", OpNeq, "alice"}, "name <> ?", []any{"alice"}},
+		{Filter{"name", OpIn, "alice, bob"}, "name IN (?,?)", []any{"alice", "bob"}},
+		{Filter{"name", OpLike, "50%_Off"}, "LOWER(name) LIKE ? ESCAPE '!'", []any{"%50!%!_off%"}},
+		{Filter{"status", OpEq, "1"}, "status = ?", []any{1}},
+		{Filter{"status", OpIn, "1,2"}, "status IN (?,?)", []any{1, 2}},
+		{Filter{"active", OpEq, "true"}, "active = ?", []any{true}},
+		{Filter{"created_at", OpGt, "2026-01-01"}, "created_at > ?", []any{day}},
+		{Filter{"created_at", OpGte, "2026-01-01T00:00:00Z"}, "created_at >= ?", []any{day}},
+		{Filter{"created_at", OpLt, "2026-01-01T00:00:00"}, "created_at < ?", []any{day}},
+		{Filter{"created_at", OpLte, "2026-01-01"}, "created_at <= ?", []any{day}},
+		{Filter{"last_login_at", OpIsNull, "true"}, "last_login_at IS NULL", []any{}},
+		{Filter{"last_login_at", OpIsNull, "false"}, "last_login_at IS NOT NULL", []any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(filterKey(tt.filter), func(t *testing.T) {
+			cond, err := filterResource.Filterable[tt.filter.Field].Cond(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sql, args, err := cond.ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sql != tt.wantSql {
+				t.Errorf("got %s, want %s", sql, tt.wantSql)
+			}
+
+			if fmt.Sprint(args) != fmt.Sprint(tt.wantArgs) {
+				t.Errorf("got args %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestWhereRefusesInvalidFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr string
+	}{
+		{"field not filterable", Filter{"password", OpEq, "secret"}, "cannot be filtered"},
+		{"operator not allowed", Filter{"status", OpLike, "1"}, "not allowed"},
+		{"range on an enum", Filter{"status", OpGt, "1"}, "not allowed"},
+		{"null on a required field", Filter{"created_at", OpIsNull, "true"}, "not allowed"},
+		{"unknown operator", Filter{"name", "regexp", "a.*"}, "not allowed"},
+		{"text for an integer", Filter{"status", OpEq, "one"}, "should be an integer"},
+		{"text in a list of integers", Filter{"status", OpIn, "1,two"}, "should be an integer"},
+		{"text for a boolean", Filter{"active", OpEq, "yes"}, "should be true or false"},
+		{"text for a time", Filter{"created_at", OpGt, "yesterday"}, "should be a date"},
+		{"text for null", Filter{"last_login_at", OpIsNull, "maybe"}, "should be true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := filterResource.Where(&Spec{Filters: []Filter{{"name", OpEq, "alice"}, tt.filter}})
+
+			invalid, _ := invalidFilters(err)[filterKey(tt.filter)].(string)
+			if !strings.Contains(invalid, tt.wantErr) {
+				t.Errorf("got %v (%q), want %q", err, invalid, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWhereFilters(t *testing.T) {
+	where, err := filterResource.Where(&Spec{Filters: []Filter{
+		{"name", OpLike, "al"},
+		{"status", OpIn, "1,2"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql, args, err := where.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSql := "(LOWER(name) LIKE ? ESCAPE '!' AND status IN (?,?))"
+	if sql != wantSql {
+		t.Errorf("got %s, want %s", sql, wantSql)
+	}
+
+	if fmt.Sprint(args) != "[%al% 1 2]" {
+		t.Errorf("got args %v, want [%%al%% 1 2]", args)
+	}
+}
+
+// invalidFilters returns the invalid filters of a 400 validation error, nil
+// for any other error
+func invalidFilters(err error) map[string]any {
+	validationErr, ok := err.(*errors.ValidationError)
+	if !ok || validationErr.StatusCode() != http.StatusBadRequest {
+		return nil
+	}
+
+	return validationErr.Errors()
+}
@@ -0,0 +1,164 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parse reads an expression such as "users.read || (users.admin && !users.banned)".
+// Precedence from high to low is !, && then ||, parentheses group.
+func Parse(expr string) (Requirement, error) {
+	p := &parser{expr: expr}
+
+	p.next()
+	requirement, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token != "" {
+		return nil, fmt.Errorf("unexpected '%s' at position %d in permission expression '%s'", p.token, p.start, expr)
+	}
+
+	return requirement, nil
+}
+
+// MustParse is like Parse but panics on invalid expressions, it is meant for
+// route registration so typos fail at startup.
+func MustParse(expr string) Requirement {
+	requirement, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	return requirement
+}
+
+type parser struct {
+	expr  string
+	pos   int
+	start int
+	token string
+}
+
+// next reads the following token, positions are byte offsets and names are
+// decoded rune by rune so they may hold any letter
+func (p *parser) next() {
+	for p.pos < len(p.expr) {
+		r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		p.pos += size
+	}
+
+	p.start = p.pos
+	if p.pos >= len(p.expr) {
+		p.token = ""
+		return
+	}
+
+	rest := p.expr[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, "&&"), strings.HasPrefix(rest, "||"):
+		p.token = rest[:2]
+	case rest[0] == '!' || rest[0] == '(' || rest[0] == ')':
+		p.token = rest[:1]
+	default:
+		end := 0
+		for end < len(rest) {
+			r, size := utf8.DecodeRuneInString(rest[end:])
+			if !isNameChar(r) {
+				break
+			}
+			end += size
+		}
+		if end == 0 {
+			_, end = utf8.DecodeRuneInString(rest)
+		}
+		p.token = rest[:end]
+	}
+
+	p.pos += len(p.token)
+}
+
+func (p *parser) parseOr() (Requirement, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := []Requirement{left}
+	for p.token == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, right)
+	}
+
+	return AnyOf(requirements...), nil
+}
+
+func (p *parser) parseAnd() (Requirement, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := []Requirement{left}
+	for p.token == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, right)
+	}
+
+	return AllOf(requirements...), nil
+}
+
+func (p *parser) parseUnary() (Requirement, error) {
+	switch {
+	case p.token == "!":
+		p.next()
+		requirement, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(requirement), nil
+	case p.token == "(":
+		p.next()
+		requirement, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, fmt.Errorf("missing ')' at position %d in permission expression '%s'", p.start, p.expr)
+		}
+		p.next()
+		return requirement, nil
+	case p.token != "" && startsName(p.token):
+		name := p.token
+		p.next()
+		return Perm(name), nil
+	case p.token == "":
+		return nil, fmt.Errorf("unexpected end of permission expression '%s'", p.expr)
+	default:
+		return nil, fmt.Errorf("unexpected '%s' at position %d in permission expression '%s'", p.token, p.start, p.expr)
+	}
+}
+
+func startsName(token string) bool {
+	r, _ := utf8.DecodeRuneInString(token)
+
+	return isNameChar(r)
+}
+
+func isNameChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-' || r == ':' || r == '*'
+}
@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"single permission", "users.read", "users.read"},
+		{"surrounding spaces", "  users.read\t", "users.read"},
+		{"and", "users.read && roles.read", "users.read && roles.read"},
+		{"or", "users.read || roles.read", "users.read || roles.read"},
+		{"and binds tighter than or", "a || b && c", "a || (b && c)"},
+		{"and binds tighter than or on the left", "a && b || c", "(a && b) || c"},
+		{"not binds tighter than and", "!a && b", "!a && b"},
+		{"parentheses group", "(a || b) && c", "(a || b) && c"},
+		{"not of a group", "!(a || b)", "!(a || b)"},
+		{"double not", "!!a", "!(!a)"},
+		{"nested parentheses", "((a))", "a"},
+		{"chained or", "a || b || c", "a || b || c"},
+		{"no spaces", "a&&(b||!c)", "a && (b || !c)"},
+		{"name characters", "tenants:1.users_admin-all.*", "tenants:1.users_admin-all.*"},
+		{"non-ASCII letters", "rôles.lire && 用户.读", "rôles.lire && 用户.读"},
+		{"non-ASCII space", "a &&　b", "a && b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSatisfied(t *testing.T) {
+	granted := map[string]bool{"a": true, "c": true}
+	has := func(permission string) bool {
+		return granted[permission]
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"!b", true},
+		{"a && b", false},
+		{"a || b", true},
+		{"b || a && c", true},
+		{"(b || a) && !c", false},
+		{"b && a || c", true},
+		{"b && (a || c)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got := MustParse(tt.expr).Satisfied(has)
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr string
+	}{
+		{"empty", "", "unexpected end"},
+		{"blank", "   ", "unexpected end"},
+		{"dangling and", "a &&", "unexpected end"},
+		{"dangling or", "a ||", "unexpected end"},
+		{"dangling not", "!", "unexpected end"},
+		{"leading and", "&& a", "unexpected '&&' at position 0"},
+		{"missing operator", "a b", "unexpected 'b' at position 2"},
+		{"single ampersand", "a & b", "unexpected '&' at position 2"},
+		{"unclosed parenthesis", "(a || b", "missing ')' at position 7"},
+		{"unopened parenthesis", "a)", "unexpected ')' at position 1"},
+		{"empty parentheses", "()", "unexpected ')' at position 1"},
+		{"non-ASCII symbol", "a && ✓", "unexpected '✓' at position 5"},
+		{"invalid UTF-8", "a && \xff", "unexpected '\xff' at position 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil {
+				t.Fatalf("got nil, want an error containing %q", tt.wantErr)
+			}
+
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %q, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMustParsePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("got no panic for a malformed expression")
+		}
+	}()
+
+	MustParse("a &&")
+}
@@ -0,0 +1,36 @@
+package rbac
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	declaredMu sync.Mutex
+	declared   = map[string]bool{}
+)
+
+// Declare records permission names referenced by the application, routes
+// declare theirs through the middlewares when they are registered.
+func Declare(permissions ...string) {
+	declaredMu.Lock()
+	defer declaredMu.Unlock()
+
+	for _, permission := range permissions {
+		declared[permission] = true
+	}
+}
+
+// Declared returns every declared permission name, sorted
+func Declared() []string {
+	declaredMu.Lock()
+	defer declaredMu.Unlock()
+
+	permissions := []string{}
+	for permission := range declared {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+
+	return permissions
+}
@@ -0,0 +1,168 @@
+package rbac
+
+import (
+	"strings"
+)
+
+// Requirement is a boolean expression over permission names
+type Requirement interface {
+	// Satisfied reports whether the requirement holds, has tells whether a
+	// single permission is granted.
+	Satisfied(has func(permission string) bool) bool
+
+	// Permissions lists every permission name referenced by the requirement
+	Permissions() []string
+
+	String() string
+}
+
+// Perm requires a single permission
+func Perm(name string) Requirement {
+	return permRequirement(name)
+}
+
+// AllOf requires every child requirement
+func AllOf(requirements ...Requirement) Requirement {
+	if len(requirements) == 1 {
+		return requirements[0]
+	}
+
+	return allOfRequirement(requirements)
+}
+
+// AnyOf requires at least one child requirement
+func AnyOf(requirements ...Requirement) Requirement {
+	if len(requirements) == 1 {
+		return requirements[0]
+	}
+
+	return anyOfRequirement(requirements)
+}
+
+// Not inverts a requirement
+func Not(requirement Requirement) Requirement {
+	return notRequirement{requirement}
+}
+
+// Unmet returns the failed parts of a requirement, AllOf is expanded so the
+// caller sees which of its children failed.
+func Unmet(requirement Requirement, has func(permission string) bool) []string {
+	if requirement.Satisfied(has) {
+		return []string{}
+	}
+
+	if all, ok := requirement.(allOfRequirement); ok {
+		unmet := []string{}
+		for _, child := range all {
+			unmet = append(unmet, Unmet(child, has)...)
+		}
+
+		return unmet
+	}
+
+	return []string{requirement.String()}
+}
+
+type permRequirement string
+
+func (r permRequirement) Satisfied(has func(string) bool) bool {
+	return has(string(r))
+}
+
+func (r permRequirement) Permissions() []string {
+	return []string{string(r)}
+}
+
+func (r permRequirement) String() string {
+	return string(r)
+}
+
+type allOfRequirement []Requirement
+
+func (r allOfRequirement) Satisfied(has func(string) bool) bool {
+	for _, child := range r {
+		if !child.Satisfied(has) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r allOfRequirement) Permissions() []string {
+	return collectPermissions(r)
+}
+
+func (r allOfRequirement) String() string {
+	return joinRequirements(r, " && ")
+}
+
+type anyOfRequirement []Requirement
+
+func (r anyOfRequirement) Satisfied(has func(string) bool) bool {
+	for _, child := range r {
+		if child.Satisfied(has) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r anyOfRequirement) Permissions() []string {
+	return collectPermissions(r)
+}
+
+func (r anyOfRequirement) String() string {
+	return joinRequirements(r, " || ")
+}
+
+type notRequirement struct {
+	requirement Requirement
+}
+
+func (r notRequirement) Satisfied(has func(string) bool) bool {
+	return !r.requirement.Satisfied(has)
+}
+
+func (r notRequirement) Permissions() []string {
+	return r.requirement.Permissions()
+}
+
+func (r notRequirement) String() string {
+	if _, ok := r.requirement.(permRequirement); ok {
+		return "!" + r.requirement.String()
+	}
+
+	return "!(" + r.requirement.String() + ")"
+}
+
+func collectPermissions(requirements []Requirement) []string {
+	seen := map[string]bool{}
+	permissions := []string{}
+
+	for _, child := range requirements {
+		for _, permission := range child.Permissions() {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	return permissions
+}
+
+func joinRequirements(requirements []Requirement, separator string) string {
+	parts := []string{}
+	for _, child := range requirements {
+		switch child.(type) {
+		case allOfRequirement, anyOfRequirement:
+			parts = append(parts, "("+child.String()+")")
+		default:
+			parts = append(parts, child.String())
+		}
+	}
+
+	return strings.Join(parts, separator)
+}
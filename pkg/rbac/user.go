@@ -0,0 +1,34 @@
+package rbac
+
+import "github.com/Adhiana46/echo-boilerplate/dto"
+
+// Granted evaluates a requirement for a user. Route middlewares and the
+// permission check API both go through here so they never disagree.
+func Granted(user *dto.UserResponseWithID, requirement Requirement) bool {
+	return requirement.Satisfied(UserHas(user))
+}
+
+// UserHas returns the single permission lookup of a user
+func UserHas(user *dto.UserResponseWithID) func(permission string) bool {
+	return func(permission string) bool {
+		if user == nil {
+			return false
+		}
+
+		return user.HasPermission(permission)
+	}
+}
+
+// Missing returns the permissions the user does not hold, in input order
+func Missing(user *dto.UserResponseWithID, permissions []string) []string {
+	has := UserHas(user)
+
+	missing := []string{}
+	for _, permission := range permissions {
+		if !has(permission) {
+			missing = append(missing, permission)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,318 @@
+package txmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DB is the connection of the persistents. Their queries run on the
+// transaction of the context when there is one. Otherwise writes go to the
+// primary and reads to a healthy replica, or to the primary when there is
+// none or when the request wrote within the read-your-writes window.
+//
+// Queries are written with ? placeholders, DB turns them into the ones of its
+// driver.
+type DB struct {
+	*sqlx.DB // the primary
+
+	dialect        Dialect
+	replicas       []*replica
+	next           atomic.Uint64
+	readYourWrites time.Duration
+	stop           chan struct{}
+}
+
+type Options struct {
+	// Dialect is the flavour of SQL the database speaks, Postgres by default
+	Dialect  Dialect
+	Replicas []*sqlx.DB
+	// CheckInterval is the period of the health checks of the replicas
+	CheckInterval time.Duration
+	// ReadYourWrites is how long the reads of a request go to the primary
+	// after it wrote, for the contexts prepared by WithReadYourWrites
+	ReadYourWrites time.Duration
+}
+
+func NewDB(primary *sqlx.DB, opts Options) *DB {
+	if opts.Dialect == "" {
+		opts.Dialect = Postgres
+	}
+
+	db := &DB{
+		DB:             primary,
+		dialect:        opts.Dialect,
+		readYourWrites: opts.ReadYourWrites,
+		stop:           make(chan struct{}),
+	}
+
+	for _, conn := range opts.Replicas {
+		db.replicas = append(db.replicas, &replica{db: conn})
+	}
+
+	if len(db.replicas) > 0 {
+		db.checkReplicas()
+		go db.runHealthChecks(opts.CheckInterval)
+	}
+
+	return db
+}
+
+// Dialect is the flavour of SQL of the database
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// Close stops the health checks and closes the replicas and the primary
+func (db *DB) Close() error {
+	close(db.stop)
+
+	for _, r := range db.replicas {
+		r.db.Close()
+	}
+
+	return db.DB.Close()
+}
+
+// Atomic runs fn with a context whose queries run in a transaction, or in a
+// savepoint of the transaction of ctx when there is one, so that the
+// statements of fn commit or roll back together
+func (db *DB) Atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s := fromContext(ctx); s != nil && s.tx != nil {
+		sp, err := db.BeginTx(ctx, &sql.TxOptions{})
+		if err != nil {
+			return err
+		}
+
+		err = fn(ctx)
+		if err != nil {
+			sp.Rollback()
+			return err
+		}
+
+		return sp.Commit()
+	}
+
+	markWrite(ctx)
+
+	tx, err := db.DB.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	s := &scope{tx: tx}
+
+	err = fn(context.WithValue(ctx, ctxKey{}, s))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	// the hooks wait for the store of ctx when it has one, see Detached
+	for _, hook := range s.afterCommit {
+		AfterCommit(ctx, hook)
+	}
+
+	return nil
+}
+
+// Execer is what Insert runs on, the DB or one of its Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Insert runs the INSERT on conn and returns the key of the new row. Postgres
+// returns it with RETURNING, the drivers of the other dialects report it as
+// the last insert id.
+func (db *DB) Insert(ctx context.Context, conn Execer, key string, query string, args ...any) (int, error) {
+	if db.dialect == Postgres {
+		var id int
+		err := conn.QueryRowContext(ctx, query+" RETURNING "+key, args...).Scan(&id)
+
+		return id, err
+	}
+
+	res, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	return int(id), err
+}
+
+// Tx is a transaction begun by a persistent
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	Commit() error
+	Rollback() error
+}
+
+// conn is what a query runs on, a transaction, a replica or the primary
+type conn interface {
+	sqlx.ExtContext
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction of the context, a replica for reads that
+// may go to one, or the primary
+func (db *DB) conn(ctx context.Context, query string) conn {
+	if s := fromContext(ctx); s != nil && s.tx != nil {
+		return s.tx
+	}
+
+	if !isRead(query) {
+		markWrite(ctx)
+		return db.DB
+	}
+
+	if onPrimary(ctx) || wroteWithin(ctx, db.readYourWrites) {
+		return db.DB
+	}
+
+	if r := db.replica(); r != nil {
+		return r.db
+	}
+
+	return db.DB
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query = db.Rebind(query)
+
+	if s := fromContext(ctx); s != nil && s.tx != nil {
+		return s.tx.ExecContext(ctx, query, args...)
+	}
+
+	markWrite(ctx)
+
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query = db.Rebind(query)
+
+	return db.conn(ctx, query).QueryContext(ctx, query, args...)
+}
+
+func (db *DB) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	query = db.Rebind(query)
+
+	return db.conn(ctx, query).QueryxContext(ctx, query, args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	query = db.Rebind(query)
+
+	return db.conn(ctx, query).QueryRowContext(ctx, query, args...)
+}
+
+func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row {
+	query = db.Rebind(query)
+
+	return db.conn(ctx, query).QueryRowxContext(ctx, query, args...)
+}
+
+func (db *DB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	query = db.Rebind(query)
+
+	return sqlx.GetContext(ctx, db.conn(ctx, query), dest, query, args...)
+}
+
+func (db *DB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	query = db.Rebind(query)
+
+	return sqlx.SelectContext(ctx, db.conn(ctx, query), dest, query, args...)
+}
+
+// BeginTx begins a transaction on the primary, or a savepoint of the
+// transaction of the context so that the work of the persistent commits or
+// rolls back with it
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	s := fromContext(ctx)
+	if s == nil || s.tx == nil {
+		markWrite(ctx)
+
+		tx, err := db.DB.BeginTxx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &boundTx{Tx: tx, db: db}, nil
+	}
+
+	s.savepoints++
+	name := fmt.Sprintf("sp_%d", s.savepoints)
+
+	_, err := s.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &boundTx{Tx: &savepoint{Tx: s.tx, name: name}, db: db}, nil
+}
+
+// boundTx binds the placeholders of the queries run on a Tx as DB does
+type boundTx struct {
+	Tx
+	db *DB
+}
+
+func (t *boundTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.db.Rebind(query), args...)
+}
+
+func (t *boundTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, t.db.Rebind(query), args...)
+}
+
+func (t *boundTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, t.db.Rebind(query), args...)
+}
+
+func (t *boundTx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return t.Tx.GetContext(ctx, dest, t.db.Rebind(query), args...)
+}
+
+func (t *boundTx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return t.Tx.SelectContext(ctx, dest, t.db.Rebind(query), args...)
+}
+
+// savepoint is a Tx nested in the transaction of a context, committing it
+// releases the savepoint and the outer transaction commits the work
+type savepoint struct {
+	*sqlx.Tx
+	name string
+}
+
+func (s *savepoint) Commit() error {
+	_, err := s.Tx.Exec("RELEASE SAVEPOINT " + s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	_, err := s.Tx.Exec("ROLLBACK TO SAVEPOINT " + s.name)
+	return err
+}
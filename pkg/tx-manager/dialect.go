@@ -0,0 +1,11 @@
+package txmanager
+
+// Dialect is the SQL flavour of the database behind a DB, the persistents
+// that run on several databases branch on it where their SQL differs
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+)
@@ -0,0 +1,130 @@
+package txmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// pingTimeout bounds the health check of a replica
+const pingTimeout = 2 * time.Second
+
+type replica struct {
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+// replica picks the next healthy replica round-robin, nil when none is
+func (db *DB) replica() *replica {
+	n := len(db.replicas)
+	start := db.next.Add(1)
+
+	for i := 0; i < n; i++ {
+		r := db.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) runHealthChecks(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			db.checkReplicas()
+		}
+	}
+}
+
+// checkReplicas pings every replica, reads skip the ones that do not answer
+// until they do again
+func (db *DB) checkReplicas() {
+	for i, r := range db.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				logger.Println("[DB]:", fmt.Sprintf("replica %d is up", i))
+			} else {
+				logger.Warnf("[DB]: replica %d is down: %v", i, err)
+			}
+		}
+	}
+}
+
+// writeStatement matches the statements and the locking clauses that must
+// run on the primary
+var writeStatement = regexp.MustCompile(`(?i)\b(insert|update|delete|merge|for\s+share|for\s+key\s+share)\b`)
+
+// isRead reports whether a query only reads and may run on a replica
+func isRead(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if !strings.HasPrefix(query, "select") && !strings.HasPrefix(query, "with") {
+		return false
+	}
+
+	return !writeStatement.MatchString(query)
+}
+
+type primaryKey struct{}
+
+// OnPrimary keeps the reads of the context on the primary, for a write that
+// reads back its rows which a replica may not have yet
+func OnPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func onPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+type writesKey struct{}
+
+// writes is the time of the last write of a request, in unix nanoseconds
+type writes struct {
+	at atomic.Int64
+}
+
+// WithReadYourWrites prepares the context of a request so that, once it
+// wrote, its reads go to the primary for the read-your-writes window
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesKey{}, &writes{})
+}
+
+func markWrite(ctx context.Context) {
+	if w, ok := ctx.Value(writesKey{}).(*writes); ok {
+		w.at.Store(time.Now().UnixNano())
+	}
+}
+
+func wroteWithin(ctx context.Context, window time.Duration) bool {
+	w, ok := ctx.Value(writesKey{}).(*writes)
+	if !ok || window <= 0 {
+		return false
+	}
+
+	at := w.at.Load()
+
+	return at != 0 && time.Since(time.Unix(0, at)) < window
+}
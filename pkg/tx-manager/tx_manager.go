@@ -0,0 +1,179 @@
+package txmanager
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// maxAttempts bounds the runs of a transaction that fails to serialize
+const maxAttempts = 3
+
+// retryDelay is the wait before the second run, it doubles on every retry
+const retryDelay = 20 * time.Millisecond
+
+type ctxKey struct{}
+
+// scope is the transaction carried by a context, savepoints numbers the
+// transactions persistents begin inside of it and afterCommit holds what runs
+// once it commits. tx is nil for the transactions of another store, see
+// Detached.
+type scope struct {
+	tx          *sqlx.Tx
+	savepoints  int
+	afterCommit []func()
+}
+
+type TxManager interface {
+	// WithinTx runs fn in a serializable transaction, the persistents called
+	// with the context fn receives join it. fn is run again when the
+	// transaction fails to serialize so it must not have effects outside of
+	// the database. A nested WithinTx joins the outer transaction.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var (
+	txManagerInstance     *txManager
+	txManagerInstanceOnce sync.Once
+)
+
+type txManager struct {
+	db *DB
+}
+
+func NewTxManager(db *DB) TxManager {
+	txManagerInstanceOnce.Do(func() {
+		txManagerInstance = &txManager{
+			db: db,
+		}
+	})
+
+	return txManagerInstance
+}
+
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if fromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	delay := retryDelay
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = m.run(ctx, fn)
+		if !IsSerializationFailure(err) || attempt == maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	return err
+}
+
+func (m *txManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	markWrite(ctx)
+
+	tx, err := m.db.DB.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	s := &scope{tx: tx}
+
+	err = fn(context.WithValue(ctx, ctxKey{}, s))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range s.afterCommit {
+		hook()
+	}
+
+	return nil
+}
+
+// InTx reports whether the context carries a transaction
+func InTx(ctx context.Context) bool {
+	return fromContext(ctx) != nil
+}
+
+// AfterCommit runs fn once the transaction carried by the context commits, or
+// right away when there is none. A rolled back run drops it.
+func AfterCommit(ctx context.Context, fn func()) {
+	s := fromContext(ctx)
+	if s == nil {
+		fn()
+		return
+	}
+
+	s.afterCommit = append(s.afterCommit, fn)
+}
+
+// Detached returns a context carrying a transaction that another store than
+// DB keeps itself, such as the in-memory one. InTx reports it and AfterCommit
+// holds its hooks until commit runs them, DB ignores it.
+func Detached(ctx context.Context) (txCtx context.Context, commit func()) {
+	s := &scope{}
+
+	return context.WithValue(ctx, ctxKey{}, s), func() {
+		for _, hook := range s.afterCommit {
+			hook()
+		}
+	}
+}
+
+// IsSerializationFailure reports whether the database gave up on a
+// transaction because of a concurrent one, running it again may succeed
+func IsSerializationFailure(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		// serialization_failure and deadlock_detected
+		return pgErr.SQLState() == "40001" || pgErr.SQLState() == "40P01"
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_LOCK_DEADLOCK and ER_LOCK_WAIT_TIMEOUT
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// SQLITE_BUSY and its extended codes, the database stayed locked by
+		// another connection for longer than the busy timeout
+		return sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+	}
+
+	return false
+}
+
+func fromContext(ctx context.Context) *scope {
+	s, _ := ctx.Value(ctxKey{}).(*scope)
+	return s
+}
@@ -2,10 +2,8 @@ package utils
 
 import (
 	"context"
-	"strings"
 
 	"github.com/Adhiana46/echo-boilerplate/dto"
-	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 )
@@ -24,32 +22,63 @@ func GetUserFromContext(ctx context.Context) *dto.UserResponseWithID {
 	return user
 }
 
-// parse sortBy=name.asc,updated_at.desc -> map[string]string
-func QuerySortToMap(sortBy string) (map[string]string, error) {
-	if sortBy == "" {
-		return map[string]string{}, nil
+// GetTenantFromContext returns the active tenant, it is nil outside of a
+// tenant (commands, seeders)
+func GetTenantFromContext(ctx context.Context) *dto.TenantResponseWithID {
+	rawValue := ctx.Value("tenant")
+	if rawValue == nil {
+		return nil
 	}
 
-	result := map[string]string{}
-	raws := strings.Split(sortBy, ",")
-	for _, raw := range raws {
-		chunks := strings.Split(raw, ".")
+	tenant, ok := rawValue.(*dto.TenantResponseWithID)
+	if !ok {
+		return nil
+	}
 
-		if len(chunks) != 2 {
-			return nil, errors.NewBadRequestError("malformed sortBy query parameter, should be field.orderdirection")
-		}
+	return tenant
+}
 
-		field, order := chunks[0], chunks[1]
-		order = strings.ToLower(order)
+// WithTenant returns a copy of ctx with the active tenant set
+func WithTenant(ctx context.Context, tenant *dto.TenantResponseWithID) context.Context {
+	return context.WithValue(ctx, "tenant", tenant)
+}
 
-		if order != "asc" && order != "desc" {
-			return nil, errors.NewBadRequestError("malformed orderdirection in sortBy query parameter, should be asc or desc")
-		}
+// GetTenantIdFromContext returns the id of the active tenant or 0
+func GetTenantIdFromContext(ctx context.Context) int {
+	tenant := GetTenantFromContext(ctx)
+	if tenant == nil {
+		return 0
+	}
+
+	return tenant.ID
+}
+
+// RequestInfo identifies the HTTP request behind a change, for the audit log
+type RequestInfo struct {
+	Id        string
+	IP        string
+	UserAgent string
+}
 
-		result[field] = order
+// WithRequestInfo returns a copy of ctx with the request set
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, "request", info)
+}
+
+// GetRequestInfoFromContext returns the request, it is nil outside of HTTP
+// requests (commands, seeders)
+func GetRequestInfoFromContext(ctx context.Context) *RequestInfo {
+	rawValue := ctx.Value("request")
+	if rawValue == nil {
+		return nil
+	}
+
+	info, ok := rawValue.(*RequestInfo)
+	if !ok {
+		return nil
 	}
 
-	return result, nil
+	return info
 }
 
 func ValidationErrors(validationErrs validator.ValidationErrors, trans *ut.Translator) map[string][]string {
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+)
+
+// ETag is the entity tag of a version of a resource
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch reads the version of an If-Match header, ok is false when the
+// header is missing and version is 0 for If-Match: *
+func ParseIfMatch(header string) (version int, ok bool, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false, nil
+	}
+
+	if header == "*" {
+		return 0, true, nil
+	}
+
+	version, err = strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version <= 0 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		return 0, false, errors.NewBadRequestError("Invalid If-Match header, should be the ETag of the resource")
+	}
+
+	return version, true, nil
+}
+
+// CheckVersion ensures an update is based on the current version of the
+// resource, ifMatch tells whether expected came from If-Match (0 then
+// matches any version) or from the body
+func CheckVersion(current int, expected int, ifMatch bool) error {
+	if ifMatch && expected == 0 {
+		return nil
+	}
+
+	if expected == 0 {
+		return errors.NewPreconditionRequiredError("An If-Match header or a version is required to update this resource")
+	}
+
+	if expected != current {
+		return errors.NewVersionConflictError(current, ifMatch)
+	}
+
+	return nil
+}
@@ -12,18 +12,33 @@ import (
 	"time"
 
 	"github.com/Adhiana46/echo-boilerplate/config"
+	"github.com/Adhiana46/echo-boilerplate/constants"
+	"github.com/Adhiana46/echo-boilerplate/dto"
+	"github.com/Adhiana46/echo-boilerplate/entity"
+	auditHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/audit/delivery/http"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	grantHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/grant/delivery/http"
+	groupHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/group/delivery/http"
+	"github.com/Adhiana46/echo-boilerplate/internal/permission"
 	permissionHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/permission/delivery/http"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
 	roleHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/role/delivery/http"
+	tenantHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/tenant/delivery/http"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
 	userHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/user/delivery/http"
 	cachePkg "github.com/Adhiana46/echo-boilerplate/pkg/cache"
 	"github.com/Adhiana46/echo-boilerplate/pkg/errors"
+	"github.com/Adhiana46/echo-boilerplate/pkg/event"
 	"github.com/Adhiana46/echo-boilerplate/pkg/logger"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
 	m "github.com/Adhiana46/echo-boilerplate/pkg/middlewares"
+	"github.com/Adhiana46/echo-boilerplate/pkg/queryspec"
+	"github.com/Adhiana46/echo-boilerplate/pkg/rbac"
 	tokenmanager "github.com/Adhiana46/echo-boilerplate/pkg/token-manager"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/Adhiana46/echo-boilerplate/pkg/utils"
 	validatorPkg "github.com/Adhiana46/echo-boilerplate/pkg/validator"
 	"github.com/go-playground/validator/v10"
-	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -31,17 +46,30 @@ import (
 type Server struct {
 	e            *echo.Echo
 	cfg          *config.Config
-	db           *sqlx.DB
+	db           *txmanager.DB
+	mem          *memdb.DB
 	cache        cachePkg.Cache
 	tokenManager *tokenmanager.TokenManager
 
+	// usecases
+	grantUsecase      grant.GrantUsecase
+	permissionUsecase permission.PermissionUsecase
+	roleUsecase       role.RoleUsecase
+	userUsecase       user.UserUsecase
+
 	// handlers
+	auditLogHandler   auditHttpHandler.Handler
+	grantHandler      grantHttpHandler.Handler
+	groupHandler      groupHttpHandler.Handler
 	permissionHandler permissionHttpHandler.Handler
 	roleHandler       roleHttpHandler.Handler
+	tenantHandler     tenantHttpHandler.Handler
 	userHandler       userHttpHandler.Handler
 }
 
-func NewServer(cfg *config.Config, db *sqlx.DB, cache cachePkg.Cache, tokenManager *tokenmanager.TokenManager) *Server {
+// NewServer builds the server on the SQL database in db, or on mem when db is
+// nil
+func NewServer(cfg *config.Config, db *txmanager.DB, mem *memdb.DB, cache cachePkg.Cache, tokenManager *tokenmanager.TokenManager) *Server {
 	// Validator
 	validate := validator.New()
 	// register function to get tag name from json tags.
@@ -55,6 +83,9 @@ func NewServer(cfg *config.Config, db *sqlx.DB, cache cachePkg.Cache, tokenManag
 
 	e := echo.New()
 	e.Validator = validatorPkg.NewEchoValidator(validate)
+	// the client IP of the audit log is only taken from X-Forwarded-For when
+	// the request comes through a trusted proxy
+	e.IPExtractor = ipExtractor(cfg.Http)
 
 	// error handler
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
@@ -109,22 +140,52 @@ func NewServer(cfg *config.Config, db *sqlx.DB, cache cachePkg.Cache, tokenManag
 		c.JSON(statusCode, resp)
 	}
 
+	// list cursors
+	cursorSecret := cfg.Pagination.CursorSecret
+	if cursorSecret == "" {
+		cursorSecret = cfg.JWT.SecretKey
+	}
+	queryspec.SetCursorSecret(cursorSecret)
+	queryspec.SetMaxLimit(cfg.Pagination.MaxLimit)
+
 	e.Pre(middleware.AddTrailingSlash())
 
 	// Middlewares
+	e.Use(middleware.RequestID())
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			requestId := c.Response().Header().Get(echo.HeaderXRequestID)
+
 			logger.WithFields(logger.Fields{
-				"at":     time.Now().Format("2006-01-02 15:04:05"),
-				"method": c.Request().Method,
-				"uri":    c.Request().URL.String(),
-				"ip":     c.Request().RemoteAddr,
+				"at":         time.Now().Format("2006-01-02 15:04:05"),
+				"method":     c.Request().Method,
+				"uri":        c.Request().URL.String(),
+				"ip":         c.Request().RemoteAddr,
+				"request_id": requestId,
 			}).Info("incoming request")
 
+			// the audit log records who changed what from where
+			c.SetRequest(c.Request().WithContext(utils.WithRequestInfo(c.Request().Context(), &utils.RequestInfo{
+				Id:        requestId,
+				IP:        c.RealIP(),
+				UserAgent: c.Request().UserAgent(),
+			})))
+
 			return next(c)
 		}
 	})
 
+	// once a request wrote, its reads skip the replicas for a while
+	if cfg.Pg.ReadYourWrites > 0 {
+		e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.SetRequest(c.Request().WithContext(txmanager.WithReadYourWrites(c.Request().Context())))
+
+				return next(c)
+			}
+		})
+	}
+
 	e.GET("/", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Hello, World!")
 	})
@@ -133,16 +194,39 @@ func NewServer(cfg *config.Config, db *sqlx.DB, cache cachePkg.Cache, tokenManag
 		e:            e,
 		cfg:          cfg,
 		db:           db,
+		mem:          mem,
 		cache:        cache,
 		tokenManager: tokenManager,
 	}
 
 	srv.setupHttpHandler()
 	srv.setupRoutes()
+	srv.setupListeners()
 
 	return srv
 }
 
+// ipExtractor reads the client IP from the X-Forwarded-For entries added by
+// the trusted proxies, or from the connection when there are none. The
+// proxies were validated with the config.
+func ipExtractor(cfg config.HttpConfig) echo.IPExtractor {
+	nets, _ := cfg.TrustedNets()
+	if len(nets) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, ipNet := range nets {
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
 func (s *Server) Run() error {
 	log.Println("[Server]:", fmt.Sprintf("Running server on %s:%s", s.cfg.Http.Host, s.cfg.Http.Port))
 	return s.e.Start(fmt.Sprintf("%s:%s", s.cfg.Http.Host, s.cfg.Http.Port))
@@ -152,36 +236,212 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.e.Shutdown(ctx)
 }
 
+// SyncPermissions creates the permissions declared by the routes and the
+// policies that are missing in the database
+func (s *Server) SyncPermissions(ctx context.Context) (*dto.SyncPermissionsResponse, error) {
+	return s.permissionUsecase.SyncPermissions(ctx, rbac.Declared())
+}
+
+// VerifyPermissions compares the declared permissions with the database, it
+// logs the differences or fails depending on app.permission_check
+func (s *Server) VerifyPermissions(ctx context.Context) error {
+	if s.cfg.App.PermissionCheck == "off" {
+		return nil
+	}
+
+	res, err := s.permissionUsecase.VerifyPermissions(ctx, rbac.Declared())
+	if err != nil {
+		return err
+	}
+
+	if len(res.Unknown) > 0 {
+		logger.Warn("[Permission]:", "unknown permissions referenced by routes, run permissions:sync:", strings.Join(res.Unknown, ", "))
+	}
+	if len(res.Orphaned) > 0 {
+		logger.Warn("[Permission]:", "orphaned permissions not referenced anywhere:", strings.Join(res.Orphaned, ", "))
+	}
+
+	if s.cfg.App.PermissionCheck == "fail" && (len(res.Unknown) > 0 || len(res.Orphaned) > 0) {
+		return fmt.Errorf("permission check failed: %d unknown, %d orphaned", len(res.Unknown), len(res.Orphaned))
+	}
+
+	return nil
+}
+
+// SweepExpiredGrants removes the role grants expired for longer than
+// grant.retention
+func (s *Server) SweepExpiredGrants(ctx context.Context) (int, error) {
+	return s.grantUsecase.SweepExpired(ctx, time.Now().Add(-s.cfg.Grant.Retention))
+}
+
+// PurgeTrash removes for good the users, roles and permissions deleted for
+// longer than trash.retention. Users go first since their memberships may
+// hold the purged roles.
+func (s *Server) PurgeTrash(ctx context.Context) (*dto.PurgeTrashResponse, error) {
+	before := time.Now().Add(-s.cfg.Trash.Retention)
+
+	users, err := s.userUsecase.PurgeTrashed(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleUsecase.PurgeTrashed(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.permissionUsecase.PurgeTrashed(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PurgeTrashResponse{
+		Users:       users,
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+// RunGrantSweeper sweeps expired role grants every grant.sweep_interval until
+// the context is done
+func (s *Server) RunGrantSweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Grant.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SweepExpiredGrants(ctx); err != nil {
+				logger.Error("[Grant]:", "sweeping expired grants failed:", err)
+			}
+		}
+	}
+}
+
 func (s *Server) setupHttpHandler() {
-	s.permissionHandler = InitializedPermissionHandler(s.db, s.cache, s.tokenManager)
-	s.roleHandler = InitializedRoleHandler(s.db, s.cache, s.tokenManager)
-	s.userHandler = InitializedUserHandler(s.db, s.cache, s.tokenManager)
+	if s.db == nil {
+		s.setupMemoryHttpHandler()
+		return
+	}
+
+	s.grantUsecase = InitializedGrantUsecase(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.permissionUsecase = InitializedPermissionUsecase(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.roleUsecase = InitializedRoleUsecase(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.userUsecase = InitializedUserUsecase(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+
+	s.auditLogHandler = InitializedAuditLogHandler(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.grantHandler = InitializedGrantHandler(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.groupHandler = InitializedGroupHandler(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.permissionHandler = InitializedPermissionHandler(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.roleHandler = InitializedRoleHandler(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.tenantHandler = InitializedTenantHandler(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.userHandler = InitializedUserHandler(s.db, s.cache, &s.cfg.Cache, s.tokenManager)
+}
+
+func (s *Server) setupMemoryHttpHandler() {
+	s.grantUsecase = InitializedMemoryGrantUsecase(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.permissionUsecase = InitializedMemoryPermissionUsecase(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.roleUsecase = InitializedMemoryRoleUsecase(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.userUsecase = InitializedMemoryUserUsecase(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+
+	s.auditLogHandler = InitializedMemoryAuditLogHandler(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.grantHandler = InitializedMemoryGrantHandler(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.groupHandler = InitializedMemoryGroupHandler(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.permissionHandler = InitializedMemoryPermissionHandler(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.roleHandler = InitializedMemoryRoleHandler(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.tenantHandler = InitializedMemoryTenantHandler(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
+	s.userHandler = InitializedMemoryUserHandler(s.mem, s.cache, &s.cfg.Cache, s.tokenManager)
 }
 
 func (s *Server) setupRoutes() {
+	groupAuditLog := s.e.Group("/api/v1/audit-logs", m.Authenticate(s.tokenManager))
+	groupAuditLog.GET("/", s.auditLogHandler.GetAll(), m.Permissions("audit-logs.read"))
+
+	groupGroup := s.e.Group("/api/v1/groups", m.Authenticate(s.tokenManager))
+	groupGroup.POST("/", s.groupHandler.Store(), m.Permissions("groups.create"))
+	groupGroup.PUT("/:uuid", s.groupHandler.Update(), m.Permissions("groups.update"))
+	groupGroup.DELETE("/:uuid", s.groupHandler.Delete(), m.Permissions("groups.delete"))
+	groupGroup.GET("/:uuid", s.groupHandler.GetByUuid(), m.Permissions("groups.read"))
+	groupGroup.GET("/", s.groupHandler.GetAll(), m.Permissions("groups.read"))
+	groupGroup.GET("/:uuid/users/", s.groupHandler.GetUsers(), m.Permissions("groups.read"))
+	groupGroup.POST("/:uuid/users/", s.groupHandler.AddUsers(), m.Permissions("groups.members"))
+	groupGroup.DELETE("/:uuid/users/", s.groupHandler.RemoveUsers(), m.Permissions("groups.members"))
+	groupGroup.POST("/:uuid/roles/", s.groupHandler.GrantRoles(), m.Permissions("groups.roles.grant"))
+	groupGroup.DELETE("/:uuid/roles/:name", s.groupHandler.RevokeRole(), m.Permissions("groups.roles.revoke"))
+
 	groupPermission := s.e.Group("/api/v1/permissions", m.Authenticate(s.tokenManager))
 	groupPermission.POST("/", s.permissionHandler.Store(), m.Permissions("permissions.create"))
-	groupPermission.PUT("/:uuid", (s.permissionHandler.Update()), m.Permissions("permissions.update"))
-	groupPermission.DELETE("/:uuid", s.permissionHandler.Delete(), m.Permissions("permissions.delete"))
-	groupPermission.GET("/:uuid", s.permissionHandler.GetByUuid(), m.Permissions("permissions.read"))
+	groupPermission.PUT("/:uuid", s.permissionHandler.Update())            // authorized by the permission policy
+	groupPermission.DELETE("/:uuid", s.permissionHandler.Delete())         // authorized by the permission policy
+	groupPermission.POST("/:uuid/restore/", s.permissionHandler.Restore()) // authorized by the permission policy
+	groupPermission.GET("/:uuid", s.permissionHandler.GetByUuid())         // authorized by the permission policy
 	groupPermission.GET("/", s.permissionHandler.GetAll(), m.Permissions("permissions.read"))
 
 	groupRole := s.e.Group("/api/v1/roles", m.Authenticate(s.tokenManager))
 	groupRole.POST("/", s.roleHandler.Store(), m.Permissions("roles.create"))
-	groupRole.PUT("/:uuid", s.roleHandler.Update(), m.Permissions("roles.update"))
-	groupRole.DELETE("/:uuid", s.roleHandler.Delete(), m.Permissions("roles.delete"))
-	groupRole.GET("/:uuid", s.roleHandler.GetByUuid(), m.Permissions("roles.read"))
+	groupRole.PUT("/:uuid", s.roleHandler.Update())            // authorized by the role policy
+	groupRole.DELETE("/:uuid", s.roleHandler.Delete())         // authorized by the role policy
+	groupRole.POST("/:uuid/restore/", s.roleHandler.Restore()) // authorized by the role policy
+	groupRole.GET("/:uuid", s.roleHandler.GetByUuid())         // authorized by the role policy
 	groupRole.GET("/", s.roleHandler.GetAll(), m.Permissions("roles.read"))
+	groupRole.POST("/:uuid/permissions/", s.roleHandler.GrantPermissions())        // authorized by the role policy
+	groupRole.DELETE("/:uuid/permissions/:name", s.roleHandler.RevokePermission()) // authorized by the role policy
+	groupRole.POST("/:uuid/users/", s.roleHandler.AssignUsers())                   // authorized by the role policy
+	groupRole.DELETE("/:uuid/users/", s.roleHandler.UnassignUsers())               // authorized by the role policy
+
+	groupTenant := s.e.Group("/api/v1/tenants", m.Authenticate(s.tokenManager))
+	groupTenant.POST("/", s.tenantHandler.Store(), m.Permissions("tenants.create"))
+	groupTenant.PUT("/:uuid", s.tenantHandler.Update(), m.Permissions("tenants.update"))
+	groupTenant.DELETE("/:uuid", s.tenantHandler.Delete(), m.Permissions("tenants.delete"))
+	groupTenant.GET("/:uuid", s.tenantHandler.GetByUuid(), m.Permissions("tenants.read"))
+	groupTenant.GET("/", s.tenantHandler.GetAll(), m.Permissions("tenants.read"))
+	groupTenant.POST("/:uuid/users/", s.tenantHandler.AddUsers(), m.Permissions("tenants.users"))
+	groupTenant.DELETE("/:uuid/users/", s.tenantHandler.RemoveUsers(), m.Permissions("tenants.users"))
 
 	groupUser := s.e.Group("/api/v1/users", m.Authenticate(s.tokenManager))
 	groupUser.POST("/", s.userHandler.Store(), m.Permissions("users.create"))
-	groupUser.PUT("/:uuid", s.userHandler.Update(), m.Permissions("users.update"))
-	groupUser.DELETE("/:uuid", s.userHandler.Delete(), m.Permissions("users.delete"))
-	groupUser.GET("/:uuid", s.userHandler.GetByUuid(), m.Permissions("users.read"))
+	groupUser.PUT("/:uuid", s.userHandler.Update())            // authorized by the user policy
+	groupUser.DELETE("/:uuid", s.userHandler.Delete())         // authorized by the user policy
+	groupUser.POST("/:uuid/restore/", s.userHandler.Restore()) // authorized by the user policy
+	groupUser.GET("/:uuid", s.userHandler.GetByUuid())         // authorized by the user policy
 	groupUser.GET("/", s.userHandler.GetAll(), m.Permissions("users.read"))
+	groupUser.GET("/search/", s.userHandler.Search(), m.Permissions("users.read"))
+	groupUser.GET("/:uuid/permissions/:name", s.userHandler.ExplainPermission(), m.Permissions("users.read", "roles.read"))
+	groupUser.POST("/:uuid/grants/", s.grantHandler.Store(), m.Permissions("roles.assign"))
+	groupUser.DELETE("/:uuid/grants/:grant", s.grantHandler.Delete(), m.Permissions("roles.assign"))
+
+	groupCache := s.e.Group("/api/v1/cache", m.Authenticate(s.tokenManager))
+	groupCache.GET("/stats/", s.cacheStats(), m.Permissions("cache.read"))
 
 	groupAuth := s.e.Group("/api/v1/auth")
 	groupAuth.POST("/signin/", s.userHandler.SignIn())
 	groupAuth.POST("/signout/", s.userHandler.SignOut())
 	groupAuth.POST("/refresh-token/", s.userHandler.RefreshToken())
+	groupAuth.POST("/switch-tenant/", s.userHandler.SwitchTenant(), m.Authenticate(s.tokenManager))
+	groupAuth.POST("/permissions/check/", s.userHandler.CheckPermissions(), m.Authenticate(s.tokenManager))
+}
+
+// cacheStats reports the hits and misses of the entities read through the
+// cache since the server started
+func (s *Server) cacheStats() func(echo.Context) error {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, utils.JsonSuccess(http.StatusOK, "", cachePkg.CollectStats(), nil))
+	}
+}
+
+func (s *Server) setupListeners() {
+	event.Subscribe(constants.EVENT_ROLE_GRANT_EXPIRED, func(ctx context.Context, e event.Event) {
+		expired := e.Payload.(*entity.RoleGrant)
+
+		logger.WithFields(logger.Fields{
+			"at":          e.OccurredAt.Format("2006-01-02 15:04:05"),
+			"grant":       expired.Uuid,
+			"tenant_id":   expired.TenantId,
+			"user_id":     expired.UserId,
+			"role_id":     expired.RoleId,
+			"valid_until": expired.ValidUntil.Format("2006-01-02 15:04:05"),
+		}).Info("role grant expired")
+	})
 }
@@ -4,62 +4,258 @@
 package server
 
 import (
+	"github.com/Adhiana46/echo-boilerplate/config"
+	auditData "github.com/Adhiana46/echo-boilerplate/internal/audit/data"
+	auditHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/audit/delivery/http"
+	auditRepo "github.com/Adhiana46/echo-boilerplate/internal/audit/repository"
+	auditUsecase "github.com/Adhiana46/echo-boilerplate/internal/audit/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	grantData "github.com/Adhiana46/echo-boilerplate/internal/grant/data"
+	grantHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/grant/delivery/http"
+	grantRepo "github.com/Adhiana46/echo-boilerplate/internal/grant/repository"
+	grantUsecase "github.com/Adhiana46/echo-boilerplate/internal/grant/usecase"
+	groupData "github.com/Adhiana46/echo-boilerplate/internal/group/data"
+	groupHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/group/delivery/http"
+	groupRepo "github.com/Adhiana46/echo-boilerplate/internal/group/repository"
+	groupUsecase "github.com/Adhiana46/echo-boilerplate/internal/group/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/permission"
 	permissionData "github.com/Adhiana46/echo-boilerplate/internal/permission/data"
 	permissionHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/permission/delivery/http"
 	permissionRepo "github.com/Adhiana46/echo-boilerplate/internal/permission/repository"
 	permissionUsecase "github.com/Adhiana46/echo-boilerplate/internal/permission/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
 	roleData "github.com/Adhiana46/echo-boilerplate/internal/role/data"
 	roleHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/role/delivery/http"
 	roleRepo "github.com/Adhiana46/echo-boilerplate/internal/role/repository"
 	roleUsecase "github.com/Adhiana46/echo-boilerplate/internal/role/usecase"
+	tenantData "github.com/Adhiana46/echo-boilerplate/internal/tenant/data"
+	tenantHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/tenant/delivery/http"
+	tenantRepo "github.com/Adhiana46/echo-boilerplate/internal/tenant/repository"
+	tenantUsecase "github.com/Adhiana46/echo-boilerplate/internal/tenant/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
 	userData "github.com/Adhiana46/echo-boilerplate/internal/user/data"
 	userHttpHandler "github.com/Adhiana46/echo-boilerplate/internal/user/delivery/http"
 	userRepo "github.com/Adhiana46/echo-boilerplate/internal/user/repository"
 	userUsecase "github.com/Adhiana46/echo-boilerplate/internal/user/usecase"
 	cachePkg "github.com/Adhiana46/echo-boilerplate/pkg/cache"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
 	tokenmanager "github.com/Adhiana46/echo-boilerplate/pkg/token-manager"
+	txmanager "github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/google/wire"
-	"github.com/jmoiron/sqlx"
 )
 
 var ProviderSet = wire.NewSet(
 	// Http Handler
+	auditHttpHandler.NewAuditLogHttpHandler,
+	grantHttpHandler.NewGrantHttpHandler,
+	groupHttpHandler.NewGroupHttpHandler,
 	permissionHttpHandler.NewPermissionHttpHandler,
 	roleHttpHandler.NewRoleHttpHandler,
+	tenantHttpHandler.NewTenantHttpHandler,
 	userHttpHandler.NewUserHttpHandler,
 
 	// Usecase
+	auditUsecase.NewAuditLogUsecase,
+	grantUsecase.NewGrantUsecase,
+	groupUsecase.NewGroupUsecase,
 	permissionUsecase.NewPermissionUsecase,
 	roleUsecase.NewRoleUsecase,
+	tenantUsecase.NewTenantUsecase,
 	userUsecase.NewUserUsecase,
 
 	// Repository
+	auditRepo.NewAuditLogRepository,
+	grantRepo.NewGrantRepository,
+	groupRepo.NewGroupRepository,
 	permissionRepo.NewPermissionRepository,
 	roleRepo.NewRoleRepository,
+	tenantRepo.NewTenantRepository,
 	userRepo.NewUserRepository,
 	userRepo.NewUserDeviceRepository,
+)
 
+// SqlSet provides the data sources backed by Postgres, MySQL or SQLite
+var SqlSet = wire.NewSet(
 	// Data Source
-	permissionData.NewPostgresPermissionPersistent,
-	roleData.NewPostgresRolePersistent,
-	userData.NewPostgresUserPersistent,
-	userData.NewPostgresUserDevicePersistent,
+	auditData.NewSqlAuditLogPersistent,
+	grantData.NewSqlGrantPersistent,
+	groupData.NewSqlGroupPersistent,
+	permissionData.NewSqlPermissionPersistent,
+	roleData.NewSqlRolePersistent,
+	tenantData.NewSqlTenantPersistent,
+	userData.NewSqlUserPersistent,
+	userData.NewSqlUserDevicePersistent,
+
+	// Transaction
+	txmanager.NewTxManager,
 )
 
-func InitializedPermissionHandler(db *sqlx.DB, cache cachePkg.Cache, tokenManager *tokenmanager.TokenManager) permissionHttpHandler.Handler {
+// MemorySet provides the data sources kept in memory, for DB_DRIVER=memory
+var MemorySet = wire.NewSet(
+	// Data Source
+	auditData.NewMemoryAuditLogPersistent,
+	grantData.NewMemoryGrantPersistent,
+	groupData.NewMemoryGroupPersistent,
+	permissionData.NewMemoryPermissionPersistent,
+	roleData.NewMemoryRolePersistent,
+	tenantData.NewMemoryTenantPersistent,
+	userData.NewMemoryUserPersistent,
+	userData.NewMemoryUserDevicePersistent,
+
+	// Transaction
+	memdb.NewTxManager,
+)
+
+func InitializedAuditLogHandler(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) auditHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedGrantHandler(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) grantHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedGrantUsecase(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) grant.GrantUsecase {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedGroupHandler(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) groupHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedPermissionHandler(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) permissionHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedPermissionUsecase(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) permission.PermissionUsecase {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedRoleHandler(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) roleHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedRoleUsecase(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) role.RoleUsecase {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedTenantHandler(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) tenantHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedUserHandler(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) userHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedUserUsecase(db *txmanager.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) user.UserUsecase {
+	panic(wire.Build(
+		ProviderSet,
+		SqlSet,
+	))
+}
+
+func InitializedMemoryAuditLogHandler(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) auditHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryGrantHandler(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) grantHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryGrantUsecase(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) grant.GrantUsecase {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryGroupHandler(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) groupHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryPermissionHandler(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) permissionHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryPermissionUsecase(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) permission.PermissionUsecase {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryRoleHandler(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) roleHttpHandler.Handler {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryRoleUsecase(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) role.RoleUsecase {
+	panic(wire.Build(
+		ProviderSet,
+		MemorySet,
+	))
+}
+
+func InitializedMemoryTenantHandler(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) tenantHttpHandler.Handler {
 	panic(wire.Build(
 		ProviderSet,
+		MemorySet,
 	))
 }
 
-func InitializedRoleHandler(db *sqlx.DB, cache cachePkg.Cache, tokenManager *tokenmanager.TokenManager) roleHttpHandler.Handler {
+func InitializedMemoryUserHandler(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) userHttpHandler.Handler {
 	panic(wire.Build(
 		ProviderSet,
+		MemorySet,
 	))
 }
 
-func InitializedUserHandler(db *sqlx.DB, cache cachePkg.Cache, tokenManager *tokenmanager.TokenManager) userHttpHandler.Handler {
+func InitializedMemoryUserUsecase(db *memdb.DB, cache cachePkg.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) user.UserUsecase {
 	panic(wire.Build(
 		ProviderSet,
+		MemorySet,
 	))
 }
@@ -7,56 +7,404 @@
 package server
 
 import (
-	"github.com/Adhiana46/echo-boilerplate/internal/permission/data"
-	"github.com/Adhiana46/echo-boilerplate/internal/permission/delivery/http"
-	"github.com/Adhiana46/echo-boilerplate/internal/permission/repository"
-	"github.com/Adhiana46/echo-boilerplate/internal/permission/usecase"
-	data2 "github.com/Adhiana46/echo-boilerplate/internal/role/data"
-	http2 "github.com/Adhiana46/echo-boilerplate/internal/role/delivery/http"
+	"github.com/Adhiana46/echo-boilerplate/config"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit/data"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit/delivery/http"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit/repository"
+	"github.com/Adhiana46/echo-boilerplate/internal/audit/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/grant"
+	data5 "github.com/Adhiana46/echo-boilerplate/internal/grant/data"
+	http2 "github.com/Adhiana46/echo-boilerplate/internal/grant/delivery/http"
+	repository4 "github.com/Adhiana46/echo-boilerplate/internal/grant/repository"
+	usecase2 "github.com/Adhiana46/echo-boilerplate/internal/grant/usecase"
+	data4 "github.com/Adhiana46/echo-boilerplate/internal/group/data"
+	http3 "github.com/Adhiana46/echo-boilerplate/internal/group/delivery/http"
+	repository5 "github.com/Adhiana46/echo-boilerplate/internal/group/repository"
+	usecase3 "github.com/Adhiana46/echo-boilerplate/internal/group/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/permission"
+	data6 "github.com/Adhiana46/echo-boilerplate/internal/permission/data"
+	http4 "github.com/Adhiana46/echo-boilerplate/internal/permission/delivery/http"
+	repository6 "github.com/Adhiana46/echo-boilerplate/internal/permission/repository"
+	usecase4 "github.com/Adhiana46/echo-boilerplate/internal/permission/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/role"
+	data3 "github.com/Adhiana46/echo-boilerplate/internal/role/data"
+	http5 "github.com/Adhiana46/echo-boilerplate/internal/role/delivery/http"
 	repository2 "github.com/Adhiana46/echo-boilerplate/internal/role/repository"
-	usecase2 "github.com/Adhiana46/echo-boilerplate/internal/role/usecase"
-	data3 "github.com/Adhiana46/echo-boilerplate/internal/user/data"
-	http3 "github.com/Adhiana46/echo-boilerplate/internal/user/delivery/http"
+	usecase5 "github.com/Adhiana46/echo-boilerplate/internal/role/usecase"
+	data7 "github.com/Adhiana46/echo-boilerplate/internal/tenant/data"
+	http6 "github.com/Adhiana46/echo-boilerplate/internal/tenant/delivery/http"
+	repository7 "github.com/Adhiana46/echo-boilerplate/internal/tenant/repository"
+	usecase6 "github.com/Adhiana46/echo-boilerplate/internal/tenant/usecase"
+	"github.com/Adhiana46/echo-boilerplate/internal/user"
+	data2 "github.com/Adhiana46/echo-boilerplate/internal/user/data"
+	http7 "github.com/Adhiana46/echo-boilerplate/internal/user/delivery/http"
 	repository3 "github.com/Adhiana46/echo-boilerplate/internal/user/repository"
-	usecase3 "github.com/Adhiana46/echo-boilerplate/internal/user/usecase"
+	usecase7 "github.com/Adhiana46/echo-boilerplate/internal/user/usecase"
 	"github.com/Adhiana46/echo-boilerplate/pkg/cache"
+	"github.com/Adhiana46/echo-boilerplate/pkg/memdb"
 	"github.com/Adhiana46/echo-boilerplate/pkg/token-manager"
+	"github.com/Adhiana46/echo-boilerplate/pkg/tx-manager"
 	"github.com/google/wire"
-	"github.com/jmoiron/sqlx"
 )
 
 // Injectors from wire.go:
 
-func InitializedPermissionHandler(db *sqlx.DB, cache2 cache.Cache, tokenManager *tokenmanager.TokenManager) http.Handler {
-	permissionPersistent := data.NewPostgresPermissionPersistent(db)
-	permissionRepository := repository.NewPermissionRepository(permissionPersistent)
-	permissionUsecase := usecase.NewPermissionUsecase(permissionRepository)
-	handler := http.NewPermissionHttpHandler(permissionUsecase)
+func InitializedAuditLogHandler(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http.Handler {
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogUsecase := usecase.NewAuditLogUsecase(auditLogRepository, userRepository)
+	handler := http.NewAuditLogHttpHandler(auditLogUsecase)
 	return handler
 }
 
-func InitializedRoleHandler(db *sqlx.DB, cache2 cache.Cache, tokenManager *tokenmanager.TokenManager) http2.Handler {
-	rolePersistent := data2.NewPostgresRolePersistent(db)
-	roleRepository := repository2.NewRoleRepository(rolePersistent)
-	permissionPersistent := data.NewPostgresPermissionPersistent(db)
-	permissionRepository := repository.NewPermissionRepository(permissionPersistent)
-	roleUsecase := usecase2.NewRoleUsecase(roleRepository, permissionRepository)
-	handler := http2.NewRoleHttpHandler(roleUsecase)
+func InitializedGrantHandler(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http2.Handler {
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	grantRepository := repository4.NewGrantRepository(grantPersistent)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	grantUsecase := usecase2.NewGrantUsecase(grantRepository, roleRepository, userRepository, auditLogRepository, txManager)
+	handler := http2.NewGrantHttpHandler(grantUsecase)
 	return handler
 }
 
-func InitializedUserHandler(db *sqlx.DB, cache2 cache.Cache, tokenManager *tokenmanager.TokenManager) http3.Handler {
-	userPersistent := data3.NewPostgresUserPersistent(db)
-	rolePersistent := data2.NewPostgresRolePersistent(db)
-	userRepository := repository3.NewUserRepository(userPersistent, rolePersistent)
-	roleRepository := repository2.NewRoleRepository(rolePersistent)
-	userDevicePersistent := data3.NewPostgresUserDevicePersistent(db)
+func InitializedGrantUsecase(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) grant.GrantUsecase {
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	grantRepository := repository4.NewGrantRepository(grantPersistent)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	grantUsecase := usecase2.NewGrantUsecase(grantRepository, roleRepository, userRepository, auditLogRepository, txManager)
+	return grantUsecase
+}
+
+func InitializedGroupHandler(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http3.Handler {
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	groupRepository := repository5.NewGroupRepository(groupPersistent)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	groupUsecase := usecase3.NewGroupUsecase(groupRepository, roleRepository, userRepository)
+	handler := http3.NewGroupHttpHandler(groupUsecase)
+	return handler
+}
+
+func InitializedPermissionHandler(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http4.Handler {
+	permissionPersistent := data6.NewSqlPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	permissionUsecase := usecase4.NewPermissionUsecase(permissionRepository, userRepository, auditLogRepository, txManager)
+	handler := http4.NewPermissionHttpHandler(permissionUsecase)
+	return handler
+}
+
+func InitializedPermissionUsecase(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) permission.PermissionUsecase {
+	permissionPersistent := data6.NewSqlPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	permissionUsecase := usecase4.NewPermissionUsecase(permissionRepository, userRepository, auditLogRepository, txManager)
+	return permissionUsecase
+}
+
+func InitializedRoleHandler(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http5.Handler {
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	permissionPersistent := data6.NewSqlPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	roleUsecase := usecase5.NewRoleUsecase(roleRepository, permissionRepository, userRepository, auditLogRepository, txManager)
+	handler := http5.NewRoleHttpHandler(roleUsecase)
+	return handler
+}
+
+func InitializedRoleUsecase(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) role.RoleUsecase {
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	permissionPersistent := data6.NewSqlPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	roleUsecase := usecase5.NewRoleUsecase(roleRepository, permissionRepository, userRepository, auditLogRepository, txManager)
+	return roleUsecase
+}
+
+func InitializedTenantHandler(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http6.Handler {
+	tenantPersistent := data7.NewSqlTenantPersistent(db)
+	tenantRepository := repository7.NewTenantRepository(tenantPersistent, cache2)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewSqlUserPersistent(db)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	tenantUsecase := usecase6.NewTenantUsecase(tenantRepository, roleRepository, userRepository)
+	handler := http6.NewTenantHttpHandler(tenantUsecase)
+	return handler
+}
+
+func InitializedUserHandler(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http7.Handler {
+	userPersistent := data2.NewSqlUserPersistent(db)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	tenantPersistent := data7.NewSqlTenantPersistent(db)
+	tenantRepository := repository7.NewTenantRepository(tenantPersistent, cache2)
+	userDevicePersistent := data2.NewSqlUserDevicePersistent(db)
+	userDeviceRepository := repository3.NewUserDeviceRepository(userDevicePersistent)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	userUsecase := usecase7.NewUserUsecase(userRepository, roleRepository, tenantRepository, userDeviceRepository, auditLogRepository, tokenManager, txManager)
+	handler := http7.NewUserHttpHandler(userUsecase)
+	return handler
+}
+
+func InitializedUserUsecase(db *txmanager.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) user.UserUsecase {
+	userPersistent := data2.NewSqlUserPersistent(db)
+	rolePersistent := data3.NewSqlRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewSqlGroupPersistent(db)
+	grantPersistent := data5.NewSqlGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	tenantPersistent := data7.NewSqlTenantPersistent(db)
+	tenantRepository := repository7.NewTenantRepository(tenantPersistent, cache2)
+	userDevicePersistent := data2.NewSqlUserDevicePersistent(db)
+	userDeviceRepository := repository3.NewUserDeviceRepository(userDevicePersistent)
+	auditLogPersistent := data.NewSqlAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := txmanager.NewTxManager(db)
+	userUsecase := usecase7.NewUserUsecase(userRepository, roleRepository, tenantRepository, userDeviceRepository, auditLogRepository, tokenManager, txManager)
+	return userUsecase
+}
+
+func InitializedMemoryAuditLogHandler(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http.Handler {
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogUsecase := usecase.NewAuditLogUsecase(auditLogRepository, userRepository)
+	handler := http.NewAuditLogHttpHandler(auditLogUsecase)
+	return handler
+}
+
+func InitializedMemoryGrantHandler(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http2.Handler {
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	grantRepository := repository4.NewGrantRepository(grantPersistent)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	grantUsecase := usecase2.NewGrantUsecase(grantRepository, roleRepository, userRepository, auditLogRepository, txManager)
+	handler := http2.NewGrantHttpHandler(grantUsecase)
+	return handler
+}
+
+func InitializedMemoryGrantUsecase(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) grant.GrantUsecase {
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	grantRepository := repository4.NewGrantRepository(grantPersistent)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	grantUsecase := usecase2.NewGrantUsecase(grantRepository, roleRepository, userRepository, auditLogRepository, txManager)
+	return grantUsecase
+}
+
+func InitializedMemoryGroupHandler(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http3.Handler {
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	groupRepository := repository5.NewGroupRepository(groupPersistent)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	groupUsecase := usecase3.NewGroupUsecase(groupRepository, roleRepository, userRepository)
+	handler := http3.NewGroupHttpHandler(groupUsecase)
+	return handler
+}
+
+func InitializedMemoryPermissionHandler(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http4.Handler {
+	permissionPersistent := data6.NewMemoryPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	permissionUsecase := usecase4.NewPermissionUsecase(permissionRepository, userRepository, auditLogRepository, txManager)
+	handler := http4.NewPermissionHttpHandler(permissionUsecase)
+	return handler
+}
+
+func InitializedMemoryPermissionUsecase(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) permission.PermissionUsecase {
+	permissionPersistent := data6.NewMemoryPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	permissionUsecase := usecase4.NewPermissionUsecase(permissionRepository, userRepository, auditLogRepository, txManager)
+	return permissionUsecase
+}
+
+func InitializedMemoryRoleHandler(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http5.Handler {
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	permissionPersistent := data6.NewMemoryPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	roleUsecase := usecase5.NewRoleUsecase(roleRepository, permissionRepository, userRepository, auditLogRepository, txManager)
+	handler := http5.NewRoleHttpHandler(roleUsecase)
+	return handler
+}
+
+func InitializedMemoryRoleUsecase(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) role.RoleUsecase {
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	permissionPersistent := data6.NewMemoryPermissionPersistent(db)
+	permissionRepository := repository6.NewPermissionRepository(permissionPersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	roleUsecase := usecase5.NewRoleUsecase(roleRepository, permissionRepository, userRepository, auditLogRepository, txManager)
+	return roleUsecase
+}
+
+func InitializedMemoryTenantHandler(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http6.Handler {
+	tenantPersistent := data7.NewMemoryTenantPersistent(db)
+	tenantRepository := repository7.NewTenantRepository(tenantPersistent, cache2)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	tenantUsecase := usecase6.NewTenantUsecase(tenantRepository, roleRepository, userRepository)
+	handler := http6.NewTenantHttpHandler(tenantUsecase)
+	return handler
+}
+
+func InitializedMemoryUserHandler(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) http7.Handler {
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	tenantPersistent := data7.NewMemoryTenantPersistent(db)
+	tenantRepository := repository7.NewTenantRepository(tenantPersistent, cache2)
+	userDevicePersistent := data2.NewMemoryUserDevicePersistent(db)
 	userDeviceRepository := repository3.NewUserDeviceRepository(userDevicePersistent)
-	userUsecase := usecase3.NewUserUsecase(userRepository, roleRepository, userDeviceRepository, tokenManager)
-	handler := http3.NewUserHttpHandler(userUsecase)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	userUsecase := usecase7.NewUserUsecase(userRepository, roleRepository, tenantRepository, userDeviceRepository, auditLogRepository, tokenManager, txManager)
+	handler := http7.NewUserHttpHandler(userUsecase)
 	return handler
 }
 
+func InitializedMemoryUserUsecase(db *memdb.DB, cache2 cache.Cache, cacheCfg *config.CacheConfig, tokenManager *tokenmanager.TokenManager) user.UserUsecase {
+	userPersistent := data2.NewMemoryUserPersistent(db)
+	rolePersistent := data3.NewMemoryRolePersistent(db)
+	roleRepository := repository2.NewRoleRepository(rolePersistent, cache2, cacheCfg)
+	groupPersistent := data4.NewMemoryGroupPersistent(db)
+	grantPersistent := data5.NewMemoryGrantPersistent(db)
+	userRepository := repository3.NewUserRepository(userPersistent, roleRepository, groupPersistent, grantPersistent, cache2, cacheCfg)
+	tenantPersistent := data7.NewMemoryTenantPersistent(db)
+	tenantRepository := repository7.NewTenantRepository(tenantPersistent, cache2)
+	userDevicePersistent := data2.NewMemoryUserDevicePersistent(db)
+	userDeviceRepository := repository3.NewUserDeviceRepository(userDevicePersistent)
+	auditLogPersistent := data.NewMemoryAuditLogPersistent(db)
+	auditLogRepository := repository.NewAuditLogRepository(auditLogPersistent)
+	txManager := memdb.NewTxManager(db)
+	userUsecase := usecase7.NewUserUsecase(userRepository, roleRepository, tenantRepository, userDeviceRepository, auditLogRepository, tokenManager, txManager)
+	return userUsecase
+}
+
 // wire.go:
 
-var ProviderSet = wire.NewSet(http.NewPermissionHttpHandler, http2.NewRoleHttpHandler, http3.NewUserHttpHandler, usecase.NewPermissionUsecase, usecase2.NewRoleUsecase, usecase3.NewUserUsecase, repository.NewPermissionRepository, repository2.NewRoleRepository, repository3.NewUserRepository, repository3.NewUserDeviceRepository, data.NewPostgresPermissionPersistent, data2.NewPostgresRolePersistent, data3.NewPostgresUserPersistent, data3.NewPostgresUserDevicePersistent)
+var ProviderSet = wire.NewSet(http.NewAuditLogHttpHandler, http2.NewGrantHttpHandler, http3.NewGroupHttpHandler, http4.NewPermissionHttpHandler, http5.NewRoleHttpHandler, http6.NewTenantHttpHandler, http7.NewUserHttpHandler, usecase.NewAuditLogUsecase, usecase2.NewGrantUsecase, usecase3.NewGroupUsecase, usecase4.NewPermissionUsecase, usecase5.NewRoleUsecase, usecase6.NewTenantUsecase, usecase7.NewUserUsecase, repository.NewAuditLogRepository, repository4.NewGrantRepository, repository5.NewGroupRepository, repository6.NewPermissionRepository, repository2.NewRoleRepository, repository7.NewTenantRepository, repository3.NewUserRepository, repository3.NewUserDeviceRepository)
+
+// SqlSet provides the data sources backed by Postgres, MySQL or SQLite
+var SqlSet = wire.NewSet(data.NewSqlAuditLogPersistent, data5.NewSqlGrantPersistent, data4.NewSqlGroupPersistent, data6.NewSqlPermissionPersistent, data3.NewSqlRolePersistent, data7.NewSqlTenantPersistent, data2.NewSqlUserPersistent, data2.NewSqlUserDevicePersistent, txmanager.NewTxManager)
+
+// MemorySet provides the data sources kept in memory, for DB_DRIVER=memory
+var MemorySet = wire.NewSet(data.NewMemoryAuditLogPersistent, data5.NewMemoryGrantPersistent, data4.NewMemoryGroupPersistent, data6.NewMemoryPermissionPersistent, data3.NewMemoryRolePersistent, data7.NewMemoryTenantPersistent, data2.NewMemoryUserPersistent, data2.NewMemoryUserDevicePersistent, memdb.NewTxManager)